@@ -26,10 +26,15 @@ EOF
 cap_prefix="-cap_"
 caps="$cap_prefix$(seq -s ",$cap_prefix" 0 $(cat /proc/sys/kernel/cap_last_cap))"
 
-# TODO: check mode
-if [ "$MODE" = "json-rpc" ] || [ "$MODE" = "json-rpc-native" ]
+# validate the configuration (config file and environment variables) before starting anything
+signal-cli-rest-api config validate
+mode=$(signal-cli-rest-api config get mode)
+
+if [ "$mode" = "json-rpc" ] || [ "$mode" = "json-rpc-native" ]
 then
 /usr/bin/jsonrpc2-helper
+# allow the REST API to stop and start the signal-cli daemon (e.g. while an account is exported)
+sed -i 's/^chmod=0700.*/chmod=0770\nchown=root:signal-api/' /etc/supervisor/supervisord.conf
 if [ -n "$JAVA_OPTS" ] ; then
     echo "export JAVA_OPTS='$JAVA_OPTS'" >> /etc/default/supervisor
 fi
@@ -1,27 +1,16 @@
-local http = require("http")
 local json = require("json")
+local signal = require("signal")
 
 function exec()
-	local url = "http://127.0.0.1:8080/v2/send"
 	local customEndpointPayload = json.decode(pluginInputData.payload)
-	local sendEndpointPayload = {
-		recipients = {customEndpointPayload.recipient},
-		message = customEndpointPayload.message,
-		number = pluginInputData.Params.number
-	}
 
-	local encodedSendEndpointPayload = json.encode(sendEndpointPayload)
-	print(encodedSendEndpointPayload)
+	local response, error_message = signal.send(pluginInputData.Params.number, customEndpointPayload.recipient, customEndpointPayload.message)
+	if error_message ~= nil then
+		pluginOutputData:SetPayload(json.encode({error = error_message}))
+		pluginOutputData:SetHttpStatusCode(400)
+		return
+	end
 
-	response, error_message = http.request("POST", url, {
-		timeout="30s",
-		headers={
-			Accept="*/*",
-			["Content-Type"]="application/json"
-		},
-		body=encodedSendEndpointPayload
-	})
-
-	pluginOutputData:SetPayload(response["body"])
-	pluginOutputData:SetHttpStatusCode(response.status_code)
+	pluginOutputData:SetPayload(json.encode(response))
+	pluginOutputData:SetHttpStatusCode(201)
 end
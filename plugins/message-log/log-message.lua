@@ -0,0 +1,12 @@
+function on_message(envelope, account)
+	if envelope.dataMessage == nil or envelope.dataMessage.message == nil then
+		return
+	end
+
+	local sender = envelope.sourceNumber or envelope.sourceUuid
+	local groupId = ""
+	if envelope.dataMessage.groupInfo ~= nil then
+		groupId = " in group " .. envelope.dataMessage.groupInfo.groupId
+	end
+	print(account .. " received a message from " .. tostring(sender) .. groupId .. ": " .. envelope.dataMessage.message)
+end
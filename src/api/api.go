@@ -2,10 +2,14 @@ package api
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -75,12 +79,43 @@ type ChangeGroupAdminsRequest struct {
 	Admins []string `json:"admins"`
 }
 
+type SyncGroupsRequest struct {
+	DryRun bool                  `json:"dry_run"`
+	Groups []client.DesiredGroup `json:"groups"`
+}
+
+type DenyGroupJoinRequestsRequest struct {
+	Members []string `json:"members"`
+	Ban     bool     `json:"ban"`
+}
+
+type UpdateGroupInviteLinkRequest struct {
+	State string `json:"state,omitempty" enums:"disabled,enabled,enabled-with-approval"`
+	Reset bool   `json:"reset"`
+}
+
+type GroupInviteLinkResponse struct {
+	InviteLink string `json:"invite_link"`
+}
+
 type LoggingConfiguration struct {
 	Level string `json:"Level"`
 }
 
 type Configuration struct {
-	Logging LoggingConfiguration `json:"logging"`
+	Logging                 LoggingConfiguration               `json:"logging"`
+	ReceiveWebhookUrl       *string                            `json:"receive_webhook_url,omitempty"`
+	DefaultSignalTextMode   *string                            `json:"default_signal_text_mode,omitempty" enums:"normal,styled"`
+	AutoReceiveSchedule     *utils.AutoReceiveScheduleSettings `json:"auto_receive_schedule,omitempty"`
+	AttachmentRetentionDays *int                               `json:"attachment_retention_days,omitempty"`
+	TrustModes              map[string]string                  `json:"trust_modes,omitempty"`
+	Reset                   []string                           `json:"reset,omitempty"`
+}
+
+type ConfigurationResponse struct {
+	Logging    LoggingConfiguration          `json:"logging"`
+	Settings   map[string]utils.SettingValue `json:"settings"`
+	TrustModes map[string]string             `json:"trust_modes"`
 }
 
 type RegisterNumberRequest struct {
@@ -97,6 +132,32 @@ type VerifyNumberSettings struct {
 	Pin string `json:"pin,omitempty"`
 }
 
+type StartOnboardingRequest struct {
+	Type       string `json:"type" enums:"link,register"`
+	DeviceName string `json:"device_name,omitempty"`
+	Number     string `json:"number,omitempty"`
+	UseVoice   bool   `json:"use_voice,omitempty"`
+	Captcha    string `json:"captcha,omitempty"`
+}
+
+type ExportAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type ImportAccountResponse struct {
+	Number string `json:"number"`
+}
+
+type OnboardingRegisterRequest struct {
+	UseVoice bool   `json:"use_voice,omitempty"`
+	Captcha  string `json:"captcha,omitempty"`
+}
+
+type OnboardingVerifyRequest struct {
+	Token string `json:"token"`
+	Pin   string `json:"pin,omitempty"`
+}
+
 type SendReactionRequest struct {
 	Recipient    string `json:"recipient"`
 	Reaction     string `json:"reaction"`
@@ -142,6 +203,7 @@ type SendMessageV2 struct {
 	NotifySelf        *bool               `json:"notify_self,omitempty"`
 	LinkPreview       *ds.LinkPreviewType `json:"link_preview,omitempty"`
 	ViewOnce          *bool               `json:"view_once,omitempty"`
+	Signature         *string             `json:"signature,omitempty"`
 }
 
 type TypingIndicatorRequest struct {
@@ -181,8 +243,14 @@ type TrustModeRequest struct {
 	TrustMode string `json:"trust_mode"`
 }
 
-type TrustModeResponse struct {
+type AccountSettingsRequest struct {
+	TrustMode *string `json:"trust_mode,omitempty" enums:"on-first-use,always,never"`
+	utils.AccountSettings
+}
+
+type AccountSettingsResponse struct {
 	TrustMode string `json:"trust_mode"`
+	utils.AccountSettings
 }
 
 var connectionUpgrader = websocket.Upgrader{
@@ -259,9 +327,45 @@ type ClosePollRequest struct {
 	PollTimestamp string `json:"poll_timestamp" example:"1769271479"`
 }
 
+type BroadcastRequest struct {
+	Number            string              `json:"number"`
+	Recipients        []string            `json:"recipients" example:"<phone number>,<username>,<group id>"`
+	Message           string              `json:"message"`
+	Base64Attachments []string            `json:"base64_attachments,omitempty" example:"<BASE64 ENCODED DATA>,data:<MIME-TYPE>;base64<comma><BASE64 ENCODED DATA>,data:<MIME-TYPE>;filename=<FILENAME>;base64<comma><BASE64 ENCODED DATA>"`
+	Sticker           string              `json:"sticker,omitempty"`
+	Mentions          []ds.MessageMention `json:"mentions,omitempty"`
+	TextMode          *string             `json:"text_mode,omitempty" enums:"normal,styled"`
+	NotifySelf        *bool               `json:"notify_self,omitempty"`
+	LinkPreview       *ds.LinkPreviewType `json:"link_preview,omitempty"`
+	ViewOnce          *bool               `json:"view_once,omitempty"`
+}
+
+type BroadcastResponse struct {
+	Results []ds.BroadcastResult `json:"results"`
+}
+
+type CreateSignedUrlRequest struct {
+	Path      string `json:"path" example:"/v1/attachments/<attachment id> OR /v1/contacts/<number>/<uuid>/avatar OR /v1/groups/<number>/<group id>/avatar"`
+	ExpiresIn int64  `json:"expires_in,omitempty" example:"3600"`
+}
+
+type SignedUrlResponse struct {
+	Url       string `json:"url"`
+	ExpiresAt int64  `json:"expires_at" example:"1769271479"`
+}
+
+var signableUrlPaths = []*regexp.Regexp{
+	regexp.MustCompile(`^/v1/attachments/[^/]+$`),
+	regexp.MustCompile(`^/v1/contacts/[^/]+/[^/]+/avatar$`),
+	regexp.MustCompile(`^/v1/groups/[^/]+/[^/]+/avatar$`),
+}
+
 type Api struct {
-	signalClient *client.SignalClient
-	wsMutex      sync.Mutex
+	signalClient     *client.SignalClient
+	wsMutex          sync.Mutex
+	idempotencyStore *utils.IdempotencyStore
+	pluginManager    *utils.PluginManager
+	settingsStore    *utils.SettingsStore
 }
 
 func NewApi(signalClient *client.SignalClient) *Api {
@@ -270,6 +374,101 @@ func NewApi(signalClient *client.SignalClient) *Api {
 	}
 }
 
+func (a *Api) SetSettingsStore(settingsStore *utils.SettingsStore) {
+	a.settingsStore = settingsStore
+}
+
+func (a *Api) settings() utils.Settings {
+	if a.settingsStore == nil {
+		return utils.DefaultSettings()
+	}
+	return a.settingsStore.Get()
+}
+
+func (a *Api) SetIdempotencyStore(idempotencyStore *utils.IdempotencyStore) {
+	a.idempotencyStore = idempotencyStore
+}
+
+func (a *Api) SetPluginManager(pluginManager *utils.PluginManager) {
+	a.pluginManager = pluginManager
+}
+
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent returns a middleware that honors the Idempotency-Key header: the
+// response of a successful request is stored and replayed for every retry with
+// the same key, instead of executing the request again. Failed requests release
+// the key again. Note that this includes signal-cli timeouts, although the
+// message might have been sent nevertheless - a retry then sends it again.
+func (a *Api) Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader("Idempotency-Key")
+		if idempotencyKey == "" || a.idempotencyStore == nil {
+			c.Next()
+			return
+		}
+
+		if len(idempotencyKey) > 255 {
+			c.AbortWithStatusJSON(400, Error{Msg: "Couldn't process request - Idempotency-Key must not be longer than 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(400, Error{Msg: "Couldn't process request - invalid request"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := sha256.Sum256(body)
+
+		key := c.Request.Method + " " + c.Request.URL.Path + " " + idempotencyKey
+		entry, err := a.idempotencyStore.Begin(key, hex.EncodeToString(requestHash[:]))
+		if err != nil {
+			c.AbortWithStatusJSON(422, Error{Msg: err.Error()})
+			return
+		}
+
+		if entry != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(entry.StatusCode, entry.ContentType, entry.Body)
+			c.Abort()
+			return
+		}
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			// if the handler panicked or didn't write a response, the status code
+			// isn't meaningful (gin reports 200 until something is written)
+			statusCode := writer.Status()
+			if completed && writer.Written() && statusCode >= 200 && statusCode < 300 {
+				a.idempotencyStore.Complete(key, &utils.IdempotencyEntry{StatusCode: statusCode, ContentType: writer.Header().Get("Content-Type"), Body: writer.body.Bytes()})
+			} else {
+				a.idempotencyStore.Complete(key, nil)
+			}
+		}()
+		c.Next()
+		// responses without body (e.g. 204) are otherwise only written by gin
+		// after all handlers are done
+		writer.WriteHeaderNow()
+		completed = true
+	}
+}
+
 // @Summary Lists general information about the API
 // @Tags General
 // @Description Returns the supported API versions and the internal build nr
@@ -468,7 +667,9 @@ func (a *Api) VerifyRegisteredNumber(c *gin.Context) {
 // @Produce  json
 // @Success 201 {string} string "OK"
 // @Failure 400 {object} Error
+// @Failure 403 {object} Error
 // @Param data body SendMessageV1 true "Input Data"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
 // @Router /v1/send [post]
 // @Deprecated
 func (a *Api) Send(c *gin.Context) {
@@ -486,6 +687,10 @@ func (a *Api) Send(c *gin.Context) {
 
 	resp, err := a.signalClient.SendV1(req.Number, req.Message, req.Recipients, base64Attachments, req.IsGroup)
 	if err != nil {
+		if _, ok := err.(*client.SendBlockedError); ok {
+			c.JSON(403, Error{Msg: err.Error()})
+			return
+		}
 		c.JSON(400, Error{Msg: err.Error()})
 		return
 	}
@@ -494,12 +699,14 @@ func (a *Api) Send(c *gin.Context) {
 
 // @Summary Send a signal message.
 // @Tags Messages
-// @Description Send a signal message. Set the text_mode to 'styled' in case you want to add formatting to your text message. Styling Options: \*italic text\*, \*\*bold text\*\*, ~strikethrough text~, ||spoiler||, \`monospace\`. If you want to escape a formatting character, prefix it with two backslashes.
+// @Description Send a signal message. Set the text_mode to 'styled' in case you want to add formatting to your text message. Styling Options: \*italic text\*, \*\*bold text\*\*, ~strikethrough text~, ||spoiler||, \`monospace\`. If you want to escape a formatting character, prefix it with two backslashes. The text_mode, notify_self and signature default to the account specific settings (see '/v1/configuration/{number}/settings'), set the signature to an empty string to send a message without signature.
 // @Accept  json
 // @Produce  json
 // @Success 201 {object} ds.SendMessageResponse
 // @Failure 400 {object} SendMessageError
+// @Failure 403 {object} Error
 // @Param data body SendMessageV2 true "Input Data"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
 // @Router /v2/send [post]
 func (a *Api) SendV2(c *gin.Context) {
 	var req SendMessageV2
@@ -532,24 +739,22 @@ func (a *Api) SendV2(c *gin.Context) {
 		return
 	}
 
-	textMode := req.TextMode
-	if textMode == nil {
-		defaultSignalTextMode := utils.GetEnv("DEFAULT_SIGNAL_TEXT_MODE", "normal")
-		if defaultSignalTextMode == "styled" {
-			styledStr := "styled"
-			textMode = &styledStr
-		}
-	}
-
 	if req.ViewOnce != nil && *req.ViewOnce && (len(req.Base64Attachments) == 0) {
 		c.JSON(400, Error{Msg: "'view_once' can only be set for image attachments!"})
 		return
 	}
 
+	accountSettings := a.signalClient.GetAccountSettings(req.Number)
+	textMode, notifySelf := a.sendDefaults(accountSettings, req.TextMode, req.NotifySelf)
+	signature := req.Signature
+	if signature == nil {
+		signature = accountSettings.Signature
+	}
+
 	data, err := a.signalClient.SendV2(
-		req.Number, req.Message, req.Recipients, req.Base64Attachments, req.Sticker,
+		req.Number, appendSignature(req.Message, signature), req.Recipients, req.Base64Attachments, req.Sticker,
 		req.Mentions, req.QuoteTimestamp, req.QuoteAuthor, req.QuoteMessage, req.QuoteMentions,
-		textMode, req.EditTimestamp, req.NotifySelf, req.LinkPreview, req.ViewOnce)
+		textMode, req.EditTimestamp, notifySelf, req.LinkPreview, req.ViewOnce)
 	if err != nil {
 		switch err.(type) {
 		case *client.RateLimitErrorType:
@@ -561,18 +766,101 @@ func (a *Api) SendV2(c *gin.Context) {
 				c.JSON(400, Error{Msg: err.Error()})
 				return
 			}
+		case *client.SendBlockedError:
+			c.JSON(403, Error{Msg: err.Error()})
+			return
 		default:
 			c.JSON(400, Error{Msg: err.Error()})
 			return
 		}
+	}
+
+	c.JSON(201, data)
+}
+
+// @Summary Send a signal message to many recipients and groups at once.
+// @Tags Messages
+// @Description Send the same signal message to any mix of phone numbers, usernames and groups. The attachments are only decoded and stored once. However, signal-cli can't reuse already uploaded attachments, so they are uploaded again for every group, once for all phone numbers and once for all usernames. The message is sent to the recipients in parallel. The result of every recipient is reported individually - check the 'success' flag of each result, as some recipients might fail while others succeed. In case a recipient is rate limited, the challenge tokens that can be used to lift the rate limit restrictions via the '/v1/accounts/{number}/rate-limit-challenge' endpoint are attached to its result.
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} BroadcastResponse
+// @Failure 400 {object} Error
+// @Param data body BroadcastRequest true "Input Data"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
+// @Router /v2/broadcast [post]
+func (a *Api) Broadcast(c *gin.Context) {
+	var req BroadcastRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return
+	}
+
+	if len(req.Recipients) == 0 {
+		c.JSON(400, Error{Msg: "Couldn't process request - please provide at least one recipient"})
+		return
+	}
+
+	if req.Number == "" {
+		c.JSON(400, Error{Msg: "Couldn't process request - please provide a valid number"})
+		return
+	}
+
+	if req.Sticker != "" && !strings.Contains(req.Sticker, ":") {
+		c.JSON(400, Error{Msg: "Couldn't process request - please provide valid sticker delimiter"})
+		return
+	}
+
+	if req.ViewOnce != nil && *req.ViewOnce && (len(req.Base64Attachments) == 0) {
+		c.JSON(400, Error{Msg: "'view_once' can only be set for image attachments!"})
+		return
+	}
+
+	accountSettings := a.signalClient.GetAccountSettings(req.Number)
+	textMode, notifySelf := a.sendDefaults(accountSettings, req.TextMode, req.NotifySelf)
+
+	signalCliSendRequest := ds.SignalCliSendRequest{Number: req.Number, Message: appendSignature(req.Message, accountSettings.Signature),
+		Recipients: req.Recipients, Base64Attachments: req.Base64Attachments, Sticker: req.Sticker, Mentions: req.Mentions, TextMode: textMode,
+		NotifySelf: notifySelf, LinkPreview: req.LinkPreview, ViewOnce: req.ViewOnce}
+	results, err := a.signalClient.Broadcast(signalCliSendRequest, a.settings().BroadcastMaxConcurrency)
+	if err != nil {
 		c.JSON(400, Error{Msg: err.Error()})
 		return
 	}
 
-	c.JSON(201, data)
+	c.JSON(201, BroadcastResponse{Results: results})
 }
 
-func (a *Api) handleSignalReceive(ws *websocket.Conn, number string, stop chan struct{}) {
+// sendDefaults returns the text mode and the notify self flag of a send request.
+// Values that the request doesn't specify are taken from the account settings
+// (or the global settings).
+func (a *Api) sendDefaults(accountSettings utils.AccountSettings, textMode *string, notifySelf *bool) (*string, *bool) {
+	if textMode == nil {
+		textMode = accountSettings.TextMode
+	}
+	if textMode == nil {
+		if a.settings().DefaultSignalTextMode == "styled" {
+			styledStr := "styled"
+			textMode = &styledStr
+		}
+	}
+
+	if notifySelf == nil {
+		notifySelf = accountSettings.NotifySelf
+	}
+	return textMode, notifySelf
+}
+
+// appendSignature appends the signature (e.g. a footer) of the account to the
+// message. Messages without text (e.g. stickers) are left untouched.
+func appendSignature(message string, signature *string) string {
+	if message == "" || signature == nil || *signature == "" {
+		return message
+	}
+	return message + "\n" + *signature
+}
+
+func (a *Api) handleSignalReceive(ws *websocket.Conn, number string, normalize bool, stop chan struct{}) {
 	receiveChannel, channelUuid, err := a.signalClient.GetReceiveChannel()
 	if err != nil {
 		log.Error("Couldn't get receive channel: ", err.Error())
@@ -605,6 +893,20 @@ func (a *Api) handleSignalReceive(ws *websocket.Conn, number string, stop chan s
 					}
 
 					if response.Account == number {
+						if normalize {
+							normalizedMsg, err := client.NormalizeReceivedMessageJson([]byte(data))
+							if err != nil {
+								log.Error("Couldn't normalize message ", data, ":", err.Error())
+								continue
+							}
+							normalizedMsgBytes, err := json.Marshal(normalizedMsg)
+							if err != nil {
+								log.Error("Couldn't serialize message: ", err.Error())
+								continue
+							}
+							data = string(normalizedMsgBytes)
+						}
+
 						a.wsMutex.Lock()
 						err = ws.WriteMessage(websocket.TextMessage, []byte(data))
 						if err != nil {
@@ -692,9 +994,33 @@ func StringToBool(input string) bool {
 // @Param ignore_avatars query string false "Specify whether avatar downloads should be ignored when receiving messages" (default: false)"
 // @Param ignore_stickers query string false "Specify whether sticker pack downloads should be ignored when receiving messages" (default: false)"
 // @Param max_messages query string false "Specify the maximum number of messages to receive (default: unlimited)". Not available in json-rpc mode.
-// @Param send_read_receipts query string false "Specify whether read receipts should be sent when receiving messages" (default: false)"
+// @Param send_read_receipts query string false "Specify whether read receipts should be sent when receiving messages" (default: the send_read_receipts setting of the account or false)"
 // @Router /v1/receive/{number} [get]
 func (a *Api) Receive(c *gin.Context) {
+	a.receive(c, false)
+}
+
+// @Summary Receive Signal Messages in the normalized format.
+// @Tags Messages
+// @Description Receives Signal Messages from the Signal Network and returns them in a normalized format (snake_case fields, group ids in the REST API format and attachments resolved to download URLs). If you are running the docker container in normal/native mode, this is a GET endpoint. In json-rpc mode this is a websocket endpoint.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []data.ReceivedMessage
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param timeout query string false "Receive timeout in seconds (default: 1)"
+// @Param ignore_attachments query string false "Specify whether the attachments of the received message should be ignored" (default: false)"
+// @Param ignore_stories query string false "Specify whether stories should be ignored when receiving messages" (default: false)"
+// @Param ignore_avatars query string false "Specify whether avatar downloads should be ignored when receiving messages" (default: false)"
+// @Param ignore_stickers query string false "Specify whether sticker pack downloads should be ignored when receiving messages" (default: false)"
+// @Param max_messages query string false "Specify the maximum number of messages to receive (default: unlimited)". Not available in json-rpc mode.
+// @Param send_read_receipts query string false "Specify whether read receipts should be sent when receiving messages" (default: the send_read_receipts setting of the account or false)"
+// @Router /v2/receive/{number} [get]
+func (a *Api) ReceiveV2(c *gin.Context) {
+	a.receive(c, true)
+}
+
+func (a *Api) receive(c *gin.Context, normalize bool) {
 	number, err := url.PathUnescape(c.Param("number"))
 	if err != nil {
 		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
@@ -709,7 +1035,7 @@ func (a *Api) Receive(c *gin.Context) {
 		}
 		defer ws.Close()
 		var stop = make(chan struct{})
-		go a.handleSignalReceive(ws, number, stop)
+		go a.handleSignalReceive(ws, number, normalize, stop)
 		go a.wsPing(ws, stop)
 		wsPong(ws, stop)
 	} else {
@@ -751,12 +1077,24 @@ func (a *Api) Receive(c *gin.Context) {
 			return
 		}
 
-		sendReadReceipts := c.DefaultQuery("send_read_receipts", "false")
+		defaultSendReadReceipts := a.signalClient.GetAccountSettings(number).SendReadReceipts
+		sendReadReceipts := c.DefaultQuery("send_read_receipts", strconv.FormatBool(defaultSendReadReceipts != nil && *defaultSendReadReceipts))
 		if sendReadReceipts != "true" && sendReadReceipts != "false" {
 			c.JSON(400, Error{Msg: "Couldn't process request - send_read_receipts parameter needs to be either 'true' or 'false'"})
 			return
 		}
 
+		if normalize {
+			messages, err := a.signalClient.ReceiveV2(number, timeoutInt, StringToBool(ignoreAttachments), StringToBool(ignoreStories), StringToBool(ignoreAvatars), StringToBool(ignoreStickers), maxMessagesInt, StringToBool(sendReadReceipts))
+			if err != nil {
+				c.JSON(400, Error{Msg: err.Error()})
+				return
+			}
+
+			c.JSON(200, messages)
+			return
+		}
+
 		jsonStr, err := a.signalClient.Receive(number, timeoutInt, StringToBool(ignoreAttachments), StringToBool(ignoreStories), StringToBool(ignoreAvatars), StringToBool(ignoreStickers), maxMessagesInt, StringToBool(sendReadReceipts))
 		if err != nil {
 			c.JSON(400, Error{Msg: err.Error()})
@@ -829,8 +1167,13 @@ func (a *Api) CreateGroup(c *gin.Context) {
 		groupLinkState = groupLinkState.FromString(req.GroupLinkState)
 	}
 
+	expirationTime := req.ExpirationTime
+	if expirationTime == nil {
+		expirationTime = a.signalClient.GetAccountSettings(number).ExpirationInSeconds
+	}
+
 	groupId, err := a.signalClient.CreateGroup(number, req.Name, req.Members, req.Description, editGroupPermission, addMembersPermission,
-		sendMessagesPermission, groupLinkState, req.ExpirationTime)
+		sendMessagesPermission, groupLinkState, expirationTime)
 	if err != nil {
 		c.JSON(400, Error{Msg: err.Error()})
 		return
@@ -1040,6 +1383,178 @@ func (a *Api) RemoveAdminsFromGroup(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// parseGroupMembersRequest parses the number, the group id and the members of
+// the request. In case something is invalid, an error response is sent.
+func parseGroupMembersRequest(c *gin.Context, req interface{}, members func() []string) (string, string, bool) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return "", "", false
+	}
+	if number == "" {
+		c.JSON(400, Error{Msg: "Couldn't process request - number missing"})
+		return "", "", false
+	}
+
+	groupId := c.Param("groupid")
+	if groupId == "" {
+		c.JSON(400, Error{Msg: "Couldn't process request - group id missing"})
+		return "", "", false
+	}
+
+	err = c.BindJSON(req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return "", "", false
+	}
+
+	if len(members()) == 0 {
+		c.JSON(400, Error{Msg: "Couldn't process request - group members missing"})
+		return "", "", false
+	}
+	return number, groupId, true
+}
+
+func handleGroupUpdateError(c *gin.Context, err error) {
+	switch err.(type) {
+	case *client.NotFoundError:
+		c.JSON(404, Error{Msg: err.Error()})
+	default:
+		c.JSON(400, Error{Msg: err.Error()})
+	}
+}
+
+// @Summary Approve pending join requests.
+// @Tags Groups
+// @Description Approve the requests of members that want to join the group via the group invite link.
+// @Accept json
+// @Produce json
+// @Success 204 {string} OK
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param data body ChangeGroupMembersRequest true "Members"
+// @Param number path string true "Registered Phone Number"
+// @Param groupid path string true "Group ID"
+// @Router /v1/groups/{number}/{groupid}/join-requests/approve [post]
+func (a *Api) ApproveGroupJoinRequests(c *gin.Context) {
+	var req ChangeGroupMembersRequest
+	number, groupId, ok := parseGroupMembersRequest(c, &req, func() []string { return req.Members })
+	if !ok {
+		return
+	}
+
+	err := a.signalClient.ApproveGroupJoinRequests(number, groupId, req.Members)
+	if err != nil {
+		handleGroupUpdateError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Deny pending join requests.
+// @Tags Groups
+// @Description Deny the requests of members that want to join the group via the group invite link. Banned members can't request to join the group again.
+// @Accept json
+// @Produce json
+// @Success 204 {string} OK
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param data body DenyGroupJoinRequestsRequest true "Members"
+// @Param number path string true "Registered Phone Number"
+// @Param groupid path string true "Group ID"
+// @Router /v1/groups/{number}/{groupid}/join-requests/deny [post]
+func (a *Api) DenyGroupJoinRequests(c *gin.Context) {
+	var req DenyGroupJoinRequestsRequest
+	number, groupId, ok := parseGroupMembersRequest(c, &req, func() []string { return req.Members })
+	if !ok {
+		return
+	}
+
+	err := a.signalClient.DenyGroupJoinRequests(number, groupId, req.Members, req.Ban)
+	if err != nil {
+		handleGroupUpdateError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Revoke pending invites.
+// @Tags Groups
+// @Description Revoke the invites of members that were added to the group, but didn't accept the invite yet.
+// @Accept json
+// @Produce json
+// @Success 204 {string} OK
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param data body ChangeGroupMembersRequest true "Members"
+// @Param number path string true "Registered Phone Number"
+// @Param groupid path string true "Group ID"
+// @Router /v1/groups/{number}/{groupid}/invites [delete]
+func (a *Api) RevokeGroupInvites(c *gin.Context) {
+	var req ChangeGroupMembersRequest
+	number, groupId, ok := parseGroupMembersRequest(c, &req, func() []string { return req.Members })
+	if !ok {
+		return
+	}
+
+	err := a.signalClient.RevokeGroupInvites(number, groupId, req.Members)
+	if err != nil {
+		handleGroupUpdateError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Update the group invite link.
+// @Tags Groups
+// @Description Enable or disable the group invite link, switch whether new members need to be approved by an admin ('enabled-with-approval') and/or reset the invite link, so that the previous link can't be used anymore.
+// @Accept json
+// @Produce json
+// @Success 200 {object} GroupInviteLinkResponse
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param data body UpdateGroupInviteLinkRequest true "Invite Link"
+// @Param number path string true "Registered Phone Number"
+// @Param groupid path string true "Group ID"
+// @Router /v1/groups/{number}/{groupid}/invite-link [put]
+func (a *Api) UpdateGroupInviteLink(c *gin.Context) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return
+	}
+
+	groupId := c.Param("groupid")
+	if groupId == "" {
+		c.JSON(400, Error{Msg: "Couldn't process request - group id missing"})
+		return
+	}
+
+	var req UpdateGroupInviteLinkRequest
+	err = c.BindJSON(&req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return
+	}
+
+	if req.State != "" && !utils.StringInSlice(req.State, []string{"enabled", "enabled-with-approval", "disabled"}) {
+		c.JSON(400, Error{Msg: "Invalid group link provided - only 'enabled', 'enabled-with-approval' and 'disabled' allowed!"})
+		return
+	}
+	if req.State == "" && !req.Reset {
+		c.JSON(400, Error{Msg: "Couldn't process request - please provide a state and/or reset the invite link"})
+		return
+	}
+
+	var groupLinkState client.GroupLinkState
+	inviteLink, err := a.signalClient.UpdateGroupInviteLink(number, groupId, groupLinkState.FromString(req.State), req.Reset)
+	if err != nil {
+		handleGroupUpdateError(c, err)
+		return
+	}
+	c.JSON(200, GroupInviteLinkResponse{InviteLink: inviteLink})
+}
+
 // @Summary List all Signal Groups.
 // @Tags Groups
 // @Description List all Signal Groups.
@@ -1127,6 +1642,86 @@ func (a *Api) GetGroup(c *gin.Context) {
 	}
 }
 
+// @Summary Bring the Signal Groups into the desired state.
+// @Tags Groups
+// @Description Creates and updates the listed groups, adds and removes members and admins and changes the permissions, so that the groups match the desired state. Only the necessary changes are applied. Groups are identified by their id or - if no id is provided - by their name. Fields that are not provided are left as they are (e.g. if 'members' is missing, no members are added or removed). The account itself is never removed. As members might only be known by their uuid, phone numbers are resolved to uuids first - in case a phone number can't be resolved, no members or admins are removed from that group and the error of the group's result says so. With 'dry_run' the planned changes are only returned.
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []client.GroupSyncResult
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param data body SyncGroupsRequest true "Desired Groups"
+// @Router /v1/groups/{number}/sync [put]
+func (a *Api) SyncGroups(c *gin.Context) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return
+	}
+
+	var req SyncGroupsRequest
+	err = c.BindJSON(&req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return
+	}
+
+	for _, group := range req.Groups {
+		if group.Name == "" {
+			c.JSON(400, Error{Msg: "Couldn't process request - please provide a name for every group"})
+			return
+		}
+		if group.Permissions != nil {
+			for _, permission := range []string{group.Permissions.AddMembers, group.Permissions.EditGroup, group.Permissions.SendMessages} {
+				if permission != "" && !utils.StringInSlice(permission, []string{"every-member", "only-admins"}) {
+					c.JSON(400, Error{Msg: "Invalid permission provided for group '" + group.Name + "' - only 'every-member' and 'only-admins' allowed!"})
+					return
+				}
+			}
+		}
+	}
+
+	results, err := a.signalClient.SyncGroups(number, req.Groups, req.DryRun)
+	if err != nil {
+		c.JSON(400, Error{Msg: err.Error()})
+		return
+	}
+	c.JSON(200, results)
+}
+
+// @Summary Show the audit log of a Signal Group.
+// @Tags Groups
+// @Description Show the changes of a Signal Group (members, admins, invites, join requests, name, description, permissions, disappearing messages timer and invite link), oldest first. The changes are detected by comparing the group with the previously known state of the group, whenever a group update is received or a group is changed via the REST API. 'changed_by' is only set if it is known who changed the group.
+// @Produce  json
+// @Success 200 {object} []data.GroupChange
+// @Failure 400 {object} Error
+// @Param number path string true "Registered Phone Number"
+// @Param groupid path string true "Group ID"
+// @Param limit query int false "Only return the most recent changes"
+// @Router /v1/groups/{number}/{groupid}/audit-log [get]
+func (a *Api) GetGroupAuditLog(c *gin.Context) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return
+	}
+	groupId := c.Param("groupid")
+
+	limit := c.DefaultQuery("limit", "0")
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil || limitInt < 0 {
+		c.JSON(400, Error{Msg: "Couldn't process request - limit needs to be a positive number!"})
+		return
+	}
+
+	auditLog, err := a.signalClient.GetGroupAuditLog(number, groupId, limitInt)
+	if err != nil {
+		c.JSON(400, Error{Msg: err.Error()})
+		return
+	}
+	c.JSON(200, auditLog)
+}
+
 // @Summary Returns the avatar of a Signal Group.
 // @Tags Groups
 // @Description Returns the avatar of a Signal Group.
@@ -1136,8 +1731,14 @@ func (a *Api) GetGroup(c *gin.Context) {
 // @Failure 400 {object} Error
 // @Param number path string true "Registered Phone Number"
 // @Param groupid path string true "Group ID"
+// @Param expires query string false "Expiration timestamp of a signed URL"
+// @Param signature query string false "Signature of a signed URL"
 // @Router /v1/groups/{number}/{groupid}/avatar [get]
 func (a *Api) GetGroupAvatar(c *gin.Context) {
+	if !a.verifySignedUrl(c) {
+		return
+	}
+
 	number, err := url.PathUnescape(c.Param("number"))
 	if err != nil {
 		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
@@ -1365,14 +1966,183 @@ func (a *Api) GetQrCodeLinkUri(c *gin.Context) {
 	c.JSON(200, DeviceLinkUriResponse{DeviceLinkUri: deviceLinkUri})
 }
 
+// @Summary Start the onboarding of an account.
+// @Tags Devices
+// @Description Start linking a device (type 'link') or registering a number (type 'register'). The returned onboarding session shows the progress: a link session waits for the QR code to be scanned ('awaiting-scan', see '/v1/onboarding/{id}/qrcode' or the device_link_uri) and is 'linked' afterwards. A register session either waits for the verification code ('awaiting-verification', see '/v1/onboarding/{id}/verify') or requires a captcha ('captcha-required', see '/v1/onboarding/{id}/register') and is 'registered' afterwards. Once the onboarding is done, the session contains the number of the account. Sessions are kept for 24 hours after their last change.
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} data.OnboardingSession
+// @Failure 400 {object} Error
+// @Param data body StartOnboardingRequest true "Request"
+// @Router /v1/onboarding [post]
+func (a *Api) StartOnboarding(c *gin.Context) {
+	var req StartOnboardingRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return
+	}
+
+	var session ds.OnboardingSession
+	switch req.Type {
+	case "link":
+		if req.DeviceName == "" {
+			c.JSON(400, Error{Msg: "Please provide a name for the device"})
+			return
+		}
+		session, err = a.signalClient.StartLinkOnboarding(req.DeviceName)
+	case "register":
+		if req.Number == "" {
+			c.JSON(400, Error{Msg: "Please provide a number"})
+			return
+		}
+		session, err = a.signalClient.StartRegisterOnboarding(req.Number, req.UseVoice, req.Captcha)
+	default:
+		c.JSON(400, Error{Msg: "Invalid type provided - only 'link' and 'register' allowed!"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(400, Error{Msg: err.Error()})
+		return
+	}
+	c.JSON(201, session)
+}
+
+// @Summary Show the state of an onboarding session.
+// @Tags Devices
+// @Description Show the state of an onboarding session and the number of the account, once the onboarding is done.
+// @Produce  json
+// @Success 200 {object} data.OnboardingSession
+// @Failure 404 {object} Error
+// @Param id path string true "Onboarding Session Id"
+// @Router /v1/onboarding/{id} [get]
+func (a *Api) GetOnboardingSession(c *gin.Context) {
+	session, err := a.signalClient.GetOnboardingSession(c.Param("id"))
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.JSON(200, session)
+}
+
+// @Summary Show the QR code of an onboarding session.
+// @Tags Devices
+// @Description Show the QR code that needs to be scanned with the primary device to link the device of the onboarding session.
+// @Produce  json
+// @Success 200 {string} string	"Image"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param id path string true "Onboarding Session Id"
+// @Param qrcode_version query int false "QRCode Version (defaults to 10)"
+// @Router /v1/onboarding/{id}/qrcode [get]
+func (a *Api) GetOnboardingQrCode(c *gin.Context) {
+	qrCodeVersionInt := 10
+	if qrCodeVersion := c.Query("qrcode_version"); qrCodeVersion != "" {
+		var err error
+		qrCodeVersionInt, err = strconv.Atoi(qrCodeVersion)
+		if err != nil {
+			c.JSON(400, Error{Msg: "The qrcode_version parameter needs to be an integer!"})
+			return
+		}
+	}
+
+	png, err := a.signalClient.GetOnboardingQrCode(c.Param("id"), qrCodeVersionInt)
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.Data(200, "image/png", png)
+}
+
+// @Summary Request the verification code of an onboarding session again.
+// @Tags Devices
+// @Description Request the verification code of a register session again, e.g. with the solved captcha in case the session requires a captcha. To get the captcha, go to https://signalcaptchas.org/registration/generate.html
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} data.OnboardingSession
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param id path string true "Onboarding Session Id"
+// @Param data body OnboardingRegisterRequest false "Request"
+// @Router /v1/onboarding/{id}/register [post]
+func (a *Api) RetryOnboardingRegistration(c *gin.Context) {
+	var req OnboardingRegisterRequest
+	if c.Request.ContentLength != 0 {
+		err := c.BindJSON(&req)
+		if err != nil {
+			c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+			return
+		}
+	}
+
+	session, err := a.signalClient.RetryRegisterOnboarding(c.Param("id"), req.UseVoice, req.Captcha)
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.JSON(200, session)
+}
+
+// @Summary Verify the number of an onboarding session.
+// @Tags Devices
+// @Description Verify the number of a register session with the verification code that was sent via SMS (or voice call).
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} data.OnboardingSession
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Param id path string true "Onboarding Session Id"
+// @Param data body OnboardingVerifyRequest true "Request"
+// @Router /v1/onboarding/{id}/verify [post]
+func (a *Api) VerifyOnboarding(c *gin.Context) {
+	var req OnboardingVerifyRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return
+	}
+
+	if req.Token == "" {
+		c.JSON(400, Error{Msg: "Please provide the verification code"})
+		return
+	}
+
+	session, err := a.signalClient.VerifyRegisterOnboarding(c.Param("id"), req.Token, req.Pin)
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.JSON(200, session)
+}
+
+func onboardingError(c *gin.Context, err error) {
+	if err == client.ErrOnboardingSessionNotFound {
+		c.JSON(404, Error{Msg: err.Error()})
+		return
+	}
+	c.JSON(400, Error{Msg: err.Error()})
+}
+
 // @Summary List all accounts
 // @Tags Accounts
-// @Description Lists all of the accounts linked or registered
+// @Description Lists all of the accounts linked or registered. With 'expand=true' the details of every account (see '/v1/accounts/{number}') are returned instead of the numbers only.
 // @Produce json
+// @Param expand query bool false "Return the details of the accounts"
 // @Success 200 {object} []string
 // @Failure 400 {object} Error
 // @Router /v1/accounts [get]
 func (a *Api) GetAccounts(c *gin.Context) {
+	if c.Query("expand") == "true" {
+		details, err := a.signalClient.GetAccountsDetails()
+		if err != nil {
+			c.JSON(500, Error{Msg: "Couldn't get list of accounts: " + err.Error()})
+			return
+		}
+		c.JSON(200, details)
+		return
+	}
+
 	devices, err := a.signalClient.GetAccounts()
 	if err != nil {
 		c.JSON(500, Error{Msg: "Couldn't get list of accounts: " + err.Error()})
@@ -1382,6 +2152,35 @@ func (a *Api) GetAccounts(c *gin.Context) {
 	c.JSON(200, devices)
 }
 
+// @Summary Show account details.
+// @Tags Accounts
+// @Description Shows the details of an account: uuid, username, profile, device id, registration lock, discoverability settings, trust mode and the time the last message was received. The device id, registration lock and discoverability settings are read from the signal-cli data files and are omitted in case they can't be determined.
+// @Produce json
+// @Param number path string true "Registered Phone Number"
+// @Success 200 {object} client.AccountDetails
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Router /v1/accounts/{number} [get]
+func (a *Api) GetAccount(c *gin.Context) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return
+	}
+
+	details, err := a.signalClient.GetAccountDetails(number)
+	if err != nil {
+		if err == client.ErrAccountNotFound {
+			c.JSON(404, Error{Msg: err.Error()})
+			return
+		}
+		c.JSON(500, Error{Msg: "Couldn't get account details: " + err.Error()})
+		return
+	}
+
+	c.JSON(200, details)
+}
+
 // @Summary List all attachments.
 // @Tags Attachments
 // @Description List all downloaded attachments
@@ -1438,8 +2237,14 @@ func (a *Api) RemoveAttachment(c *gin.Context) {
 // @Success 200 {string} OK
 // @Failure 400 {object} Error
 // @Param attachment path string true "Attachment ID"
+// @Param expires query string false "Expiration timestamp of a signed URL"
+// @Param signature query string false "Signature of a signed URL"
 // @Router /v1/attachments/{attachment} [get]
 func (a *Api) ServeAttachment(c *gin.Context) {
+	if !a.verifySignedUrl(c) {
+		return
+	}
+
 	attachment := c.Param("attachment")
 
 	attachmentBytes, err := a.signalClient.GetAttachment(attachment)
@@ -1475,6 +2280,76 @@ func (a *Api) ServeAttachment(c *gin.Context) {
 	}
 }
 
+// verifySignedUrl checks the signature of signed URLs. Requests without a
+// signature parameter are passed through unchanged. As a reverse proxy might let
+// requests with a signature parameter through without credentials, a signature
+// parameter that is present has to be valid - even if it is empty.
+func (a *Api) verifySignedUrl(c *gin.Context) bool {
+	if _, ok := c.GetQuery("signature"); !ok {
+		return true
+	}
+
+	signatures := c.QueryArray("signature")
+	expires := c.QueryArray("expires")
+	if len(signatures) != 1 || signatures[0] == "" || len(expires) != 1 {
+		c.JSON(403, Error{Msg: "Invalid signature"})
+		return false
+	}
+
+	err := a.signalClient.GetUrlSigner().Verify(c.Request.URL.EscapedPath(), expires[0], signatures[0])
+	if err != nil {
+		c.JSON(403, Error{Msg: err.Error()})
+		return false
+	}
+	return true
+}
+
+// @Summary Create a signed URL.
+// @Tags Attachments
+// @Description Creates a time-limited, signed URL for an attachment, a contact avatar or a group avatar. Signed URLs can be shared with third parties (e.g. put into emails or other chat systems) without exposing the rest of the API. The validity (expires_in, in seconds) is limited by the signed_urls.max_validity setting.
+// @Accept  json
+// @Produce  json
+// @Success 201 {object} SignedUrlResponse
+// @Failure 400 {object} Error
+// @Param data body CreateSignedUrlRequest true "Input Data"
+// @Router /v1/signed-urls [post]
+func (a *Api) CreateSignedUrl(c *gin.Context) {
+	var req CreateSignedUrlRequest
+	err := c.BindJSON(&req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return
+	}
+
+	path, err := url.Parse(req.Path)
+	if err != nil || path.IsAbs() || path.RawQuery != "" {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid path"})
+		return
+	}
+
+	signable := false
+	for _, signableUrlPath := range signableUrlPaths {
+		if signableUrlPath.MatchString(path.EscapedPath()) {
+			signable = true
+			break
+		}
+	}
+	if !signable {
+		c.JSON(400, Error{Msg: "Couldn't process request - only attachments, contact avatars and group avatars can be signed"})
+		return
+	}
+
+	maxValidity := a.settings().SignedUrls.MaxValidity
+	if req.ExpiresIn < 0 || req.ExpiresIn > int64(maxValidity) {
+		c.JSON(400, Error{Msg: "Couldn't process request - expires_in needs to be a positive number and can't exceed " +
+			strconv.Itoa(maxValidity) + " seconds"})
+		return
+	}
+
+	signedUrl, expiresAt := a.signalClient.SignUrl(path.EscapedPath(), time.Duration(req.ExpiresIn)*time.Second)
+	c.JSON(201, SignedUrlResponse{Url: signedUrl, ExpiresAt: expiresAt.Unix()})
+}
+
 // @Summary Update Profile.
 // @Tags Profiles
 // @Description Set your name and optional an avatar.
@@ -1617,7 +2492,7 @@ func (a *Api) TrustIdentity(c *gin.Context) {
 
 // @Summary Set the REST API configuration.
 // @Tags General
-// @Description Set the REST API configuration.
+// @Description Change the REST API configuration at runtime: the log level, the webhook URL for received messages (json-rpc mode only), the default text mode, the auto receive schedule (not supported in json-rpc mode), the attachment retention and the trust modes of accounts (not supported in json-rpc mode). Only the specified settings are changed. The settings listed in `reset` are reset to the value from the config file or the environment. The changes are persisted and applied at once - in case one of the settings is invalid, nothing is changed.
 // @Accept  json
 // @Produce  json
 // @Success 204 {string} string "OK"
@@ -1633,22 +2508,50 @@ func (a *Api) SetConfiguration(c *gin.Context) {
 		return
 	}
 
+	overrides := utils.SettingsOverrides{
+		ReceiveWebhookUrl:       req.ReceiveWebhookUrl,
+		DefaultSignalTextMode:   req.DefaultSignalTextMode,
+		AutoReceiveSchedule:     req.AutoReceiveSchedule,
+		AttachmentRetentionDays: req.AttachmentRetentionDays,
+	}
 	if req.Logging.Level != "" {
-		err = utils.SetLogLevel(req.Logging.Level)
+		overrides.LogLevel = &req.Logging.Level
+	}
+
+	trustModes := make(map[string]utils.SignalCliTrustMode)
+	for number, trustModeStr := range req.TrustModes {
+		trustMode, err := utils.StringToTrustMode(trustModeStr)
 		if err != nil {
-			c.JSON(400, Error{Msg: err.Error()})
+			c.JSON(400, Error{Msg: "Invalid trust mode for number " + number})
 			return
 		}
+		trustModes[number] = trustMode
+	}
+	if len(trustModes) > 0 && a.signalClient.GetSignalCliMode() == client.JsonRpc {
+		c.JSON(400, Error{Msg: "Trust modes can't be set in json-rpc mode, use the json_rpc.trust_new_identities setting instead!"})
+		return
+	}
+
+	err = a.settingsStore.Update(overrides, req.Reset, trustModes)
+	if err != nil {
+		switch err := err.(type) {
+		case *utils.SettingsError:
+			c.JSON(400, Error{Msg: "Invalid configuration: " + strings.Join(err.Problems, "; ")})
+		default:
+			c.JSON(500, Error{Msg: "Couldn't persist configuration"})
+			log.Error("Couldn't persist configuration: ", err.Error())
+		}
+		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
 // @Summary List the REST API configuration.
 // @Tags General
-// @Description List the REST API configuration.
+// @Description List the effective REST API configuration. For every setting, the source of the value is returned: `default`, `file` (config file), `env` (environment variable) or `api` (changed via the REST API).
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} Configuration
+// @Success 200 {object} ConfigurationResponse
 // @Failure 400 {object} Error
 // @Router /v1/configuration [get]
 func (a *Api) GetConfiguration(c *gin.Context) {
@@ -1659,9 +2562,18 @@ func (a *Api) GetConfiguration(c *gin.Context) {
 		logLevel = "info"
 	} else if log.GetLevel() == log.WarnLevel {
 		logLevel = "warn"
+	} else if log.GetLevel() == log.ErrorLevel {
+		logLevel = "error"
 	}
 
-	configuration := Configuration{Logging: LoggingConfiguration{Level: logLevel}}
+	configuration := ConfigurationResponse{
+		Logging:    LoggingConfiguration{Level: logLevel},
+		Settings:   a.settingsStore.Values(),
+		TrustModes: make(map[string]string),
+	}
+	for number, trustMode := range a.signalClient.GetSignalCliApiConfig().GetTrustModes() {
+		configuration.TrustModes[number], _ = utils.TrustModeToString(trustMode)
+	}
 	c.JSON(200, configuration)
 }
 
@@ -1872,6 +2784,7 @@ func (a *Api) UpdateGroup(c *gin.Context) {
 // @Failure 400 {object} Error
 // @Param data body SendReactionRequest true "Reaction"
 // @Param number path string true "Registered phone number"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
 // @Router /v1/reactions/{number} [post]
 func (a *Api) SendReaction(c *gin.Context) {
 	var req SendReactionRequest
@@ -1925,6 +2838,7 @@ func (a *Api) SendReaction(c *gin.Context) {
 // @Failure 400 {object} Error
 // @Param data body RemoveReactionRequest true "Reaction"
 // @Param number path string true "Registered phone number"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
 // @Router /v1/reactions/{number} [delete]
 func (a *Api) RemoveReaction(c *gin.Context) {
 	var req RemoveReactionRequest
@@ -2313,14 +3227,77 @@ func (a *Api) SetTrustMode(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Update account specific settings.
+// @Tags General
+// @Description Replace the account specific settings. The settings are used as defaults whenever a request doesn't specify the value explicitly: the text mode, notify_self and the signature (which is appended to every message) are applied when sending messages, expiration_in_seconds (the disappearing messages timer) is applied to groups that are created via the REST API, send_read_receipts is applied when receiving messages and receive_webhook_url replaces the global receive webhook URL for the messages of the account (json-rpc mode only). Settings that are not set fall back to the global settings. The trust mode is only changed in case it is set.
+// @Accept json
+// @Produce json
+// @Param number path string true "Registered Phone Number"
+// @Success 204
+// @Param data body AccountSettingsRequest true "Request"
+// @Failure 400 {object} Error
+// @Router /v1/configuration/{number}/settings [put]
+func (a *Api) UpdateAccountSpecificSettings(c *gin.Context) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return
+	}
+	if number == "" {
+		c.JSON(400, Error{Msg: "Couldn't process request - number missing"})
+		return
+	}
+
+	var req AccountSettingsRequest
+	err = c.BindJSON(&req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return
+	}
+
+	if req.ReceiveWebhookUrl != nil && a.signalClient.GetSignalCliMode() != client.JsonRpc {
+		c.JSON(400, Error{Msg: "Invalid configuration: receive_webhook_url: can only be used with mode json-rpc"})
+		return
+	}
+
+	var trustMode *utils.SignalCliTrustMode
+	if req.TrustMode != nil {
+		if a.signalClient.GetSignalCliMode() == client.JsonRpc {
+			c.JSON(400, Error{Msg: "Trust modes can't be set in json-rpc mode, use the json_rpc.trust_new_identities setting instead!"})
+			return
+		}
+
+		mode, err := utils.StringToTrustMode(*req.TrustMode)
+		if err != nil {
+			c.JSON(400, Error{Msg: "Invalid trust mode"})
+			return
+		}
+		trustMode = &mode
+	}
+
+	// the settings and the trust mode are persisted together, so that a failed
+	// request doesn't change only one of them
+	err = a.signalClient.SetAccountSettings(number, req.AccountSettings, trustMode)
+	if err != nil {
+		switch err := err.(type) {
+		case *utils.SettingsError:
+			c.JSON(400, Error{Msg: "Invalid configuration: " + strings.Join(err.Problems, "; ")})
+		default:
+			c.JSON(500, Error{Msg: "Couldn't persist account settings"})
+			log.Error("Couldn't persist account settings: ", err.Error())
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 // @Summary List account specific settings.
 // @Tags General
 // @Description List account specific settings.
 // @Accept json
 // @Produce json
 // @Param number path string true "Registered Phone Number"
-// @Success 200
-// @Param data body TrustModeResponse true "Request"
+// @Success 200 {object} AccountSettingsResponse
 // @Failure 400 {object} Error
 // @Router /v1/configuration/{number}/settings [get]
 func (a *Api) GetTrustMode(c *gin.Context) {
@@ -2334,15 +3311,15 @@ func (a *Api) GetTrustMode(c *gin.Context) {
 		return
 	}
 
-	trustMode := TrustModeResponse{}
-	trustMode.TrustMode, err = utils.TrustModeToString(a.signalClient.GetTrustMode(number))
+	resp := AccountSettingsResponse{AccountSettings: a.signalClient.GetAccountSettings(number)}
+	resp.TrustMode, err = utils.TrustModeToString(a.signalClient.GetTrustMode(number))
 	if err != nil {
 		c.JSON(400, Error{Msg: "Invalid trust mode"})
 		log.Error("Invalid trust mode: ", err.Error())
 		return
 	}
 
-	c.JSON(200, trustMode)
+	c.JSON(200, resp)
 }
 
 // @Summary Send a synchronization message with the local contacts list to all linked devices.
@@ -2373,6 +3350,125 @@ func (a *Api) SendContacts(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Import contacts.
+// @Tags Contacts
+// @Description Adds or updates the contacts of a CSV or vCard (3.0 and 4.0) file. The CSV file needs a header row with (some of) the columns 'name', 'nickname', 'note', 'number' and 'username'. Of a vCard, the properties FN (or N), NICKNAME, NOTE, TEL and X-SIGNAL-USERNAME are used. Every row (or card) is imported on its own, the result contains the outcome of every row. The file must not be larger than 5 MiB. In normal mode, every row starts signal-cli once, so at most 100 rows can be imported at once (use the json-rpc mode for larger imports). Optionally, the contacts are sent to the linked devices afterwards.
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param number path string true "Registered Phone Number"
+// @Param file formData file true "CSV or vCard file"
+// @Param format formData string false "Format of the file (csv or vcard). Detected automatically, if not provided."
+// @Param send_contacts formData bool false "Send the contacts to the linked devices afterwards (default: false)"
+// @Success 200 {object} client.ContactImportResult
+// @Failure 400 {object} Error
+// @Failure 413 {object} Error
+// @Router /v1/contacts/{number}/import [post]
+func (a *Api) ImportContacts(c *gin.Context) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return
+	}
+	if number == "" {
+		c.JSON(400, Error{Msg: "Couldn't process request - number missing"})
+		return
+	}
+
+	sendContacts := false
+	if c.PostForm("send_contacts") != "" {
+		sendContacts, err = strconv.ParseBool(c.PostForm("send_contacts"))
+		if err != nil {
+			c.JSON(400, Error{Msg: "Couldn't process request - send_contacts needs to be either true or false"})
+			return
+		}
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(400, Error{Msg: "Please provide the file"})
+		return
+	}
+	if fileHeader.Size > client.MaxContactImportFileSize {
+		c.JSON(413, Error{Msg: client.ErrContactImportFileTooLarge.Error()})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't read file: " + err.Error()})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, client.MaxContactImportFileSize+1))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't read file: " + err.Error()})
+		return
+	}
+
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		format = "csv"
+		if strings.Contains(strings.ToUpper(string(data)), "BEGIN:VCARD") {
+			format = "vcard"
+		}
+	}
+	if !utils.StringInSlice(format, []string{"csv", "vcard"}) {
+		c.JSON(400, Error{Msg: "Invalid format - only 'csv' and 'vcard' are supported"})
+		return
+	}
+
+	result, err := a.signalClient.ImportContacts(number, data, format, sendContacts)
+	if err != nil {
+		if err == client.ErrContactImportFileTooLarge || err == client.ErrTooManyContactsToImport {
+			c.JSON(413, Error{Msg: err.Error()})
+			return
+		}
+		c.JSON(400, Error{Msg: "Couldn't import contacts: " + err.Error()})
+		return
+	}
+	c.JSON(200, result)
+}
+
+// @Summary Export contacts.
+// @Tags Contacts
+// @Description Exports the contacts of the given number as CSV (with the columns 'name', 'nickname', 'note', 'number' and 'username') or vCard file. The vCards of contacts without name contain a placeholder name and are marked with 'X-SIGNAL-UNNAMED:TRUE'.
+// @Produce  text/csv
+// @Produce  text/vcard
+// @Param number path string true "Registered Phone Number"
+// @Param format query string false "Format of the file (csv, vcard3 or vcard4). Default: csv"
+// @Success 200 {string} string "CSV or vCard file"
+// @Failure 400 {object} Error
+// @Router /v1/contacts/{number}/export [get]
+func (a *Api) ExportContacts(c *gin.Context) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return
+	}
+	if number == "" {
+		c.JSON(400, Error{Msg: "Couldn't process request - number missing"})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	if !utils.StringInSlice(format, []string{"csv", "vcard3", "vcard4"}) {
+		c.JSON(400, Error{Msg: "Invalid format - only 'csv', 'vcard3' and 'vcard4' are supported"})
+		return
+	}
+
+	data, err := a.signalClient.ExportContacts(number, format)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't export contacts: " + err.Error()})
+		return
+	}
+
+	contentType, extension := "text/csv; charset=utf-8", "csv"
+	if format != "csv" {
+		contentType, extension = "text/vcard; charset=utf-8", "vcf"
+	}
+	c.Header("Content-Disposition", "attachment; filename=\"contacts-"+strings.TrimPrefix(number, "+")+"."+extension+"\"")
+	c.Data(200, contentType, data)
+}
+
 // @Summary Lift rate limit restrictions by solving a captcha.
 // @Tags Accounts
 // @Description When running into rate limits, sometimes the limit can be lifted, by solving a CAPTCHA. To get the captcha token, go to https://signalcaptchas.org/challenge/generate.html For the staging environment, use: https://signalcaptchas.org/staging/registration/generate.html. The "challenge_token" is the token from the failed send attempt. The "captcha" is the captcha result, starting with signalcaptcha://
@@ -2456,6 +3552,106 @@ func (a *Api) UpdateAccountSettings(c *gin.Context) {
 	c.Status(201)
 }
 
+// @Summary Export an account.
+// @Tags Accounts
+// @Description Export the signal-cli data of the account together with its account specific settings as an archive, which is encrypted with the given password. The archive can be imported on another host with '/v1/accounts/import'. signal-cli is paused while the account is exported - in json-rpc mode the signal-cli daemon is stopped, so requests that need signal-cli fail in the meantime. Don't use the account on this host anymore after it was imported on another host.
+// @Accept  json
+// @Produce  octet-stream
+// @Param number path string true "Registered Phone Number"
+// @Param data body ExportAccountRequest true "Request"
+// @Success 200 {string} string "Archive"
+// @Failure 400 {object} Error
+// @Failure 404 {object} Error
+// @Router /v1/accounts/{number}/export [post]
+func (a *Api) ExportAccount(c *gin.Context) {
+	number, err := url.PathUnescape(c.Param("number"))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
+		return
+	}
+
+	var req ExportAccountRequest
+	err = c.BindJSON(&req)
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't process request - invalid request"})
+		return
+	}
+
+	if len(req.Password) < 8 {
+		c.JSON(400, Error{Msg: "Please provide a password with at least 8 characters"})
+		return
+	}
+
+	archive, err := a.signalClient.ExportAccount(number, req.Password)
+	if err != nil {
+		if err == client.ErrAccountNotFound {
+			c.JSON(404, Error{Msg: err.Error()})
+			return
+		}
+		c.JSON(400, Error{Msg: "Couldn't export account: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+strings.TrimPrefix(number, "+")+".signal-account\"")
+	c.Data(200, "application/octet-stream", archive)
+}
+
+// @Summary Import an account.
+// @Tags Accounts
+// @Description Import an account from an archive that was created with '/v1/accounts/{number}/export'. The archive must not be larger than 256 MiB (1 GiB once extracted). signal-cli is paused while the account is imported - in json-rpc mode the signal-cli daemon is stopped, so requests that need signal-cli fail in the meantime, and loads the account when it is started again.
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param archive formData file true "Archive"
+// @Param password formData string true "Password of the archive"
+// @Success 201 {object} ImportAccountResponse
+// @Failure 400 {object} Error
+// @Failure 409 {object} Error
+// @Failure 413 {object} Error
+// @Router /v1/accounts/import [post]
+func (a *Api) ImportAccount(c *gin.Context) {
+	password := c.PostForm("password")
+	if password == "" {
+		c.JSON(400, Error{Msg: "Please provide the password of the archive"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("archive")
+	if err != nil {
+		c.JSON(400, Error{Msg: "Please provide the archive"})
+		return
+	}
+	if fileHeader.Size > client.MaxAccountArchiveSize {
+		c.JSON(413, Error{Msg: client.ErrAccountArchiveTooLarge.Error()})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't read archive: " + err.Error()})
+		return
+	}
+	defer file.Close()
+	archive, err := io.ReadAll(io.LimitReader(file, client.MaxAccountArchiveSize+1))
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't read archive: " + err.Error()})
+		return
+	}
+
+	number, err := a.signalClient.ImportAccount(archive, password)
+	if err != nil {
+		if err == client.ErrAccountAlreadyExists {
+			c.JSON(409, Error{Msg: err.Error()})
+			return
+		}
+		if err == client.ErrAccountArchiveTooLarge {
+			c.JSON(413, Error{Msg: err.Error()})
+			return
+		}
+		c.JSON(400, Error{Msg: "Couldn't import account: " + err.Error()})
+		return
+	}
+	c.JSON(201, ImportAccountResponse{Number: number})
+}
+
 // @Summary Set a username.
 // @Tags Accounts
 // @Description Allows to set the username that should be used for this account. This can either be just the nickname (e.g. test) or the complete username with discriminator (e.g. test.123). Returns the new username with discriminator and the username link.
@@ -2675,8 +3871,15 @@ func (a *Api) ListContact(c *gin.Context) {
 // @Produce  json
 // @Success 200 {string} string	"Image"
 // @Param number path string true "Registered Phone Number"
+// @Param uuid path string true "Contact UUID"
+// @Param expires query string false "Expiration timestamp of a signed URL"
+// @Param signature query string false "Signature of a signed URL"
 // @Router /v1/contacts/{number}/{uuid}/avatar [get]
 func (a *Api) GetProfileAvatar(c *gin.Context) {
+	if !a.verifySignedUrl(c) {
+		return
+	}
+
 	number, err := url.PathUnescape(c.Param("number"))
 	if err != nil {
 		c.JSON(400, Error{Msg: "Couldn't process request - malformed number"})
@@ -2779,6 +3982,7 @@ func (a *Api) RemovePin(c *gin.Context) {
 // @Failure 400 {object} Error
 // @Param number path string true "Registered Phone Number"
 // @Param data body RemoteDeleteRequest true "Type"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
 // @Router /v1/remote-delete/{number} [delete]
 func (a *Api) RemoteDelete(c *gin.Context) {
 	var req RemoteDeleteRequest
@@ -2816,6 +4020,7 @@ func (a *Api) RemoteDelete(c *gin.Context) {
 // @Failure 400 {object} Error
 // @Param number path string true "Registered Phone Number"
 // @Param data body CreatePollRequest true "Type"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
 // @Router /v1/polls/{number} [post]
 func (a *Api) CreatePoll(c *gin.Context) {
 	var req CreatePollRequest
@@ -2872,6 +4077,7 @@ func (a *Api) CreatePoll(c *gin.Context) {
 // @Failure 400 {object} Error
 // @Param number path string true "Registered Phone Number"
 // @Param data body VoteRequest true "Type"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
 // @Router /v1/polls/{number}/vote [post]
 func (a *Api) VoteInPoll(c *gin.Context) {
 	var req VoteRequest
@@ -2942,6 +4148,7 @@ func (a *Api) VoteInPoll(c *gin.Context) {
 // @Failure 400 {object} Error
 // @Param number path string true "Registered Phone Number"
 // @Param data body ClosePollRequest true "Type"
+// @Param Idempotency-Key header string false "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again."
 // @Router /v1/polls/{number} [delete]
 func (a *Api) ClosePoll(c *gin.Context) {
 	var req ClosePollRequest
@@ -2985,3 +4192,38 @@ func (a *Api) ClosePoll(c *gin.Context) {
 
 	c.Status(204)
 }
+
+// @Summary List plugins.
+// @Tags Plugins
+// @Description List all loaded plugins together with their version, endpoint, init status and the status of their last (scheduled or message triggered) execution.
+// @Produce  json
+// @Success 200 {object} []utils.PluginStatus
+// @Router /v1/plugins [get]
+func (a *Api) ListPlugins(c *gin.Context) {
+	if a.pluginManager == nil {
+		c.JSON(200, []utils.PluginStatus{})
+		return
+	}
+	c.JSON(200, a.pluginManager.Statuses())
+}
+
+// @Summary Reload plugins.
+// @Tags Plugins
+// @Description Reloads all plugins from the plugin directory. Plugins are also reloaded automatically whenever the plugin directory changes (see PLUGIN_RELOAD_INTERVAL).
+// @Produce  json
+// @Success 200 {object} []utils.PluginStatus
+// @Failure 400 {object} Error
+// @Router /v1/plugins/reload [post]
+func (a *Api) ReloadPlugins(c *gin.Context) {
+	if a.pluginManager == nil {
+		c.JSON(400, Error{Msg: "Plugins are not enabled"})
+		return
+	}
+
+	err := a.pluginManager.Reload()
+	if err != nil {
+		c.JSON(400, Error{Msg: "Couldn't reload plugins: " + err.Error()})
+		return
+	}
+	c.JSON(200, a.pluginManager.Statuses())
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bbernhard/signal-cli-rest-api/client"
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestVerifySignedUrl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	signalClient := client.NewSignalClient(t.TempDir(), "", "", client.Normal, "", "", "")
+	urlSigner, _ := utils.NewUrlSigner("secret")
+	signalClient.SetUrlSigner(urlSigner, time.Hour, "")
+	a := NewApi(signalClient)
+
+	signedUrl := urlSigner.Sign("/v1/attachments/abc.png", time.Now().Add(time.Hour))
+	signedQuery := signedUrl[len("/v1/attachments/abc.png?"):]
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"/v1/attachments/abc.png", true},
+		{signedUrl, true},
+		{"/v1/attachments/abc.png?signature=", false},
+		{"/v1/attachments/abc.png?signature", false},
+		{signedUrl + "&signature=", false},
+		{signedUrl + "&" + signedQuery, false},
+		{"/v1/attachments/abc.png?signature=&" + signedQuery, false},
+		{signedUrl + "&expires=1", false},
+		{"/v1/attachments/other.png?" + signedQuery, false},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("GET", test.url, nil)
+		if result := a.verifySignedUrl(c); result != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.url, test.expected, result)
+		}
+		if !test.expected && recorder.Code != 403 {
+			t.Errorf("%s: expected status 403, got %d", test.url, recorder.Code)
+		}
+	}
+}
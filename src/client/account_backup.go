@@ -0,0 +1,529 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// The account archive is a tar.gz file, which is encrypted with AES-256-GCM.
+// The key is derived from the password with PBKDF2. Layout:
+// <magic><salt (16 bytes)><nonce (12 bytes)><encrypted tar.gz>
+const accountArchiveMagic = "SIGNAL-API-ACCOUNT-1"
+const accountArchiveKdfIterations = 600000
+
+const accountArchiveManifestFile = "manifest.json"
+const accountArchiveAccountFile = "account.json"
+const accountArchiveApiConfigFile = "api-config.yml"
+
+// MaxAccountArchiveSize is the maximum size of an (encrypted) account archive.
+// The content of the archive must not be larger than
+// maxAccountArchiveExtractedSize.
+const MaxAccountArchiveSize = 256 * 1024 * 1024
+
+var maxAccountArchiveExtractedSize int64 = 1024 * 1024 * 1024
+
+var ErrAccountNotFound = errors.New("Account not found")
+var ErrAccountArchiveTooLarge = errors.New("The account archive is too large")
+var ErrAccountAlreadyExists = errors.New("The account already exists - unregister it or delete its local data first")
+
+type accountArchiveManifest struct {
+	Version   int       `json:"version"`
+	Number    string    `json:"number"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func deriveAccountArchiveKey(password string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, password, salt, accountArchiveKdfIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptAccountArchive(data []byte, password string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := deriveAccountArchiveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte(accountArchiveMagic), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, []byte(accountArchiveMagic)), nil
+}
+
+func decryptAccountArchive(data []byte, password string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(accountArchiveMagic)) {
+		return nil, errors.New("Invalid account archive")
+	}
+	data = data[len(accountArchiveMagic):]
+	if len(data) < 16 {
+		return nil, errors.New("Invalid account archive")
+	}
+
+	gcm, err := deriveAccountArchiveKey(password, data[:16])
+	if err != nil {
+		return nil, err
+	}
+	data = data[16:]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("Invalid account archive")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(accountArchiveMagic))
+	if err != nil {
+		return nil, errors.New("Couldn't decrypt the account archive - wrong password?")
+	}
+	return plaintext, nil
+}
+
+// signalCliAccounts is the content of the accounts.json file of signal-cli.
+// Unknown fields are kept as they are.
+type signalCliAccounts map[string]interface{}
+
+func loadSignalCliAccounts(dataDir string) (signalCliAccounts, error) {
+	accounts := signalCliAccounts{"accounts": []interface{}{}, "version": 2}
+	data, err := os.ReadFile(filepath.Join(dataDir, "accounts.json"))
+	if os.IsNotExist(err) {
+		return accounts, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&accounts)
+	if err != nil {
+		return nil, errors.New("Couldn't parse accounts.json: " + err.Error())
+	}
+	return accounts, nil
+}
+
+func (a signalCliAccounts) list() []interface{} {
+	list, _ := a["accounts"].([]interface{})
+	return list
+}
+
+func (a signalCliAccounts) find(number string) map[string]interface{} {
+	for _, account := range a.list() {
+		if entry, ok := account.(map[string]interface{}); ok && entry["number"] == number {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (a signalCliAccounts) persist(dataDir string) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(dataDir, "accounts.json.tmp")
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(dataDir, "accounts.json"))
+}
+
+// isValidAccountPath checks that the path of an account (which is a plain
+// number in signal-cli) doesn't point outside of the data directory.
+func isValidAccountPath(accountPath string) bool {
+	if accountPath == "" {
+		return false
+	}
+	for _, c := range accountPath {
+		if !strings.ContainsRune("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_-", c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *SignalClient) signalCliDataDir() string {
+	return filepath.Join(s.signalCliConfig, "data")
+}
+
+// supervisorctl runs the given supervisorctl action (e.g. stop or start) for
+// the signal-cli daemon.
+func supervisorctl(action string) error {
+	out, err := exec.Command("supervisorctl", action, utils.SIGNAL_CLI_DAEMON_PROGRAM).CombinedOutput()
+	if err != nil || strings.Contains(string(out), "ERROR") {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return errors.New("Couldn't " + action + " the signal-cli daemon: " + msg)
+	}
+	return nil
+}
+
+// pauseSignalCli makes sure that signal-cli doesn't touch the account data
+// until the returned resume function is called. In json-rpc mode the signal-cli
+// daemon is stopped (supervisorctl waits until it has written everything to
+// disk and exited) and started again on resume, otherwise the REST API waits
+// for the running signal-cli commands and blocks new ones.
+func (s *SignalClient) pauseSignalCli() (func(), error) {
+	if s.signalCliMode != JsonRpc {
+		return s.cliClient.Pause(), nil
+	}
+
+	err := supervisorctl("stop")
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		err := supervisorctl("start")
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}, nil
+}
+
+// ExportAccount creates an encrypted archive with the signal-cli data of the
+// account and its entry in the api-config.yml.
+func (s *SignalClient) ExportAccount(number string, password string) ([]byte, error) {
+	data, err := s.archiveAccount(number)
+	if err != nil {
+		return nil, err
+	}
+	// the key derivation is slow on purpose, so the archive is only encrypted
+	// once signal-cli was resumed
+	return encryptAccountArchive(data, password)
+}
+
+// archiveAccount creates the (unencrypted) tar.gz archive of the account.
+// signal-cli is paused while the archive is created.
+func (s *SignalClient) archiveAccount(number string) ([]byte, error) {
+	resume, err := s.pauseSignalCli()
+	if err != nil {
+		return nil, err
+	}
+	defer resume()
+
+	dataDir := s.signalCliDataDir()
+	accounts, err := loadSignalCliAccounts(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	account := accounts.find(number)
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+	accountPath, _ := account["path"].(string)
+	if !isValidAccountPath(accountPath) {
+		return nil, errors.New("Invalid account path in accounts.json")
+	}
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	writeFile := func(name string, data []byte) error {
+		err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(data)
+		return err
+	}
+
+	manifest, _ := json.Marshal(accountArchiveManifest{Version: 1, Number: number, Path: accountPath, CreatedAt: time.Now().UTC()})
+	if err = writeFile(accountArchiveManifestFile, manifest); err != nil {
+		return nil, err
+	}
+	accountJson, _ := json.Marshal(account)
+	if err = writeFile(accountArchiveAccountFile, accountJson); err != nil {
+		return nil, err
+	}
+	if entry, exists := s.signalCliApiConfig.GetEntry(number); exists {
+		apiConfig, err := yaml.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		if err = writeFile(accountArchiveApiConfigFile, apiConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range []string{accountPath, accountPath + ".d"} {
+		root := filepath.Join(dataDir, name)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err = filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(dataDir, filePath)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = path.Join("data", filepath.ToSlash(relPath))
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			file, err := os.Open(filePath)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(tarWriter, file)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type accountArchiveFile struct {
+	name  string
+	isDir bool
+}
+
+// ImportAccount restores an account from an archive that was created with
+// ExportAccount. The files of the account are extracted to a staging directory
+// in the data directory first and are only moved into place once the archive
+// was validated. In json-rpc mode, the signal-cli daemon loads the account when
+// it is started again after the import.
+func (s *SignalClient) ImportAccount(archive []byte, password string) (string, error) {
+	if len(archive) > MaxAccountArchiveSize {
+		return "", ErrAccountArchiveTooLarge
+	}
+	data, err := decryptAccountArchive(archive, password)
+	if err != nil {
+		return "", err
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", errors.New("Invalid account archive: " + err.Error())
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	dataDir := s.signalCliDataDir()
+	err = os.MkdirAll(dataDir, 0700)
+	if err != nil {
+		return "", err
+	}
+	// the staging directory is on the same filesystem as the data directory,
+	// so that the files can be renamed into place
+	stagingDir, err := os.MkdirTemp(dataDir, ".import-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	var manifest accountArchiveManifest
+	var account map[string]interface{}
+	var apiConfigEntry *utils.SignalCliApiConfigEntry
+	files := []accountArchiveFile{}
+	var extractedSize int64
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", errors.New("Invalid account archive: " + err.Error())
+		}
+		content := io.LimitReader(tarReader, maxAccountArchiveExtractedSize-extractedSize+1)
+
+		switch header.Name {
+		case accountArchiveManifestFile, accountArchiveAccountFile, accountArchiveApiConfigFile:
+			var value []byte
+			value, err = io.ReadAll(content)
+			if err != nil {
+				break
+			}
+			extractedSize += int64(len(value))
+			if extractedSize > maxAccountArchiveExtractedSize {
+				return "", ErrAccountArchiveTooLarge
+			}
+
+			switch header.Name {
+			case accountArchiveManifestFile:
+				err = json.Unmarshal(value, &manifest)
+			case accountArchiveAccountFile:
+				decoder := json.NewDecoder(bytes.NewReader(value))
+				decoder.UseNumber()
+				err = decoder.Decode(&account)
+			case accountArchiveApiConfigFile:
+				apiConfigEntry = &utils.SignalCliApiConfigEntry{}
+				err = yaml.Unmarshal(value, apiConfigEntry)
+			}
+		default:
+			name := strings.TrimSuffix(header.Name, "/")
+			if path.Clean(name) != name || !strings.HasPrefix(name, "data/") ||
+				(header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg) {
+				return "", errors.New("Invalid account archive: unexpected file " + header.Name)
+			}
+			file := accountArchiveFile{name: name, isDir: header.Typeflag == tar.TypeDir}
+			files = append(files, file)
+
+			var written int64
+			written, err = stageAccountArchiveFile(filepath.Join(stagingDir, filepath.FromSlash(name)), file.isDir,
+				header.FileInfo().Mode().Perm(), content)
+			extractedSize += written
+			if extractedSize > maxAccountArchiveExtractedSize {
+				return "", ErrAccountArchiveTooLarge
+			}
+		}
+		if err != nil {
+			return "", errors.New("Invalid account archive: " + err.Error())
+		}
+	}
+
+	if manifest.Version != 1 || manifest.Number == "" || account == nil || !isValidAccountPath(manifest.Path) {
+		return "", errors.New("Invalid account archive")
+	}
+	prefix := "data/" + manifest.Path
+	for _, file := range files {
+		if file.name != prefix && file.name != prefix+".d" && !strings.HasPrefix(file.name, prefix+".d/") {
+			return "", errors.New("Invalid account archive: unexpected file " + file.name)
+		}
+	}
+
+	resume, err := s.pauseSignalCli()
+	if err != nil {
+		return "", err
+	}
+	defer resume()
+
+	accounts, err := loadSignalCliAccounts(dataDir)
+	if err != nil {
+		return "", err
+	}
+	if accounts.find(manifest.Number) != nil {
+		return "", ErrAccountAlreadyExists
+	}
+
+	accountPath, err := freeAccountPath(dataDir, manifest.Path)
+	if err != nil {
+		return "", err
+	}
+
+	removeAccountFiles := func() {
+		os.RemoveAll(filepath.Join(dataDir, accountPath))
+		os.RemoveAll(filepath.Join(dataDir, accountPath+".d"))
+	}
+	for _, suffix := range []string{"", ".d"} {
+		stagedPath := filepath.Join(stagingDir, "data", manifest.Path+suffix)
+		if _, err := os.Lstat(stagedPath); os.IsNotExist(err) {
+			continue
+		}
+		err = os.Rename(stagedPath, filepath.Join(dataDir, accountPath+suffix))
+		if err != nil {
+			removeAccountFiles()
+			return "", err
+		}
+	}
+
+	account["path"] = accountPath
+	accounts["accounts"] = append(accounts.list(), account)
+	err = accounts.persist(dataDir)
+	if err != nil {
+		removeAccountFiles()
+		return "", err
+	}
+
+	if apiConfigEntry != nil {
+		err = s.signalCliApiConfig.SetEntry(manifest.Number, *apiConfigEntry)
+		if err != nil {
+			return manifest.Number, errors.New("The account was imported, but its settings couldn't be stored: " + err.Error())
+		}
+	}
+	return manifest.Number, nil
+}
+
+// stageAccountArchiveFile writes a file (or directory) of the account archive
+// to the staging directory and returns the number of bytes written.
+func stageAccountArchiveFile(target string, isDir bool, mode os.FileMode, content io.Reader) (int64, error) {
+	if isDir {
+		return 0, os.MkdirAll(target, 0700)
+	}
+
+	err := os.MkdirAll(filepath.Dir(target), 0700)
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode|0600)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return written, err
+}
+
+// freeAccountPath returns the given account path, or a new one in case there
+// is already an account with that path.
+func freeAccountPath(dataDir string, accountPath string) (string, error) {
+	for i := 0; i < 100; i++ {
+		_, err1 := os.Stat(filepath.Join(dataDir, accountPath))
+		_, err2 := os.Stat(filepath.Join(dataDir, accountPath+".d"))
+		if os.IsNotExist(err1) && os.IsNotExist(err2) {
+			return accountPath, nil
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(900000))
+		if err != nil {
+			return "", err
+		}
+		accountPath = strconv.FormatInt(n.Int64()+100000, 10)
+	}
+	return "", errors.New("Couldn't find a free account path")
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+)
+
+func newBackupTestClient(t *testing.T) *SignalClient {
+	configDir := t.TempDir()
+	apiConfig := utils.NewSignalCliApiConfig()
+	if err := apiConfig.Load(filepath.Join(configDir, "api-config.yml")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &SignalClient{signalCliConfig: configDir, signalCliMode: Normal, signalCliApiConfig: apiConfig,
+		cliClient: NewCliClient(Normal, apiConfig, time.Second)}
+}
+
+func writeBackupTestFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectNoStagingDirectory makes sure that the files of an import aren't left
+// behind in the data directory.
+func expectNoStagingDirectory(t *testing.T, dataDir string) {
+	if stagingDirs, _ := filepath.Glob(filepath.Join(dataDir, ".import-*")); len(stagingDirs) != 0 {
+		t.Errorf("expected the staging directory to be removed: %v", stagingDirs)
+	}
+}
+
+func TestExportAndImportAccount(t *testing.T) {
+	source := newBackupTestClient(t)
+	dataDir := source.signalCliDataDir()
+	writeBackupTestFile(t, filepath.Join(dataDir, "accounts.json"),
+		`{"accounts":[{"path":"123456","environment":"LIVE","number":"+431212131491291","uuid":"abc"}],"version":2}`)
+	writeBackupTestFile(t, filepath.Join(dataDir, "123456"), `{"username":"test"}`)
+	writeBackupTestFile(t, filepath.Join(dataDir, "123456.d", "account.db"), "database")
+	textMode := "styled"
+	source.SetAccountSettings("+431212131491291", utils.AccountSettings{TextMode: &textMode}, nil)
+
+	if _, err := source.ExportAccount("+431212131491292", "password"); err != ErrAccountNotFound {
+		t.Errorf("expected ErrAccountNotFound, got %v", err)
+	}
+	archive, err := source.ExportAccount("+431212131491291", "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	target := newBackupTestClient(t)
+	// there is already another account with the same path on the target host
+	writeBackupTestFile(t, filepath.Join(target.signalCliDataDir(), "accounts.json"),
+		`{"accounts":[{"path":"123456","environment":"LIVE","number":"+431212131491293"}],"version":2}`)
+	writeBackupTestFile(t, filepath.Join(target.signalCliDataDir(), "123456"), `{}`)
+
+	if _, err := target.ImportAccount(archive, "wrong password"); err == nil {
+		t.Error("expected error, as the password is wrong")
+	}
+	number, err := target.ImportAccount(archive, "password")
+	if err != nil || number != "+431212131491291" {
+		t.Fatalf("unexpected result: %s (%v)", number, err)
+	}
+
+	accounts, err := loadSignalCliAccounts(target.signalCliDataDir())
+	if err != nil || len(accounts.list()) != 2 {
+		t.Fatalf("unexpected accounts: %v (%v)", accounts, err)
+	}
+	account := accounts.find("+431212131491291")
+	accountPath := account["path"].(string)
+	if accountPath == "123456" || account["uuid"] != "abc" {
+		t.Errorf("unexpected account: %v", account)
+	}
+	database, err := os.ReadFile(filepath.Join(target.signalCliDataDir(), accountPath+".d", "account.db"))
+	if err != nil || string(database) != "database" {
+		t.Errorf("unexpected database: %s (%v)", database, err)
+	}
+	if settings := target.GetAccountSettings("+431212131491291"); settings.TextMode == nil || *settings.TextMode != "styled" {
+		t.Errorf("unexpected account settings: %+v", settings)
+	}
+	expectNoStagingDirectory(t, target.signalCliDataDir())
+
+	if _, err := target.ImportAccount(archive, "password"); err != ErrAccountAlreadyExists {
+		t.Errorf("expected ErrAccountAlreadyExists, got %v", err)
+	}
+}
+
+func TestImportAccountTooLarge(t *testing.T) {
+	source := newBackupTestClient(t)
+	dataDir := source.signalCliDataDir()
+	writeBackupTestFile(t, filepath.Join(dataDir, "accounts.json"),
+		`{"accounts":[{"path":"123456","environment":"LIVE","number":"+431212131491291","uuid":"abc"}],"version":2}`)
+	writeBackupTestFile(t, filepath.Join(dataDir, "123456.d", "account.db"), strings.Repeat("a", 4096))
+	archive, err := source.ExportAccount("+431212131491291", "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defer func(size int64) { maxAccountArchiveExtractedSize = size }(maxAccountArchiveExtractedSize)
+	maxAccountArchiveExtractedSize = 4096
+
+	target := newBackupTestClient(t)
+	if _, err := target.ImportAccount(archive, "password"); err != ErrAccountArchiveTooLarge {
+		t.Errorf("expected ErrAccountArchiveTooLarge, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target.signalCliDataDir(), "123456.d")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be imported")
+	}
+	expectNoStagingDirectory(t, target.signalCliDataDir())
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	uuid "github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+type AccountProfile struct {
+	GivenName  string `json:"given_name"`
+	FamilyName string `json:"family_name"`
+	About      string `json:"about"`
+	HasAvatar  bool   `json:"has_avatar"`
+	AvatarUrl  string `json:"avatar_url,omitempty"`
+}
+
+type AccountDetails struct {
+	Number               string         `json:"number"`
+	Uuid                 string         `json:"uuid"`
+	Username             string         `json:"username,omitempty"`
+	UsernameLink         string         `json:"username_link,omitempty"`
+	Profile              AccountProfile `json:"profile"`
+	DeviceId             *int64         `json:"device_id,omitempty"`
+	PrimaryDevice        *bool          `json:"primary_device,omitempty"`
+	RegistrationLock     *bool          `json:"registration_lock,omitempty"`
+	DiscoverableByNumber *bool          `json:"discoverable_by_number,omitempty"`
+	NumberSharing        *bool          `json:"number_sharing,omitempty"`
+	TrustMode            string         `json:"trust_mode"`
+	LastReceiveTimestamp int64          `json:"last_receive_timestamp,omitempty"`
+}
+
+// signalCliAccountFile contains the parts of the signal-cli account file that
+// are not available via the signal-cli commands. The file format is internal to
+// signal-cli, so it is tracked whether the fields whose absence would be
+// misleading exist - in case they are missing (e.g. because a newer signal-cli
+// version renamed them), they are reported as unknown.
+type signalCliAccountFile struct {
+	DeviceId             *int64                  `json:"deviceId"`
+	Username             string                  `json:"username"`
+	UsernameLinkEntropy  string                  `json:"usernameLinkEntropy"`
+	UsernameLinkServerId string                  `json:"usernameLinkServerId"`
+	RegistrationLockPin  signalCliOptionalString `json:"registrationLockPin"`
+	LastReceiveTimestamp int64                   `json:"lastReceiveTimestamp"`
+	ConfigurationStore   struct {
+		PhoneNumberUnlisted    *bool  `json:"phoneNumberUnlisted"`
+		PhoneNumberSharingMode string `json:"phoneNumberSharingMode"`
+	} `json:"configurationStore"`
+}
+
+// signalCliOptionalString distinguishes a field that is null (e.g. the
+// registration lock pin of an account without registration lock) from a field
+// that doesn't exist.
+type signalCliOptionalString struct {
+	Exists bool
+	Value  *string
+}
+
+func (o *signalCliOptionalString) UnmarshalJSON(data []byte) error {
+	o.Exists = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+// usernameLink creates the link (https://signal.me/#eu/...) that can be shared
+// to let others find the account by its username.
+func (f signalCliAccountFile) usernameLink() string {
+	entropy, err := base64.StdEncoding.DecodeString(f.UsernameLinkEntropy)
+	if err != nil || len(entropy) == 0 {
+		return ""
+	}
+	serverId, err := uuid.FromString(f.UsernameLinkServerId)
+	if err != nil {
+		return ""
+	}
+	return "https://signal.me/#eu/" + base64.RawURLEncoding.EncodeToString(append(entropy, serverId.Bytes()...))
+}
+
+// GetAccountDetails returns the details of the account, which are gathered
+// from the signal-cli data directory, the profile of the account and the
+// api-config.yml.
+func (s *SignalClient) GetAccountDetails(number string) (AccountDetails, error) {
+	accounts, err := loadSignalCliAccounts(s.signalCliDataDir())
+	if err != nil {
+		return AccountDetails{}, err
+	}
+	account := accounts.find(number)
+	if account == nil {
+		return AccountDetails{}, ErrAccountNotFound
+	}
+	return s.getAccountDetails(account), nil
+}
+
+// GetAccountsDetails returns the details of all accounts.
+func (s *SignalClient) GetAccountsDetails() ([]AccountDetails, error) {
+	accounts, err := loadSignalCliAccounts(s.signalCliDataDir())
+	if err != nil {
+		return nil, err
+	}
+
+	details := []AccountDetails{}
+	for _, account := range accounts.list() {
+		if entry, ok := account.(map[string]interface{}); ok {
+			details = append(details, s.getAccountDetails(entry))
+		}
+	}
+	return details, nil
+}
+
+func (s *SignalClient) getAccountDetails(account map[string]interface{}) AccountDetails {
+	details := AccountDetails{}
+	details.Number, _ = account["number"].(string)
+	details.Uuid, _ = account["uuid"].(string)
+	details.TrustMode, _ = utils.TrustModeToString(s.GetTrustMode(details.Number))
+
+	accountPath, _ := account["path"].(string)
+	if isValidAccountPath(accountPath) {
+		var accountFile signalCliAccountFile
+		data, err := os.ReadFile(filepath.Join(s.signalCliDataDir(), accountPath))
+		if err == nil {
+			err = json.Unmarshal(data, &accountFile)
+		}
+		if err != nil {
+			log.Warn("Couldn't read the signal-cli account file of ", details.Number, ": ", err.Error())
+		}
+
+		details.Username = accountFile.Username
+		details.UsernameLink = accountFile.usernameLink()
+		if accountFile.DeviceId != nil {
+			primaryDevice := *accountFile.DeviceId == 1
+			details.DeviceId = accountFile.DeviceId
+			details.PrimaryDevice = &primaryDevice
+		}
+		if accountFile.RegistrationLockPin.Exists {
+			registrationLock := accountFile.RegistrationLockPin.Value != nil && *accountFile.RegistrationLockPin.Value != ""
+			details.RegistrationLock = &registrationLock
+		}
+		details.LastReceiveTimestamp = accountFile.LastReceiveTimestamp
+		if accountFile.ConfigurationStore.PhoneNumberUnlisted != nil {
+			discoverable := !*accountFile.ConfigurationStore.PhoneNumberUnlisted
+			details.DiscoverableByNumber = &discoverable
+		}
+		if accountFile.ConfigurationStore.PhoneNumberSharingMode != "" {
+			numberSharing := accountFile.ConfigurationStore.PhoneNumberSharingMode == "EVERYBODY"
+			details.NumberSharing = &numberSharing
+		}
+	}
+
+	contacts, err := s.ListContacts(details.Number, false, details.Number)
+	if err != nil {
+		log.Warn("Couldn't get the profile of ", details.Number, ": ", err.Error())
+	} else if len(contacts) > 0 {
+		profile := contacts[0].Profile
+		details.Profile = AccountProfile{GivenName: profile.GivenName, FamilyName: profile.FamilyName, About: profile.About,
+			HasAvatar: profile.HasAvatar}
+		if profile.HasAvatar && details.Uuid != "" {
+			avatarPath := "/v1/contacts/" + details.Number + "/" + details.Uuid + "/avatar"
+			if s.urlSigner != nil {
+				details.Profile.AvatarUrl, _ = s.SignUrl(avatarPath, 0)
+			} else {
+				details.Profile.AvatarUrl = s.publicUrl + avatarPath
+			}
+		}
+	}
+
+	return details
+}
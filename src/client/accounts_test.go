@@ -0,0 +1,79 @@
+package client
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetAccountDetails(t *testing.T) {
+	s := newBackupTestClient(t)
+	dataDir := s.signalCliDataDir()
+	writeBackupTestFile(t, filepath.Join(dataDir, "accounts.json"),
+		`{"accounts":[{"path":"123456","environment":"LIVE","number":"+431212131491291","uuid":"abc"}],"version":2}`)
+	writeBackupTestFile(t, filepath.Join(dataDir, "123456"), `{"deviceId":2,"username":"test.01",
+		"usernameLinkEntropy":"AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=","usernameLinkServerId":"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"registrationLockPin":"1234","lastReceiveTimestamp":1700000000000,
+		"configurationStore":{"phoneNumberUnlisted":true,"phoneNumberSharingMode":"NOBODY"}}`)
+
+	if _, err := s.GetAccountDetails("+431212131491292"); err != ErrAccountNotFound {
+		t.Errorf("expected ErrAccountNotFound, got %v", err)
+	}
+
+	details, err := s.GetAccountDetails("+431212131491291")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if details.Uuid != "abc" || details.Username != "test.01" || details.DeviceId == nil || *details.DeviceId != 2 ||
+		details.PrimaryDevice == nil || *details.PrimaryDevice {
+		t.Errorf("unexpected account details: %+v", details)
+	}
+	if details.RegistrationLock == nil || !*details.RegistrationLock || details.LastReceiveTimestamp != 1700000000000 {
+		t.Errorf("unexpected account details: %+v", details)
+	}
+	if details.DiscoverableByNumber == nil || *details.DiscoverableByNumber || details.NumberSharing == nil || *details.NumberSharing {
+		t.Errorf("unexpected discoverability settings: %+v", details)
+	}
+	if details.UsernameLink != "https://signal.me/#eu/AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh9rp7gQna0R0YC0AMBP1DDI" {
+		t.Errorf("unexpected username link: %s", details.UsernameLink)
+	}
+
+	list, err := s.GetAccountsDetails()
+	if err != nil || len(list) != 1 || list[0].Number != "+431212131491291" {
+		t.Errorf("unexpected result: %+v (%v)", list, err)
+	}
+}
+
+func TestGetAccountDetailsWithUnknownFields(t *testing.T) {
+	s := newBackupTestClient(t)
+	dataDir := s.signalCliDataDir()
+	writeBackupTestFile(t, filepath.Join(dataDir, "accounts.json"),
+		`{"accounts":[{"path":"123456","environment":"LIVE","number":"+431212131491291","uuid":"abc"},`+
+			`{"path":"234567","environment":"LIVE","number":"+431212131491292","uuid":"def"}],"version":2}`)
+	// the fields signal-cli keeps in its account file are missing (e.g. because they were renamed)
+	writeBackupTestFile(t, filepath.Join(dataDir, "123456"), `{"username":"test.01"}`)
+	writeBackupTestFile(t, filepath.Join(dataDir, "234567"), `{"deviceId":1,"registrationLockPin":null}`)
+
+	details, err := s.GetAccountDetails("+431212131491291")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if details.DeviceId != nil || details.PrimaryDevice != nil || details.RegistrationLock != nil || details.DiscoverableByNumber != nil {
+		t.Errorf("expected the missing fields to be unknown: %+v", details)
+	}
+	data, _ := json.Marshal(details)
+	for _, field := range []string{"device_id", "primary_device", "registration_lock", "discoverable_by_number"} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("expected %s to be omitted: %s", field, string(data))
+		}
+	}
+
+	details, err = s.GetAccountDetails("+431212131491292")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if details.PrimaryDevice == nil || !*details.PrimaryDevice || details.RegistrationLock == nil || *details.RegistrationLock {
+		t.Errorf("unexpected account details: %+v", details)
+	}
+}
@@ -3,7 +3,9 @@ package client
 import (
 	"encoding/base64"
 	"errors"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 
@@ -60,11 +62,29 @@ func (attachmentEntry *AttachmentEntry) extractMetaData(attachmentData string) {
 				continue
 			}
 
-			attachmentEntry.setFieldValueByName(metaDataFieldName, metaDataLineItem[len(metaDataKey):])
+			value := metaDataLineItem[len(metaDataKey):]
+			if metaDataFieldName == "FileName" {
+				value = unescapeFileName(value)
+			}
+			attachmentEntry.setFieldValueByName(metaDataFieldName, value)
 		}
 	}
 }
 
+// unescapeFileName decodes the percent-encoded filename of a data URI (e.g.
+// "a%20b.txt", as used in received messages). Filenames that aren't valid
+// percent-encoded strings are used as they are. Only the last path element is
+// kept, so that the attachment can't be stored outside of its directory.
+func unescapeFileName(fileName string) string {
+	if unescaped, err := url.PathUnescape(fileName); err == nil {
+		fileName = unescaped
+	}
+	if fileName == "" {
+		return fileName
+	}
+	return filepath.Base(filepath.FromSlash(fileName))
+}
+
 func (attachmentEntry *AttachmentEntry) storeBase64AsTemporaryFile() error {
 	if strings.Compare(attachmentEntry.Base64, "") == 0 {
 		return errors.New("The base64 data does not exist.")
@@ -165,7 +185,7 @@ func (attachmentEntry *AttachmentEntry) toDataForSignal() string {
 	result := "data:" + attachmentEntry.MimeInfo
 
 	if len(attachmentEntry.FileName) > 0 {
-		result = result + ";filename=" + attachmentEntry.FileName
+		result = result + ";filename=" + url.PathEscape(attachmentEntry.FileName)
 	}
 
 	return result + ";base64," + attachmentEntry.Base64
@@ -48,6 +48,12 @@ func Test_Attachment_ExtractMetadata_ShouldPrepareDataFor_toDataForSignal(t *tes
 		{
 			"base64 prefix at start +data", "base64,data:someData", false, "data:someData", false, "", "", "data:someData",
 		},
+		{
+			"+base64 +data +escaped filename", "data:someData;filename=a%20b%3Bc.name;base64,MTIzNDU=", true, "MTIzNDU=", true, "a b;c.name", "someData", "data:someData;filename=a%20b%3Bc.name;base64,MTIzNDU=",
+		},
+		{
+			"+base64 +data +filename with path", "data:someData;filename=..%2F..%2Ffile.name;base64,MTIzNDU=", true, "MTIzNDU=", true, "file.name", "someData", "data:someData;filename=file.name;base64,MTIzNDU=",
+		},
 	}
 
 	attachmentTmp := flag.String("attachment-tmp-dir", string(os.PathSeparator)+"tmp"+string(os.PathSeparator), "Attachment tmp directory")
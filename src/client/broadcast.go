@@ -0,0 +1,158 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+)
+
+type broadcastJob struct {
+	recipientType ds.RecpType
+	recipients    []string //as provided by the REST API consumer
+}
+
+func (s *SignalClient) runBroadcastJob(signalCliSendRequest ds.SignalCliSendRequest, job broadcastJob, attachmentEntries []AttachmentEntry) []ds.BroadcastResult {
+	signalCliSendRequest.RecipientType = job.recipientType
+	signalCliSendRequest.Recipients = []string{}
+	for _, recipient := range job.recipients {
+		signalCliSendRequest.Recipients = append(signalCliSendRequest.Recipients, strings.TrimPrefix(recipient, groupPrefix))
+	}
+
+	results := []ds.BroadcastResult{}
+	resp, err := s.sendWithHooks(signalCliSendRequest, attachmentEntries)
+	if err != nil {
+		var challengeTokens []string
+		if rateLimitError, ok := err.(*RateLimitErrorType); ok {
+			challengeTokens = rateLimitError.ChallengeTokens
+		}
+		for _, recipient := range job.recipients {
+			results = append(results, ds.BroadcastResult{Recipient: recipient, Success: false, Error: err.Error(), ChallengeTokens: challengeTokens})
+		}
+		return results
+	}
+
+	if job.recipientType == ds.Group {
+		//failures of individual group members don't make the whole group send fail
+		return append(results, ds.BroadcastResult{Recipient: job.recipients[0], Success: true, Timestamp: resp.Timestamp, Errors: resp.Errors})
+	}
+
+	failedRecipients := make(map[string]string)
+	if resp.Errors != nil {
+		for _, sendMessageError := range resp.Errors.Recipients {
+			if sendMessageError.Number != "" {
+				failedRecipients[sendMessageError.Number] = sendMessageError.Reason
+			}
+			if sendMessageError.Username != "" {
+				failedRecipients[sendMessageError.Username] = sendMessageError.Reason
+			}
+			if sendMessageError.Uuid != "" {
+				failedRecipients[sendMessageError.Uuid] = sendMessageError.Reason
+			}
+		}
+	}
+
+	for _, recipient := range job.recipients {
+		if reason, ok := failedRecipients[recipient]; ok {
+			results = append(results, ds.BroadcastResult{Recipient: recipient, Success: false, Timestamp: resp.Timestamp, Error: reason})
+		} else {
+			results = append(results, ds.BroadcastResult{Recipient: recipient, Success: true, Timestamp: resp.Timestamp})
+		}
+	}
+	return results
+}
+
+// Broadcast sends the same message to any mix of phone numbers, usernames and
+// groups. The attachments are only decoded and stored once; phone numbers and
+// usernames are sent in one batch each and every group is sent to separately.
+// Uploading the attachments only once isn't possible, as signal-cli can't reuse
+// already uploaded attachments: they are uploaded again for every send, i.e. once
+// for the phone numbers, once for the usernames and once per group. At most
+// maxConcurrency sends run in parallel (in normal and native mode the sends are
+// done sequentially, as signal-cli can't be invoked concurrently for the same
+// account).
+// The returned results are in the same order as the provided recipients.
+func (s *SignalClient) Broadcast(signalCliSendRequest ds.SignalCliSendRequest, maxConcurrency int) ([]ds.BroadcastResult, error) {
+	if signalCliSendRequest.Number == "" {
+		return nil, errors.New("Please provide a valid number")
+	}
+
+	if len(signalCliSendRequest.Recipients) == 0 {
+		return nil, errors.New("Please provide at least one recipient")
+	}
+
+	jobs := []broadcastJob{}
+	numbersJob := broadcastJob{recipientType: ds.Number}
+	usernamesJob := broadcastJob{recipientType: ds.Username}
+	uniqueRecipients := []string{}
+	seenRecipients := make(map[string]bool)
+	for _, recipient := range signalCliSendRequest.Recipients {
+		if seenRecipients[recipient] {
+			continue
+		}
+		seenRecipients[recipient] = true
+		uniqueRecipients = append(uniqueRecipients, recipient)
+
+		recipientType, err := getRecipientType(recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		if recipientType == ds.Group {
+			jobs = append(jobs, broadcastJob{recipientType: ds.Group, recipients: []string{recipient}})
+		} else if recipientType == ds.Number {
+			numbersJob.recipients = append(numbersJob.recipients, recipient)
+		} else if recipientType == ds.Username {
+			usernamesJob.recipients = append(usernamesJob.recipients, recipient)
+		} else {
+			return nil, errors.New("Invalid recipient type")
+		}
+	}
+
+	if len(numbersJob.recipients) > 0 {
+		jobs = append(jobs, numbersJob)
+	}
+	if len(usernamesJob.recipients) > 0 {
+		jobs = append(jobs, usernamesJob)
+	}
+
+	attachmentEntries, err := s.storeAttachments(signalCliSendRequest.Base64Attachments)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupAttachmentEntries(attachmentEntries, nil)
+
+	if s.signalCliMode != JsonRpc || maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	var resultsMutex sync.Mutex
+	resultsByRecipient := make(map[string]ds.BroadcastResult)
+	semaphore := make(chan struct{}, maxConcurrency)
+	for _, job := range jobs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(job broadcastJob) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			results := s.runBroadcastJob(signalCliSendRequest, job, attachmentEntries)
+			resultsMutex.Lock()
+			for _, result := range results {
+				resultsByRecipient[result.Recipient] = result
+			}
+			resultsMutex.Unlock()
+		}(job)
+	}
+	wg.Wait()
+
+	results := []ds.BroadcastResult{}
+	for _, recipient := range uniqueRecipients {
+		results = append(results, resultsByRecipient[recipient])
+	}
+	return results, nil
+}
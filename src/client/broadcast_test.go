@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+)
+
+func TestBroadcast(t *testing.T) {
+	rateLimitedGroupId := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32)))
+	rateLimitedGroup := groupPrefix + base64.StdEncoding.EncodeToString([]byte(rateLimitedGroupId))
+
+	var sendsMutex sync.Mutex
+	sends := []map[string]interface{}{}
+	s := newFakeSignalCliDaemon(t, func(method string, params json.RawMessage) string {
+		var request map[string]interface{}
+		json.Unmarshal(params, &request)
+		sendsMutex.Lock()
+		sends = append(sends, request)
+		sendsMutex.Unlock()
+
+		if request["group-id"] == rateLimitedGroupId {
+			return `"error":{"code":-5,"message":"Rate limit exceeded","data":{"response":{"results":[{"token":"challenge-token"}]}}}`
+		}
+		if _, ok := request["recipient"]; ok {
+			return `"result":{"timestamp":1700000000000,"results":[{"recipientAddress":{"uuid":"a","number":"+431212131491291"},"type":"SUCCESS"},` +
+				`{"recipientAddress":{"uuid":"b","number":"+431212131491292"},"type":"UNREGISTERED_FAILURE"}]}`
+		}
+		return `"result":{"timestamp":1700000000000,"results":[]}`
+	})
+
+	recipients := []string{"+431212131491291", sampleGroupRecipient, "group.user.01", "+431212131491292", rateLimitedGroup,
+		"+431212131491291", sampleGroupRecipient}
+	results, err := s.Broadcast(ds.SignalCliSendRequest{Number: "+431212131491290", Message: "hello", Recipients: recipients,
+		Base64Attachments: []string{base64.StdEncoding.EncodeToString([]byte("attachment"))}}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ds.BroadcastResult{
+		{Recipient: "+431212131491291", Success: true, Timestamp: "1700000000000"},
+		{Recipient: sampleGroupRecipient, Success: true, Timestamp: "1700000000000"},
+		{Recipient: "group.user.01", Success: true, Timestamp: "1700000000000"},
+		{Recipient: "+431212131491292", Success: false, Timestamp: "1700000000000", Error: "UNREGISTERED_FAILURE"},
+		{Recipient: rateLimitedGroup, Success: false, Error: "Rate limit exceeded", ChallengeTokens: []string{"challenge-token"}},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected results:\n%+v\nexpected:\n%+v", results, expected)
+	}
+
+	// one send per group, one for all phone numbers and one for all usernames
+	if len(sends) != 4 {
+		t.Fatalf("expected 4 sends, got %d", len(sends))
+	}
+	for _, send := range sends {
+		if recipients, ok := send["recipient"]; ok && !reflect.DeepEqual(recipients, []interface{}{"+431212131491291", "+431212131491292"}) {
+			t.Errorf("unexpected phone number recipients: %v", recipients)
+		}
+		if usernames, ok := send["username"]; ok && !reflect.DeepEqual(usernames, []interface{}{"user.01"}) {
+			t.Errorf("unexpected username recipients: %v", usernames)
+		}
+		if !reflect.DeepEqual(send["attachment"], sends[0]["attachment"]) {
+			t.Errorf("expected the attachment to be stored only once: %v, %v", send["attachment"], sends[0]["attachment"])
+		}
+	}
+}
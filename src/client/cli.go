@@ -6,20 +6,25 @@ import (
 	"errors"
 	utils "github.com/bbernhard/signal-cli-rest-api/utils"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
 type CliClient struct {
 	signalCliMode      SignalCliMode
 	signalCliApiConfig *utils.SignalCliApiConfig
+	cmdTimeout         time.Duration
+	mutex              sync.RWMutex // locked exclusively while the account data is exported or imported
 }
 
-func NewCliClient(signalCliMode SignalCliMode, signalCliApiConfig *utils.SignalCliApiConfig) *CliClient {
+func NewCliClient(signalCliMode SignalCliMode, signalCliApiConfig *utils.SignalCliApiConfig, cmdTimeout time.Duration) *CliClient {
 	return &CliClient{
 		signalCliMode:      signalCliMode,
 		signalCliApiConfig: signalCliApiConfig,
+		cmdTimeout:         cmdTimeout,
 	}
 }
 
@@ -49,7 +54,8 @@ func stripInfoAndWarnMessages(input string) (string, string, string) {
 	return output, infoMessages, warnMessages
 }
 
-func (s *CliClient) Execute(wait bool, args []string, stdin string) (string, error) {
+// command prepares the signal-cli command with the given arguments.
+func (s *CliClient) command(args []string, stdin string) (*exec.Cmd, error) {
 	containerId, err := getContainerId()
 
 	log.Debug("If you want to run this command manually, run the following steps on your host system:")
@@ -65,7 +71,7 @@ func (s *CliClient) Execute(wait bool, args []string, stdin string) (string, err
 	} else if s.signalCliMode == Native {
 		signalCliBinary = "signal-cli-native"
 	} else {
-		return "", errors.New("Invalid signal-cli mode")
+		return nil, errors.New("Invalid signal-cli mode")
 	}
 
 	//check if args contain number
@@ -98,72 +104,121 @@ func (s *CliClient) Execute(wait bool, args []string, stdin string) (string, err
 	log.Debug("*) su signal-api")
 	log.Debug("*) ", fullCmd)
 
-	cmdTimeout, err := utils.GetIntEnv("SIGNAL_CLI_CMD_TIMEOUT", 120)
-	if err != nil {
-		log.Error("Env variable 'SIGNAL_CLI_CMD_TIMEOUT' contains an invalid timeout...falling back to default timeout (120 seconds)")
-		cmdTimeout = 120
-	}
-
 	cmd := exec.Command(signalCliBinary, args...)
 	if stdin != "" {
 		cmd.Stdin = strings.NewReader(stdin)
 	}
-	if wait {
-		var stdoutBuffer bytes.Buffer
-		var stderrBuffer bytes.Buffer
-		cmd.Stdout = &stdoutBuffer
-		cmd.Stderr = &stderrBuffer
+	return cmd, nil
+}
+
+// Pause waits until the running signal-cli commands are finished and blocks
+// new commands until the returned resume function is called.
+func (s *CliClient) Pause() func() {
+	s.mutex.Lock()
+	return s.mutex.Unlock
+}
+
+func (s *CliClient) Execute(wait bool, args []string, stdin string) (string, error) {
+	if !wait {
+		return s.Start(args, nil)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	cmd, err := s.command(args, stdin)
+	if err != nil {
+		return "", err
+	}
 
-		err := cmd.Start()
+	var stdoutBuffer bytes.Buffer
+	var stderrBuffer bytes.Buffer
+	cmd.Stdout = &stdoutBuffer
+	cmd.Stderr = &stderrBuffer
+
+	err = cmd.Start()
+	if err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case <-time.After(s.cmdTimeout):
+		err := cmd.Process.Kill()
 		if err != nil {
 			return "", err
 		}
-
-		done := make(chan error, 1)
-		go func() {
-			done <- cmd.Wait()
-		}()
-		select {
-		case <-time.After(time.Duration(cmdTimeout) * time.Second):
-			err := cmd.Process.Kill()
-			if err != nil {
-				return "", err
-			}
-			return "", errors.New("process killed as timeout reached")
-		case err := <-done:
-			if err != nil {
-				combinedOutput := stdoutBuffer.String() + stderrBuffer.String()
-				log.Debug("signal-cli output (stdout): ", stdoutBuffer.String())
-				log.Debug("signal-cli output (stderr): ", stderrBuffer.String())
-				return "", errors.New(combinedOutput)
-			}
+		return "", errors.New("process killed as timeout reached")
+	case err := <-done:
+		if err != nil {
+			combinedOutput := stdoutBuffer.String() + stderrBuffer.String()
+			log.Debug("signal-cli output (stdout): ", stdoutBuffer.String())
+			log.Debug("signal-cli output (stderr): ", stderrBuffer.String())
+			return "", errors.New(combinedOutput)
 		}
+	}
 
-		combinedOutput := stdoutBuffer.String() + stderrBuffer.String()
-		log.Debug("signal-cli output (stdout): ", stdoutBuffer.String())
-		log.Debug("signal-cli output (stderr): ", stderrBuffer.String())
-		strippedOutput, infoMessages, warnMessages := stripInfoAndWarnMessages(combinedOutput)
-		for _, line := range strings.Split(infoMessages, "\n") {
-			if line != "" {
-				log.Info(line)
-			}
+	combinedOutput := stdoutBuffer.String() + stderrBuffer.String()
+	log.Debug("signal-cli output (stdout): ", stdoutBuffer.String())
+	log.Debug("signal-cli output (stderr): ", stderrBuffer.String())
+	strippedOutput, infoMessages, warnMessages := stripInfoAndWarnMessages(combinedOutput)
+	for _, line := range strings.Split(infoMessages, "\n") {
+		if line != "" {
+			log.Info(line)
 		}
+	}
 
-		for _, line := range strings.Split(warnMessages, "\n") {
-			if line != "" {
-				log.Warn(line)
-			}
+	for _, line := range strings.Split(warnMessages, "\n") {
+		if line != "" {
+			log.Warn(line)
 		}
+	}
 
-		return strippedOutput, nil
-	} else {
-		stdout, err := cmd.StdoutPipe()
+	return strippedOutput, nil
+}
+
+// Start starts signal-cli without waiting for it to terminate and returns the
+// first line of the output. Once signal-cli terminates, done (if set) is invoked
+// with the remaining output and the error (if any).
+func (s *CliClient) Start(args []string, done func(output string, err error)) (string, error) {
+	s.mutex.RLock()
+	cmd, err := s.command(args, "")
+	if err != nil {
+		s.mutex.RUnlock()
+		return "", err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		s.mutex.RUnlock()
+		return "", err
+	}
+	var stderrBuffer bytes.Buffer
+	cmd.Stderr = &stderrBuffer
+	err = cmd.Start()
+	if err != nil {
+		s.mutex.RUnlock()
+		return "", err
+	}
+
+	buf := bufio.NewReader(stdout)
+	line, _, _ := buf.ReadLine()
+
+	go func() {
+		output, _ := io.ReadAll(buf)
+		err := cmd.Wait()
+		s.mutex.RUnlock()
+		log.Debug("signal-cli output (stdout): ", string(output))
+		log.Debug("signal-cli output (stderr): ", stderrBuffer.String())
 		if err != nil {
-			return "", err
+			err = errors.New(string(output) + stderrBuffer.String())
 		}
-		cmd.Start()
-		buf := bufio.NewReader(stdout) // Notice that this is not in a loop
-		line, _, _ := buf.ReadLine()
-		return string(line), nil
-	}
+		if done != nil {
+			done(string(output), err)
+		}
+	}()
+	return string(line), nil
 }
@@ -10,6 +10,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -125,6 +127,7 @@ type GroupEntry struct {
 	InviteLink      string              `json:"invite_link"`
 	Admins          []string            `json:"admins"`
 	Permissions     ds.GroupPermissions `json:"permissions"`
+	ExpirationTime  int                 `json:"expiration_time"`
 }
 
 type GroupMember struct {
@@ -150,6 +153,7 @@ type ExpandedGroupEntry struct {
 	InviteLink      string              `json:"invite_link"`
 	Admins          []GroupAdmin        `json:"admins"`
 	Permissions     ds.GroupPermissions `json:"permissions"`
+	ExpirationTime  int                 `json:"expiration_time"`
 }
 
 type IdentityEntry struct {
@@ -176,6 +180,7 @@ type SignalCliGroupEntry struct {
 	PermissionEditDetails string        `json:"permissionEditDetails"`
 	PermissionAddMember   string        `json:"permissionAddMember"`
 	PermissionSendMessage string        `json:"permissionSendMessage"`
+	MessageExpirationTime int           `json:"messageExpirationTime"`
 }
 
 type SignalCliIdentityEntry struct {
@@ -406,6 +411,19 @@ type SignalClient struct {
 	signalCliApiConfig       *utils.SignalCliApiConfig
 	cliClient                *CliClient
 	receiveWebhookUrl        string
+	receiveWebhookUrlMutex   sync.RWMutex
+	urlSigner                *utils.UrlSigner
+	signedUrlValidity        time.Duration
+	publicUrl                string
+	attachmentInlineMaxSize  int64
+	signalCliCmdTimeout      time.Duration
+	receivedMessageHandlers  []func(data []byte)
+	receivedMessageMutex     sync.RWMutex
+	preSendHooks             []PreSendHook
+	postSendHooks            []PostSendHook
+	sendHooksMutex           sync.RWMutex
+	onboardingSessions       *onboardingSessions
+	groupChanges             *groupChanges
 }
 
 func NewSignalClient(signalCliConfig string, attachmentTmpDir string, avatarTmpDir string, signalCliMode SignalCliMode,
@@ -419,6 +437,7 @@ func NewSignalClient(signalCliConfig string, attachmentTmpDir string, avatarTmpD
 		jsonRpc2Clients:          make(map[string]*JsonRpc2Client),
 		signalCliApiConfigPath:   signalCliApiConfigPath,
 		receiveWebhookUrl:        receiveWebhookUrl,
+		signalCliCmdTimeout:      120 * time.Second,
 	}
 }
 
@@ -426,6 +445,45 @@ func (s *SignalClient) GetSignalCliMode() SignalCliMode {
 	return s.signalCliMode
 }
 
+func (s *SignalClient) SetUrlSigner(urlSigner *utils.UrlSigner, signedUrlValidity time.Duration, publicUrl string) {
+	s.urlSigner = urlSigner
+	s.signedUrlValidity = signedUrlValidity
+	s.publicUrl = strings.TrimSuffix(publicUrl, "/")
+}
+
+func (s *SignalClient) SetAttachmentInlineMaxSize(attachmentInlineMaxSize int64) {
+	s.attachmentInlineMaxSize = attachmentInlineMaxSize
+}
+
+func (s *SignalClient) SetSignalCliCommandTimeout(timeout time.Duration) {
+	s.signalCliCmdTimeout = timeout
+}
+
+// SetReceiveWebhookUrl changes the URL received messages are posted to (json-rpc mode only).
+func (s *SignalClient) SetReceiveWebhookUrl(receiveWebhookUrl string) {
+	s.receiveWebhookUrlMutex.Lock()
+	defer s.receiveWebhookUrlMutex.Unlock()
+	s.receiveWebhookUrl = receiveWebhookUrl
+}
+
+// getReceiveWebhookUrl returns the URL the messages that are received for the
+// given account are posted to. The webhook URL of the account takes precedence
+// over the global one.
+func (s *SignalClient) getReceiveWebhookUrl(account string) string {
+	accountSettings := s.signalCliApiConfig.GetAccountSettings(account)
+	if accountSettings.ReceiveWebhookUrl != nil {
+		return *accountSettings.ReceiveWebhookUrl
+	}
+
+	s.receiveWebhookUrlMutex.RLock()
+	defer s.receiveWebhookUrlMutex.RUnlock()
+	return s.receiveWebhookUrl
+}
+
+func (s *SignalClient) GetSignalCliApiConfig() *utils.SignalCliApiConfig {
+	return s.signalCliApiConfig
+}
+
 func (s *SignalClient) Init(maxRetries int) error {
 	s.signalCliApiConfig = utils.NewSignalCliApiConfig()
 	err := s.signalCliApiConfig.Load(s.signalCliApiConfigPath)
@@ -448,10 +506,11 @@ func (s *SignalClient) Init(maxRetries int) error {
 				return err
 			}
 
-			go s.jsonRpc2Clients[number].ReceiveData(number, s.receiveWebhookUrl) //receive messages in goroutine
+			s.jsonRpc2Clients[number].SetReceivedMessageTransformer(s.processReceivedMessage)
+			go s.jsonRpc2Clients[number].ReceiveData(number, s.getReceiveWebhookUrl) //receive messages in goroutine
 		}
 	} else {
-		s.cliClient = NewCliClient(s.signalCliMode, s.signalCliApiConfig)
+		s.cliClient = NewCliClient(s.signalCliMode, s.signalCliApiConfig, s.signalCliCmdTimeout)
 	}
 
 	return nil
@@ -479,7 +538,38 @@ func validateLinkPreview(message string, linkPreview *ds.LinkPreviewType) error
 	return nil
 }
 
-func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest) (*ds.SendMessageResponse, error) {
+// storeAttachments stores the given base64 encoded attachments as temporary
+// files, so that they can be handed over to signal-cli.
+func (s *SignalClient) storeAttachments(base64Attachments []string) ([]AttachmentEntry, error) {
+	attachmentEntries := []AttachmentEntry{}
+	for _, base64Attachment := range base64Attachments {
+		attachmentEntry := NewAttachmentEntry(base64Attachment, s.attachmentTmpDir)
+
+		err := attachmentEntry.storeBase64AsTemporaryFile()
+		if err != nil {
+			cleanupAttachmentEntries(attachmentEntries, nil)
+			return nil, err
+		}
+
+		attachmentEntries = append(attachmentEntries, *attachmentEntry)
+	}
+	return attachmentEntries, nil
+}
+
+func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest, runHooks bool) (*ds.SendMessageResponse, error) {
+	attachmentEntries, err := s.storeAttachments(signalCliSendRequest.Base64Attachments)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupAttachmentEntries(attachmentEntries, nil)
+
+	if !runHooks {
+		return s.sendWithAttachmentEntries(signalCliSendRequest, attachmentEntries)
+	}
+	return s.sendWithHooks(signalCliSendRequest, attachmentEntries)
+}
+
+func (s *SignalClient) sendWithAttachmentEntries(signalCliSendRequest ds.SignalCliSendRequest, attachmentEntries []AttachmentEntry) (*ds.SendMessageResponse, error) {
 	var rawData string
 	var linkPreviewAttachmentEntry *AttachmentEntry = nil
 
@@ -511,19 +601,6 @@ func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest) (*ds.S
 		groupId = string(grpId)
 	}
 
-	attachmentEntries := []AttachmentEntry{}
-	for _, base64Attachment := range signalCliSendRequest.Base64Attachments {
-		attachmentEntry := NewAttachmentEntry(base64Attachment, s.attachmentTmpDir)
-
-		err := attachmentEntry.storeBase64AsTemporaryFile()
-		if err != nil {
-			cleanupAttachmentEntries(attachmentEntries, linkPreviewAttachmentEntry)
-			return nil, err
-		}
-
-		attachmentEntries = append(attachmentEntries, *attachmentEntry)
-	}
-
 	if s.signalCliMode == JsonRpc {
 		jsonRpc2Client, err := s.getJsonRpc2Client()
 		if err != nil {
@@ -608,7 +685,7 @@ func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest) (*ds.S
 				linkPreviewAttachmentEntry = NewAttachmentEntry(signalCliSendRequest.LinkPreview.Base64Thumbnail, s.attachmentTmpDir)
 				err := linkPreviewAttachmentEntry.storeBase64AsTemporaryFile()
 				if err != nil {
-					cleanupAttachmentEntries(attachmentEntries, linkPreviewAttachmentEntry)
+					cleanupAttachmentEntries(nil, linkPreviewAttachmentEntry)
 					return nil, err
 				}
 				request.PreviewImage = &linkPreviewAttachmentEntry.FilePath
@@ -617,7 +694,7 @@ func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest) (*ds.S
 
 		rawData, err = jsonRpc2Client.getRaw("send", &signalCliSendRequest.Number, request)
 		if err != nil {
-			cleanupAttachmentEntries(attachmentEntries, linkPreviewAttachmentEntry)
+			cleanupAttachmentEntries(nil, linkPreviewAttachmentEntry)
 			return nil, err
 		}
 	} else {
@@ -689,7 +766,7 @@ func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest) (*ds.S
 				linkPreviewAttachmentEntry = NewAttachmentEntry(signalCliSendRequest.LinkPreview.Base64Thumbnail, s.attachmentTmpDir)
 				err := linkPreviewAttachmentEntry.storeBase64AsTemporaryFile()
 				if err != nil {
-					cleanupAttachmentEntries(attachmentEntries, linkPreviewAttachmentEntry)
+					cleanupAttachmentEntries(nil, linkPreviewAttachmentEntry)
 					return nil, err
 				}
 				cmd = append(cmd, "--preview-image")
@@ -711,7 +788,7 @@ func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest) (*ds.S
 
 		rawData, err = s.cliClient.Execute(true, cmd, signalCliSendRequest.Message)
 		if err != nil {
-			cleanupAttachmentEntries(attachmentEntries, linkPreviewAttachmentEntry)
+			cleanupAttachmentEntries(nil, linkPreviewAttachmentEntry)
 			return nil, err
 		}
 	}
@@ -731,7 +808,7 @@ func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest) (*ds.S
 	var signalCliSendResponse SignalCliSendResponse
 	err = json.Unmarshal([]byte(rawData), &signalCliSendResponse)
 	if err != nil {
-		cleanupAttachmentEntries(attachmentEntries, linkPreviewAttachmentEntry)
+		cleanupAttachmentEntries(nil, linkPreviewAttachmentEntry)
 
 		if strings.Contains(err.Error(), signalCliV2GroupError) {
 			return nil, errors.New("Cannot send message to group - please first update your profile.")
@@ -739,7 +816,7 @@ func (s *SignalClient) send(signalCliSendRequest ds.SignalCliSendRequest) (*ds.S
 		return nil, err
 	}
 
-	cleanupAttachmentEntries(attachmentEntries, linkPreviewAttachmentEntry)
+	cleanupAttachmentEntries(nil, linkPreviewAttachmentEntry)
 
 	resp := ds.SendMessageResponse{Timestamp: strconv.FormatInt(signalCliSendResponse.Timestamp, 10)}
 	for _, entry := range signalCliSendResponse.Results {
@@ -925,7 +1002,7 @@ func (s *SignalClient) SendV1(number string, message string, recipients []string
 	signalCliSendRequest := ds.SignalCliSendRequest{Number: number, Message: message, Recipients: recipients, Base64Attachments: base64Attachments,
 		RecipientType: recipientType, Sticker: "", Mentions: nil, QuoteTimestamp: nil, QuoteAuthor: nil, QuoteMessage: nil,
 		QuoteMentions: nil, TextMode: nil, EditTimestamp: nil, LinkPreview: nil}
-	resp, err := s.send(signalCliSendRequest)
+	resp, err := s.send(signalCliSendRequest, true)
 	return resp, err
 }
 
@@ -945,6 +1022,23 @@ func (s *SignalClient) getJsonRpc2Clients() []*JsonRpc2Client {
 }
 
 func (s *SignalClient) SendV2(number string, message string, recps []string, base64Attachments []string, sticker string, mentions []ds.MessageMention,
+	quoteTimestamp *int64, quoteAuthor *string, quoteMessage *string, quoteMentions []ds.MessageMention, textMode *string, editTimestamp *int64, notifySelf *bool,
+	linkPreview *ds.LinkPreviewType, viewOnce *bool) (*[]ds.SendMessageResponse, error) {
+	return s.sendV2(true, number, message, recps, base64Attachments, sticker, mentions, quoteTimestamp, quoteAuthor, quoteMessage, quoteMentions,
+		textMode, editTimestamp, notifySelf, linkPreview, viewOnce)
+}
+
+// SendV2WithoutHooks sends the message like SendV2, but without running the
+// pre-send and post-send hooks. This is used for messages that are sent from
+// within a send hook, which would otherwise invoke the hook again.
+func (s *SignalClient) SendV2WithoutHooks(number string, message string, recps []string, base64Attachments []string, sticker string, mentions []ds.MessageMention,
+	quoteTimestamp *int64, quoteAuthor *string, quoteMessage *string, quoteMentions []ds.MessageMention, textMode *string, editTimestamp *int64, notifySelf *bool,
+	linkPreview *ds.LinkPreviewType, viewOnce *bool) (*[]ds.SendMessageResponse, error) {
+	return s.sendV2(false, number, message, recps, base64Attachments, sticker, mentions, quoteTimestamp, quoteAuthor, quoteMessage, quoteMentions,
+		textMode, editTimestamp, notifySelf, linkPreview, viewOnce)
+}
+
+func (s *SignalClient) sendV2(runHooks bool, number string, message string, recps []string, base64Attachments []string, sticker string, mentions []ds.MessageMention,
 	quoteTimestamp *int64, quoteAuthor *string, quoteMessage *string, quoteMentions []ds.MessageMention, textMode *string, editTimestamp *int64, notifySelf *bool,
 	linkPreview *ds.LinkPreviewType, viewOnce *bool) (*[]ds.SendMessageResponse, error) {
 	if len(recps) == 0 {
@@ -998,7 +1092,7 @@ func (s *SignalClient) SendV2(number string, message string, recps []string, bas
 			RecipientType: ds.Group, Sticker: sticker, Mentions: mentions, QuoteTimestamp: quoteTimestamp,
 			QuoteAuthor: quoteAuthor, QuoteMessage: quoteMessage, QuoteMentions: quoteMentions,
 			TextMode: textMode, EditTimestamp: editTimestamp, NotifySelf: notifySelf, LinkPreview: linkPreview, ViewOnce: viewOnce}
-		resp, err := s.send(signalCliSendRequest)
+		resp, err := s.send(signalCliSendRequest, runHooks)
 		if err != nil {
 			return nil, err
 		}
@@ -1010,7 +1104,7 @@ func (s *SignalClient) SendV2(number string, message string, recps []string, bas
 			RecipientType: ds.Number, Sticker: sticker, Mentions: mentions, QuoteTimestamp: quoteTimestamp,
 			QuoteAuthor: quoteAuthor, QuoteMessage: quoteMessage, QuoteMentions: quoteMentions,
 			TextMode: textMode, EditTimestamp: editTimestamp, NotifySelf: notifySelf, LinkPreview: linkPreview, ViewOnce: viewOnce}
-		resp, err := s.send(signalCliSendRequest)
+		resp, err := s.send(signalCliSendRequest, runHooks)
 		if err != nil {
 			return nil, err
 		}
@@ -1022,7 +1116,7 @@ func (s *SignalClient) SendV2(number string, message string, recps []string, bas
 			RecipientType: ds.Username, Sticker: sticker, Mentions: mentions, QuoteTimestamp: quoteTimestamp,
 			QuoteAuthor: quoteAuthor, QuoteMessage: quoteMessage, QuoteMentions: quoteMentions,
 			TextMode: textMode, EditTimestamp: editTimestamp, NotifySelf: notifySelf, LinkPreview: linkPreview, ViewOnce: viewOnce}
-		resp, err := s.send(signalCliSendRequest)
+		resp, err := s.send(signalCliSendRequest, runHooks)
 		if err != nil {
 			return nil, err
 		}
@@ -1073,6 +1167,9 @@ func (s *SignalClient) Receive(number string, timeout int64, ignoreAttachments b
 
 		jsonStr := "["
 		for i, line := range lines {
+			if line != "" {
+				line = string(s.processReceivedMessage([]byte(line)))
+			}
 			jsonStr += line
 			if i != (len(lines) - 1) {
 				jsonStr += ","
@@ -1278,11 +1375,19 @@ func (s *SignalClient) updateGroupMembers(number string, groupId string, members
 }
 
 func (s *SignalClient) AddMembersToGroup(number string, groupId string, members []string) error {
-	return s.updateGroupMembers(number, groupId, members, true)
+	err := s.updateGroupMembers(number, groupId, members, true)
+	if err == nil {
+		s.groupChangedByAccount(number, groupId)
+	}
+	return err
 }
 
 func (s *SignalClient) RemoveMembersFromGroup(number string, groupId string, members []string) error {
-	return s.updateGroupMembers(number, groupId, members, false)
+	err := s.updateGroupMembers(number, groupId, members, false)
+	if err == nil {
+		s.groupChangedByAccount(number, groupId)
+	}
+	return err
 }
 
 func (s *SignalClient) updateGroupAdmins(number string, groupId string, admins []string, add bool) error {
@@ -1340,11 +1445,19 @@ func (s *SignalClient) updateGroupAdmins(number string, groupId string, admins [
 }
 
 func (s *SignalClient) AddAdminsToGroup(number string, groupId string, admins []string) error {
-	return s.updateGroupAdmins(number, groupId, admins, true)
+	err := s.updateGroupAdmins(number, groupId, admins, true)
+	if err == nil {
+		s.groupChangedByAccount(number, groupId)
+	}
+	return err
 }
 
 func (s *SignalClient) RemoveAdminsFromGroup(number string, groupId string, admins []string) error {
-	return s.updateGroupAdmins(number, groupId, admins, false)
+	err := s.updateGroupAdmins(number, groupId, admins, false)
+	if err == nil {
+		s.groupChangedByAccount(number, groupId)
+	}
+	return err
 }
 
 func signalCliGroupEntryToExpandedGroupEntry(signalCliGroupEntry SignalCliGroupEntry) ExpandedGroupEntry {
@@ -1356,13 +1469,14 @@ func signalCliGroupEntryToExpandedGroupEntry(signalCliGroupEntry SignalCliGroupE
 	groupEntry.Member = signalCliGroupEntry.IsMember
 	groupEntry.Description = signalCliGroupEntry.Description
 	groupEntry.Permissions.SendMessages = signalCliGroupPermissionToRestApiGroupPermission(signalCliGroupEntry.PermissionSendMessage)
-	groupEntry.Permissions.EditGroup = signalCliGroupPermissionToRestApiGroupPermission(signalCliGroupEntry.PermissionSendMessage)
+	groupEntry.Permissions.EditGroup = signalCliGroupPermissionToRestApiGroupPermission(signalCliGroupEntry.PermissionEditDetails)
 	groupEntry.Permissions.AddMembers = signalCliGroupPermissionToRestApiGroupPermission(signalCliGroupEntry.PermissionAddMember)
 	groupEntry.Members = signalCliGroupEntry.Members
 	groupEntry.PendingInvites = signalCliGroupEntry.PendingMembers
 	groupEntry.PendingRequests = signalCliGroupEntry.RequestingMembers
 	groupEntry.Admins = signalCliGroupEntry.Admins
 	groupEntry.InviteLink = signalCliGroupEntry.GroupInviteLink
+	groupEntry.ExpirationTime = signalCliGroupEntry.MessageExpirationTime
 	return groupEntry
 }
 
@@ -1412,7 +1526,8 @@ func (s *SignalClient) GetGroups(number string) ([]GroupEntry, error) {
 	for _, expandedGroupEntry := range expandedGroupEntries {
 		groupEntry := GroupEntry{InternalId: expandedGroupEntry.InternalId, Name: expandedGroupEntry.Name,
 			Id: expandedGroupEntry.Id, Blocked: expandedGroupEntry.Blocked, Member: expandedGroupEntry.Member, Description: expandedGroupEntry.Description,
-			Permissions: expandedGroupEntry.Permissions, InviteLink: expandedGroupEntry.InviteLink}
+			Permissions: expandedGroupEntry.Permissions, InviteLink: expandedGroupEntry.InviteLink,
+			ExpirationTime: expandedGroupEntry.ExpirationTime}
 
 		members := []string{}
 		for _, val := range expandedGroupEntry.Members {
@@ -1653,51 +1768,15 @@ func (s *SignalClient) DeleteGroup(number string, groupId string) error {
 }
 
 func (s *SignalClient) GetQrCodeLink(deviceName string, qrCodeVersion int) ([]byte, error) {
-	if s.signalCliMode == JsonRpc {
-		jsonRpc2Client, err := s.getJsonRpc2Client()
-		if err != nil {
-			return []byte{}, err
-		}
-
-		type StartRequest struct{}
-		type Response struct {
-			DeviceLinkUri string `json:"deviceLinkUri"`
-		}
-
-		result, err := jsonRpc2Client.getRaw("startLink", nil, &StartRequest{})
-		if err != nil {
-			return []byte{}, errors.New("Couldn't create QR code: " + err.Error())
-		}
-
-		var resp Response
-		err = json.Unmarshal([]byte(result), &resp)
-		if err != nil {
-			return []byte{}, errors.New("Couldn't create QR code: " + err.Error())
-		}
-
-		q, err := qrcode.NewWithForcedVersion(string(resp.DeviceLinkUri), qrCodeVersion, qrcode.Highest)
-		if err != nil {
-			return []byte{}, errors.New("Couldn't create QR code: " + err.Error())
-		}
-
-		var png []byte
-		png, err = q.PNG(256)
-		if err != nil {
-			return []byte{}, errors.New("Couldn't create QR code: " + err.Error())
-		}
-
-		s.finishLinkAsync(jsonRpc2Client, deviceName, resp.DeviceLinkUri)
-
-		return png, nil
-	}
-	command := []string{"--config", s.signalCliConfig, "link", "-n", deviceName}
-
-	tsdeviceLink, err := s.cliClient.Execute(false, command, "")
+	deviceLinkUri, err := s.startLink(deviceName, nil)
 	if err != nil {
 		return []byte{}, errors.New("Couldn't create QR code: " + err.Error())
 	}
+	return createQrCode(deviceLinkUri, qrCodeVersion)
+}
 
-	q, err := qrcode.NewWithForcedVersion(string(tsdeviceLink), qrCodeVersion, qrcode.Highest)
+func createQrCode(deviceLinkUri string, qrCodeVersion int) ([]byte, error) {
+	q, err := qrcode.NewWithForcedVersion(deviceLinkUri, qrCodeVersion, qrcode.Highest)
 	if err != nil {
 		return []byte{}, errors.New("Couldn't create QR code: " + err.Error())
 	}
@@ -1712,6 +1791,17 @@ func (s *SignalClient) GetQrCodeLink(deviceName string, qrCodeVersion int) ([]by
 }
 
 func (s *SignalClient) GetDeviceLinkUri(deviceName string) (string, error) {
+	deviceLinkUri, err := s.startLink(deviceName, nil)
+	if err != nil {
+		return "", errors.New("Couldn't create link URI: " + err.Error())
+	}
+	return deviceLinkUri, nil
+}
+
+// startLink starts linking a new device and returns the device link URI. The
+// linking is finished in the background - once the QR code was scanned (or the
+// linking failed), done (if set) is invoked with the number of the linked account.
+func (s *SignalClient) startLink(deviceName string, done func(number string, err error)) (string, error) {
 	if s.signalCliMode == JsonRpc {
 		type StartResponse struct {
 			DeviceLinkUri string `json:"deviceLinkUri"`
@@ -1723,7 +1813,7 @@ func (s *SignalClient) GetDeviceLinkUri(deviceName string) (string, error) {
 
 		raw, err := jsonRpc2Client.getRaw("startLink", nil, struct{}{})
 		if err != nil {
-			return "", errors.New("Couldn't start link: " + err.Error())
+			return "", err
 		}
 
 		var resp StartResponse
@@ -1731,37 +1821,63 @@ func (s *SignalClient) GetDeviceLinkUri(deviceName string) (string, error) {
 			return "", errors.New("Couldn't parse startLink response: " + err.Error())
 		}
 
-		// Complete the linking handshake in the background, just like GetQrCodeLink does.
-		s.finishLinkAsync(jsonRpc2Client, deviceName, resp.DeviceLinkUri)
+		s.finishLinkAsync(jsonRpc2Client, deviceName, resp.DeviceLinkUri, done)
 		return resp.DeviceLinkUri, nil
 	}
 
 	cmd := []string{"--config", s.signalCliConfig, "link", "-n", deviceName}
-	deviceLinkUri, err := s.cliClient.Execute(false, cmd, "")
+	deviceLinkUri, err := s.cliClient.Start(cmd, func(output string, err error) {
+		number := ""
+		if err == nil {
+			// signal-cli prints "Associated with: <number>" once the device is linked
+			for _, line := range strings.Split(output, "\n") {
+				if strings.HasPrefix(line, "Associated with:") {
+					number = strings.TrimSpace(strings.TrimPrefix(line, "Associated with:"))
+				}
+			}
+		}
+		s.linkFinished(deviceName, number, err, done)
+	})
 	if err != nil {
-		return "", errors.New("Couldn't create link URI: " + err.Error())
+		return "", err
 	}
 	return strings.TrimSpace(deviceLinkUri), nil
 }
 
-func (s *SignalClient) finishLinkAsync(jsonRpc2Client *JsonRpc2Client, deviceName string, deviceLinkUri string) {
+func (s *SignalClient) finishLinkAsync(jsonRpc2Client *JsonRpc2Client, deviceName string, deviceLinkUri string, done func(number string, err error)) {
 	type finishRequest struct {
 		DeviceLinkUri string `json:"deviceLinkUri"`
 		DeviceName    string `json:"deviceName"`
 	}
+	type finishResponse struct {
+		Number string `json:"number"`
+	}
 
 	go func() {
 		req := finishRequest{DeviceLinkUri: deviceLinkUri, DeviceName: deviceName}
 		result, err := jsonRpc2Client.getRaw("finishLink", nil, &req)
-		if err != nil {
-			log.Debug("Error linking device: ", err.Error())
-			return
+		var resp finishResponse
+		if err == nil {
+			log.Debug("Linking device result: ", result)
+			json.Unmarshal([]byte(result), &resp)
 		}
-		log.Debug("Linking device result: ", result)
-		s.signalCliApiConfig.Load(s.signalCliApiConfigPath)
+		s.linkFinished(deviceName, resp.Number, err, done)
 	}()
 }
 
+func (s *SignalClient) linkFinished(deviceName string, number string, err error, done func(number string, err error)) {
+	if err != nil {
+		log.Error("Couldn't link device ", deviceName, ": ", err.Error())
+	} else {
+		log.Info("Successfully linked device ", deviceName, " ", number)
+		s.signalCliApiConfig.Load(s.signalCliApiConfigPath)
+	}
+
+	if done != nil {
+		done(number, err)
+	}
+}
+
 func (s *SignalClient) GetAccounts() ([]string, error) {
 	accounts := make([]string, 0)
 	var rawData string
@@ -1841,6 +1957,32 @@ func (s *SignalClient) RemoveAttachment(attachment string) error {
 	return nil
 }
 
+// RemoveAttachmentsOlderThan removes all attachments that weren't modified within the given
+// duration and returns the number of removed attachments.
+func (s *SignalClient) RemoveAttachmentsOlderThan(maxAge time.Duration) (int, error) {
+	attachmentsPath := s.signalCliConfig + "/attachments/"
+	if _, err := os.Stat(attachmentsPath); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-maxAge)
+	err := filepath.Walk(attachmentsPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
 func (s *SignalClient) GetAttachment(attachment string) ([]byte, error) {
 	path, err := securejoin.SecureJoin(s.signalCliConfig+"/attachments/", attachment)
 	if err != nil {
@@ -2199,6 +2341,10 @@ func (s *SignalClient) UpdateGroup(number string, groupId string, base64Avatar *
 		cleanupTmpFiles([]string{avatarTmpPath})
 	}
 
+	if err == nil {
+		s.groupChangedByAccount(number, groupId)
+	}
+
 	return err
 }
 
@@ -2391,9 +2537,14 @@ func (s *SignalClient) SendStopTyping(number string, recipient string) error {
 	return err
 }
 
-func (s *SignalClient) SearchForNumbers(number string, numbers []string) ([]SearchResultEntry, error) {
-	searchResultEntries := []SearchResultEntry{}
+type signalCliUserStatus struct {
+	Recipient    string `json:"recipient"`
+	Number       string `json:"number"`
+	Uuid         string `json:"uuid"`
+	IsRegistered bool   `json:"isRegistered"`
+}
 
+func (s *SignalClient) getUserStatus(number string, numbers []string) ([]signalCliUserStatus, error) {
 	var err error
 	var rawData string
 	if s.signalCliMode == JsonRpc {
@@ -2404,7 +2555,7 @@ func (s *SignalClient) SearchForNumbers(number string, numbers []string) ([]Sear
 
 		jsonRpc2Clients := s.getJsonRpc2Clients()
 		if len(jsonRpc2Clients) == 0 {
-			return searchResultEntries, errors.New("No JsonRpc2Client registered!")
+			return nil, errors.New("No JsonRpc2Client registered!")
 		}
 		for _, jsonRpc2Client := range jsonRpc2Clients {
 			rawData, err = jsonRpc2Client.getRaw("getUserStatus", &number, request)
@@ -2414,7 +2565,7 @@ func (s *SignalClient) SearchForNumbers(number string, numbers []string) ([]Sear
 		}
 
 		if err != nil {
-			return searchResultEntries, err
+			return nil, err
 		}
 	} else {
 		cmd := []string{"--config", s.signalCliConfig, "--output", "json"}
@@ -2427,16 +2578,21 @@ func (s *SignalClient) SearchForNumbers(number string, numbers []string) ([]Sear
 	}
 
 	if err != nil {
-		return searchResultEntries, err
+		return nil, err
 	}
 
-	type SignalCliResponse struct {
-		Number       string `json:"number"`
-		IsRegistered bool   `json:"isRegistered"`
+	var resp []signalCliUserStatus
+	err = json.Unmarshal([]byte(rawData), &resp)
+	if err != nil {
+		return nil, err
 	}
+	return resp, nil
+}
 
-	var resp []SignalCliResponse
-	err = json.Unmarshal([]byte(rawData), &resp)
+func (s *SignalClient) SearchForNumbers(number string, numbers []string) ([]SearchResultEntry, error) {
+	searchResultEntries := []SearchResultEntry{}
+
+	resp, err := s.getUserStatus(number, numbers)
 	if err != nil {
 		return searchResultEntries, err
 	}
@@ -2466,33 +2622,42 @@ func (s *SignalClient) SendContacts(number string) error {
 }
 
 func (s *SignalClient) UpdateContact(number string, recipient string, name *string, expirationInSeconds *int) error {
+	return s.updateContact(number, contactUpdate{Recipient: recipient, Name: name, Expiration: expirationInSeconds})
+}
+
+// contactUpdate contains the details of a contact that are changed with the
+// updateContact command of signal-cli. Details that are not set (nil) are left
+// as they are.
+type contactUpdate struct {
+	Recipient     string  `json:"recipient"`
+	Name          *string `json:"name,omitempty"`
+	NickGivenName *string `json:"nickGivenName,omitempty"`
+	Note          *string `json:"note,omitempty"`
+	Expiration    *int    `json:"expiration,omitempty"`
+}
+
+func (s *SignalClient) updateContact(number string, update contactUpdate) error {
 	var err error
 	var jsonRpc2Client *JsonRpc2Client
 	if s.signalCliMode == JsonRpc {
-		type Request struct {
-			Recipient  string `json:"recipient"`
-			Name       string `json:"name,omitempty"`
-			Expiration int    `json:"expiration,omitempty"`
-		}
-		request := Request{Recipient: recipient}
-		if name != nil {
-			request.Name = *name
-		}
-		if expirationInSeconds != nil {
-			request.Expiration = *expirationInSeconds
-		}
 		jsonRpc2Client, err = s.getJsonRpc2Client()
 		if err != nil {
 			return err
 		}
-		_, err = jsonRpc2Client.getRaw("updateContact", &number, request)
+		_, err = jsonRpc2Client.getRaw("updateContact", &number, update)
 	} else {
-		cmd := []string{"--config", s.signalCliConfig, "-a", number, "updateContact", recipient}
-		if name != nil {
-			cmd = append(cmd, []string{"-n", *name}...)
+		cmd := []string{"--config", s.signalCliConfig, "-a", number, "updateContact", update.Recipient}
+		if update.Name != nil {
+			cmd = append(cmd, []string{"-n", *update.Name}...)
+		}
+		if update.NickGivenName != nil {
+			cmd = append(cmd, []string{"--nick-given-name", *update.NickGivenName}...)
 		}
-		if expirationInSeconds != nil {
-			cmd = append(cmd, []string{"-e", strconv.Itoa(*expirationInSeconds)}...)
+		if update.Note != nil {
+			cmd = append(cmd, []string{"--note", *update.Note}...)
+		}
+		if update.Expiration != nil {
+			cmd = append(cmd, []string{"-e", strconv.Itoa(*update.Expiration)}...)
 		}
 		_, err = s.cliClient.Execute(true, cmd, "")
 	}
@@ -2590,9 +2755,21 @@ func (s *SignalClient) SetTrustMode(number string, trustMode utils.SignalCliTrus
 	if s.signalCliMode == JsonRpc {
 		return errors.New("Not supported in json-rpc mode, use the environment variable JSON_RPC_TRUST_NEW_IDENTITIES instead!")
 	} else {
-		s.signalCliApiConfig.SetTrustModeForNumber(number, trustMode)
-		return s.signalCliApiConfig.Persist()
+		return s.signalCliApiConfig.UpdateTrustModeForNumber(number, trustMode)
+	}
+}
+
+func (s *SignalClient) GetAccountSettings(number string) utils.AccountSettings {
+	return s.signalCliApiConfig.GetAccountSettings(number)
+}
+
+// SetAccountSettings replaces the settings of the given account and changes its
+// trust mode, in case it is set. Either both are changed or nothing is changed.
+func (s *SignalClient) SetAccountSettings(number string, accountSettings utils.AccountSettings, trustMode *utils.SignalCliTrustMode) error {
+	if trustMode != nil && s.signalCliMode == JsonRpc {
+		return errors.New("Not supported in json-rpc mode, use the environment variable JSON_RPC_TRUST_NEW_IDENTITIES instead!")
 	}
+	return s.signalCliApiConfig.SetAccountSettings(number, accountSettings, trustMode)
 }
 
 func (s *SignalClient) GetTrustMode(number string) utils.SignalCliTrustMode {
@@ -0,0 +1,416 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ContactRecord is a contact as it is imported or exported.
+type ContactRecord struct {
+	Name     string `json:"name,omitempty"`
+	Nickname string `json:"nickname,omitempty"`
+	Note     string `json:"note,omitempty"`
+	Number   string `json:"number,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// ContactImportRow is the result of importing a single row (CSV) or card
+// (vCard). Rows are counted from 1, the header of a CSV file is row 1.
+type ContactImportRow struct {
+	Row       int    `json:"row"`
+	Recipient string `json:"recipient,omitempty"`
+	Imported  bool   `json:"imported"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ContactImportResult struct {
+	Imported          int                `json:"imported"`
+	Failed            int                `json:"failed"`
+	Rows              []ContactImportRow `json:"rows"`
+	SendContactsError string             `json:"send_contacts_error,omitempty"`
+}
+
+type contactImportRecord struct {
+	row     int
+	contact ContactRecord
+	err     error
+}
+
+var contactCsvColumns = []string{"name", "nickname", "note", "number", "username"}
+
+// MaxContactImportFileSize is the maximum size of a CSV or vCard file that is
+// imported.
+const MaxContactImportFileSize = 5 * 1024 * 1024
+
+// maxContactImportRowsNormalMode is the maximum number of rows that can be
+// imported at once in normal mode. Every row starts signal-cli once (which
+// takes a second or two), so larger files would keep the request open for too
+// long - they need to be split or imported in json-rpc mode.
+var maxContactImportRowsNormalMode = 100
+
+var ErrContactImportFileTooLarge = errors.New("The file is too large - it must not be larger than 5 MiB")
+var ErrTooManyContactsToImport = errors.New("Too many contacts - in normal mode, at most 100 contacts can be imported at once. " +
+	"Split the file or use the json-rpc mode")
+
+// recipient returns the recipient signal-cli identifies the contact with.
+func (c ContactRecord) recipient() (string, error) {
+	if c.Number != "" {
+		number := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", "/", "").Replace(c.Number)
+		if !strings.HasPrefix(number, "+") || len(number) < 2 {
+			return "", errors.New("The number " + c.Number + " needs to be in international format (e.g. +431212131491291)")
+		}
+		for _, r := range number[1:] {
+			if r < '0' || r > '9' {
+				return "", errors.New("The number " + c.Number + " contains invalid characters")
+			}
+		}
+		return number, nil
+	}
+	if c.Username != "" {
+		return "u:" + strings.TrimPrefix(c.Username, "u:"), nil
+	}
+	return "", errors.New("Please provide a number or a username")
+}
+
+func parseContactsCsv(data []byte) ([]contactImportRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("Couldn't read CSV header: " + err.Error())
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	_, hasNumber := columns["number"]
+	_, hasUsername := columns["username"]
+	if !hasNumber && !hasUsername {
+		return nil, errors.New("The CSV header needs to contain at least one of the columns 'number' and 'username'")
+	}
+
+	records := []contactImportRecord{}
+	for row := 2; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				records = append(records, contactImportRecord{row: row, err: err})
+				continue
+			}
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, exists := columns[name]; exists && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		records = append(records, contactImportRecord{row: row, contact: ContactRecord{Name: field("name"), Nickname: field("nickname"),
+			Note: field("note"), Number: field("number"), Username: field("username")}})
+	}
+	return records, nil
+}
+
+func formatContactsCsv(contacts []ContactRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	err := writer.Write(contactCsvColumns)
+	if err != nil {
+		return nil, err
+	}
+	for _, contact := range contacts {
+		err = writer.Write([]string{contact.Name, contact.Nickname, contact.Note, contact.Number, contact.Username})
+		if err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func unescapeVCardValue(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\:`, ":", `\\`, `\`).Replace(value)
+}
+
+func escapeVCardValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\r\n", `\n`, "\n", `\n`, ",", `\,`, ";", `\;`).Replace(value)
+}
+
+// unfoldVCardLines joins the lines that were folded (continuation lines start
+// with a space or a tab).
+func unfoldVCardLines(data []byte) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseContactsVCard parses vCard 3.0 and 4.0 files. Every card is one row.
+func parseContactsVCard(data []byte) ([]contactImportRecord, error) {
+	records := []contactImportRecord{}
+	var current *contactImportRecord
+	var structuredName string
+	var unnamed bool
+	for _, line := range unfoldVCardLines(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))) {
+		separator := strings.Index(line, ":")
+		if separator < 0 {
+			continue
+		}
+		property := strings.ToUpper(line[:separator])
+		value := line[separator+1:]
+
+		// property groups (e.g. item1.TEL) are ignored
+		if dot := strings.Index(property, "."); dot >= 0 && (strings.Index(property, ";") < 0 || dot < strings.Index(property, ";")) {
+			property = property[dot+1:]
+		}
+		name := property
+		if i := strings.Index(property, ";"); i >= 0 {
+			name = property[:i]
+		}
+
+		if name == "BEGIN" && strings.EqualFold(value, "VCARD") {
+			current = &contactImportRecord{row: len(records) + 1}
+			structuredName = ""
+			unnamed = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch name {
+		case "END":
+			if unnamed {
+				// the FN is only a placeholder, as FN is required
+				current.contact.Name = ""
+			} else if current.contact.Name == "" && structuredName != "" {
+				// N is family;given;additional;prefixes;suffixes
+				parts := strings.Split(structuredName, ";")
+				nameParts := []string{}
+				for _, i := range []int{1, 2, 0} {
+					if i < len(parts) && strings.TrimSpace(parts[i]) != "" {
+						nameParts = append(nameParts, unescapeVCardValue(strings.TrimSpace(parts[i])))
+					}
+				}
+				current.contact.Name = strings.Join(nameParts, " ")
+			}
+			records = append(records, *current)
+			current = nil
+		case "FN":
+			current.contact.Name = unescapeVCardValue(value)
+		case "N":
+			structuredName = value
+		case "NICKNAME":
+			// multiple nicknames are separated by a comma, only the first one is used
+			current.contact.Nickname = unescapeVCardValue(strings.SplitN(strings.ReplaceAll(value, `\,`, "\x00"), ",", 2)[0])
+			current.contact.Nickname = strings.ReplaceAll(current.contact.Nickname, "\x00", ",")
+		case "NOTE":
+			current.contact.Note = unescapeVCardValue(value)
+		case "TEL":
+			if current.contact.Number == "" {
+				current.contact.Number = strings.TrimPrefix(strings.TrimPrefix(value, "tel:"), "TEL:")
+				if i := strings.Index(current.contact.Number, ";"); i >= 0 { // e.g. tel:+43...;ext=123
+					current.contact.Number = current.contact.Number[:i]
+				}
+			}
+		case "X-SIGNAL-USERNAME":
+			current.contact.Username = unescapeVCardValue(value)
+		case vCardUnnamedProperty:
+			unnamed = strings.EqualFold(value, "TRUE")
+		case "IMPP":
+			if strings.HasPrefix(strings.ToLower(value), "signal:") && current.contact.Username == "" {
+				current.contact.Username = value[len("signal:"):]
+			}
+		}
+	}
+
+	if current != nil {
+		current.err = errors.New("The vCard isn't terminated with END:VCARD")
+		records = append(records, *current)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("No vCard found")
+	}
+	return records, nil
+}
+
+// foldVCardLine folds lines that are longer than 75 characters, as required by
+// the vCard specification. Multi-byte characters are never split.
+func foldVCardLine(line string) string {
+	var buf strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > 75 {
+			buf.WriteString("\r\n ")
+			length = 1
+		}
+		buf.WriteRune(r)
+		length += size
+	}
+	buf.WriteString("\r\n")
+	return buf.String()
+}
+
+// vCardUnnamedProperty marks the vCards of contacts without name. FN is
+// required, so it contains a placeholder (e.g. the number) in that case, which
+// must not be imported as name.
+const vCardUnnamedProperty = "X-SIGNAL-UNNAMED"
+
+func formatContactsVCard(contacts []ContactRecord, version string) []byte {
+	var buf bytes.Buffer
+	for _, contact := range contacts {
+		buf.WriteString("BEGIN:VCARD\r\n")
+		buf.WriteString("VERSION:" + version + "\r\n")
+		if contact.Name != "" {
+			buf.WriteString(foldVCardLine("FN:" + escapeVCardValue(contact.Name)))
+			if version == "3.0" {
+				buf.WriteString(foldVCardLine("N:;" + escapeVCardValue(contact.Name) + ";;;"))
+			}
+		} else {
+			buf.WriteString(foldVCardLine("FN:" + escapeVCardValue(firstNonEmpty(contact.Nickname, contact.Number, contact.Username))))
+			if version == "3.0" {
+				buf.WriteString("N:;;;;\r\n")
+			}
+			buf.WriteString(vCardUnnamedProperty + ":TRUE\r\n")
+		}
+		if contact.Nickname != "" {
+			buf.WriteString(foldVCardLine("NICKNAME:" + escapeVCardValue(contact.Nickname)))
+		}
+		if contact.Note != "" {
+			buf.WriteString(foldVCardLine("NOTE:" + escapeVCardValue(contact.Note)))
+		}
+		if contact.Number != "" {
+			if version == "3.0" {
+				buf.WriteString("TEL;TYPE=CELL:" + contact.Number + "\r\n")
+			} else {
+				buf.WriteString("TEL;TYPE=cell;VALUE=uri:tel:" + contact.Number + "\r\n")
+			}
+		}
+		if contact.Username != "" {
+			buf.WriteString(foldVCardLine("X-SIGNAL-USERNAME:" + escapeVCardValue(contact.Username)))
+		}
+		buf.WriteString("END:VCARD\r\n")
+	}
+	return buf.Bytes()
+}
+
+// ImportContacts adds or updates the contacts of the given CSV (format "csv")
+// or vCard (format "vcard") file. Every row is imported on its own, rows that
+// can't be imported are reported in the result. Afterwards, the contacts can be
+// sent to the linked devices. signal-cli can't update several contacts at once,
+// so in normal mode every row starts a signal-cli process and the number of rows
+// is limited.
+func (s *SignalClient) ImportContacts(number string, data []byte, format string, sendContacts bool) (ContactImportResult, error) {
+	result := ContactImportResult{Rows: []ContactImportRow{}}
+	if len(data) > MaxContactImportFileSize {
+		return result, ErrContactImportFileTooLarge
+	}
+
+	var records []contactImportRecord
+	var err error
+	switch format {
+	case "csv":
+		records, err = parseContactsCsv(data)
+	case "vcard":
+		records, err = parseContactsVCard(data)
+	default:
+		return result, errors.New("Invalid format - only 'csv' and 'vcard' are supported")
+	}
+	if err != nil {
+		return result, err
+	}
+	if s.signalCliMode != JsonRpc && len(records) > maxContactImportRowsNormalMode {
+		return result, ErrTooManyContactsToImport
+	}
+
+	for _, record := range records {
+		row := ContactImportRow{Row: record.row}
+		err = record.err
+		if err == nil {
+			row.Recipient, err = record.contact.recipient()
+		}
+		if err == nil {
+			update := contactUpdate{Recipient: row.Recipient}
+			if record.contact.Name != "" {
+				update.Name = &record.contact.Name
+			}
+			if record.contact.Nickname != "" {
+				update.NickGivenName = &record.contact.Nickname
+			}
+			if record.contact.Note != "" {
+				update.Note = &record.contact.Note
+			}
+			err = s.updateContact(number, update)
+		}
+
+		if err != nil {
+			row.Error = err.Error()
+			result.Failed++
+		} else {
+			row.Imported = true
+			result.Imported++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if sendContacts && result.Imported > 0 {
+		err = s.SendContacts(number)
+		if err != nil {
+			log.Error("Couldn't send contacts to the linked devices: ", err.Error())
+			result.SendContactsError = err.Error()
+		}
+	}
+	return result, nil
+}
+
+// ExportContacts exports the contacts of the account as CSV (format "csv") or
+// vCard (format "vcard3" or "vcard4") file.
+func (s *SignalClient) ExportContacts(number string, format string) ([]byte, error) {
+	if !(format == "csv" || format == "vcard3" || format == "vcard4") {
+		return nil, errors.New("Invalid format - only 'csv', 'vcard3' and 'vcard4' are supported")
+	}
+
+	contacts, err := s.ListContacts(number, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	records := []ContactRecord{}
+	for _, contact := range contacts {
+		nickname := contact.Nickname.Name
+		if nickname == "" {
+			nickname = strings.TrimSpace(contact.Nickname.GivenName + " " + contact.Nickname.FamilyName)
+		}
+		records = append(records, ContactRecord{Name: contact.Name, Nickname: nickname, Note: contact.Note, Number: contact.Number,
+			Username: contact.Username})
+	}
+
+	switch format {
+	case "vcard3":
+		return formatContactsVCard(records, "3.0"), nil
+	case "vcard4":
+		return formatContactsVCard(records, "4.0"), nil
+	}
+	return formatContactsCsv(records)
+}
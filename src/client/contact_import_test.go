@@ -0,0 +1,168 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseContactsCsv(t *testing.T) {
+	data := "Number,Name,Note,username\n" +
+		"+43 121 2131-491291,Alice,\"Met at the conference, 2024\",\n" +
+		",Bob,,bob.42\n" +
+		"\"broken,Carol\n"
+
+	records, err := parseContactsCsv([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+
+	expected := ContactRecord{Name: "Alice", Note: "Met at the conference, 2024", Number: "+43 121 2131-491291"}
+	if records[0].row != 2 || !reflect.DeepEqual(records[0].contact, expected) {
+		t.Errorf("got %+v, want %+v", records[0], expected)
+	}
+	if recipient, _ := records[0].contact.recipient(); recipient != "+431212131491291" {
+		t.Errorf("unexpected recipient %s", recipient)
+	}
+	if recipient, _ := records[1].contact.recipient(); records[1].row != 3 || recipient != "u:bob.42" {
+		t.Errorf("unexpected recipient %s in row %d", recipient, records[1].row)
+	}
+	if records[2].row != 4 || records[2].err == nil {
+		t.Errorf("expected an error in row 4, got %+v", records[2])
+	}
+}
+
+func TestParseContactsCsvWithoutRecipientColumn(t *testing.T) {
+	_, err := parseContactsCsv([]byte("name,note\nAlice,\n"))
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestContactRecipient(t *testing.T) {
+	for _, number := range []string{"431212131491291", "+43abc", "+"} {
+		if _, err := (ContactRecord{Number: number}).recipient(); err == nil {
+			t.Errorf("expected an error for number %s", number)
+		}
+	}
+	if _, err := (ContactRecord{Name: "Alice"}).recipient(); err == nil {
+		t.Errorf("expected an error for a contact without number and username")
+	}
+}
+
+func TestParseContactsVCard(t *testing.T) {
+	data := "BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"N:Doe;John;;;\r\n" +
+		"NICKNAME:Johnny,JD\r\n" +
+		"NOTE:First line\\nsecond line\\, with a comma and a very long text that\r\n" +
+		"  was folded\r\n" +
+		"item1.TEL;TYPE=CELL:+43 (121) 2131491291\r\n" +
+		"TEL;TYPE=HOME:+431212131491292\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:Alice\r\n" +
+		"N:Smith;Alice;;;\r\n" +
+		"X-SIGNAL-USERNAME:alice.01\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:Bob\r\n" +
+		"TEL;VALUE=uri;TYPE=cell:tel:+431212131491293\r\n"
+
+	records, err := parseContactsVCard([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+
+	expected := ContactRecord{Name: "John Doe", Nickname: "Johnny",
+		Note: "First line\nsecond line, with a comma and a very long text that was folded", Number: "+43 (121) 2131491291"}
+	if records[0].row != 1 || !reflect.DeepEqual(records[0].contact, expected) {
+		t.Errorf("got %+v, want %+v", records[0].contact, expected)
+	}
+	expected = ContactRecord{Name: "Alice", Username: "alice.01"}
+	if records[1].row != 2 || !reflect.DeepEqual(records[1].contact, expected) {
+		t.Errorf("got %+v, want %+v", records[1].contact, expected)
+	}
+	if records[2].contact.Number != "+431212131491293" || records[2].err == nil {
+		t.Errorf("expected an error for the unterminated vCard, got %+v", records[2])
+	}
+}
+
+func TestFormatContactsCsv(t *testing.T) {
+	data, err := formatContactsCsv([]ContactRecord{{Name: "Alice", Note: "a, b", Number: "+431212131491291"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expected := "name,nickname,note,number,username\nAlice,,\"a, b\",+431212131491291,\n"
+	if string(data) != expected {
+		t.Errorf("got %q, want %q", string(data), expected)
+	}
+
+	records, err := parseContactsCsv(data)
+	if err != nil || len(records) != 1 || records[0].contact.Note != "a, b" {
+		t.Errorf("couldn't parse exported CSV: %+v", records)
+	}
+}
+
+func TestFormatContactsVCard(t *testing.T) {
+	contacts := []ContactRecord{
+		{Name: "Alice; Smith", Nickname: "Ali", Note: strings.Repeat("ä", 60), Number: "+431212131491291", Username: "alice.01"},
+		{Username: "bob.42"},
+	}
+
+	vcard3 := string(formatContactsVCard(contacts, "3.0"))
+	for _, line := range []string{"VERSION:3.0", "FN:Alice\\; Smith", "N:;Alice\\; Smith;;;", "NICKNAME:Ali", "TEL;TYPE=CELL:+431212131491291",
+		"X-SIGNAL-USERNAME:alice.01", "FN:bob.42", "N:;;;;", "X-SIGNAL-UNNAMED:TRUE"} {
+		if !strings.Contains(vcard3, line+"\r\n") {
+			t.Errorf("expected line %s in %q", line, vcard3)
+		}
+	}
+	for _, line := range strings.Split(vcard3, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line %q isn't folded", line)
+		}
+	}
+
+	vcard4 := string(formatContactsVCard(contacts, "4.0"))
+	if !strings.Contains(vcard4, "TEL;TYPE=cell;VALUE=uri:tel:+431212131491291\r\n") || strings.Contains(vcard4, "\r\nN:") {
+		t.Errorf("unexpected vCard 4.0 %q", vcard4)
+	}
+
+	records, err := parseContactsVCard([]byte(vcard4))
+	if err != nil || len(records) != 2 || !reflect.DeepEqual(records[0].contact, contacts[0]) {
+		t.Errorf("couldn't parse exported vCard: %+v", records)
+	}
+	// the placeholder of a contact without name isn't imported as name
+	if len(records) == 2 && !reflect.DeepEqual(records[1].contact, contacts[1]) {
+		t.Errorf("got %+v, want %+v", records[1].contact, contacts[1])
+	}
+	records, err = parseContactsVCard([]byte(vcard3))
+	if err != nil || len(records) != 2 || records[1].contact.Name != "" {
+		t.Errorf("couldn't parse exported vCard: %+v", records)
+	}
+}
+
+func TestImportContactsLimitsRowsInNormalMode(t *testing.T) {
+	defer func(maxRows int) { maxContactImportRowsNormalMode = maxRows }(maxContactImportRowsNormalMode)
+	maxContactImportRowsNormalMode = 2
+
+	// the rows are checked before anything is imported, so signal-cli isn't started
+	s := &SignalClient{signalCliMode: Normal}
+	data := "number\n+431212131491291\n+431212131491292\n+431212131491293\n"
+	if _, err := s.ImportContacts("+431212131491290", []byte(data), "csv", false); err != ErrTooManyContactsToImport {
+		t.Errorf("expected ErrTooManyContactsToImport, got %v", err)
+	}
+
+	tooLarge := "number\n" + strings.Repeat(" ", MaxContactImportFileSize)
+	if _, err := s.ImportContacts("+431212131491290", []byte(tooLarge), "csv", false); err != ErrContactImportFileTooLarge {
+		t.Errorf("expected ErrContactImportFileTooLarge, got %v", err)
+	}
+}
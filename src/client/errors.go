@@ -31,3 +31,11 @@ type InvalidTransportError struct {
 func (e *InvalidTransportError) Error() string {
 	return e.Description
 }
+
+type SendBlockedError struct {
+	Reason string
+}
+
+func (e *SendBlockedError) Error() string {
+	return "Message was blocked: " + e.Reason
+}
@@ -0,0 +1,395 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// groupAuditLogMaxEntries is the number of group changes that are kept per group.
+const groupAuditLogMaxEntries = 1000
+
+// groupChanges keeps the last known state of the groups of every account and the
+// audit log of every group. Both are persisted, so that changes that happen while
+// the REST API isn't running are detected as well.
+type groupChanges struct {
+	mutex            sync.Mutex
+	accountMutexes   map[string]*sync.Mutex
+	pendingRefreshes map[string][]groupChangeSource
+	store            *utils.KeyValueStore
+}
+
+// groupRefreshDelay is the time the group updates of an account are collected
+// before its groups are fetched, so that a burst of group updates (e.g. after
+// the account was offline) only needs a single listGroups call.
+var groupRefreshDelay = 2 * time.Second
+
+// groupChangeSource describes who changed a group, in case it is known (e.g.
+// because a group update message was received).
+type groupChangeSource struct {
+	InternalGroupId string
+	ChangedBy       ds.GroupChangeMember
+	Timestamp       int64
+}
+
+// lock makes sure that the groups of an account are compared one after
+// another, otherwise an older state could be compared with a newer one.
+func (g *groupChanges) lock(number string) func() {
+	g.mutex.Lock()
+	accountMutex, exists := g.accountMutexes[number]
+	if !exists {
+		accountMutex = &sync.Mutex{}
+		g.accountMutexes[number] = accountMutex
+	}
+	g.mutex.Unlock()
+
+	accountMutex.Lock()
+	return accountMutex.Unlock
+}
+
+// namespace returns the namespace of the given kind ("group-snapshots" or
+// "group-audit-log") of a group. Every group has its own namespaces, so that a
+// change of one group doesn't rewrite the state of all the other groups. The
+// account and the group id are encoded, as they contain characters that are not
+// allowed in namespace names.
+func (g *groupChanges) namespace(kind string, number string, groupId string) (*utils.KeyValueNamespace, error) {
+	return g.store.Namespace(kind + "/" + base64.RawURLEncoding.EncodeToString([]byte(number)) + "/" +
+		base64.RawURLEncoding.EncodeToString([]byte(groupId)))
+}
+
+func (g *groupChanges) appendToAuditLog(number string, change ds.GroupChange) error {
+	auditLogNamespace, err := g.namespace("group-audit-log", number, change.GroupId)
+	if err != nil {
+		return err
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	auditLog := []ds.GroupChange{}
+	if value, exists := auditLogNamespace.Get("changes"); exists {
+		err := json.Unmarshal(value, &auditLog)
+		if err != nil {
+			return err
+		}
+	}
+
+	auditLog = append(auditLog, change)
+	if len(auditLog) > groupAuditLogMaxEntries {
+		auditLog = auditLog[len(auditLog)-groupAuditLogMaxEntries:]
+	}
+
+	value, err := json.Marshal(auditLog)
+	if err != nil {
+		return err
+	}
+	return auditLogNamespace.Set("changes", value, 0)
+}
+
+func (g *groupChanges) getAuditLog(number string, groupId string) ([]ds.GroupChange, error) {
+	auditLog := []ds.GroupChange{}
+	auditLogNamespace, err := g.namespace("group-audit-log", number, groupId)
+	if err != nil {
+		return nil, err
+	}
+	value, exists := auditLogNamespace.Get("changes")
+	if !exists {
+		return auditLog, nil
+	}
+	err = json.Unmarshal(value, &auditLog)
+	return auditLog, err
+}
+
+// SetGroupChangesStore sets the store the group states and the audit logs are
+// persisted in. Group changes are only detected if a store is set.
+func (s *SignalClient) SetGroupChangesStore(store *utils.KeyValueStore) error {
+	s.groupChanges = &groupChanges{accountMutexes: make(map[string]*sync.Mutex), pendingRefreshes: make(map[string][]groupChangeSource),
+		store: store}
+	return nil
+}
+
+// GetGroupAuditLog returns the changes of the group (oldest first). If a limit
+// is provided, only the most recent changes are returned.
+func (s *SignalClient) GetGroupAuditLog(number string, groupId string, limit int) ([]ds.GroupChange, error) {
+	if s.groupChanges == nil {
+		return nil, errors.New("The group audit log is not available")
+	}
+
+	auditLog, err := s.groupChanges.getAuditLog(number, groupId)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(auditLog) > limit {
+		auditLog = auditLog[len(auditLog)-limit:]
+	}
+	return auditLog, nil
+}
+
+func groupMemberKey(member GroupMember) string {
+	if member.Uuid != "" {
+		return member.Uuid
+	}
+	return member.Number
+}
+
+func diffGroupMembers(oldMembers []GroupMember, newMembers []GroupMember, addedType string, removedType string) []ds.GroupChangeEntry {
+	changes := []ds.GroupChangeEntry{}
+
+	oldKeys := make(map[string]bool)
+	for _, member := range oldMembers {
+		oldKeys[groupMemberKey(member)] = true
+	}
+	newKeys := make(map[string]bool)
+	for _, member := range newMembers {
+		newKeys[groupMemberKey(member)] = true
+	}
+
+	for _, member := range newMembers {
+		if !oldKeys[groupMemberKey(member)] {
+			changes = append(changes, ds.GroupChangeEntry{Type: addedType, Member: &ds.GroupChangeMember{Number: member.Number, Uuid: member.Uuid}})
+		}
+	}
+	for _, member := range oldMembers {
+		if !newKeys[groupMemberKey(member)] {
+			changes = append(changes, ds.GroupChangeEntry{Type: removedType, Member: &ds.GroupChangeMember{Number: member.Number, Uuid: member.Uuid}})
+		}
+	}
+	return changes
+}
+
+func groupAdminsToGroupMembers(admins []GroupAdmin) []GroupMember {
+	members := []GroupMember{}
+	for _, admin := range admins {
+		members = append(members, GroupMember{Number: admin.Number, Uuid: admin.Uuid})
+	}
+	return members
+}
+
+// diffGroups returns the changes between two states of the same group.
+func diffGroups(oldGroup ExpandedGroupEntry, newGroup ExpandedGroupEntry) []ds.GroupChangeEntry {
+	changes := []ds.GroupChangeEntry{}
+
+	changes = append(changes, diffGroupMembers(oldGroup.Members, newGroup.Members, "member_added", "member_removed")...)
+	changes = append(changes, diffGroupMembers(groupAdminsToGroupMembers(oldGroup.Admins), groupAdminsToGroupMembers(newGroup.Admins),
+		"admin_added", "admin_removed")...)
+	changes = append(changes, diffGroupMembers(oldGroup.PendingInvites, newGroup.PendingInvites, "invite_added", "invite_removed")...)
+	changes = append(changes, diffGroupMembers(oldGroup.PendingRequests, newGroup.PendingRequests, "join_request_added", "join_request_removed")...)
+
+	if oldGroup.Name != newGroup.Name {
+		changes = append(changes, ds.GroupChangeEntry{Type: "name_changed", OldValue: oldGroup.Name, NewValue: newGroup.Name})
+	}
+	if oldGroup.Description != newGroup.Description {
+		changes = append(changes, ds.GroupChangeEntry{Type: "description_changed", OldValue: oldGroup.Description, NewValue: newGroup.Description})
+	}
+
+	permissions := []struct {
+		name     string
+		oldValue string
+		newValue string
+	}{
+		{"add_members", oldGroup.Permissions.AddMembers, newGroup.Permissions.AddMembers},
+		{"edit_group", oldGroup.Permissions.EditGroup, newGroup.Permissions.EditGroup},
+		{"send_messages", oldGroup.Permissions.SendMessages, newGroup.Permissions.SendMessages},
+	}
+	for _, permission := range permissions {
+		if permission.oldValue != permission.newValue {
+			changes = append(changes, ds.GroupChangeEntry{Type: "permission_changed", Permission: permission.name,
+				OldValue: permission.oldValue, NewValue: permission.newValue})
+		}
+	}
+
+	if oldGroup.ExpirationTime != newGroup.ExpirationTime {
+		changes = append(changes, ds.GroupChangeEntry{Type: "expiration_changed", OldValue: strconv.Itoa(oldGroup.ExpirationTime),
+			NewValue: strconv.Itoa(newGroup.ExpirationTime)})
+	}
+	if oldGroup.InviteLink != newGroup.InviteLink {
+		changes = append(changes, ds.GroupChangeEntry{Type: "invite_link_changed", OldValue: oldGroup.InviteLink, NewValue: newGroup.InviteLink})
+	}
+
+	return changes
+}
+
+// trackGroupChanges compares the groups with the last known state of the
+// groups and records the changes. The first time a group is seen, it is only
+// remembered. In case there are several sources for the same group, the changes
+// are attributed to the last one.
+func (s *SignalClient) trackGroupChanges(number string, groups []ExpandedGroupEntry, sources ...groupChangeSource) {
+	sourcesByGroup := make(map[string]groupChangeSource)
+	for _, source := range sources {
+		sourcesByGroup[source.InternalGroupId] = source
+	}
+
+	for _, group := range groups {
+		snapshots, err := s.groupChanges.namespace("group-snapshots", number, group.Id)
+		if err != nil {
+			log.Error("Couldn't load the last known state of group ", group.Id, ": ", err.Error())
+			continue
+		}
+
+		snapshotValue, err := json.Marshal(group)
+		if err != nil {
+			log.Error("Couldn't serialize group ", group.Id, ": ", err.Error())
+			continue
+		}
+
+		previousSnapshotValue, exists := snapshots.Get("group")
+		if exists && bytes.Equal(previousSnapshotValue, snapshotValue) {
+			continue
+		}
+
+		if exists {
+			var previousGroup ExpandedGroupEntry
+			err = json.Unmarshal(previousSnapshotValue, &previousGroup)
+			if err != nil {
+				log.Error("Couldn't parse the last known state of group ", group.Id, ": ", err.Error())
+			} else if changes := diffGroups(previousGroup, group); len(changes) > 0 {
+				change := ds.GroupChange{GroupId: group.Id, GroupName: group.Name, Timestamp: time.Now().UnixMilli(), Changes: changes}
+				if source, exists := sourcesByGroup[group.InternalId]; exists {
+					changedBy := source.ChangedBy
+					change.ChangedBy = &changedBy
+					change.Timestamp = source.Timestamp
+				}
+				s.emitGroupChange(number, change)
+			}
+		}
+
+		err = snapshots.Set("group", snapshotValue, 0)
+		if err != nil {
+			log.Error("Couldn't persist the state of group ", group.Id, ": ", err.Error())
+		}
+	}
+}
+
+// emitGroupChange adds the group change to the audit log of the group and
+// passes it on to the websocket and webhook consumers (json-rpc mode only).
+func (s *SignalClient) emitGroupChange(number string, change ds.GroupChange) {
+	err := s.groupChanges.appendToAuditLog(number, change)
+	if err != nil {
+		log.Error("Couldn't add group change to the audit log of ", change.GroupId, ": ", err.Error())
+	}
+
+	if s.signalCliMode != JsonRpc {
+		return
+	}
+
+	params, err := json.Marshal(struct {
+		Account      string         `json:"account"`
+		GroupChanged ds.GroupChange `json:"group_changed"`
+	}{Account: number, GroupChanged: change})
+	if err != nil {
+		log.Error("Couldn't serialize group change: ", err.Error())
+		return
+	}
+	jsonRpc2Client, err := s.getJsonRpc2Client()
+	if err != nil {
+		log.Error("Couldn't publish group change: ", err.Error())
+		return
+	}
+	jsonRpc2Client.PublishReceivedMessage(params, s.getReceiveWebhookUrl)
+}
+
+// refreshGroupChanges fetches the groups of the account and compares them with
+// the last known state, so that the changes of the groups are detected. This is
+// the only place the groups are tracked - listing the groups via the REST API
+// doesn't have any side effects.
+func (s *SignalClient) refreshGroupChanges(number string, sources ...groupChangeSource) {
+	if s.groupChanges == nil {
+		return
+	}
+
+	unlock := s.groupChanges.lock(number)
+	defer unlock()
+
+	groups, err := s.GetGroupsExpanded(number)
+	if err != nil {
+		log.Error("Couldn't detect group changes of ", number, ": ", err.Error())
+		return
+	}
+	s.trackGroupChanges(number, groups, sources...)
+}
+
+// groupChangedByAccount refreshes the groups after the account changed a group
+// via the REST API. The group id can either be the REST API group id or the
+// internal group id.
+func (s *SignalClient) groupChangedByAccount(number string, groupId string) {
+	if s.groupChanges == nil {
+		return
+	}
+
+	internalGroupId := groupId
+	if strings.HasPrefix(groupId, groupPrefix) {
+		var err error
+		internalGroupId, err = ConvertGroupIdToInternalGroupId(groupId)
+		if err != nil {
+			return
+		}
+	}
+	go s.refreshGroupChanges(number, groupChangeSource{InternalGroupId: internalGroupId,
+		ChangedBy: ds.GroupChangeMember{Number: number}, Timestamp: time.Now().UnixMilli()})
+}
+
+// scheduleGroupRefresh refreshes the groups of the account after
+// groupRefreshDelay. The group updates that are received in the meantime are
+// handled by the same refresh.
+func (s *SignalClient) scheduleGroupRefresh(number string, source groupChangeSource) {
+	g := s.groupChanges
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	sources, scheduled := g.pendingRefreshes[number]
+	g.pendingRefreshes[number] = append(sources, source)
+	if scheduled {
+		return
+	}
+
+	time.AfterFunc(groupRefreshDelay, func() {
+		g.mutex.Lock()
+		sources := g.pendingRefreshes[number]
+		delete(g.pendingRefreshes, number)
+		g.mutex.Unlock()
+
+		s.refreshGroupChanges(number, sources...)
+	})
+}
+
+// handleGroupUpdate refreshes the groups whenever a group update is received,
+// so that the changes can be attributed to the member that changed the group.
+func (s *SignalClient) handleGroupUpdate(data []byte) {
+	if s.groupChanges == nil {
+		return
+	}
+
+	var msg ds.SignalCliReceivedMessage
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return
+	}
+
+	var dataMessage *ds.SignalCliDataMessage
+	if msg.Envelope.DataMessage != nil {
+		dataMessage = msg.Envelope.DataMessage
+	} else if msg.Envelope.SyncMessage != nil && msg.Envelope.SyncMessage.SentMessage != nil {
+		dataMessage = &msg.Envelope.SyncMessage.SentMessage.SignalCliDataMessage
+	}
+	if dataMessage == nil || dataMessage.GroupInfo == nil {
+		return
+	}
+	if dataMessage.GroupInfo.Type != "UPDATE" && !dataMessage.IsExpirationUpdate {
+		return
+	}
+
+	s.scheduleGroupRefresh(msg.Account, groupChangeSource{
+		InternalGroupId: dataMessage.GroupInfo.GroupId,
+		ChangedBy:       ds.GroupChangeMember{Number: firstNonEmpty(msg.Envelope.SourceNumber, msg.Envelope.Source), Uuid: msg.Envelope.SourceUuid},
+		Timestamp:       msg.Envelope.Timestamp,
+	})
+}
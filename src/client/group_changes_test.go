@@ -0,0 +1,208 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+)
+
+func TestDiffGroups(t *testing.T) {
+	oldGroup := ExpandedGroupEntry{
+		Name:            "Group",
+		Members:         []GroupMember{{Number: "+431212131491291", Uuid: "a"}, {Number: "+431212131491292", Uuid: "b"}},
+		Admins:          []GroupAdmin{{Number: "+431212131491291", Uuid: "a"}},
+		PendingRequests: []GroupMember{{Uuid: "c"}},
+		Permissions:     ds.GroupPermissions{AddMembers: "every-member", EditGroup: "only-admins", SendMessages: "every-member"},
+	}
+	newGroup := oldGroup
+	newGroup.Name = "Renamed Group"
+	newGroup.Members = []GroupMember{{Number: "+431212131491291", Uuid: "a"}, {Uuid: "c"}}
+	newGroup.PendingRequests = []GroupMember{}
+	newGroup.Permissions.AddMembers = "only-admins"
+	newGroup.ExpirationTime = 3600
+
+	changes := diffGroups(oldGroup, newGroup)
+	expected := []ds.GroupChangeEntry{
+		{Type: "member_added", Member: &ds.GroupChangeMember{Uuid: "c"}},
+		{Type: "member_removed", Member: &ds.GroupChangeMember{Number: "+431212131491292", Uuid: "b"}},
+		{Type: "join_request_removed", Member: &ds.GroupChangeMember{Uuid: "c"}},
+		{Type: "name_changed", OldValue: "Group", NewValue: "Renamed Group"},
+		{Type: "permission_changed", Permission: "add_members", OldValue: "every-member", NewValue: "only-admins"},
+		{Type: "expiration_changed", OldValue: "0", NewValue: "3600"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i].Type != expected[i].Type || changes[i].Permission != expected[i].Permission ||
+			changes[i].OldValue != expected[i].OldValue || changes[i].NewValue != expected[i].NewValue {
+			t.Errorf("change %d: got %+v, want %+v", i, changes[i], expected[i])
+		}
+		if (changes[i].Member == nil) != (expected[i].Member == nil) || (changes[i].Member != nil && *changes[i].Member != *expected[i].Member) {
+			t.Errorf("change %d: got member %+v, want %+v", i, changes[i].Member, expected[i].Member)
+		}
+	}
+
+	if changes := diffGroups(oldGroup, oldGroup); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestTrackGroupChanges(t *testing.T) {
+	s := &SignalClient{signalCliMode: Normal}
+	if err := s.SetGroupChangesStore(utils.NewKeyValueStore(t.TempDir())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	number := "+431212131491291"
+	group := ExpandedGroupEntry{Id: "group.abc", InternalId: "abc", Name: "Group", Members: []GroupMember{{Number: number}}}
+
+	// the first time the groups are only remembered
+	s.trackGroupChanges(number, []ExpandedGroupEntry{group})
+	auditLog, err := s.GetGroupAuditLog(number, "group.abc", 0)
+	if err != nil || len(auditLog) != 0 {
+		t.Fatalf("unexpected audit log: %+v (%v)", auditLog, err)
+	}
+
+	group.Members = append(group.Members, GroupMember{Number: "+431212131491292"})
+	s.trackGroupChanges(number, []ExpandedGroupEntry{group}, groupChangeSource{InternalGroupId: "abc",
+		ChangedBy: ds.GroupChangeMember{Number: "+431212131491293"}, Timestamp: 1700000000000})
+	group.Name = "Renamed Group"
+	s.trackGroupChanges(number, []ExpandedGroupEntry{group})
+	s.trackGroupChanges(number, []ExpandedGroupEntry{group})
+
+	auditLog, err = s.GetGroupAuditLog(number, "group.abc", 0)
+	if err != nil || len(auditLog) != 2 {
+		t.Fatalf("unexpected audit log: %+v (%v)", auditLog, err)
+	}
+	if auditLog[0].ChangedBy == nil || auditLog[0].ChangedBy.Number != "+431212131491293" || auditLog[0].Timestamp != 1700000000000 ||
+		auditLog[0].Changes[0].Type != "member_added" {
+		t.Errorf("unexpected group change: %+v", auditLog[0])
+	}
+	if auditLog[1].ChangedBy != nil || auditLog[1].GroupName != "Renamed Group" || auditLog[1].Changes[0].Type != "name_changed" {
+		t.Errorf("unexpected group change: %+v", auditLog[1])
+	}
+
+	auditLog, err = s.GetGroupAuditLog(number, "group.abc", 1)
+	if err != nil || len(auditLog) != 1 || auditLog[0].GroupName != "Renamed Group" {
+		t.Errorf("unexpected audit log: %+v (%v)", auditLog, err)
+	}
+}
+
+func TestTrackGroupChangesOnlyPersistsChangedGroups(t *testing.T) {
+	dir := t.TempDir()
+	s := &SignalClient{signalCliMode: Normal}
+	if err := s.SetGroupChangesStore(utils.NewKeyValueStore(dir)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	number := "+431212131491291"
+	groups := []ExpandedGroupEntry{{Id: "group.abc", InternalId: "abc", Name: "Group A"}, {Id: "group.def", InternalId: "def", Name: "Group B"}}
+	s.trackGroupChanges(number, groups)
+
+	snapshotFiles, _ := filepath.Glob(filepath.Join(dir, "group-snapshots", "*", "*.json"))
+	if len(snapshotFiles) != 2 {
+		t.Fatalf("expected one snapshot file per group, got %v", snapshotFiles)
+	}
+	snapshots := make(map[string]string)
+	for _, snapshotFile := range snapshotFiles {
+		data, _ := os.ReadFile(snapshotFile)
+		snapshots[snapshotFile] = string(data)
+		// make sure that a rewrite of the file would be noticed
+		os.WriteFile(snapshotFile, append(data, ' '), 0600)
+	}
+
+	groups[0].Name = "Renamed Group A"
+	s.trackGroupChanges(number, groups)
+
+	rewritten := 0
+	for _, snapshotFile := range snapshotFiles {
+		data, _ := os.ReadFile(snapshotFile)
+		if string(data) != snapshots[snapshotFile]+" " {
+			rewritten++
+		}
+	}
+	if rewritten != 1 {
+		t.Errorf("expected only the snapshot of the changed group to be written, %d were written", rewritten)
+	}
+
+	if auditLog, err := s.GetGroupAuditLog(number, "group.def", 0); err != nil || len(auditLog) != 0 {
+		t.Errorf("unexpected audit log: %+v (%v)", auditLog, err)
+	}
+	if auditLog, err := s.GetGroupAuditLog(number, "group.abc", 0); err != nil || len(auditLog) != 1 {
+		t.Errorf("unexpected audit log: %+v (%v)", auditLog, err)
+	}
+}
+
+func TestListingGroupsHasNoSideEffects(t *testing.T) {
+	s := newFakeSignalCliDaemon(t, func(method string, params json.RawMessage) string {
+		return `"result":[{"id":"abc","name":"Group","isMember":true,"members":[{"number":"+431212131491291","uuid":"a"}]}]`
+	})
+	dir := t.TempDir()
+	if err := s.SetGroupChangesStore(utils.NewKeyValueStore(dir)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	groups, err := s.GetGroupsExpanded("+431212131491291")
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("unexpected groups: %+v (%v)", groups, err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expected listing the groups not to persist anything, got %v", files)
+	}
+
+	s.refreshGroupChanges("+431212131491291")
+	if files, _ := os.ReadDir(dir); len(files) == 0 {
+		t.Error("expected the refresh to persist the state of the groups")
+	}
+}
+
+func TestNormalizeGroupChangedMessage(t *testing.T) {
+	msg, err := NormalizeReceivedMessageJson([]byte(`{"account":"+431212131491291","group_changed":{"group_id":"group.abc",
+		"changed_by":{"number":"+431212131491292"},"timestamp":1700000000000,"changes":[{"type":"name_changed","new_value":"Group"}]}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Type != ds.GroupChangedReceivedMessage || msg.GroupId() != "group.abc" || msg.Source.Number != "+431212131491292" ||
+		msg.Timestamp != 1700000000000 {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestGroupUpdatesAreCoalesced(t *testing.T) {
+	// fake signal-cli daemon, which counts the listGroups calls
+	listGroupsCalls := make(chan string, 10)
+	s := newFakeSignalCliDaemon(t, func(method string, params json.RawMessage) string {
+		listGroupsCalls <- method
+		return `"result":[]`
+	})
+	if err := s.SetGroupChangesStore(utils.NewKeyValueStore(t.TempDir())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func(delay time.Duration) { groupRefreshDelay = delay }(groupRefreshDelay)
+	groupRefreshDelay = 50 * time.Millisecond
+
+	for _, groupId := range []string{"abc", "def", "abc"} {
+		s.handleGroupUpdate([]byte(`{"account": "+431212131491291", "envelope": {"sourceNumber": "+431212131491292", "timestamp": 1700000000000,
+			"dataMessage": {"groupInfo": {"groupId": "` + groupId + `", "type": "UPDATE"}}}}`))
+	}
+
+	select {
+	case method := <-listGroupsCalls:
+		if method != "listGroups" {
+			t.Fatalf("unexpected method %s", method)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the groups weren't refreshed")
+	}
+	select {
+	case <-listGroupsCalls:
+		t.Error("expected the group updates to be handled by a single refresh")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"errors"
+	"strings"
+)
+
+// groupUpdate contains the changes of a group that are applied with the
+// updateGroup command of signal-cli.
+type groupUpdate struct {
+	Members       []string `json:"member,omitempty"`
+	RemoveMembers []string `json:"remove-member,omitempty"`
+	Ban           []string `json:"ban,omitempty"`
+	ResetLink     bool     `json:"reset-link,omitempty"`
+	Link          string   `json:"link,omitempty"`
+	GroupId       string   `json:"groupId"`
+}
+
+func (s *SignalClient) updateGroup(number string, update groupUpdate) error {
+	err := s.executeGroupUpdate(number, update)
+	if err == nil {
+		s.groupChangedByAccount(number, update.GroupId)
+	}
+	return err
+}
+
+func (s *SignalClient) executeGroupUpdate(number string, update groupUpdate) error {
+	if s.signalCliMode == JsonRpc {
+		jsonRpc2Client, err := s.getJsonRpc2Client()
+		if err != nil {
+			return err
+		}
+		_, err = jsonRpc2Client.getRaw("updateGroup", &number, update)
+		return err
+	}
+
+	cmd := []string{"--config", s.signalCliConfig, "-a", number, "updateGroup", "-g", update.GroupId}
+	if len(update.Members) > 0 {
+		cmd = append(cmd, "-m")
+		cmd = append(cmd, update.Members...)
+	}
+	if len(update.RemoveMembers) > 0 {
+		cmd = append(cmd, "-r")
+		cmd = append(cmd, update.RemoveMembers...)
+	}
+	if len(update.Ban) > 0 {
+		cmd = append(cmd, "--ban")
+		cmd = append(cmd, update.Ban...)
+	}
+	if update.ResetLink {
+		cmd = append(cmd, "--reset-link")
+	}
+	if update.Link != "" {
+		cmd = append(cmd, []string{"--link", update.Link}...)
+	}
+	_, err := s.cliClient.Execute(true, cmd, "")
+	return err
+}
+
+// getGroupForUpdate returns the group together with its internal group id.
+func (s *SignalClient) getGroupForUpdate(number string, groupId string) (*ExpandedGroupEntry, string, error) {
+	internalGroupId, err := ConvertGroupIdToInternalGroupId(groupId)
+	if err != nil {
+		return nil, "", errors.New("Invalid group id")
+	}
+
+	group, err := s.GetGroupExpanded(number, groupId)
+	if err != nil {
+		return nil, "", err
+	}
+	if group == nil {
+		return nil, "", &NotFoundError{Description: "No group with that group id (" + groupId + ") found"}
+	}
+	return group, internalGroupId, nil
+}
+
+// checkPendingGroupMembers makes sure that all members (phone numbers or uuids)
+// are in the given list of pending members. Otherwise signal-cli would e.g.
+// add a member to the group instead of approving a join request.
+func checkPendingGroupMembers(pendingMembers []GroupMember, members []string, description string) error {
+	missing := []string{}
+	for _, member := range members {
+		found := false
+		for _, pendingMember := range pendingMembers {
+			if member != "" && (pendingMember.Number == member || pendingMember.Uuid == member) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, member)
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.New(description + ": " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ApproveGroupJoinRequests adds the members that requested to join the group
+// (via the group invite link) to the group.
+func (s *SignalClient) ApproveGroupJoinRequests(number string, groupId string, members []string) error {
+	group, internalGroupId, err := s.getGroupForUpdate(number, groupId)
+	if err != nil {
+		return err
+	}
+
+	err = checkPendingGroupMembers(group.PendingRequests, members, "No pending join request found for")
+	if err != nil {
+		return err
+	}
+
+	return s.updateGroup(number, groupUpdate{GroupId: internalGroupId, Members: members})
+}
+
+// DenyGroupJoinRequests refuses the join requests of the given members. Banned
+// members can't request to join the group again.
+func (s *SignalClient) DenyGroupJoinRequests(number string, groupId string, members []string, ban bool) error {
+	group, internalGroupId, err := s.getGroupForUpdate(number, groupId)
+	if err != nil {
+		return err
+	}
+
+	err = checkPendingGroupMembers(group.PendingRequests, members, "No pending join request found for")
+	if err != nil {
+		return err
+	}
+
+	update := groupUpdate{GroupId: internalGroupId, RemoveMembers: members}
+	if ban {
+		update.Ban = members
+	}
+	return s.updateGroup(number, update)
+}
+
+// RevokeGroupInvites revokes the invites of members that were added to the
+// group, but didn't accept the invite yet.
+func (s *SignalClient) RevokeGroupInvites(number string, groupId string, members []string) error {
+	group, internalGroupId, err := s.getGroupForUpdate(number, groupId)
+	if err != nil {
+		return err
+	}
+
+	err = checkPendingGroupMembers(group.PendingInvites, members, "No pending invite found for")
+	if err != nil {
+		return err
+	}
+
+	return s.updateGroup(number, groupUpdate{GroupId: internalGroupId, RemoveMembers: members})
+}
+
+// UpdateGroupInviteLink changes the state of the group invite link and/or
+// resets it (so that the previous invite link can't be used anymore) and
+// returns the invite link afterwards.
+func (s *SignalClient) UpdateGroupInviteLink(number string, groupId string, state GroupLinkState, reset bool) (string, error) {
+	_, internalGroupId, err := s.getGroupForUpdate(number, groupId)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.updateGroup(number, groupUpdate{GroupId: internalGroupId, ResetLink: reset, Link: state.String()})
+	if err != nil {
+		return "", err
+	}
+
+	groups, err := s.GetGroupsExpanded(number)
+	if err != nil {
+		return "", err
+	}
+	for _, group := range groups {
+		if group.Id == groupId {
+			return group.InviteLink, nil
+		}
+	}
+	return "", &NotFoundError{Description: "No group with that group id (" + groupId + ") found"}
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCheckPendingGroupMembers(t *testing.T) {
+	pendingMembers := []GroupMember{{Number: "+431212131491291", Uuid: "abc"}, {Number: "", Uuid: "def"}}
+
+	if err := checkPendingGroupMembers(pendingMembers, []string{"+431212131491291", "def"}, "No pending join request found for"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := checkPendingGroupMembers(pendingMembers, []string{"abc", "+431212131491292", ""}, "No pending join request found for")
+	if err == nil || err.Error() != "No pending join request found for: +431212131491292, " {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// newGroupRequestsTestClient returns a client with a fake signal-cli daemon,
+// that knows a single group (group.YWJj) with a pending join request and a
+// pending invite. The parameters of the updateGroup calls are sent to the
+// returned channel.
+func newGroupRequestsTestClient(t *testing.T) (*SignalClient, chan groupUpdate) {
+	updates := make(chan groupUpdate, 10)
+	inviteLink := "https://signal.group/#old"
+	s := newFakeSignalCliDaemon(t, func(method string, params json.RawMessage) string {
+		switch method {
+		case "listGroups":
+			return `"result":[{"id":"abc","name":"Group","isMember":true,"groupInviteLink":"` + inviteLink + `",` +
+				`"members":[{"number":"+431212131491291","uuid":"a"}],` +
+				`"requestingMembers":[{"number":"+431212131491292","uuid":"b"},{"uuid":"c"}],` +
+				`"pendingMembers":[{"number":"+431212131491293","uuid":"d"}]}]`
+		case "updateGroup":
+			var update groupUpdate
+			if err := json.Unmarshal(params, &update); err != nil {
+				t.Errorf("invalid updateGroup params: %s", string(params))
+			}
+			if update.ResetLink {
+				inviteLink = "https://signal.group/#new"
+			}
+			updates <- update
+			return `"result":{}`
+		}
+		return `"result":[]`
+	})
+	return s, updates
+}
+
+func expectGroupUpdate(t *testing.T, updates chan groupUpdate, expected groupUpdate) {
+	t.Helper()
+	select {
+	case update := <-updates:
+		if !reflect.DeepEqual(update, expected) {
+			t.Errorf("got %+v, want %+v", update, expected)
+		}
+	default:
+		t.Errorf("expected the group to be updated with %+v", expected)
+	}
+}
+
+func TestGroupJoinRequests(t *testing.T) {
+	s, updates := newGroupRequestsTestClient(t)
+	number := "+431212131491291"
+
+	if err := s.ApproveGroupJoinRequests(number, "group.YWJj", []string{"+431212131491292", "c"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectGroupUpdate(t, updates, groupUpdate{GroupId: "abc", Members: []string{"+431212131491292", "c"}})
+
+	if err := s.DenyGroupJoinRequests(number, "group.YWJj", []string{"b"}, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectGroupUpdate(t, updates, groupUpdate{GroupId: "abc", RemoveMembers: []string{"b"}})
+
+	if err := s.DenyGroupJoinRequests(number, "group.YWJj", []string{"c"}, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectGroupUpdate(t, updates, groupUpdate{GroupId: "abc", RemoveMembers: []string{"c"}, Ban: []string{"c"}})
+
+	// a member that didn't request to join would be added to the group instead
+	if err := s.ApproveGroupJoinRequests(number, "group.YWJj", []string{"+431212131491293"}); err == nil {
+		t.Error("expected an error, as there is no join request")
+	}
+	if err := s.DenyGroupJoinRequests(number, "group.ZGVm", []string{"b"}, false); err == nil {
+		t.Error("expected an error, as the group doesn't exist")
+	} else if _, isNotFoundError := err.(*NotFoundError); !isNotFoundError {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+	if len(updates) != 0 {
+		t.Errorf("expected no further group updates, got %d", len(updates))
+	}
+}
+
+func TestRevokeGroupInvites(t *testing.T) {
+	s, updates := newGroupRequestsTestClient(t)
+	number := "+431212131491291"
+
+	if err := s.RevokeGroupInvites(number, "group.YWJj", []string{"+431212131491293"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectGroupUpdate(t, updates, groupUpdate{GroupId: "abc", RemoveMembers: []string{"+431212131491293"}})
+
+	// a member of the group would be removed instead
+	if err := s.RevokeGroupInvites(number, "group.YWJj", []string{"a"}); err == nil {
+		t.Error("expected an error, as there is no pending invite")
+	}
+	if len(updates) != 0 {
+		t.Errorf("expected no further group updates, got %d", len(updates))
+	}
+}
+
+func TestUpdateGroupInviteLink(t *testing.T) {
+	s, updates := newGroupRequestsTestClient(t)
+	number := "+431212131491291"
+
+	inviteLink, err := s.UpdateGroupInviteLink(number, "group.YWJj", EnabledWithApproval, false)
+	if err != nil || inviteLink != "https://signal.group/#old" {
+		t.Fatalf("unexpected result: %s (%v)", inviteLink, err)
+	}
+	expectGroupUpdate(t, updates, groupUpdate{GroupId: "abc", Link: "enabled-with-approval"})
+
+	// the link state is left alone, if only the link is reset
+	inviteLink, err = s.UpdateGroupInviteLink(number, "group.YWJj", DefaultGroupLinkState, true)
+	if err != nil || inviteLink != "https://signal.group/#new" {
+		t.Fatalf("unexpected result: %s (%v)", inviteLink, err)
+	}
+	expectGroupUpdate(t, updates, groupUpdate{GroupId: "abc", ResetLink: true})
+}
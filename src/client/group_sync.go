@@ -0,0 +1,446 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// DesiredGroup describes the desired state of a group. Fields that are not set
+// (nil) are left as they are. Members and admins are phone numbers or uuids.
+type DesiredGroup struct {
+	Id           string               `json:"id,omitempty"`
+	Name         string               `json:"name"`
+	Description  *string              `json:"description,omitempty"`
+	Base64Avatar *string              `json:"base64_avatar,omitempty"`
+	Members      []string             `json:"members,omitempty"`
+	Admins       []string             `json:"admins,omitempty"`
+	Permissions  *ds.GroupPermissions `json:"permissions,omitempty"`
+}
+
+type GroupSyncAction struct {
+	Type    string   `json:"type" enums:"create_group,update_group,add_members,remove_members,add_admins,remove_admins"`
+	Members []string `json:"members,omitempty"`
+	Changes []string `json:"changes,omitempty"`
+	Applied bool     `json:"applied"`
+}
+
+type GroupSyncResult struct {
+	Name    string            `json:"name"`
+	GroupId string            `json:"group_id,omitempty"`
+	Actions []GroupSyncAction `json:"actions"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// groupSyncPlan contains the actions that are needed to get from the current
+// state of a group to the desired state.
+type groupSyncPlan struct {
+	desired       DesiredGroup
+	current       *ExpandedGroupEntry
+	currentAvatar []byte
+	unresolved    []string
+	result        GroupSyncResult
+}
+
+// findGroupMember checks whether the member with the given phone number or uuid
+// is in the list of members. As members might only be known by their uuid, phone
+// numbers are also compared by the uuid they were resolved to.
+func findGroupMember(members []GroupMember, id string, uuids map[string]string) bool {
+	for _, member := range members {
+		if id != "" && (member.Number == id || member.Uuid == id || (uuids[id] != "" && member.Uuid == uuids[id])) {
+			return true
+		}
+	}
+	return false
+}
+
+// unresolvedGroupMembers returns the wanted phone numbers that are neither in
+// the list of members (by number) nor could be resolved to a uuid. Members that
+// are only known by their uuid might be one of them, so they can't be removed.
+func unresolvedGroupMembers(members []GroupMember, wanted []string, uuids map[string]string) []string {
+	unresolved := []string{}
+	for _, id := range wanted {
+		if _, ok := uuids[id]; ok || !utils.IsPhoneNumber(id) || findGroupMember(members, id, nil) {
+			continue
+		}
+		if !utils.StringInSlice(id, unresolved) {
+			unresolved = append(unresolved, id)
+		}
+	}
+	return unresolved
+}
+
+func isSameAccount(number string, accountUuid string, member GroupMember) bool {
+	return member.Number == number || (accountUuid != "" && member.Uuid == accountUuid)
+}
+
+// allGroupMembers returns the members, the pending invites and the admins of the group.
+func allGroupMembers(group *ExpandedGroupEntry) []GroupMember {
+	members := append(append([]GroupMember{}, group.Members...), group.PendingInvites...)
+	return append(members, groupAdminsToGroupMembers(group.Admins)...)
+}
+
+// missingGroupMembers returns the wanted members that are not in the list of members.
+func missingGroupMembers(members []GroupMember, wanted []string, uuids map[string]string) []string {
+	missing := []string{}
+	for _, id := range wanted {
+		if !findGroupMember(members, id, uuids) && !utils.StringInSlice(id, missing) {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// unwantedGroupMembers returns the members that are not in the list of wanted
+// members. The account itself is never part of the result.
+func unwantedGroupMembers(members []GroupMember, wanted []string, uuids map[string]string, number string, accountUuid string) []string {
+	unwanted := []string{}
+	for _, member := range members {
+		if isSameAccount(number, accountUuid, member) {
+			continue
+		}
+		found := false
+		for _, id := range wanted {
+			if findGroupMember([]GroupMember{member}, id, uuids) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unwanted = append(unwanted, firstNonEmpty(member.Number, member.Uuid))
+		}
+	}
+	return unwanted
+}
+
+// wantedGroupMembers returns the desired members, which always include the
+// desired admins (as only members can be admins).
+func wantedGroupMembers(desired DesiredGroup) []string {
+	if desired.Members == nil && desired.Admins == nil {
+		return nil
+	}
+	wanted := append([]string{}, desired.Members...)
+	for _, admin := range desired.Admins {
+		if !utils.StringInSlice(admin, wanted) {
+			wanted = append(wanted, admin)
+		}
+	}
+	return wanted
+}
+
+// planGroupSync computes the actions that are needed to get from the current
+// state of the group (nil, if the group doesn't exist yet) to the desired state.
+// uuids maps the phone numbers of the desired members to their uuids (see
+// resolveUuids). Members and admins are only removed if all the desired phone
+// numbers could be resolved.
+func planGroupSync(number string, accountUuid string, desired DesiredGroup, current *ExpandedGroupEntry, currentAvatar []byte,
+	uuids map[string]string) []GroupSyncAction {
+	actions := []GroupSyncAction{}
+	wantedMembers := wantedGroupMembers(desired)
+
+	if current == nil {
+		members := []string{}
+		for _, member := range wantedMembers {
+			if member != number && (accountUuid == "" || member != accountUuid) {
+				members = append(members, member)
+			}
+		}
+		actions = append(actions, GroupSyncAction{Type: "create_group", Members: members})
+		if desired.Base64Avatar != nil {
+			actions = append(actions, GroupSyncAction{Type: "update_group", Changes: []string{"avatar"}})
+		}
+		admins := []string{}
+		for _, admin := range desired.Admins {
+			if admin != number && (accountUuid == "" || admin != accountUuid) {
+				admins = append(admins, admin)
+			}
+		}
+		if len(admins) > 0 {
+			actions = append(actions, GroupSyncAction{Type: "add_admins", Members: admins})
+		}
+		return actions
+	}
+
+	changes := []string{}
+	if desired.Name != "" && desired.Name != current.Name {
+		changes = append(changes, "name")
+	}
+	if desired.Description != nil && *desired.Description != current.Description {
+		changes = append(changes, "description")
+	}
+	if desired.Base64Avatar != nil {
+		avatar, err := base64.StdEncoding.DecodeString(*desired.Base64Avatar)
+		if err != nil || !bytes.Equal(avatar, currentAvatar) {
+			changes = append(changes, "avatar")
+		}
+	}
+	if desired.Permissions != nil {
+		if desired.Permissions.AddMembers != "" && desired.Permissions.AddMembers != current.Permissions.AddMembers {
+			changes = append(changes, "permissions.add_members")
+		}
+		if desired.Permissions.EditGroup != "" && desired.Permissions.EditGroup != current.Permissions.EditGroup {
+			changes = append(changes, "permissions.edit_group")
+		}
+		if desired.Permissions.SendMessages != "" && desired.Permissions.SendMessages != current.Permissions.SendMessages {
+			changes = append(changes, "permissions.send_messages")
+		}
+	}
+	if len(changes) > 0 {
+		actions = append(actions, GroupSyncAction{Type: "update_group", Changes: changes})
+	}
+
+	if wantedMembers != nil {
+		// members that were already invited don't need to be added again
+		currentMembers := append(append([]GroupMember{}, current.Members...), current.PendingInvites...)
+		if missing := missingGroupMembers(currentMembers, wantedMembers, uuids); len(missing) > 0 {
+			actions = append(actions, GroupSyncAction{Type: "add_members", Members: missing})
+		}
+	}
+
+	// a member that is only known by its uuid might be one of the unresolved phone numbers
+	resolved := len(unresolvedGroupMembers(allGroupMembers(current), wantedMembers, uuids)) == 0
+	admins := groupAdminsToGroupMembers(current.Admins)
+	if desired.Admins != nil {
+		if missing := missingGroupMembers(admins, desired.Admins, uuids); len(missing) > 0 {
+			actions = append(actions, GroupSyncAction{Type: "add_admins", Members: missing})
+		}
+		if resolved {
+			if unwanted := unwantedGroupMembers(admins, desired.Admins, uuids, number, accountUuid); len(unwanted) > 0 {
+				actions = append(actions, GroupSyncAction{Type: "remove_admins", Members: unwanted})
+			}
+		}
+	}
+
+	if desired.Members != nil && resolved {
+		if unwanted := unwantedGroupMembers(current.Members, wantedMembers, uuids, number, accountUuid); len(unwanted) > 0 {
+			actions = append(actions, GroupSyncAction{Type: "remove_members", Members: unwanted})
+		}
+	}
+
+	return actions
+}
+
+// findDesiredGroup returns the group with the given id or - in case no id is
+// provided - the group (the account is a member of) with the given name.
+func findDesiredGroup(groups []ExpandedGroupEntry, desired DesiredGroup) (*ExpandedGroupEntry, error) {
+	var found *ExpandedGroupEntry
+	for i := range groups {
+		if desired.Id != "" {
+			if groups[i].Id == desired.Id {
+				return &groups[i], nil
+			}
+			continue
+		}
+
+		if groups[i].Member && groups[i].Name == desired.Name {
+			if found != nil {
+				return nil, errors.New("There are multiple groups with the name '" + desired.Name + "' - please provide the group id")
+			}
+			found = &groups[i]
+		}
+	}
+
+	if desired.Id != "" {
+		return nil, &NotFoundError{Description: "No group with that group id (" + desired.Id + ") found"}
+	}
+	return found, nil
+}
+
+// SyncGroups brings the groups of the account into the desired state, by
+// creating and updating groups as well as adding and removing members and
+// admins. Only the necessary changes are applied. In dry run mode, the planned
+// changes are only returned. In case a change fails, the remaining changes of
+// that group are skipped.
+func (s *SignalClient) SyncGroups(number string, desiredGroups []DesiredGroup, dryRun bool) ([]GroupSyncResult, error) {
+	groups, err := s.GetGroupsExpanded(number)
+	if err != nil {
+		return nil, err
+	}
+
+	accountUuid := ""
+	if accounts, err := loadSignalCliAccounts(s.signalCliDataDir()); err == nil {
+		if account := accounts.find(number); account != nil {
+			accountUuid, _ = account["uuid"].(string)
+		}
+	}
+
+	plans := []groupSyncPlan{}
+	seenGroups := make(map[string]bool)
+	unmatchedNumbers := []string{}
+	for _, desired := range desiredGroups {
+		plan := groupSyncPlan{desired: desired, result: GroupSyncResult{Name: desired.Name, Actions: []GroupSyncAction{}}}
+
+		current, err := findDesiredGroup(groups, desired)
+		if err != nil {
+			plan.result.Error = err.Error()
+			plans = append(plans, plan)
+			continue
+		}
+
+		if current != nil {
+			plan.result.GroupId = current.Id
+			if seenGroups[current.Id] {
+				plan.result.Error = "The group " + current.Id + " is listed more than once"
+				plans = append(plans, plan)
+				continue
+			}
+			seenGroups[current.Id] = true
+
+			if desired.Base64Avatar != nil {
+				plan.currentAvatar, _ = s.GetAvatar(number, current.Id, GroupAvatar)
+			}
+
+			// phone numbers that aren't found by number might belong to members that are only known by their uuid
+			for _, id := range unresolvedGroupMembers(allGroupMembers(current), wantedGroupMembers(desired), nil) {
+				if !utils.StringInSlice(id, unmatchedNumbers) {
+					unmatchedNumbers = append(unmatchedNumbers, id)
+				}
+			}
+		}
+		plan.current = current
+		plans = append(plans, plan)
+	}
+
+	uuids := s.resolveUuids(number, unmatchedNumbers)
+	for i := range plans {
+		plan := &plans[i]
+		if plan.result.Error != "" {
+			continue
+		}
+
+		plan.result.Actions = planGroupSync(number, accountUuid, plan.desired, plan.current, plan.currentAvatar, uuids)
+		if plan.current != nil {
+			plan.unresolved = unresolvedGroupMembers(allGroupMembers(plan.current), wantedGroupMembers(plan.desired), uuids)
+		}
+	}
+
+	results := []GroupSyncResult{}
+	for _, plan := range plans {
+		if !dryRun && plan.result.Error == "" {
+			s.applyGroupSyncPlan(number, &plan)
+		}
+		if len(plan.unresolved) > 0 {
+			unresolvedError := "Couldn't resolve " + strings.Join(plan.unresolved, ", ") + " to a uuid - no members or admins were removed"
+			if plan.result.Error != "" {
+				unresolvedError = plan.result.Error + "; " + unresolvedError
+			}
+			plan.result.Error = unresolvedError
+		}
+		results = append(results, plan.result)
+	}
+	return results, nil
+}
+
+// resolveUuids resolves the given phone numbers to the uuids (ACIs) of their
+// Signal accounts. Known recipients are resolved locally, the others are looked
+// up via getUserStatus. Numbers that aren't registered are resolved to an empty
+// uuid, numbers that couldn't be looked up are missing from the result.
+func (s *SignalClient) resolveUuids(number string, numbers []string) map[string]string {
+	uuids := make(map[string]string)
+	if len(numbers) == 0 {
+		return uuids
+	}
+
+	contacts, err := s.ListContacts(number, true, "")
+	if err != nil {
+		log.Warn("Couldn't list the recipients of ", number, ": ", err.Error())
+	}
+	for _, contact := range contacts {
+		if contact.Number != "" && contact.Uuid != "" && utils.StringInSlice(contact.Number, numbers) {
+			uuids[contact.Number] = contact.Uuid
+		}
+	}
+
+	lookup := []string{}
+	for _, id := range numbers {
+		if _, ok := uuids[id]; !ok {
+			lookup = append(lookup, id)
+		}
+	}
+	if len(lookup) == 0 {
+		return uuids
+	}
+
+	userStatuses, err := s.getUserStatus(number, lookup)
+	if err != nil {
+		log.Error("Couldn't look up the uuids of ", strings.Join(lookup, ", "), ": ", err.Error())
+		return uuids
+	}
+	for _, userStatus := range userStatuses {
+		id := firstNonEmpty(userStatus.Recipient, userStatus.Number)
+		if utils.StringInSlice(id, lookup) {
+			uuids[id] = userStatus.Uuid
+		}
+	}
+	return uuids
+}
+
+func (s *SignalClient) applyGroupSyncPlan(number string, plan *groupSyncPlan) {
+	desired := plan.desired
+	for i := range plan.result.Actions {
+		action := &plan.result.Actions[i]
+
+		var err error
+		switch action.Type {
+		case "create_group":
+			editGroupPermission, addMembersPermission, sendMessagesPermission := DefaultGroupPermission, DefaultGroupPermission, DefaultGroupPermission
+			if desired.Permissions != nil {
+				editGroupPermission = editGroupPermission.FromString(desired.Permissions.EditGroup)
+				addMembersPermission = addMembersPermission.FromString(desired.Permissions.AddMembers)
+				sendMessagesPermission = sendMessagesPermission.FromString(desired.Permissions.SendMessages)
+			}
+			description := ""
+			if desired.Description != nil {
+				description = *desired.Description
+			}
+			plan.result.GroupId, err = s.CreateGroup(number, desired.Name, action.Members, description, editGroupPermission, addMembersPermission,
+				sendMessagesPermission, DefaultGroupLinkState, s.signalCliApiConfig.GetAccountSettings(number).ExpirationInSeconds)
+		case "update_group":
+			var internalGroupId string
+			internalGroupId, err = ConvertGroupIdToInternalGroupId(plan.result.GroupId)
+			if err != nil {
+				break
+			}
+
+			var name, description, avatar *string
+			editGroupPermission, addMembersPermission, sendMessagesPermission := DefaultGroupPermission, DefaultGroupPermission, DefaultGroupPermission
+			for _, change := range action.Changes {
+				switch change {
+				case "name":
+					name = &desired.Name
+				case "description":
+					description = desired.Description
+				case "avatar":
+					avatar = desired.Base64Avatar
+				case "permissions.add_members":
+					addMembersPermission = addMembersPermission.FromString(desired.Permissions.AddMembers)
+				case "permissions.edit_group":
+					editGroupPermission = editGroupPermission.FromString(desired.Permissions.EditGroup)
+				case "permissions.send_messages":
+					sendMessagesPermission = sendMessagesPermission.FromString(desired.Permissions.SendMessages)
+				}
+			}
+			err = s.UpdateGroup(number, internalGroupId, avatar, description, name, nil, nil, editGroupPermission, addMembersPermission,
+				sendMessagesPermission)
+		case "add_members":
+			err = s.AddMembersToGroup(number, plan.result.GroupId, action.Members)
+		case "remove_members":
+			err = s.RemoveMembersFromGroup(number, plan.result.GroupId, action.Members)
+		case "add_admins":
+			err = s.AddAdminsToGroup(number, plan.result.GroupId, action.Members)
+		case "remove_admins":
+			err = s.RemoveAdminsFromGroup(number, plan.result.GroupId, action.Members)
+		}
+
+		if err != nil {
+			plan.result.Error = action.Type + " failed: " + err.Error()
+			return
+		}
+		action.Applied = true
+	}
+}
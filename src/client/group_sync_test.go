@@ -0,0 +1,167 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+)
+
+func TestPlanGroupSyncNewGroup(t *testing.T) {
+	number := "+431212131491291"
+	avatar := "aGVsbG8="
+	desired := DesiredGroup{Name: "Team", Members: []string{number, "+431212131491292"}, Admins: []string{"+431212131491293"},
+		Base64Avatar: &avatar}
+
+	actions := planGroupSync(number, "self-uuid", desired, nil, nil, nil)
+	expected := []GroupSyncAction{
+		{Type: "create_group", Members: []string{"+431212131491292", "+431212131491293"}},
+		{Type: "update_group", Changes: []string{"avatar"}},
+		{Type: "add_admins", Members: []string{"+431212131491293"}},
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("got %+v, want %+v", actions, expected)
+	}
+}
+
+func TestPlanGroupSyncExistingGroup(t *testing.T) {
+	number := "+431212131491291"
+	current := ExpandedGroupEntry{
+		Id:             "group.abc",
+		Name:           "Team",
+		Description:    "The team",
+		Members:        []GroupMember{{Number: number, Uuid: "self-uuid"}, {Number: "+431212131491292", Uuid: "b"}, {Uuid: "c"}},
+		PendingInvites: []GroupMember{{Number: "+431212131491294"}},
+		Admins:         []GroupAdmin{{Number: number, Uuid: "self-uuid"}, {Uuid: "c"}},
+		Permissions:    ds.GroupPermissions{AddMembers: "every-member", EditGroup: "only-admins", SendMessages: "every-member"},
+	}
+	avatar := "aGVsbG8="
+
+	// nothing to do
+	description := "The team"
+	desired := DesiredGroup{Name: "Team", Description: &description, Base64Avatar: &avatar, Members: []string{"b", "c", "+431212131491294"},
+		Admins: []string{"c"}, Permissions: &ds.GroupPermissions{EditGroup: "only-admins"}}
+	if actions := planGroupSync(number, "self-uuid", desired, &current, []byte("hello"), nil); len(actions) != 0 {
+		t.Errorf("expected no actions, got %+v", actions)
+	}
+
+	// members and admins are only changed if they are provided
+	description = "The new team"
+	desired = DesiredGroup{Name: "Team", Description: &description, Permissions: &ds.GroupPermissions{AddMembers: "only-admins"}}
+	actions := planGroupSync(number, "self-uuid", desired, &current, nil, nil)
+	expected := []GroupSyncAction{{Type: "update_group", Changes: []string{"description", "permissions.add_members"}}}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("got %+v, want %+v", actions, expected)
+	}
+
+	desired = DesiredGroup{Name: "Team", Members: []string{"+431212131491295"}, Admins: []string{"+431212131491292"}}
+	actions = planGroupSync(number, "self-uuid", desired, &current, nil, map[string]string{"+431212131491295": "f"})
+	expected = []GroupSyncAction{
+		{Type: "add_members", Members: []string{"+431212131491295"}},
+		{Type: "add_admins", Members: []string{"+431212131491292"}},
+		{Type: "remove_admins", Members: []string{"c"}},
+		{Type: "remove_members", Members: []string{"c"}},
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("got %+v, want %+v", actions, expected)
+	}
+}
+
+func TestPlanGroupSyncMatchesMembersByUuid(t *testing.T) {
+	number := "+431212131491291"
+	current := ExpandedGroupEntry{
+		Id:      "group.abc",
+		Name:    "Team",
+		Members: []GroupMember{{Number: number, Uuid: "self-uuid"}, {Uuid: "d"}, {Number: "+431212131491297", Uuid: "e"}},
+		Admins:  []GroupAdmin{{Number: number, Uuid: "self-uuid"}},
+	}
+	desired := DesiredGroup{Name: "Team", Members: []string{"+431212131491296", "e"}}
+
+	// the member that is only known by its uuid is the desired phone number
+	actions := planGroupSync(number, "self-uuid", desired, &current, nil, map[string]string{"+431212131491296": "d"})
+	if len(actions) != 0 {
+		t.Errorf("expected no actions, got %+v", actions)
+	}
+
+	// the phone number couldn't be resolved, so no members are removed
+	actions = planGroupSync(number, "self-uuid", desired, &current, nil, nil)
+	expected := []GroupSyncAction{{Type: "add_members", Members: []string{"+431212131491296"}}}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("got %+v, want %+v", actions, expected)
+	}
+
+	// the phone number isn't registered, so it can't be the member that is only known by its uuid
+	actions = planGroupSync(number, "self-uuid", desired, &current, nil, map[string]string{"+431212131491296": ""})
+	expected = []GroupSyncAction{{Type: "add_members", Members: []string{"+431212131491296"}}, {Type: "remove_members", Members: []string{"d"}}}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("got %+v, want %+v", actions, expected)
+	}
+}
+
+func TestSyncGroupsReportsUnresolvedMembers(t *testing.T) {
+	var userStatusAvailable atomic.Bool
+	s := newFakeSignalCliDaemon(t, func(method string, params json.RawMessage) string {
+		switch method {
+		case "listGroups":
+			return `"result":[{"id":"abc","name":"Team","isMember":true,"members":[{"uuid":"d"},{"number":"+431212131491297","uuid":"e"}]}]`
+		case "getUserStatus":
+			if userStatusAvailable.Load() {
+				return `"result":[{"recipient":"+431212131491296","number":"+431212131491296","uuid":"d","isRegistered":true}]`
+			}
+			return `"error":{"code":-1,"message":"lookup failed"}`
+		}
+		return `"result":[]`
+	})
+	desired := []DesiredGroup{{Name: "Team", Members: []string{"+431212131491296"}}}
+
+	results, err := s.SyncGroups("+431212131491291", desired, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || !strings.Contains(results[0].Error, "+431212131491296") {
+		t.Fatalf("expected the unresolved phone number to be reported: %+v", results)
+	}
+	for _, action := range results[0].Actions {
+		if action.Type == "remove_members" {
+			t.Errorf("expected no members to be removed: %+v", results[0].Actions)
+		}
+	}
+
+	userStatusAvailable.Store(true)
+	results, err = s.SyncGroups("+431212131491291", desired, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []GroupSyncAction{{Type: "remove_members", Members: []string{"+431212131491297"}}}
+	if len(results) != 1 || results[0].Error != "" || !reflect.DeepEqual(results[0].Actions, expected) {
+		t.Errorf("unexpected result: %+v", results)
+	}
+}
+
+func TestFindDesiredGroup(t *testing.T) {
+	groups := []ExpandedGroupEntry{
+		{Id: "group.a", Name: "Team", Member: true},
+		{Id: "group.b", Name: "Team", Member: false},
+		{Id: "group.c", Name: "Other", Member: true},
+		{Id: "group.d", Name: "Other", Member: true},
+	}
+
+	if group, err := findDesiredGroup(groups, DesiredGroup{Name: "Team"}); err != nil || group == nil || group.Id != "group.a" {
+		t.Errorf("unexpected result: %+v (%v)", group, err)
+	}
+	if group, err := findDesiredGroup(groups, DesiredGroup{Name: "New"}); err != nil || group != nil {
+		t.Errorf("unexpected result: %+v (%v)", group, err)
+	}
+	if _, err := findDesiredGroup(groups, DesiredGroup{Name: "Other"}); err == nil {
+		t.Error("expected error, as the name is ambiguous")
+	}
+	if group, err := findDesiredGroup(groups, DesiredGroup{Id: "group.d", Name: "Other"}); err != nil || group.Id != "group.d" {
+		t.Errorf("unexpected result: %+v (%v)", group, err)
+	}
+	if _, err := findDesiredGroup(groups, DesiredGroup{Id: "group.e", Name: "Other"}); err == nil {
+		t.Error("expected error, as the group doesn't exist")
+	}
+}
@@ -57,14 +57,15 @@ func (r *RateLimitErrorType) Error() string {
 }
 
 type JsonRpc2Client struct {
-	conn                     net.Conn
-	receivedResponsesById    map[string]chan JsonRpc2MessageResponse
-	receivedMessagesChannels map[string]chan JsonRpc2ReceivedMessage
-	signalCliApiConfig       *utils.SignalCliApiConfig
-	number                   string
-	receivedMessagesMutex    sync.Mutex
-	receivedResponsesMutex   sync.Mutex
-	address                  string
+	conn                       net.Conn
+	receivedResponsesById      map[string]chan JsonRpc2MessageResponse
+	receivedMessagesChannels   map[string]chan JsonRpc2ReceivedMessage
+	signalCliApiConfig         *utils.SignalCliApiConfig
+	number                     string
+	receivedMessagesMutex      sync.Mutex
+	receivedResponsesMutex     sync.Mutex
+	address                    string
+	receivedMessageTransformer func([]byte) []byte
 }
 
 func NewJsonRpc2Client(signalCliApiConfig *utils.SignalCliApiConfig, number string) *JsonRpc2Client {
@@ -76,6 +77,10 @@ func NewJsonRpc2Client(signalCliApiConfig *utils.SignalCliApiConfig, number stri
 	}
 }
 
+func (r *JsonRpc2Client) SetReceivedMessageTransformer(transformer func([]byte) []byte) {
+	r.receivedMessageTransformer = transformer
+}
+
 func (r *JsonRpc2Client) Dial(address string, maxRetries int) error {
 	var err error
 	r.address = address
@@ -149,22 +154,25 @@ func (r *JsonRpc2Client) getRaw(command string, account *string, args interface{
 
 	log.Debug("json-rpc command: ", string(fullCommandBytes))
 
-	_, err = r.conn.Write([]byte(string(fullCommandBytes) + "\n"))
-	if err != nil {
-		return "", err
-	}
-
-	responseChan := make(chan JsonRpc2MessageResponse)
+	// register the response channel before the command is sent, so that a fast
+	// response can't get lost
+	responseChan := make(chan JsonRpc2MessageResponse, 1)
 	r.receivedResponsesMutex.Lock()
 	r.receivedResponsesById[u.String()] = responseChan
 	r.receivedResponsesMutex.Unlock()
 
-	var resp JsonRpc2MessageResponse
-	resp = <-responseChan
+	_, err = r.conn.Write([]byte(string(fullCommandBytes) + "\n"))
+	if err != nil {
+		r.receivedResponsesMutex.Lock()
+		delete(r.receivedResponsesById, u.String())
+		r.receivedResponsesMutex.Unlock()
+		return "", err
+	}
 
-	r.receivedResponsesMutex.Lock()
-	delete(r.receivedResponsesById, u.String())
-	r.receivedResponsesMutex.Unlock()
+	resp, ok := <-responseChan
+	if !ok {
+		return "", errors.New("Lost connection to signal-cli before the response was received")
+	}
 
 	log.Debug("json-rpc command response message: ", string(resp.Result))
 	log.Debug("json-rpc response error: ", string(resp.Err.Message))
@@ -218,14 +226,18 @@ func postMessageToWebhook(webhookUrl string, data []byte) error {
 	return nil
 }
 
-func (r *JsonRpc2Client) ReceiveData(number string, receiveWebhookUrl string) {
+// ReceiveData reads the data that signal-cli sends. The received messages are
+// passed on to the receive channels and posted to the webhook URL of the account
+// the message was received for.
+func (r *JsonRpc2Client) ReceiveData(number string, receiveWebhookUrl func(account string) string) {
 	connbuf := bufio.NewReader(r.conn)
 	for {
 		str, err := connbuf.ReadString('\n')
 		if err != nil {
 			log.Error("Lost connection to signal-cli...attempting to reconnect (", err.Error(), ")")
 			r.conn.Close()
-			err = r.Dial(r.address, 15)
+			r.failPendingRequests()
+			err = r.Dial(r.address, 60)
 			if err != nil {
 				log.Fatal("Unable to reconnect to signal-cli: ", err.Error(), "...aborting")
 			}
@@ -238,31 +250,28 @@ func (r *JsonRpc2Client) ReceiveData(number string, receiveWebhookUrl string) {
 		var resp1 JsonRpc2ReceivedMessage
 		json.Unmarshal([]byte(str), &resp1)
 		if resp1.Method == "receive" {
-			r.receivedMessagesMutex.Lock()
-			for _, c := range r.receivedMessagesChannels {
-				select {
-				case c <- resp1:
-					log.Debug("Message sent to golang channel")
-				default:
-					log.Debug("Couldn't send message to golang channel, as there's no receiver")
-				}
-				continue
-			}
-			r.receivedMessagesMutex.Unlock()
-
-			if receiveWebhookUrl != "" {
-				err = postMessageToWebhook(receiveWebhookUrl, []byte(str))
+			if r.receivedMessageTransformer != nil && len(resp1.Params) > 0 {
+				resp1.Params = r.receivedMessageTransformer(resp1.Params)
+				transformedStr, err := sjson.SetRawBytes([]byte(str), "params", resp1.Params)
 				if err != nil {
-					log.Error("Couldn't post data to webhook: ", err)
+					log.Error("Couldn't update received message: ", err.Error())
+				} else {
+					str = string(transformedStr)
 				}
 			}
+
+			r.dispatchReceivedMessage(resp1, []byte(str), receiveWebhookUrl)
 		}
 
 		var resp2 JsonRpc2MessageResponse
 		err = json.Unmarshal([]byte(str), &resp2)
 		if err == nil {
 			if resp2.Id != "" {
-				if responseChan, ok := r.receivedResponsesById[resp2.Id]; ok {
+				r.receivedResponsesMutex.Lock()
+				responseChan, ok := r.receivedResponsesById[resp2.Id]
+				delete(r.receivedResponsesById, resp2.Id)
+				r.receivedResponsesMutex.Unlock()
+				if ok {
 					responseChan <- resp2
 				}
 			}
@@ -272,6 +281,53 @@ func (r *JsonRpc2Client) ReceiveData(number string, receiveWebhookUrl string) {
 	}
 }
 
+// failPendingRequests lets the commands that are still waiting for a response
+// fail, as the response won't arrive anymore once the connection is lost (e.g.
+// because the signal-cli daemon was stopped).
+func (r *JsonRpc2Client) failPendingRequests() {
+	r.receivedResponsesMutex.Lock()
+	defer r.receivedResponsesMutex.Unlock()
+	for id, responseChan := range r.receivedResponsesById {
+		close(responseChan)
+		delete(r.receivedResponsesById, id)
+	}
+}
+
+// dispatchReceivedMessage passes the received message on to the receive
+// channels and posts it to the webhook URL of the account.
+func (r *JsonRpc2Client) dispatchReceivedMessage(msg JsonRpc2ReceivedMessage, data []byte, receiveWebhookUrl func(account string) string) {
+	r.receivedMessagesMutex.Lock()
+	for _, c := range r.receivedMessagesChannels {
+		select {
+		case c <- msg:
+			log.Debug("Message sent to golang channel")
+		default:
+			log.Debug("Couldn't send message to golang channel, as there's no receiver")
+		}
+	}
+	r.receivedMessagesMutex.Unlock()
+
+	var params struct {
+		Account string `json:"account"`
+	}
+	json.Unmarshal(msg.Params, &params)
+	if webhookUrl := receiveWebhookUrl(params.Account); webhookUrl != "" {
+		err := postMessageToWebhook(webhookUrl, data)
+		if err != nil {
+			log.Error("Couldn't post data to webhook: ", err)
+		}
+	}
+}
+
+// PublishReceivedMessage passes a message that was created by the REST API (and
+// not received from signal-cli) on to the receive channels and the webhook, in
+// the same way as a received message.
+func (r *JsonRpc2Client) PublishReceivedMessage(params []byte, receiveWebhookUrl func(account string) string) {
+	msg := JsonRpc2ReceivedMessage{Method: "receive", Params: params}
+	data := `{"jsonrpc":"2.0","method":"receive","params":` + string(params) + `}`
+	r.dispatchReceivedMessage(msg, []byte(data), receiveWebhookUrl)
+}
+
 func (r *JsonRpc2Client) GetReceiveChannel() (chan JsonRpc2ReceivedMessage, string, error) {
 	c := make(chan JsonRpc2ReceivedMessage)
 
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+)
+
+// newFakeSignalCliDaemon starts a fake signal-cli json-rpc daemon and returns a
+// SignalClient in json-rpc mode that is connected to it. The handler is called
+// (sequentially) for every request and returns the "result" or "error" member of
+// the response, e.g. `"result":{}`.
+func newFakeSignalCliDaemon(t *testing.T, handler func(method string, params json.RawMessage) string) *SignalClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			var request struct {
+				Id     string          `json:"id"`
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+			}
+			json.Unmarshal([]byte(line), &request)
+			response := handler(request.Method, request.Params)
+			conn.Write([]byte(`{"jsonrpc":"2.0","id":"` + request.Id + `",` + response + `}` + "\n"))
+		}
+	}()
+
+	jsonRpc2Client := NewJsonRpc2Client(utils.NewSignalCliApiConfig(), utils.MULTI_ACCOUNT_NUMBER)
+	if err := jsonRpc2Client.Dial(listener.Addr().String(), 1); err != nil {
+		t.Fatal(err)
+	}
+	go jsonRpc2Client.ReceiveData(utils.MULTI_ACCOUNT_NUMBER, func(string) string { return "" })
+
+	return &SignalClient{signalCliMode: JsonRpc, attachmentTmpDir: t.TempDir(),
+		jsonRpc2Clients: map[string]*JsonRpc2Client{utils.MULTI_ACCOUNT_NUMBER: jsonRpc2Client}}
+}
+
+func TestJsonRpc2PendingRequestsFailOnLostConnection(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	r := NewJsonRpc2Client(utils.NewSignalCliApiConfig(), utils.MULTI_ACCOUNT_NUMBER)
+	r.conn = clientConn
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := r.getRaw("listGroups", nil, nil)
+		result <- err
+	}()
+
+	// wait until the command was sent, the response never arrives
+	if _, err := bufio.NewReader(serverConn).ReadString('\n'); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.failPendingRequests()
+
+	if err := <-result; err == nil {
+		t.Errorf("expected the pending request to fail")
+	}
+}
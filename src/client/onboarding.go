@@ -0,0 +1,254 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	uuid "github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// onboardingSessionTtl is the time after which onboarding sessions that
+// weren't updated anymore are removed.
+const onboardingSessionTtl = 24 * time.Hour
+
+var ErrOnboardingSessionNotFound = errors.New("Onboarding session not found")
+
+// onboardingSessions keeps track of the registration and linking of accounts.
+// The sessions are persisted, so that the outcome can still be queried after a
+// restart.
+type onboardingSessions struct {
+	mutex sync.Mutex
+	store *utils.KeyValueNamespace
+}
+
+func (o *onboardingSessions) get(id string) (ds.OnboardingSession, error) {
+	var session ds.OnboardingSession
+	value, exists := o.store.Get(id)
+	if !exists {
+		return session, ErrOnboardingSessionNotFound
+	}
+	err := json.Unmarshal(value, &session)
+	return session, err
+}
+
+func (o *onboardingSessions) save(session ds.OnboardingSession) error {
+	session.UpdatedAt = time.Now().UTC()
+	value, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return o.store.Set(session.Id, value, onboardingSessionTtl)
+}
+
+// update applies the given change to the session and persists it.
+func (o *onboardingSessions) update(id string, change func(session *ds.OnboardingSession)) (ds.OnboardingSession, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	session, err := o.get(id)
+	if err != nil {
+		return session, err
+	}
+	change(&session)
+	return session, o.save(session)
+}
+
+func (o *onboardingSessions) create(sessionType string) (ds.OnboardingSession, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return ds.OnboardingSession{}, err
+	}
+
+	now := time.Now().UTC()
+	session := ds.OnboardingSession{Id: id.String(), Type: sessionType, CreatedAt: now}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return session, o.save(session)
+}
+
+// SetOnboardingStore sets the store the onboarding sessions are persisted in.
+// Linking sessions that were still waiting for the QR code to be scanned can't
+// be finished after a restart, so they are marked as failed.
+func (s *SignalClient) SetOnboardingStore(store *utils.KeyValueStore) error {
+	namespace, err := store.Namespace("onboarding-sessions")
+	if err != nil {
+		return err
+	}
+
+	s.onboardingSessions = &onboardingSessions{store: namespace}
+	for id := range namespace.List("") {
+		_, err := s.onboardingSessions.update(id, func(session *ds.OnboardingSession) {
+			if session.State == ds.OnboardingAwaitingScan {
+				session.State = ds.OnboardingFailed
+				session.Error = "The linking was interrupted by a restart of the REST API"
+			}
+		})
+		if err != nil {
+			log.Error("Couldn't update onboarding session ", id, ": ", err.Error())
+		}
+	}
+	return nil
+}
+
+func (s *SignalClient) getOnboardingSessions() (*onboardingSessions, error) {
+	if s.onboardingSessions == nil {
+		return nil, errors.New("Onboarding sessions are not available")
+	}
+	return s.onboardingSessions, nil
+}
+
+func (s *SignalClient) GetOnboardingSession(id string) (ds.OnboardingSession, error) {
+	sessions, err := s.getOnboardingSessions()
+	if err != nil {
+		return ds.OnboardingSession{}, err
+	}
+	return sessions.get(id)
+}
+
+// StartLinkOnboarding starts linking a new device. The session waits for the
+// QR code (the device link URI) to be scanned and contains the number of the
+// linked account afterwards.
+func (s *SignalClient) StartLinkOnboarding(deviceName string) (ds.OnboardingSession, error) {
+	sessions, err := s.getOnboardingSessions()
+	if err != nil {
+		return ds.OnboardingSession{}, err
+	}
+
+	session, err := sessions.create("link")
+	if err != nil {
+		return session, err
+	}
+
+	// the linking might already be finished before the session is updated with
+	// the device link URI, so the state is only changed while awaiting the scan.
+	deviceLinkUri, err := s.startLink(deviceName, func(number string, err error) {
+		_, updateErr := sessions.update(session.Id, func(session *ds.OnboardingSession) {
+			if err != nil {
+				session.State = ds.OnboardingFailed
+				session.Error = err.Error()
+			} else {
+				session.State = ds.OnboardingLinked
+				session.Number = number
+			}
+		})
+		if updateErr != nil {
+			log.Error("Couldn't update onboarding session ", session.Id, ": ", updateErr.Error())
+		}
+	})
+
+	return sessions.update(session.Id, func(session *ds.OnboardingSession) {
+		session.DeviceName = deviceName
+		if err != nil {
+			session.State = ds.OnboardingFailed
+			session.Error = err.Error()
+		} else if session.State == "" {
+			session.State = ds.OnboardingAwaitingScan
+			session.DeviceLinkUri = deviceLinkUri
+		}
+	})
+}
+
+// StartRegisterOnboarding registers a number and waits for the verification
+// code afterwards.
+func (s *SignalClient) StartRegisterOnboarding(number string, useVoice bool, captcha string) (ds.OnboardingSession, error) {
+	sessions, err := s.getOnboardingSessions()
+	if err != nil {
+		return ds.OnboardingSession{}, err
+	}
+
+	session, err := sessions.create("register")
+	if err != nil {
+		return session, err
+	}
+	session, err = sessions.update(session.Id, func(session *ds.OnboardingSession) {
+		session.Number = number
+	})
+	if err != nil {
+		return session, err
+	}
+	return s.RetryRegisterOnboarding(session.Id, useVoice, captcha)
+}
+
+// RetryRegisterOnboarding requests the verification code again, e.g. with the
+// solved captcha in case the session requires a captcha.
+func (s *SignalClient) RetryRegisterOnboarding(id string, useVoice bool, captcha string) (ds.OnboardingSession, error) {
+	sessions, err := s.getOnboardingSessions()
+	if err != nil {
+		return ds.OnboardingSession{}, err
+	}
+
+	session, err := sessions.get(id)
+	if err != nil {
+		return session, err
+	}
+	if session.Type != "register" || session.State == ds.OnboardingRegistered {
+		return session, errors.New("The verification code can't be requested for this onboarding session")
+	}
+
+	err = s.RegisterNumber(session.Number, useVoice, captcha)
+	return sessions.update(id, func(session *ds.OnboardingSession) {
+		session.UseVoice = useVoice
+		session.Error = ""
+		if err == nil {
+			session.State = ds.OnboardingAwaitingVerification
+		} else if strings.Contains(strings.ToLower(err.Error()), "captcha") {
+			session.State = ds.OnboardingCaptchaRequired
+			session.Error = err.Error()
+		} else {
+			session.State = ds.OnboardingFailed
+			session.Error = err.Error()
+		}
+	})
+}
+
+// VerifyRegisterOnboarding verifies the number of the session with the received
+// verification code. In case the verification fails, the session keeps waiting
+// for the (correct) verification code.
+func (s *SignalClient) VerifyRegisterOnboarding(id string, token string, pin string) (ds.OnboardingSession, error) {
+	sessions, err := s.getOnboardingSessions()
+	if err != nil {
+		return ds.OnboardingSession{}, err
+	}
+
+	session, err := sessions.get(id)
+	if err != nil {
+		return session, err
+	}
+	if session.Type != "register" || session.State != ds.OnboardingAwaitingVerification {
+		return session, errors.New("The onboarding session doesn't wait for a verification code")
+	}
+
+	verifyErr := s.VerifyRegisteredNumber(session.Number, token, pin)
+	session, err = sessions.update(id, func(session *ds.OnboardingSession) {
+		if verifyErr == nil {
+			session.State = ds.OnboardingRegistered
+			session.Error = ""
+		} else {
+			session.Error = verifyErr.Error()
+		}
+	})
+	if verifyErr != nil {
+		return session, verifyErr
+	}
+	return session, err
+}
+
+// GetOnboardingQrCode returns the QR code that needs to be scanned to link the
+// device of the session.
+func (s *SignalClient) GetOnboardingQrCode(id string, qrCodeVersion int) ([]byte, error) {
+	session, err := s.GetOnboardingSession(id)
+	if err != nil {
+		return []byte{}, err
+	}
+	if session.State != ds.OnboardingAwaitingScan {
+		return []byte{}, errors.New("The onboarding session doesn't wait for a QR code to be scanned")
+	}
+	return createQrCode(session.DeviceLinkUri, qrCodeVersion)
+}
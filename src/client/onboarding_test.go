@@ -0,0 +1,50 @@
+package client
+
+import (
+	"testing"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+)
+
+func TestOnboardingSessionsSurviveRestart(t *testing.T) {
+	directory := t.TempDir()
+	s := &SignalClient{}
+	if err := s.SetOnboardingStore(utils.NewKeyValueStore(directory)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	linkSession, err := s.onboardingSessions.create("link")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.onboardingSessions.update(linkSession.Id, func(session *ds.OnboardingSession) {
+		session.State = ds.OnboardingAwaitingScan
+	})
+	registerSession, _ := s.onboardingSessions.create("register")
+	s.onboardingSessions.update(registerSession.Id, func(session *ds.OnboardingSession) {
+		session.State = ds.OnboardingAwaitingVerification
+		session.Number = "+431212131491291"
+	})
+
+	restarted := &SignalClient{}
+	if err := restarted.SetOnboardingStore(utils.NewKeyValueStore(directory)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	session, err := restarted.GetOnboardingSession(linkSession.Id)
+	if err != nil || session.State != ds.OnboardingFailed || session.Error == "" {
+		t.Errorf("expected interrupted link session to be failed: %+v (%v)", session, err)
+	}
+	session, err = restarted.GetOnboardingSession(registerSession.Id)
+	if err != nil || session.State != ds.OnboardingAwaitingVerification || session.Number != "+431212131491291" {
+		t.Errorf("unexpected register session: %+v (%v)", session, err)
+	}
+
+	if _, err := restarted.GetOnboardingSession("unknown"); err != ErrOnboardingSessionNotFound {
+		t.Errorf("expected ErrOnboardingSessionNotFound, got %v", err)
+	}
+	if _, err := restarted.VerifyRegisterOnboarding(linkSession.Id, "123456", ""); err == nil {
+		t.Error("expected error, as a link session can't be verified")
+	}
+}
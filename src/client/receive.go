@@ -0,0 +1,497 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"os"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	log "github.com/sirupsen/logrus"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+	utils "github.com/bbernhard/signal-cli-rest-api/utils"
+)
+
+func getAttachmentUrl(id string) string {
+	return "/v1/attachments/" + url.PathEscape(id)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func convertOptionalGroupId(internalId string) string {
+	if internalId == "" {
+		return ""
+	}
+	return convertInternalGroupIdToGroupId(internalId)
+}
+
+func normalizeAttachment(attachment *ds.SignalCliAttachment) *ds.ReceivedAttachment {
+	if attachment == nil {
+		return nil
+	}
+	attachmentUrl := attachment.Url
+	if attachmentUrl == "" {
+		attachmentUrl = getAttachmentUrl(attachment.Id)
+	}
+	return &ds.ReceivedAttachment{
+		Id:          attachment.Id,
+		Url:         attachmentUrl,
+		Data:        attachment.Data,
+		ContentType: attachment.ContentType,
+		Filename:    attachment.Filename,
+		Size:        attachment.Size,
+		Width:       attachment.Width,
+		Height:      attachment.Height,
+		Caption:     attachment.Caption,
+	}
+}
+
+func normalizeMentions(mentions []ds.SignalCliMention) []ds.ReceivedMention {
+	if len(mentions) == 0 {
+		return nil
+	}
+	result := make([]ds.ReceivedMention, 0, len(mentions))
+	for _, mention := range mentions {
+		result = append(result, ds.ReceivedMention{
+			Number: mention.Number,
+			Uuid:   mention.Uuid,
+			Name:   mention.Name,
+			Start:  mention.Start,
+			Length: mention.Length,
+		})
+	}
+	return result
+}
+
+func normalizeDataMessage(dataMessage *ds.SignalCliDataMessage) ds.ReceivedDataMessage {
+	result := ds.ReceivedDataMessage{
+		Timestamp:          dataMessage.Timestamp,
+		Message:            dataMessage.Message,
+		ExpiresInSeconds:   dataMessage.ExpiresInSeconds,
+		IsExpirationUpdate: dataMessage.IsExpirationUpdate,
+		ViewOnce:           dataMessage.ViewOnce,
+		Mentions:           normalizeMentions(dataMessage.Mentions),
+	}
+
+	if dataMessage.GroupInfo != nil {
+		result.GroupId = convertOptionalGroupId(dataMessage.GroupInfo.GroupId)
+		result.GroupName = dataMessage.GroupInfo.GroupName
+	}
+
+	for i := range dataMessage.Attachments {
+		result.Attachments = append(result.Attachments, *normalizeAttachment(&dataMessage.Attachments[i]))
+	}
+
+	for _, textStyle := range dataMessage.TextStyles {
+		result.TextStyles = append(result.TextStyles, ds.ReceivedTextStyle{Style: textStyle.Style, Start: textStyle.Start, Length: textStyle.Length})
+	}
+
+	if dataMessage.Quote != nil {
+		result.Quote = &ds.ReceivedQuote{
+			Id:         dataMessage.Quote.Id,
+			Author:     firstNonEmpty(dataMessage.Quote.AuthorNumber, dataMessage.Quote.Author),
+			AuthorUuid: dataMessage.Quote.AuthorUuid,
+			Text:       dataMessage.Quote.Text,
+			Mentions:   normalizeMentions(dataMessage.Quote.Mentions),
+		}
+	}
+
+	if dataMessage.Sticker != nil {
+		result.Sticker = &ds.ReceivedSticker{PackId: dataMessage.Sticker.PackId, StickerId: dataMessage.Sticker.StickerId}
+	}
+
+	for i := range dataMessage.Previews {
+		preview := dataMessage.Previews[i]
+		result.Previews = append(result.Previews, ds.ReceivedPreview{
+			Url:         preview.Url,
+			Title:       preview.Title,
+			Description: preview.Description,
+			Image:       normalizeAttachment(preview.Image),
+		})
+	}
+
+	if dataMessage.RemoteDelete != nil {
+		timestamp := dataMessage.RemoteDelete.Timestamp
+		result.RemoteDeleteTimestamp = &timestamp
+	}
+
+	return result
+}
+
+func normalizePoll(dataMessage *ds.SignalCliDataMessage) *ds.ReceivedPoll {
+	var poll *ds.ReceivedPoll
+	if dataMessage.PollCreate != nil {
+		poll = &ds.ReceivedPoll{
+			Action:        "create",
+			Question:      dataMessage.PollCreate.Question,
+			AllowMultiple: dataMessage.PollCreate.AllowMultiple,
+			Options:       dataMessage.PollCreate.Options,
+		}
+	} else if dataMessage.PollVote != nil {
+		poll = &ds.ReceivedPoll{
+			Action:          "vote",
+			TargetTimestamp: dataMessage.PollVote.TargetSentTimestamp,
+			OptionIndexes:   dataMessage.PollVote.OptionIndexes,
+			VoteCount:       dataMessage.PollVote.VoteCount,
+		}
+	} else if dataMessage.PollTerminate != nil {
+		poll = &ds.ReceivedPoll{
+			Action:          "terminate",
+			TargetTimestamp: dataMessage.PollTerminate.TargetSentTimestamp,
+		}
+	} else {
+		return nil
+	}
+
+	if dataMessage.GroupInfo != nil {
+		poll.GroupId = convertOptionalGroupId(dataMessage.GroupInfo.GroupId)
+	}
+	return poll
+}
+
+func normalizeReceipt(receiptMessage *ds.SignalCliReceiptMessage) *ds.ReceivedReceipt {
+	receiptType := "delivery"
+	if receiptMessage.IsRead {
+		receiptType = "read"
+	} else if receiptMessage.IsViewed {
+		receiptType = "viewed"
+	}
+	return &ds.ReceivedReceipt{Type: receiptType, When: receiptMessage.When, Timestamps: receiptMessage.Timestamps}
+}
+
+func normalizeSync(syncMessage *ds.SignalCliSyncMessage) *ds.ReceivedSync {
+	result := &ds.ReceivedSync{
+		Type:           syncMessage.Type,
+		BlockedNumbers: syncMessage.BlockedNumbers,
+	}
+
+	if syncMessage.SentMessage != nil {
+		sent := &ds.ReceivedSyncSent{
+			Destination:     firstNonEmpty(syncMessage.SentMessage.DestinationNumber, syncMessage.SentMessage.Destination),
+			DestinationUuid: syncMessage.SentMessage.DestinationUuid,
+			Data:            normalizeDataMessage(&syncMessage.SentMessage.SignalCliDataMessage),
+		}
+		if syncMessage.SentMessage.EditMessage != nil {
+			sent.Edit = &ds.ReceivedEdit{
+				TargetTimestamp: syncMessage.SentMessage.EditMessage.TargetSentTimestamp,
+				Data:            normalizeDataMessage(&syncMessage.SentMessage.EditMessage.DataMessage),
+			}
+		}
+		result.Sent = sent
+	}
+
+	for _, readMessage := range syncMessage.ReadMessages {
+		result.ReadMessages = append(result.ReadMessages, ds.ReceivedSyncRead{
+			Sender:     firstNonEmpty(readMessage.SenderNumber, readMessage.Sender),
+			SenderUuid: readMessage.SenderUuid,
+			Timestamp:  readMessage.Timestamp,
+		})
+	}
+
+	for _, blockedGroupId := range syncMessage.BlockedGroupIds {
+		result.BlockedGroupIds = append(result.BlockedGroupIds, convertInternalGroupIdToGroupId(blockedGroupId))
+	}
+
+	return result
+}
+
+func normalizeCall(callMessage *ds.SignalCliCallMessage) *ds.ReceivedCall {
+	callType := "ice_update"
+	if callMessage.OfferMessage != nil {
+		callType = "offer"
+	} else if callMessage.AnswerMessage != nil {
+		callType = "answer"
+	} else if callMessage.BusyMessage != nil {
+		callType = "busy"
+	} else if callMessage.HangupMessage != nil {
+		callType = "hangup"
+	}
+	return &ds.ReceivedCall{Type: callType}
+}
+
+// SignUrl returns a time-limited, signed variant of the given (escaped) REST API
+// path. If no validity is provided, the configured default validity is used.
+func (s *SignalClient) SignUrl(path string, validity time.Duration) (string, time.Time) {
+	if validity <= 0 {
+		validity = s.signedUrlValidity
+	}
+	expiresAt := time.Now().Add(validity)
+	return s.publicUrl + s.urlSigner.Sign(path, expiresAt), expiresAt
+}
+
+func (s *SignalClient) GetUrlSigner() *utils.UrlSigner {
+	return s.urlSigner
+}
+
+// inlineAttachment either embeds the attachment as base64 data URI (if it is
+// smaller than the configured threshold) or adds a signed download URL.
+func (s *SignalClient) inlineAttachment(attachment map[string]interface{}) {
+	id, ok := attachment["id"].(string)
+	if !ok || id == "" {
+		return
+	}
+
+	path, err := securejoin.SecureJoin(s.signalCliConfig+"/attachments/", id)
+	if err != nil {
+		return
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil { //attachment wasn't downloaded (e.g ignore_attachments is set)
+		return
+	}
+
+	if fileInfo.Size() > s.attachmentInlineMaxSize {
+		attachment["url"], _ = s.SignUrl(getAttachmentUrl(id), 0)
+		return
+	}
+
+	attachmentBytes, err := os.ReadFile(path)
+	if err != nil {
+		log.Error("Couldn't read attachment ", id, ": ", err.Error())
+		return
+	}
+
+	dataUri := "data:"
+	if contentType, ok := attachment["contentType"].(string); ok {
+		dataUri += contentType
+	}
+	if filename, ok := attachment["filename"].(string); ok && filename != "" {
+		// characters like ';', ',' or spaces would break the data URI
+		dataUri += ";filename=" + url.PathEscape(filename)
+	}
+	dataUri += ";base64," + base64.StdEncoding.EncodeToString(attachmentBytes)
+	attachment["data"] = dataUri
+}
+
+func (s *SignalClient) inlineAttachments(message map[string]interface{}, keys ...string) {
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := message[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		message = next
+	}
+
+	switch attachments := message[keys[len(keys)-1]].(type) {
+	case []interface{}:
+		for _, attachment := range attachments {
+			if attachmentMap, ok := attachment.(map[string]interface{}); ok {
+				s.inlineAttachment(attachmentMap)
+			}
+		}
+	case map[string]interface{}:
+		s.inlineAttachment(attachments)
+	}
+}
+
+// transformReceivedMessage applies the receive options (e.g. inlining of
+// attachments) to a single message as emitted by signal-cli.
+func (s *SignalClient) transformReceivedMessage(data []byte) []byte {
+	if s.attachmentInlineMaxSize <= 0 || s.urlSigner == nil {
+		return data
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var message map[string]interface{}
+	err := decoder.Decode(&message)
+	if err != nil {
+		log.Error("Couldn't parse received message: ", err.Error())
+		return data
+	}
+
+	s.inlineAttachments(message, "envelope", "dataMessage", "attachments")
+	s.inlineAttachments(message, "envelope", "editMessage", "dataMessage", "attachments")
+	s.inlineAttachments(message, "envelope", "syncMessage", "sentMessage", "attachments")
+	s.inlineAttachments(message, "envelope", "storyMessage", "fileAttachment")
+
+	transformedData, err := json.Marshal(message)
+	if err != nil {
+		log.Error("Couldn't serialize received message: ", err.Error())
+		return data
+	}
+	return transformedData
+}
+
+// AddReceivedMessageHandler registers a handler that gets invoked (in its own
+// goroutine) for every message that is received from signal-cli, regardless of
+// whether the message was received via json-rpc or the receive endpoint.
+func (s *SignalClient) AddReceivedMessageHandler(handler func(data []byte)) {
+	s.receivedMessageMutex.Lock()
+	defer s.receivedMessageMutex.Unlock()
+	s.receivedMessageHandlers = append(s.receivedMessageHandlers, handler)
+}
+
+// processReceivedMessage gets invoked for every message that is received from
+// signal-cli. It returns the (transformed) message that is passed on to the
+// REST API consumers.
+func (s *SignalClient) processReceivedMessage(data []byte) []byte {
+	data = s.transformReceivedMessage(data)
+
+	if s.signalCliMode == JsonRpc {
+		go s.sendAutomaticReadReceipt(data)
+	}
+	go s.handleGroupUpdate(data)
+
+	s.receivedMessageMutex.RLock()
+	for _, handler := range s.receivedMessageHandlers {
+		go handler(data)
+	}
+	s.receivedMessageMutex.RUnlock()
+
+	return data
+}
+
+// sendAutomaticReadReceipt sends a read receipt for a received message in case
+// the account has automatic read receipts enabled. In normal and native mode,
+// the read receipts are sent by signal-cli when receiving the messages.
+func (s *SignalClient) sendAutomaticReadReceipt(data []byte) {
+	var msg ds.SignalCliReceivedMessage
+	err := json.Unmarshal(data, &msg)
+	if err != nil || msg.Envelope.DataMessage == nil {
+		return
+	}
+
+	accountSettings := s.signalCliApiConfig.GetAccountSettings(msg.Account)
+	if accountSettings.SendReadReceipts == nil || !*accountSettings.SendReadReceipts {
+		return
+	}
+
+	source := firstNonEmpty(msg.Envelope.SourceNumber, msg.Envelope.Source, msg.Envelope.SourceUuid)
+	err = s.SendReceipt(msg.Account, source, "read", msg.Envelope.Timestamp)
+	if err != nil {
+		log.Error("Couldn't send read receipt for message ", msg.Envelope.Timestamp, ": ", err.Error())
+	}
+}
+
+// NormalizeReceivedMessage converts a message as emitted by signal-cli into the
+// normalized (v2) receive format.
+func NormalizeReceivedMessage(msg ds.SignalCliReceivedMessage) ds.ReceivedMessage {
+	if msg.GroupChanged != nil {
+		result := ds.ReceivedMessage{
+			Account:      msg.Account,
+			Type:         ds.GroupChangedReceivedMessage,
+			Timestamp:    msg.GroupChanged.Timestamp,
+			GroupChanged: msg.GroupChanged,
+		}
+		if msg.GroupChanged.ChangedBy != nil {
+			result.Source = ds.ReceivedSource{Number: msg.GroupChanged.ChangedBy.Number, Uuid: msg.GroupChanged.ChangedBy.Uuid}
+		}
+		return result
+	}
+
+	envelope := msg.Envelope
+	result := ds.ReceivedMessage{
+		Account: msg.Account,
+		Type:    ds.UnknownReceivedMessage,
+		Source: ds.ReceivedSource{
+			Number: firstNonEmpty(envelope.SourceNumber, envelope.Source),
+			Uuid:   envelope.SourceUuid,
+			Name:   envelope.SourceName,
+			Device: envelope.SourceDevice,
+		},
+		Timestamp:                envelope.Timestamp,
+		ServerReceivedTimestamp:  envelope.ServerReceivedTimestamp,
+		ServerDeliveredTimestamp: envelope.ServerDeliveredTimestamp,
+	}
+
+	if envelope.EditMessage != nil {
+		result.Type = ds.EditReceivedMessage
+		result.Edit = &ds.ReceivedEdit{
+			TargetTimestamp: envelope.EditMessage.TargetSentTimestamp,
+			Data:            normalizeDataMessage(&envelope.EditMessage.DataMessage),
+		}
+	} else if envelope.DataMessage != nil {
+		dataMessage := envelope.DataMessage
+		if dataMessage.Reaction != nil {
+			result.Type = ds.ReactionReceivedMessage
+			result.Reaction = &ds.ReceivedReaction{
+				Emoji:           dataMessage.Reaction.Emoji,
+				TargetAuthor:    firstNonEmpty(dataMessage.Reaction.TargetAuthorNumber, dataMessage.Reaction.TargetAuthor),
+				TargetUuid:      dataMessage.Reaction.TargetAuthorUuid,
+				TargetTimestamp: dataMessage.Reaction.TargetSentTimestamp,
+				IsRemove:        dataMessage.Reaction.IsRemove,
+			}
+			if dataMessage.GroupInfo != nil {
+				result.Reaction.GroupId = convertOptionalGroupId(dataMessage.GroupInfo.GroupId)
+			}
+		} else if poll := normalizePoll(dataMessage); poll != nil {
+			result.Type = ds.PollReceivedMessage
+			result.Poll = poll
+		} else {
+			result.Type = ds.DataReceivedMessage
+			data := normalizeDataMessage(dataMessage)
+			result.Data = &data
+		}
+	} else if envelope.ReceiptMessage != nil {
+		result.Type = ds.ReceiptReceivedMessage
+		result.Receipt = normalizeReceipt(envelope.ReceiptMessage)
+	} else if envelope.TypingMessage != nil {
+		result.Type = ds.TypingReceivedMessage
+		result.Typing = &ds.ReceivedTyping{
+			Action:  envelope.TypingMessage.Action,
+			GroupId: convertOptionalGroupId(envelope.TypingMessage.GroupId),
+		}
+	} else if envelope.SyncMessage != nil {
+		result.Type = ds.SyncReceivedMessage
+		result.Sync = normalizeSync(envelope.SyncMessage)
+	} else if envelope.StoryMessage != nil {
+		result.Type = ds.StoryReceivedMessage
+		result.Story = &ds.ReceivedStory{
+			AllowsReplies:  envelope.StoryMessage.AllowsReplies,
+			GroupId:        convertOptionalGroupId(envelope.StoryMessage.GroupId),
+			Attachment:     normalizeAttachment(envelope.StoryMessage.FileAttachment),
+			TextAttachment: envelope.StoryMessage.TextAttachment,
+		}
+	} else if envelope.CallMessage != nil {
+		result.Type = ds.CallReceivedMessage
+		result.Call = normalizeCall(envelope.CallMessage)
+	}
+
+	return result
+}
+
+// NormalizeReceivedMessageJson parses a single message as emitted by signal-cli
+// and converts it into the normalized (v2) receive format.
+func NormalizeReceivedMessageJson(data []byte) (ds.ReceivedMessage, error) {
+	var msg ds.SignalCliReceivedMessage
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return ds.ReceivedMessage{}, err
+	}
+	return NormalizeReceivedMessage(msg), nil
+}
+
+func (s *SignalClient) ReceiveV2(number string, timeout int64, ignoreAttachments bool, ignoreStories bool, ignoreAvatars bool, ignoreStickers bool, maxMessages int64, sendReadReceipts bool) ([]ds.ReceivedMessage, error) {
+	jsonStr, err := s.Receive(number, timeout, ignoreAttachments, ignoreStories, ignoreAvatars, ignoreStickers, maxMessages, sendReadReceipts)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawMessages []json.RawMessage
+	err = json.Unmarshal([]byte(jsonStr), &rawMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []ds.ReceivedMessage{}
+	for _, rawMessage := range rawMessages {
+		msg, err := NormalizeReceivedMessageJson(rawMessage)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
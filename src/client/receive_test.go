@@ -0,0 +1,180 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+	utils "github.com/bbernhard/signal-cli-rest-api/utils"
+)
+
+// sampleReceivedGroupMessageJSON mirrors a group message with an attachment as
+// emitted by signal-cli (`receive --output json` / the json-rpc `receive`
+// notification).
+const sampleReceivedGroupMessageJSON = `{
+  "envelope": {
+    "source": "+15551230001",
+    "sourceNumber": "+15551230001",
+    "sourceUuid": "11111111-1111-1111-1111-111111111111",
+    "sourceName": "Alice",
+    "sourceDevice": 1,
+    "timestamp": 1700000000000,
+    "dataMessage": {
+      "timestamp": 1700000000000,
+      "message": "hello",
+      "expiresInSeconds": 0,
+      "viewOnce": false,
+      "attachments": [
+        {"contentType": "image/png", "filename": "a.png", "id": "abc123.png", "size": 42}
+      ],
+      "groupInfo": {"groupId": "Pmpi+EfPWmsxiomLe9Nx2XF9HOE483p6iKiFj65iMwI=", "type": "DELIVER"}
+    }
+  },
+  "account": "+15559990000"
+}`
+
+func TestNormalizeReceivedMessageJson(t *testing.T) {
+	msg, err := NormalizeReceivedMessageJson([]byte(sampleReceivedGroupMessageJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if msg.Type != ds.DataReceivedMessage {
+		t.Fatalf("expected type %q, got %q", ds.DataReceivedMessage, msg.Type)
+	}
+	if msg.Account != "+15559990000" || msg.Source.Number != "+15551230001" || msg.Source.Name != "Alice" {
+		t.Errorf("unexpected account/source: %+v", msg)
+	}
+	if msg.Data == nil || msg.Data.Message == nil || *msg.Data.Message != "hello" {
+		t.Fatalf("unexpected data message: %+v", msg.Data)
+	}
+
+	expectedGroupId := convertInternalGroupIdToGroupId("Pmpi+EfPWmsxiomLe9Nx2XF9HOE483p6iKiFj65iMwI=")
+	if msg.Data.GroupId != expectedGroupId {
+		t.Errorf("expected group id %q, got %q", expectedGroupId, msg.Data.GroupId)
+	}
+
+	if len(msg.Data.Attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(msg.Data.Attachments))
+	}
+	if msg.Data.Attachments[0].Url != "/v1/attachments/abc123.png" {
+		t.Errorf("unexpected attachment url %q", msg.Data.Attachments[0].Url)
+	}
+}
+
+func TestNormalizeReceivedMessageType(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected ds.ReceivedMessageType
+	}{
+		{
+			name:     "reaction",
+			input:    `{"account":"+1","envelope":{"dataMessage":{"reaction":{"emoji":"👍","targetSentTimestamp":1,"isRemove":false}}}}`,
+			expected: ds.ReactionReceivedMessage,
+		},
+		{
+			name:     "edit",
+			input:    `{"account":"+1","envelope":{"editMessage":{"targetSentTimestamp":1,"dataMessage":{"message":"fixed"}}}}`,
+			expected: ds.EditReceivedMessage,
+		},
+		{
+			name:     "receipt",
+			input:    `{"account":"+1","envelope":{"receiptMessage":{"isDelivery":false,"isRead":true,"isViewed":false,"timestamps":[1]}}}`,
+			expected: ds.ReceiptReceivedMessage,
+		},
+		{
+			name:     "typing",
+			input:    `{"account":"+1","envelope":{"typingMessage":{"action":"STARTED"}}}`,
+			expected: ds.TypingReceivedMessage,
+		},
+		{
+			name:     "sync",
+			input:    `{"account":"+1","envelope":{"syncMessage":{"sentMessage":{"message":"hi","destinationNumber":"+2"}}}}`,
+			expected: ds.SyncReceivedMessage,
+		},
+		{
+			name:     "story",
+			input:    `{"account":"+1","envelope":{"storyMessage":{"allowsReplies":true}}}`,
+			expected: ds.StoryReceivedMessage,
+		},
+		{
+			name:     "call",
+			input:    `{"account":"+1","envelope":{"callMessage":{"offerMessage":{"id":1}}}}`,
+			expected: ds.CallReceivedMessage,
+		},
+		{
+			name:     "poll",
+			input:    `{"account":"+1","envelope":{"dataMessage":{"pollVote":{"targetSentTimestamp":1,"optionIndexes":[0],"voteCount":1}}}}`,
+			expected: ds.PollReceivedMessage,
+		},
+		{
+			name:     "unknown",
+			input:    `{"account":"+1","envelope":{}}`,
+			expected: ds.UnknownReceivedMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := NormalizeReceivedMessageJson([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg.Type != tt.expected {
+				t.Errorf("expected type %q, got %q", tt.expected, msg.Type)
+			}
+		})
+	}
+}
+
+func TestTransformReceivedMessageInlinesAttachments(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, "attachments"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "attachments", "small.txt"), []byte("hi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "attachments", "large.txt"), []byte("this is too large"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	urlSigner, _ := utils.NewUrlSigner("secret")
+	s := NewSignalClient(configDir, "", "", Normal, "", "", "")
+	s.SetUrlSigner(urlSigner, time.Hour, "https://signal.example.com/")
+	s.SetAttachmentInlineMaxSize(10)
+
+	input := `{"account":"+1","envelope":{"timestamp":1700000000000,"dataMessage":{"attachments":[` +
+		`{"id":"small.txt","contentType":"text/plain","filename":"a b;c,d.txt"},` +
+		`{"id":"large.txt","contentType":"text/plain"}]}}}`
+	msg, err := NormalizeReceivedMessageJson(s.transformReceivedMessage([]byte(input)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if msg.Timestamp != 1700000000000 {
+		t.Errorf("timestamp wasn't preserved: %d", msg.Timestamp)
+	}
+
+	attachments := msg.Data.Attachments
+	if attachments[0].Data != "data:text/plain;filename=a%20b%3Bc%2Cd.txt;base64,aGk=" {
+		t.Errorf("unexpected data uri %q", attachments[0].Data)
+	}
+
+	// the data uri of a received attachment can be used to send the attachment again
+	attachmentEntry := NewAttachmentEntry(attachments[0].Data, t.TempDir()+string(os.PathSeparator))
+	if err := attachmentEntry.storeBase64AsTemporaryFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer attachmentEntry.cleanUp()
+	if attachmentEntry.FileName != "a b;c,d.txt" || filepath.Base(attachmentEntry.FilePath) != "a b;c,d.txt" {
+		t.Errorf("unexpected filename %q (%s)", attachmentEntry.FileName, attachmentEntry.FilePath)
+	}
+	if attachments[1].Data != "" || !strings.HasPrefix(attachments[1].Url, "https://signal.example.com/v1/attachments/large.txt?expires=") {
+		t.Errorf("expected signed url, got %q", attachments[1].Url)
+	}
+}
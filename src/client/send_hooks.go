@@ -0,0 +1,119 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+)
+
+// PreSendHook is invoked before a message is sent. The hook can modify the send
+// request or block the message by returning an error (usually a
+// SendBlockedError).
+type PreSendHook func(signalCliSendRequest *ds.SignalCliSendRequest) error
+
+// PostSendHook is invoked after a message was sent (or sending failed).
+type PostSendHook func(signalCliSendRequest ds.SignalCliSendRequest, resp *ds.SendMessageResponse, err error)
+
+func (s *SignalClient) AddPreSendHook(hook PreSendHook) {
+	s.sendHooksMutex.Lock()
+	defer s.sendHooksMutex.Unlock()
+	s.preSendHooks = append(s.preSendHooks, hook)
+}
+
+func (s *SignalClient) AddPostSendHook(hook PostSendHook) {
+	s.sendHooksMutex.Lock()
+	defer s.sendHooksMutex.Unlock()
+	s.postSendHooks = append(s.postSendHooks, hook)
+}
+
+// runPreSendHooks runs all pre-send hooks in the order they were registered.
+// Every hook sees the modifications of the previous hooks.
+func (s *SignalClient) runPreSendHooks(signalCliSendRequest *ds.SignalCliSendRequest) error {
+	s.sendHooksMutex.RLock()
+	hooks := s.preSendHooks
+	s.sendHooksMutex.RUnlock()
+
+	for _, hook := range hooks {
+		err := hook(signalCliSendRequest)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPostSendHooks runs all post-send hooks in the background, so that they
+// don't delay the response.
+func (s *SignalClient) runPostSendHooks(signalCliSendRequest ds.SignalCliSendRequest, resp *ds.SendMessageResponse, err error) {
+	s.sendHooksMutex.RLock()
+	hooks := s.postSendHooks
+	s.sendHooksMutex.RUnlock()
+
+	for _, hook := range hooks {
+		go hook(signalCliSendRequest, resp, err)
+	}
+}
+
+// sendWithHooks runs the pre-send hooks, sends the message and runs the post-send
+// hooks. In case a pre-send hook replaced the attachments, the new attachments
+// are stored and sent instead of the given attachment entries.
+func (s *SignalClient) sendWithHooks(signalCliSendRequest ds.SignalCliSendRequest, attachmentEntries []AttachmentEntry) (*ds.SendMessageResponse, error) {
+	base64Attachments := signalCliSendRequest.Base64Attachments
+	err := s.runPreSendHooks(&signalCliSendRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(base64Attachments, signalCliSendRequest.Base64Attachments) {
+		attachmentEntries, err = s.storeAttachments(signalCliSendRequest.Base64Attachments)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanupAttachmentEntries(attachmentEntries, nil)
+	}
+
+	resp, err := s.sendWithAttachmentEntries(signalCliSendRequest, attachmentEntries)
+	s.runPostSendHooks(signalCliSendRequest, resp, err)
+	return resp, err
+}
+
+// MarshalSendRequest encodes the send request as JSON, e.g. to hand it over to
+// plugins. Group recipients are prefixed with "group.", so that they have the
+// same format as in the REST API.
+func MarshalSendRequest(signalCliSendRequest ds.SignalCliSendRequest) ([]byte, error) {
+	if signalCliSendRequest.RecipientType == ds.Group {
+		recipients := []string{}
+		for _, recipient := range signalCliSendRequest.Recipients {
+			recipients = append(recipients, groupPrefix+recipient)
+		}
+		signalCliSendRequest.Recipients = recipients
+	}
+	return json.Marshal(signalCliSendRequest)
+}
+
+// UnmarshalSendRequest decodes a send request that was encoded with
+// MarshalSendRequest. As one send request can only contain recipients of one
+// type, the recipient type can't be changed.
+func UnmarshalSendRequest(data []byte, recipientType ds.RecpType) (ds.SignalCliSendRequest, error) {
+	var signalCliSendRequest ds.SignalCliSendRequest
+	err := json.Unmarshal(data, &signalCliSendRequest)
+	if err != nil {
+		return signalCliSendRequest, err
+	}
+
+	signalCliSendRequest.RecipientType = recipientType
+	for i, recipient := range signalCliSendRequest.Recipients {
+		t, err := getRecipientType(recipient)
+		if err != nil {
+			return signalCliSendRequest, err
+		}
+		if t != recipientType {
+			return signalCliSendRequest, errors.New("The type of the recipients can't be changed")
+		}
+		signalCliSendRequest.Recipients[i] = strings.TrimPrefix(recipient, groupPrefix)
+	}
+	return signalCliSendRequest, nil
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"testing"
+
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+)
+
+const sampleGroupRecipient = "group.UG1waStFZlBXbXN4aW9tTGU5TngyWEY5SE9FNDgzcDZpS2lGajY1aU13ST0="
+
+func TestMarshalSendRequestRoundTrip(t *testing.T) {
+	signalCliSendRequest := ds.SignalCliSendRequest{Number: "+431", Message: "hello", RecipientType: ds.Group,
+		Recipients: []string{sampleGroupRecipient[len(groupPrefix):]}}
+
+	data, err := MarshalSendRequest(signalCliSendRequest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"number":"+431","message":"hello","recipients":["` + sampleGroupRecipient + `"],"base64_attachments":null}`
+	if string(data) != expected {
+		t.Errorf("unexpected json %s", data)
+	}
+
+	decoded, err := UnmarshalSendRequest(data, ds.Group)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.RecipientType != ds.Group || decoded.Recipients[0] != signalCliSendRequest.Recipients[0] {
+		t.Errorf("unexpected send request: %+v", decoded)
+	}
+}
+
+func TestUnmarshalSendRequestRejectsDifferentRecipientType(t *testing.T) {
+	_, err := UnmarshalSendRequest([]byte(`{"number":"+431","recipients":["`+sampleGroupRecipient+`"]}`), ds.Number)
+	if err == nil {
+		t.Error("expected error, as the recipient type was changed")
+	}
+}
+
+func TestPreSendHooks(t *testing.T) {
+	s := NewSignalClient(t.TempDir(), "", "", Normal, "", "", "")
+	s.AddPreSendHook(func(signalCliSendRequest *ds.SignalCliSendRequest) error {
+		signalCliSendRequest.Message += " (sent via bot)"
+		return nil
+	})
+	s.AddPreSendHook(func(signalCliSendRequest *ds.SignalCliSendRequest) error {
+		if signalCliSendRequest.Message == "secret (sent via bot)" {
+			return &SendBlockedError{Reason: "contains a secret"}
+		}
+		return nil
+	})
+
+	signalCliSendRequest := ds.SignalCliSendRequest{Message: "hello"}
+	if err := s.runPreSendHooks(&signalCliSendRequest); err != nil || signalCliSendRequest.Message != "hello (sent via bot)" {
+		t.Errorf("unexpected result: %v, %q", err, signalCliSendRequest.Message)
+	}
+
+	signalCliSendRequest = ds.SignalCliSendRequest{Message: "secret"}
+	_, err := s.sendWithHooks(signalCliSendRequest, nil)
+	if _, ok := err.(*SendBlockedError); !ok {
+		t.Errorf("expected message to be blocked, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"fmt"
+	"time"
 )
 
 type RecpType int
@@ -35,22 +36,22 @@ type LinkPreviewType struct {
 }
 
 type SignalCliSendRequest struct {
-	Number            string
-	Message           string
-	Recipients        []string
-	Base64Attachments []string
-	RecipientType     RecpType
-	Sticker           string
-	Mentions          []MessageMention
-	QuoteTimestamp    *int64
-	QuoteAuthor       *string
-	QuoteMessage      *string
-	QuoteMentions     []MessageMention
-	TextMode          *string
-	EditTimestamp     *int64
-	NotifySelf        *bool
-	LinkPreview       *LinkPreviewType
-	ViewOnce          *bool
+	Number            string           `json:"number"`
+	Message           string           `json:"message"`
+	Recipients        []string         `json:"recipients"`
+	Base64Attachments []string         `json:"base64_attachments"`
+	RecipientType     RecpType         `json:"-"`
+	Sticker           string           `json:"sticker,omitempty"`
+	Mentions          []MessageMention `json:"mentions,omitempty"`
+	QuoteTimestamp    *int64           `json:"quote_timestamp,omitempty"`
+	QuoteAuthor       *string          `json:"quote_author,omitempty"`
+	QuoteMessage      *string          `json:"quote_message,omitempty"`
+	QuoteMentions     []MessageMention `json:"quote_mentions,omitempty"`
+	TextMode          *string          `json:"text_mode,omitempty"`
+	EditTimestamp     *int64           `json:"edit_timestamp,omitempty"`
+	NotifySelf        *bool            `json:"notify_self,omitempty"`
+	LinkPreview       *LinkPreviewType `json:"link_preview,omitempty"`
+	ViewOnce          *bool            `json:"view_once,omitempty"`
 }
 
 type GroupPermissions struct {
@@ -74,3 +75,34 @@ type SendMessageResponse struct {
 	Timestamp string             `json:"timestamp"`
 	Errors    *SendMessageErrors `json:"errors,omitempty"`
 }
+
+type BroadcastResult struct {
+	Recipient       string             `json:"recipient"`
+	Success         bool               `json:"success"`
+	Timestamp       string             `json:"timestamp,omitempty"`
+	Error           string             `json:"error,omitempty"`
+	ChallengeTokens []string           `json:"challenge_tokens,omitempty"`
+	Errors          *SendMessageErrors `json:"errors,omitempty"`
+}
+
+const (
+	OnboardingAwaitingScan         = "awaiting-scan"
+	OnboardingLinked               = "linked"
+	OnboardingCaptchaRequired      = "captcha-required"
+	OnboardingAwaitingVerification = "awaiting-verification"
+	OnboardingRegistered           = "registered"
+	OnboardingFailed               = "failed"
+)
+
+type OnboardingSession struct {
+	Id            string    `json:"id"`
+	Type          string    `json:"type" enums:"link,register"`
+	State         string    `json:"state" enums:"awaiting-scan,linked,captcha-required,awaiting-verification,registered,failed"`
+	Number        string    `json:"number,omitempty"`
+	DeviceName    string    `json:"device_name,omitempty"`
+	DeviceLinkUri string    `json:"device_link_uri,omitempty"`
+	UseVoice      bool      `json:"use_voice,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
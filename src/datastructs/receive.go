@@ -0,0 +1,424 @@
+package data
+
+import (
+	"encoding/json"
+)
+
+// The SignalCli* types below mirror the JSON that signal-cli emits for received
+// messages (both in json-rpc mode and with `--output json receive`).
+
+type SignalCliReceivedMessage struct {
+	Account  string            `json:"account"`
+	Envelope SignalCliEnvelope `json:"envelope"`
+
+	// added by the REST API for detected group changes (there is no envelope in that case)
+	GroupChanged *GroupChange `json:"group_changed,omitempty"`
+}
+
+type SignalCliEnvelope struct {
+	Source                   string                   `json:"source,omitempty"`
+	SourceNumber             string                   `json:"sourceNumber,omitempty"`
+	SourceUuid               string                   `json:"sourceUuid,omitempty"`
+	SourceName               string                   `json:"sourceName,omitempty"`
+	SourceDevice             int64                    `json:"sourceDevice,omitempty"`
+	Timestamp                int64                    `json:"timestamp,omitempty"`
+	ServerReceivedTimestamp  int64                    `json:"serverReceivedTimestamp,omitempty"`
+	ServerDeliveredTimestamp int64                    `json:"serverDeliveredTimestamp,omitempty"`
+	DataMessage              *SignalCliDataMessage    `json:"dataMessage,omitempty"`
+	EditMessage              *SignalCliEditMessage    `json:"editMessage,omitempty"`
+	ReceiptMessage           *SignalCliReceiptMessage `json:"receiptMessage,omitempty"`
+	TypingMessage            *SignalCliTypingMessage  `json:"typingMessage,omitempty"`
+	SyncMessage              *SignalCliSyncMessage    `json:"syncMessage,omitempty"`
+	StoryMessage             *SignalCliStoryMessage   `json:"storyMessage,omitempty"`
+	CallMessage              *SignalCliCallMessage    `json:"callMessage,omitempty"`
+}
+
+type SignalCliDataMessage struct {
+	Timestamp          int64                   `json:"timestamp,omitempty"`
+	Message            *string                 `json:"message,omitempty"`
+	ExpiresInSeconds   int64                   `json:"expiresInSeconds,omitempty"`
+	IsExpirationUpdate bool                    `json:"isExpirationUpdate,omitempty"`
+	ViewOnce           bool                    `json:"viewOnce,omitempty"`
+	GroupInfo          *SignalCliGroupInfo     `json:"groupInfo,omitempty"`
+	Attachments        []SignalCliAttachment   `json:"attachments,omitempty"`
+	Mentions           []SignalCliMention      `json:"mentions,omitempty"`
+	TextStyles         []SignalCliTextStyle    `json:"textStyles,omitempty"`
+	Quote              *SignalCliQuote         `json:"quote,omitempty"`
+	Sticker            *SignalCliSticker       `json:"sticker,omitempty"`
+	Previews           []SignalCliPreview      `json:"previews,omitempty"`
+	Reaction           *SignalCliReaction      `json:"reaction,omitempty"`
+	RemoteDelete       *SignalCliRemoteDelete  `json:"remoteDelete,omitempty"`
+	PollCreate         *SignalCliPollCreate    `json:"pollCreate,omitempty"`
+	PollVote           *SignalCliPollVote      `json:"pollVote,omitempty"`
+	PollTerminate      *SignalCliPollTerminate `json:"pollTerminate,omitempty"`
+}
+
+type SignalCliEditMessage struct {
+	TargetSentTimestamp int64                `json:"targetSentTimestamp"`
+	DataMessage         SignalCliDataMessage `json:"dataMessage"`
+}
+
+type SignalCliReceiptMessage struct {
+	When       int64   `json:"when,omitempty"`
+	IsDelivery bool    `json:"isDelivery"`
+	IsRead     bool    `json:"isRead"`
+	IsViewed   bool    `json:"isViewed"`
+	Timestamps []int64 `json:"timestamps,omitempty"`
+}
+
+type SignalCliTypingMessage struct {
+	Action    string `json:"action"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	GroupId   string `json:"groupId,omitempty"`
+}
+
+type SignalCliSyncMessage struct {
+	Type            string                     `json:"type,omitempty"`
+	SentMessage     *SignalCliSyncSentMessage  `json:"sentMessage,omitempty"`
+	ReadMessages    []SignalCliSyncReadMessage `json:"readMessages,omitempty"`
+	BlockedNumbers  []string                   `json:"blockedNumbers,omitempty"`
+	BlockedGroupIds []string                   `json:"blockedGroupIds,omitempty"`
+}
+
+type SignalCliSyncSentMessage struct {
+	SignalCliDataMessage
+	Destination       string                `json:"destination,omitempty"`
+	DestinationNumber string                `json:"destinationNumber,omitempty"`
+	DestinationUuid   string                `json:"destinationUuid,omitempty"`
+	EditMessage       *SignalCliEditMessage `json:"editMessage,omitempty"`
+}
+
+type SignalCliSyncReadMessage struct {
+	Sender       string `json:"sender,omitempty"`
+	SenderNumber string `json:"senderNumber,omitempty"`
+	SenderUuid   string `json:"senderUuid,omitempty"`
+	Timestamp    int64  `json:"timestamp"`
+}
+
+type SignalCliStoryMessage struct {
+	AllowsReplies  bool                 `json:"allowsReplies"`
+	GroupId        string               `json:"groupId,omitempty"`
+	FileAttachment *SignalCliAttachment `json:"fileAttachment,omitempty"`
+	TextAttachment json.RawMessage      `json:"textAttachment,omitempty"`
+}
+
+type SignalCliCallMessage struct {
+	OfferMessage      json.RawMessage   `json:"offerMessage,omitempty"`
+	AnswerMessage     json.RawMessage   `json:"answerMessage,omitempty"`
+	BusyMessage       json.RawMessage   `json:"busyMessage,omitempty"`
+	HangupMessage     json.RawMessage   `json:"hangupMessage,omitempty"`
+	IceUpdateMessages []json.RawMessage `json:"iceUpdateMessages,omitempty"`
+}
+
+type SignalCliGroupInfo struct {
+	GroupId   string `json:"groupId"`
+	GroupName string `json:"groupName,omitempty"`
+	Revision  int64  `json:"revision,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+type SignalCliAttachment struct {
+	Id              string  `json:"id"`
+	ContentType     string  `json:"contentType,omitempty"`
+	Filename        *string `json:"filename,omitempty"`
+	Size            int64   `json:"size,omitempty"`
+	Width           int64   `json:"width,omitempty"`
+	Height          int64   `json:"height,omitempty"`
+	Caption         *string `json:"caption,omitempty"`
+	UploadTimestamp int64   `json:"uploadTimestamp,omitempty"`
+
+	// added by the REST API if inlining of attachments is enabled
+	Url  string `json:"url,omitempty"`
+	Data string `json:"data,omitempty"`
+}
+
+type SignalCliMention struct {
+	Name   string `json:"name,omitempty"`
+	Number string `json:"number,omitempty"`
+	Uuid   string `json:"uuid,omitempty"`
+	Start  int64  `json:"start"`
+	Length int64  `json:"length"`
+}
+
+type SignalCliTextStyle struct {
+	Style  string `json:"style"`
+	Start  int64  `json:"start"`
+	Length int64  `json:"length"`
+}
+
+type SignalCliQuote struct {
+	Id           int64              `json:"id"`
+	Author       string             `json:"author,omitempty"`
+	AuthorNumber string             `json:"authorNumber,omitempty"`
+	AuthorUuid   string             `json:"authorUuid,omitempty"`
+	Text         string             `json:"text,omitempty"`
+	Mentions     []SignalCliMention `json:"mentions,omitempty"`
+}
+
+type SignalCliSticker struct {
+	PackId    string `json:"packId"`
+	StickerId int64  `json:"stickerId"`
+}
+
+type SignalCliPreview struct {
+	Url         string               `json:"url"`
+	Title       string               `json:"title,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Image       *SignalCliAttachment `json:"image,omitempty"`
+}
+
+type SignalCliReaction struct {
+	Emoji               string `json:"emoji"`
+	TargetAuthor        string `json:"targetAuthor,omitempty"`
+	TargetAuthorNumber  string `json:"targetAuthorNumber,omitempty"`
+	TargetAuthorUuid    string `json:"targetAuthorUuid,omitempty"`
+	TargetSentTimestamp int64  `json:"targetSentTimestamp"`
+	IsRemove            bool   `json:"isRemove"`
+}
+
+type SignalCliRemoteDelete struct {
+	Timestamp int64 `json:"timestamp"`
+}
+
+type SignalCliPollCreate struct {
+	Question      string   `json:"question"`
+	AllowMultiple bool     `json:"allowMultiple"`
+	Options       []string `json:"options"`
+}
+
+type SignalCliPollVote struct {
+	Author              string  `json:"author,omitempty"`
+	AuthorNumber        string  `json:"authorNumber,omitempty"`
+	AuthorUuid          string  `json:"authorUuid,omitempty"`
+	TargetSentTimestamp int64   `json:"targetSentTimestamp"`
+	OptionIndexes       []int64 `json:"optionIndexes"`
+	VoteCount           int64   `json:"voteCount"`
+}
+
+type SignalCliPollTerminate struct {
+	TargetSentTimestamp int64 `json:"targetSentTimestamp"`
+}
+
+// The Received* types below make up the normalized (v2) receive format. Field
+// names are snake_case, group ids use the REST API's "group.<id>" form and
+// attachments carry the URL under which they can be downloaded.
+
+type ReceivedMessageType string
+
+const (
+	DataReceivedMessage         ReceivedMessageType = "data"
+	EditReceivedMessage         ReceivedMessageType = "edit"
+	ReactionReceivedMessage     ReceivedMessageType = "reaction"
+	ReceiptReceivedMessage      ReceivedMessageType = "receipt"
+	TypingReceivedMessage       ReceivedMessageType = "typing"
+	SyncReceivedMessage         ReceivedMessageType = "sync"
+	StoryReceivedMessage        ReceivedMessageType = "story"
+	CallReceivedMessage         ReceivedMessageType = "call"
+	PollReceivedMessage         ReceivedMessageType = "poll"
+	GroupChangedReceivedMessage ReceivedMessageType = "group_changed"
+	UnknownReceivedMessage      ReceivedMessageType = "unknown"
+)
+
+type ReceivedMessage struct {
+	Account                  string               `json:"account"`
+	Type                     ReceivedMessageType  `json:"type" enums:"data,edit,reaction,receipt,typing,sync,story,call,poll,group_changed,unknown"`
+	Source                   ReceivedSource       `json:"source"`
+	Timestamp                int64                `json:"timestamp"`
+	ServerReceivedTimestamp  int64                `json:"server_received_timestamp,omitempty"`
+	ServerDeliveredTimestamp int64                `json:"server_delivered_timestamp,omitempty"`
+	Data                     *ReceivedDataMessage `json:"data,omitempty"`
+	Edit                     *ReceivedEdit        `json:"edit,omitempty"`
+	Reaction                 *ReceivedReaction    `json:"reaction,omitempty"`
+	Receipt                  *ReceivedReceipt     `json:"receipt,omitempty"`
+	Typing                   *ReceivedTyping      `json:"typing,omitempty"`
+	Sync                     *ReceivedSync        `json:"sync,omitempty"`
+	Story                    *ReceivedStory       `json:"story,omitempty"`
+	Call                     *ReceivedCall        `json:"call,omitempty"`
+	Poll                     *ReceivedPoll        `json:"poll,omitempty"`
+	GroupChanged             *GroupChange         `json:"group_changed,omitempty"`
+}
+
+// GroupId returns the (REST API) id of the group the message belongs to or an
+// empty string, if it isn't a group message.
+func (r *ReceivedMessage) GroupId() string {
+	if r.Data != nil {
+		return r.Data.GroupId
+	} else if r.Edit != nil {
+		return r.Edit.Data.GroupId
+	} else if r.Reaction != nil {
+		return r.Reaction.GroupId
+	} else if r.Typing != nil {
+		return r.Typing.GroupId
+	} else if r.Story != nil {
+		return r.Story.GroupId
+	} else if r.Poll != nil {
+		return r.Poll.GroupId
+	} else if r.Sync != nil && r.Sync.Sent != nil {
+		return r.Sync.Sent.Data.GroupId
+	} else if r.GroupChanged != nil {
+		return r.GroupChanged.GroupId
+	}
+	return ""
+}
+
+type ReceivedSource struct {
+	Number string `json:"number,omitempty"`
+	Uuid   string `json:"uuid,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Device int64  `json:"device,omitempty"`
+}
+
+type ReceivedDataMessage struct {
+	Timestamp             int64                `json:"timestamp"`
+	Message               *string              `json:"message,omitempty"`
+	GroupId               string               `json:"group_id,omitempty"`
+	GroupName             string               `json:"group_name,omitempty"`
+	ExpiresInSeconds      int64                `json:"expires_in_seconds,omitempty"`
+	IsExpirationUpdate    bool                 `json:"is_expiration_update,omitempty"`
+	ViewOnce              bool                 `json:"view_once,omitempty"`
+	Attachments           []ReceivedAttachment `json:"attachments,omitempty"`
+	Mentions              []ReceivedMention    `json:"mentions,omitempty"`
+	TextStyles            []ReceivedTextStyle  `json:"text_styles,omitempty"`
+	Quote                 *ReceivedQuote       `json:"quote,omitempty"`
+	Sticker               *ReceivedSticker     `json:"sticker,omitempty"`
+	Previews              []ReceivedPreview    `json:"previews,omitempty"`
+	RemoteDeleteTimestamp *int64               `json:"remote_delete_timestamp,omitempty"`
+}
+
+type ReceivedEdit struct {
+	TargetTimestamp int64               `json:"target_timestamp"`
+	Data            ReceivedDataMessage `json:"data"`
+}
+
+type ReceivedReaction struct {
+	Emoji           string `json:"emoji"`
+	TargetAuthor    string `json:"target_author,omitempty"`
+	TargetUuid      string `json:"target_uuid,omitempty"`
+	TargetTimestamp int64  `json:"target_timestamp"`
+	IsRemove        bool   `json:"is_remove"`
+	GroupId         string `json:"group_id,omitempty"`
+}
+
+type ReceivedReceipt struct {
+	Type       string  `json:"type" enums:"delivery,read,viewed"`
+	When       int64   `json:"when,omitempty"`
+	Timestamps []int64 `json:"timestamps"`
+}
+
+type ReceivedTyping struct {
+	Action  string `json:"action" enums:"STARTED,STOPPED"`
+	GroupId string `json:"group_id,omitempty"`
+}
+
+type ReceivedSync struct {
+	Type            string             `json:"type,omitempty"`
+	Sent            *ReceivedSyncSent  `json:"sent,omitempty"`
+	ReadMessages    []ReceivedSyncRead `json:"read_messages,omitempty"`
+	BlockedNumbers  []string           `json:"blocked_numbers,omitempty"`
+	BlockedGroupIds []string           `json:"blocked_group_ids,omitempty"`
+}
+
+type ReceivedSyncSent struct {
+	Destination     string              `json:"destination,omitempty"`
+	DestinationUuid string              `json:"destination_uuid,omitempty"`
+	Data            ReceivedDataMessage `json:"data"`
+	Edit            *ReceivedEdit       `json:"edit,omitempty"`
+}
+
+type ReceivedSyncRead struct {
+	Sender     string `json:"sender,omitempty"`
+	SenderUuid string `json:"sender_uuid,omitempty"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+type ReceivedStory struct {
+	AllowsReplies  bool                `json:"allows_replies"`
+	GroupId        string              `json:"group_id,omitempty"`
+	Attachment     *ReceivedAttachment `json:"attachment,omitempty"`
+	TextAttachment json.RawMessage     `json:"text_attachment,omitempty" swaggertype:"object"`
+}
+
+type ReceivedCall struct {
+	Type string `json:"type" enums:"offer,answer,busy,hangup,ice_update"`
+}
+
+type ReceivedPoll struct {
+	Action          string   `json:"action" enums:"create,vote,terminate"`
+	GroupId         string   `json:"group_id,omitempty"`
+	Question        string   `json:"question,omitempty"`
+	AllowMultiple   bool     `json:"allow_multiple,omitempty"`
+	Options         []string `json:"options,omitempty"`
+	TargetTimestamp int64    `json:"target_timestamp,omitempty"`
+	OptionIndexes   []int64  `json:"option_indexes,omitempty"`
+	VoteCount       int64    `json:"vote_count,omitempty"`
+}
+
+type ReceivedAttachment struct {
+	Id          string  `json:"id"`
+	Url         string  `json:"url"`
+	Data        string  `json:"data,omitempty"`
+	ContentType string  `json:"content_type,omitempty"`
+	Filename    *string `json:"filename,omitempty"`
+	Size        int64   `json:"size,omitempty"`
+	Width       int64   `json:"width,omitempty"`
+	Height      int64   `json:"height,omitempty"`
+	Caption     *string `json:"caption,omitempty"`
+}
+
+type ReceivedMention struct {
+	Number string `json:"number,omitempty"`
+	Uuid   string `json:"uuid,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Start  int64  `json:"start"`
+	Length int64  `json:"length"`
+}
+
+type ReceivedTextStyle struct {
+	Style  string `json:"style"`
+	Start  int64  `json:"start"`
+	Length int64  `json:"length"`
+}
+
+type ReceivedQuote struct {
+	Id         int64             `json:"id"`
+	Author     string            `json:"author,omitempty"`
+	AuthorUuid string            `json:"author_uuid,omitempty"`
+	Text       string            `json:"text,omitempty"`
+	Mentions   []ReceivedMention `json:"mentions,omitempty"`
+}
+
+type ReceivedSticker struct {
+	PackId    string `json:"pack_id"`
+	StickerId int64  `json:"sticker_id"`
+}
+
+type ReceivedPreview struct {
+	Url         string              `json:"url"`
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Image       *ReceivedAttachment `json:"image,omitempty"`
+}
+
+// GroupChange contains the changes of a group, which were detected by comparing
+// the group with the previously known state of the group. ChangedBy is only set
+// if it is known who changed the group.
+type GroupChange struct {
+	GroupId   string             `json:"group_id"`
+	GroupName string             `json:"group_name"`
+	ChangedBy *GroupChangeMember `json:"changed_by,omitempty"`
+	Timestamp int64              `json:"timestamp"`
+	Changes   []GroupChangeEntry `json:"changes"`
+}
+
+type GroupChangeMember struct {
+	Number string `json:"number,omitempty"`
+	Uuid   string `json:"uuid,omitempty"`
+}
+
+type GroupChangeEntry struct {
+	Type       string             `json:"type" enums:"member_added,member_removed,admin_added,admin_removed,invite_added,invite_removed,join_request_added,join_request_removed,name_changed,description_changed,permission_changed,expiration_changed,invite_link_changed"`
+	Member     *GroupChangeMember `json:"member,omitempty"`
+	Permission string             `json:"permission,omitempty" enums:"add_members,edit_group,send_messages"`
+	OldValue   string             `json:"old_value,omitempty"`
+	NewValue   string             `json:"new_value,omitempty"`
+}
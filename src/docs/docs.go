@@ -6,6 +6,74 @@ import "github.com/swaggo/swag"
 const docTemplate = `{
     "basePath": "{{.BasePath}}",
     "definitions": {
+        "api.AccountSettingsRequest": {
+            "properties": {
+                "expiration_in_seconds": {
+                    "type": "integer"
+                },
+                "notify_self": {
+                    "type": "boolean"
+                },
+                "receive_webhook_url": {
+                    "type": "string"
+                },
+                "send_read_receipts": {
+                    "type": "boolean"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "text_mode": {
+                    "enum": [
+                        "normal",
+                        "styled"
+                    ],
+                    "type": "string"
+                },
+                "trust_mode": {
+                    "enum": [
+                        "on-first-use",
+                        "always",
+                        "never"
+                    ],
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "api.AccountSettingsResponse": {
+            "properties": {
+                "expiration_in_seconds": {
+                    "type": "integer"
+                },
+                "notify_self": {
+                    "type": "boolean"
+                },
+                "receive_webhook_url": {
+                    "type": "string"
+                },
+                "send_read_receipts": {
+                    "type": "boolean"
+                },
+                "signature": {
+                    "type": "string"
+                },
+                "text_mode": {
+                    "enum": [
+                        "normal",
+                        "styled"
+                    ],
+                    "type": "string"
+                },
+                "trust_mode": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "trust_mode"
+            ],
+            "type": "object"
+        },
         "api.AddDeviceRequest": {
             "properties": {
                 "uri": {
@@ -34,6 +102,83 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "api.BroadcastRequest": {
+            "properties": {
+                "base64_attachments": {
+                    "example": [
+                        "\u003cBASE64 ENCODED DATA\u003e",
+                        "data:\u003cMIME-TYPE\u003e;base64\u003ccomma\u003e\u003cBASE64 ENCODED DATA\u003e",
+                        "data:\u003cMIME-TYPE\u003e;filename=\u003cFILENAME\u003e;base64\u003ccomma\u003e\u003cBASE64 ENCODED DATA\u003e"
+                    ],
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "link_preview": {
+                    "$ref": "#/definitions/data.LinkPreviewType"
+                },
+                "mentions": {
+                    "items": {
+                        "$ref": "#/definitions/data.MessageMention"
+                    },
+                    "type": "array"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "notify_self": {
+                    "type": "boolean"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "recipients": {
+                    "example": [
+                        "\u003cphone number\u003e",
+                        "\u003cusername\u003e",
+                        "\u003cgroup id\u003e"
+                    ],
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "sticker": {
+                    "type": "string"
+                },
+                "text_mode": {
+                    "enum": [
+                        "normal",
+                        "styled"
+                    ],
+                    "type": "string"
+                },
+                "view_once": {
+                    "type": "boolean"
+                }
+            },
+            "required": [
+                "message",
+                "number",
+                "recipients"
+            ],
+            "type": "object"
+        },
+        "api.BroadcastResponse": {
+            "properties": {
+                "results": {
+                    "items": {
+                        "$ref": "#/definitions/data.BroadcastResult"
+                    },
+                    "type": "array"
+                }
+            },
+            "required": [
+                "results"
+            ],
+            "type": "object"
+        },
         "api.ChangeGroupAdminsRequest": {
             "properties": {
                 "admins": {
@@ -81,8 +226,36 @@ const docTemplate = `{
         },
         "api.Configuration": {
             "properties": {
+                "attachment_retention_days": {
+                    "type": "integer"
+                },
+                "auto_receive_schedule": {
+                    "$ref": "#/definitions/utils.AutoReceiveScheduleSettings"
+                },
+                "default_signal_text_mode": {
+                    "enum": [
+                        "normal",
+                        "styled"
+                    ],
+                    "type": "string"
+                },
                 "logging": {
                     "$ref": "#/definitions/api.LoggingConfiguration"
+                },
+                "receive_webhook_url": {
+                    "type": "string"
+                },
+                "reset": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "trust_modes": {
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "type": "object"
                 }
             },
             "required": [
@@ -90,6 +263,31 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "api.ConfigurationResponse": {
+            "properties": {
+                "logging": {
+                    "$ref": "#/definitions/api.LoggingConfiguration"
+                },
+                "settings": {
+                    "additionalProperties": {
+                        "$ref": "#/definitions/utils.SettingValue"
+                    },
+                    "type": "object"
+                },
+                "trust_modes": {
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "type": "object"
+                }
+            },
+            "required": [
+                "logging",
+                "settings",
+                "trust_modes"
+            ],
+            "type": "object"
+        },
         "api.CreateGroupRequest": {
             "properties": {
                 "description": {
@@ -177,6 +375,22 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "api.CreateSignedUrlRequest": {
+            "properties": {
+                "expires_in": {
+                    "example": 3600,
+                    "type": "integer"
+                },
+                "path": {
+                    "example": "/v1/attachments/\u003cattachment id\u003e OR /v1/contacts/\u003cnumber\u003e/\u003cuuid\u003e/avatar OR /v1/groups/\u003cnumber\u003e/\u003cgroup id\u003e/avatar",
+                    "type": "string"
+                }
+            },
+            "required": [
+                "path"
+            ],
+            "type": "object"
+        },
         "api.DeleteLocalAccountDataRequest": {
             "properties": {
                 "ignore_registered": {
@@ -186,6 +400,24 @@ const docTemplate = `{
             },
             "type": "object"
         },
+        "api.DenyGroupJoinRequestsRequest": {
+            "properties": {
+                "ban": {
+                    "type": "boolean"
+                },
+                "members": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                }
+            },
+            "required": [
+                "ban",
+                "members"
+            ],
+            "type": "object"
+        },
         "api.DeviceLinkUriResponse": {
             "properties": {
                 "device_link_uri": {
@@ -208,6 +440,39 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "api.ExportAccountRequest": {
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "password"
+            ],
+            "type": "object"
+        },
+        "api.GroupInviteLinkResponse": {
+            "properties": {
+                "invite_link": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "invite_link"
+            ],
+            "type": "object"
+        },
+        "api.ImportAccountResponse": {
+            "properties": {
+                "number": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "number"
+            ],
+            "type": "object"
+        },
         "api.LoggingConfiguration": {
             "properties": {
                 "Level": {
@@ -219,6 +484,31 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "api.OnboardingRegisterRequest": {
+            "properties": {
+                "captcha": {
+                    "type": "string"
+                },
+                "use_voice": {
+                    "type": "boolean"
+                }
+            },
+            "type": "object"
+        },
+        "api.OnboardingVerifyRequest": {
+            "properties": {
+                "pin": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "token"
+            ],
+            "type": "object"
+        },
         "api.PinMessageInGroupRequest": {
             "properties": {
                 "duration": {
@@ -372,17 +662,6 @@ const docTemplate = `{
             ],
             "type": "object"
         },
-        "api.SendMessageResponse": {
-            "properties": {
-                "timestamp": {
-                    "type": "string"
-                }
-            },
-            "required": [
-                "timestamp"
-            ],
-            "type": "object"
-        },
         "api.SendMessageV1": {
             "properties": {
                 "base64_attachment": {
@@ -467,6 +746,9 @@ const docTemplate = `{
                     },
                     "type": "array"
                 },
+                "signature": {
+                    "type": "string"
+                },
                 "sticker": {
                     "type": "string"
                 },
@@ -534,30 +816,80 @@ const docTemplate = `{
             ],
             "type": "object"
         },
-        "api.TrustIdentityRequest": {
+        "api.SignedUrlResponse": {
             "properties": {
-                "trust_all_known_keys": {
-                    "example": false,
-                    "type": "boolean"
+                "expires_at": {
+                    "example": 1769271479,
+                    "type": "integer"
                 },
-                "verified_safety_number": {
+                "url": {
                     "type": "string"
                 }
             },
+            "required": [
+                "expires_at",
+                "url"
+            ],
             "type": "object"
         },
-        "api.TrustModeRequest": {
+        "api.StartOnboardingRequest": {
             "properties": {
-                "trust_mode": {
+                "captcha": {
+                    "type": "string"
+                },
+                "device_name": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "type": {
+                    "enum": [
+                        "link",
+                        "register"
+                    ],
                     "type": "string"
+                },
+                "use_voice": {
+                    "type": "boolean"
                 }
             },
             "required": [
-                "trust_mode"
+                "type"
+            ],
+            "type": "object"
+        },
+        "api.SyncGroupsRequest": {
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "groups": {
+                    "items": {
+                        "$ref": "#/definitions/client.DesiredGroup"
+                    },
+                    "type": "array"
+                }
+            },
+            "required": [
+                "dry_run",
+                "groups"
             ],
             "type": "object"
         },
-        "api.TrustModeResponse": {
+        "api.TrustIdentityRequest": {
+            "properties": {
+                "trust_all_known_keys": {
+                    "example": false,
+                    "type": "boolean"
+                },
+                "verified_safety_number": {
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "api.TrustModeRequest": {
             "properties": {
                 "trust_mode": {
                     "type": "string"
@@ -635,6 +967,25 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "api.UpdateGroupInviteLinkRequest": {
+            "properties": {
+                "reset": {
+                    "type": "boolean"
+                },
+                "state": {
+                    "enum": [
+                        "disabled",
+                        "enabled",
+                        "enabled-with-approval"
+                    ],
+                    "type": "string"
+                }
+            },
+            "required": [
+                "reset"
+            ],
+            "type": "object"
+        },
         "api.UpdateGroupRequest": {
             "properties": {
                 "base64_avatar": {
@@ -756,6 +1107,125 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "client.AccountDetails": {
+            "properties": {
+                "device_id": {
+                    "type": "integer"
+                },
+                "discoverable_by_number": {
+                    "type": "boolean"
+                },
+                "last_receive_timestamp": {
+                    "type": "integer"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "number_sharing": {
+                    "type": "boolean"
+                },
+                "primary_device": {
+                    "type": "boolean"
+                },
+                "profile": {
+                    "$ref": "#/definitions/client.AccountProfile"
+                },
+                "registration_lock": {
+                    "type": "boolean"
+                },
+                "trust_mode": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "username_link": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "number",
+                "profile",
+                "trust_mode",
+                "uuid"
+            ],
+            "type": "object"
+        },
+        "client.AccountProfile": {
+            "properties": {
+                "about": {
+                    "type": "string"
+                },
+                "avatar_url": {
+                    "type": "string"
+                },
+                "family_name": {
+                    "type": "string"
+                },
+                "given_name": {
+                    "type": "string"
+                },
+                "has_avatar": {
+                    "type": "boolean"
+                }
+            },
+            "required": [
+                "about",
+                "family_name",
+                "given_name",
+                "has_avatar"
+            ],
+            "type": "object"
+        },
+        "client.ContactImportResult": {
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "items": {
+                        "$ref": "#/definitions/client.ContactImportRow"
+                    },
+                    "type": "array"
+                },
+                "send_contacts_error": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "failed",
+                "imported",
+                "rows"
+            ],
+            "type": "object"
+        },
+        "client.ContactImportRow": {
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "imported": {
+                    "type": "boolean"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "imported",
+                "row"
+            ],
+            "type": "object"
+        },
         "client.ContactProfile": {
             "properties": {
                 "about": {
@@ -783,6 +1253,41 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "client.DesiredGroup": {
+            "properties": {
+                "admins": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "base64_avatar": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "members": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "$ref": "#/definitions/data.GroupPermissions"
+                }
+            },
+            "required": [
+                "name"
+            ],
+            "type": "object"
+        },
         "client.GroupEntry": {
             "properties": {
                 "admins": {
@@ -797,6 +1302,9 @@ const docTemplate = `{
                 "description": {
                     "type": "string"
                 },
+                "expiration_time": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -838,6 +1346,7 @@ const docTemplate = `{
                 "admins",
                 "blocked",
                 "description",
+                "expiration_time",
                 "id",
                 "internal_id",
                 "invite_link",
@@ -850,6 +1359,65 @@ const docTemplate = `{
             ],
             "type": "object"
         },
+        "client.GroupSyncAction": {
+            "properties": {
+                "applied": {
+                    "type": "boolean"
+                },
+                "changes": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "members": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "type": {
+                    "enum": [
+                        "create_group",
+                        "update_group",
+                        "add_members",
+                        "remove_members",
+                        "add_admins",
+                        "remove_admins"
+                    ],
+                    "type": "string"
+                }
+            },
+            "required": [
+                "applied",
+                "type"
+            ],
+            "type": "object"
+        },
+        "client.GroupSyncResult": {
+            "properties": {
+                "actions": {
+                    "items": {
+                        "$ref": "#/definitions/client.GroupSyncAction"
+                    },
+                    "type": "array"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "actions",
+                "name"
+            ],
+            "type": "object"
+        },
         "client.IdentityEntry": {
             "properties": {
                 "added": {
@@ -1020,23 +1588,136 @@ const docTemplate = `{
             ],
             "type": "object"
         },
-        "data.GroupPermissions": {
+        "data.BroadcastResult": {
             "properties": {
-                "add_members": {
-                    "enum": [
-                        "only-admins",
-                        "every-member"
-                    ],
+                "challenge_tokens": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "error": {
                     "type": "string"
                 },
-                "edit_group": {
-                    "enum": [
-                        "only-admins",
-                        "every-member"
-                    ],
+                "errors": {
+                    "$ref": "#/definitions/data.SendMessageErrors"
+                },
+                "recipient": {
                     "type": "string"
                 },
-                "send_messages": {
+                "success": {
+                    "type": "boolean"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "recipient",
+                "success"
+            ],
+            "type": "object"
+        },
+        "data.GroupChange": {
+            "properties": {
+                "changed_by": {
+                    "$ref": "#/definitions/data.GroupChangeMember"
+                },
+                "changes": {
+                    "items": {
+                        "$ref": "#/definitions/data.GroupChangeEntry"
+                    },
+                    "type": "array"
+                },
+                "group_id": {
+                    "type": "string"
+                },
+                "group_name": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "changes",
+                "group_id",
+                "group_name",
+                "timestamp"
+            ],
+            "type": "object"
+        },
+        "data.GroupChangeEntry": {
+            "properties": {
+                "member": {
+                    "$ref": "#/definitions/data.GroupChangeMember"
+                },
+                "new_value": {
+                    "type": "string"
+                },
+                "old_value": {
+                    "type": "string"
+                },
+                "permission": {
+                    "enum": [
+                        "add_members",
+                        "edit_group",
+                        "send_messages"
+                    ],
+                    "type": "string"
+                },
+                "type": {
+                    "enum": [
+                        "member_added",
+                        "member_removed",
+                        "admin_added",
+                        "admin_removed",
+                        "invite_added",
+                        "invite_removed",
+                        "join_request_added",
+                        "join_request_removed",
+                        "name_changed",
+                        "description_changed",
+                        "permission_changed",
+                        "expiration_changed",
+                        "invite_link_changed"
+                    ],
+                    "type": "string"
+                }
+            },
+            "required": [
+                "type"
+            ],
+            "type": "object"
+        },
+        "data.GroupChangeMember": {
+            "properties": {
+                "number": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "data.GroupPermissions": {
+            "properties": {
+                "add_members": {
+                    "enum": [
+                        "only-admins",
+                        "every-member"
+                    ],
+                    "type": "string"
+                },
+                "edit_group": {
+                    "enum": [
+                        "only-admins",
+                        "every-member"
+                    ],
+                    "type": "string"
+                },
+                "send_messages": {
                     "enum": [
                         "only-admins",
                         "every-member"
@@ -1108,32 +1789,69 @@ const docTemplate = `{
             ],
             "type": "object"
         },
-        "receive.AdminDelete": {
+        "data.OnboardingSession": {
             "properties": {
-                "targetAuthor": {
+                "created_at": {
                     "type": "string"
                 },
-                "targetAuthorNumber": {
+                "device_link_uri": {
                     "type": "string"
                 },
-                "targetAuthorUuid": {
+                "device_name": {
                     "type": "string"
                 },
-                "targetSentTimestamp": {
-                    "type": "integer"
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "state": {
+                    "enum": [
+                        "awaiting-scan",
+                        "linked",
+                        "captcha-required",
+                        "awaiting-verification",
+                        "registered",
+                        "failed"
+                    ],
+                    "type": "string"
+                },
+                "type": {
+                    "enum": [
+                        "link",
+                        "register"
+                    ],
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "use_voice": {
+                    "type": "boolean"
                 }
             },
             "required": [
-                "targetSentTimestamp"
+                "created_at",
+                "id",
+                "state",
+                "type",
+                "updated_at"
             ],
             "type": "object"
         },
-        "receive.Attachment": {
+        "data.ReceivedAttachment": {
             "properties": {
                 "caption": {
                     "type": "string"
                 },
-                "contentType": {
+                "content_type": {
+                    "type": "string"
+                },
+                "data": {
                     "type": "string"
                 },
                 "filename": {
@@ -1148,514 +1866,585 @@ const docTemplate = `{
                 "size": {
                     "type": "integer"
                 },
-                "uploadTimestamp": {
-                    "type": "integer"
+                "url": {
+                    "type": "string"
                 },
                 "width": {
                     "type": "integer"
                 }
             },
+            "required": [
+                "id",
+                "url"
+            ],
             "type": "object"
         },
-        "receive.AttachmentData": {
+        "data.ReceivedCall": {
             "properties": {
-                "data": {
+                "type": {
+                    "enum": [
+                        "offer",
+                        "answer",
+                        "busy",
+                        "hangup",
+                        "ice_update"
+                    ],
                     "type": "string"
                 }
             },
+            "required": [
+                "type"
+            ],
             "type": "object"
         },
-        "receive.CallMessage": {
+        "data.ReceivedDataMessage": {
             "properties": {
-                "answerMessage": {
-                    "properties": {
-                        "id": {
-                            "type": "integer"
-                        },
-                        "opaque": {
-                            "type": "string"
-                        }
+                "attachments": {
+                    "items": {
+                        "$ref": "#/definitions/data.ReceivedAttachment"
                     },
-                    "type": "object"
+                    "type": "array"
                 },
-                "busyMessage": {
-                    "properties": {
-                        "id": {
-                            "type": "integer"
-                        }
-                    },
-                    "type": "object"
+                "expires_in_seconds": {
+                    "type": "integer"
                 },
-                "hangupMessage": {
-                    "properties": {
-                        "deviceId": {
-                            "type": "integer"
-                        },
-                        "id": {
-                            "type": "integer"
-                        },
-                        "type": {
-                            "type": "string"
-                        }
-                    },
-                    "required": [
-                        "deviceId"
-                    ],
-                    "type": "object"
+                "group_id": {
+                    "type": "string"
                 },
-                "iceUpdateMessages": {
+                "group_name": {
+                    "type": "string"
+                },
+                "is_expiration_update": {
+                    "type": "boolean"
+                },
+                "mentions": {
                     "items": {
-                        "properties": {
-                            "id": {
-                                "type": "integer"
-                            },
-                            "opaque": {
-                                "type": "string"
-                            }
-                        },
-                        "type": "object"
+                        "$ref": "#/definitions/data.ReceivedMention"
                     },
                     "type": "array"
                 },
-                "offerMessage": {
-                    "properties": {
-                        "id": {
-                            "type": "integer"
-                        },
-                        "opaque": {
-                            "type": "string"
-                        },
-                        "type": {
-                            "type": "string"
-                        }
-                    },
-                    "type": "object"
-                }
-            },
-            "type": "object"
-        },
-        "receive.Contact": {
-            "properties": {
-                "color": {
+                "message": {
                     "type": "string"
                 },
-                "familyName": {
-                    "type": "string"
+                "previews": {
+                    "items": {
+                        "$ref": "#/definitions/data.ReceivedPreview"
+                    },
+                    "type": "array"
                 },
-                "givenName": {
-                    "type": "string"
+                "quote": {
+                    "$ref": "#/definitions/data.ReceivedQuote"
                 },
-                "internal": {
-                    "$ref": "#/definitions/receive.Internal"
+                "remote_delete_timestamp": {
+                    "type": "integer"
                 },
-                "isArchived": {
-                    "type": "boolean"
+                "sticker": {
+                    "$ref": "#/definitions/data.ReceivedSticker"
                 },
-                "isBlocked": {
-                    "type": "boolean"
+                "text_styles": {
+                    "items": {
+                        "$ref": "#/definitions/data.ReceivedTextStyle"
+                    },
+                    "type": "array"
                 },
-                "isHidden": {
+                "timestamp": {
+                    "type": "integer"
+                },
+                "view_once": {
                     "type": "boolean"
+                }
+            },
+            "required": [
+                "timestamp"
+            ],
+            "type": "object"
+        },
+        "data.ReceivedEdit": {
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/data.ReceivedDataMessage"
                 },
-                "messageExpirationTime": {
+                "target_timestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "data",
+                "target_timestamp"
+            ],
+            "type": "object"
+        },
+        "data.ReceivedMention": {
+            "properties": {
+                "length": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
                 },
-                "nickFamilyName": {
+                "number": {
                     "type": "string"
                 },
-                "nickGivenName": {
-                    "type": "string"
+                "start": {
+                    "type": "integer"
                 },
-                "nickName": {
+                "uuid": {
                     "type": "string"
-                },
-                "note": {
+                }
+            },
+            "required": [
+                "length",
+                "start"
+            ],
+            "type": "object"
+        },
+        "data.ReceivedMessage": {
+            "properties": {
+                "account": {
                     "type": "string"
                 },
-                "number": {
-                    "type": "string"
+                "call": {
+                    "$ref": "#/definitions/data.ReceivedCall"
                 },
-                "profile": {
-                    "$ref": "#/definitions/receive.Profile"
+                "data": {
+                    "$ref": "#/definitions/data.ReceivedDataMessage"
                 },
-                "profileSharing": {
-                    "type": "boolean"
+                "edit": {
+                    "$ref": "#/definitions/data.ReceivedEdit"
                 },
-                "unregistered": {
-                    "type": "boolean"
+                "group_changed": {
+                    "$ref": "#/definitions/data.GroupChange"
                 },
-                "username": {
-                    "type": "string"
+                "poll": {
+                    "$ref": "#/definitions/data.ReceivedPoll"
                 },
-                "uuid": {
-                    "type": "string"
+                "reaction": {
+                    "$ref": "#/definitions/data.ReceivedReaction"
+                },
+                "receipt": {
+                    "$ref": "#/definitions/data.ReceivedReceipt"
+                },
+                "server_delivered_timestamp": {
+                    "type": "integer"
+                },
+                "server_received_timestamp": {
+                    "type": "integer"
+                },
+                "source": {
+                    "$ref": "#/definitions/data.ReceivedSource"
+                },
+                "story": {
+                    "$ref": "#/definitions/data.ReceivedStory"
+                },
+                "sync": {
+                    "$ref": "#/definitions/data.ReceivedSync"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/data.ReceivedMessageType"
+                        }
+                    ],
+                    "enum": [
+                        "data",
+                        "edit",
+                        "reaction",
+                        "receipt",
+                        "typing",
+                        "sync",
+                        "story",
+                        "call",
+                        "poll",
+                        "group_changed",
+                        "unknown"
+                    ]
+                },
+                "typing": {
+                    "$ref": "#/definitions/data.ReceivedTyping"
                 }
             },
             "required": [
-                "isArchived",
-                "isBlocked",
-                "isHidden",
-                "messageExpirationTime",
-                "profileSharing",
-                "unregistered"
+                "account",
+                "source",
+                "timestamp",
+                "type"
             ],
             "type": "object"
         },
-        "receive.ContactAddress": {
+        "data.ReceivedMessageType": {
+            "enum": [
+                "data",
+                "edit",
+                "reaction",
+                "receipt",
+                "typing",
+                "sync",
+                "story",
+                "call",
+                "poll",
+                "group_changed",
+                "unknown"
+            ],
+            "type": "string",
+            "x-enum-varnames": [
+                "DataReceivedMessage",
+                "EditReceivedMessage",
+                "ReactionReceivedMessage",
+                "ReceiptReceivedMessage",
+                "TypingReceivedMessage",
+                "SyncReceivedMessage",
+                "StoryReceivedMessage",
+                "CallReceivedMessage",
+                "PollReceivedMessage",
+                "GroupChangedReceivedMessage",
+                "UnknownReceivedMessage"
+            ]
+        },
+        "data.ReceivedPoll": {
             "properties": {
-                "city": {
+                "action": {
+                    "enum": [
+                        "create",
+                        "vote",
+                        "terminate"
+                    ],
                     "type": "string"
                 },
-                "country": {
-                    "type": "string"
+                "allow_multiple": {
+                    "type": "boolean"
                 },
-                "label": {
+                "group_id": {
                     "type": "string"
                 },
-                "neighborhood": {
-                    "type": "string"
+                "option_indexes": {
+                    "items": {
+                        "type": "integer"
+                    },
+                    "type": "array"
                 },
-                "pobox": {
-                    "type": "string"
+                "options": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
                 },
-                "postcode": {
+                "question": {
                     "type": "string"
                 },
-                "region": {
+                "target_timestamp": {
+                    "type": "integer"
+                },
+                "vote_count": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "action"
+            ],
+            "type": "object"
+        },
+        "data.ReceivedPreview": {
+            "properties": {
+                "description": {
                     "type": "string"
                 },
-                "street": {
+                "image": {
+                    "$ref": "#/definitions/data.ReceivedAttachment"
+                },
+                "title": {
                     "type": "string"
                 },
-                "type": {
+                "url": {
                     "type": "string"
                 }
             },
+            "required": [
+                "url"
+            ],
             "type": "object"
         },
-        "receive.ContactAvatar": {
+        "data.ReceivedQuote": {
             "properties": {
-                "attachment": {
-                    "$ref": "#/definitions/receive.Attachment"
+                "author": {
+                    "type": "string"
                 },
-                "isProfile": {
-                    "type": "boolean"
+                "author_uuid": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mentions": {
+                    "items": {
+                        "$ref": "#/definitions/data.ReceivedMention"
+                    },
+                    "type": "array"
+                },
+                "text": {
+                    "type": "string"
                 }
             },
             "required": [
-                "isProfile"
+                "id"
             ],
             "type": "object"
         },
-        "receive.ContactEmail": {
+        "data.ReceivedReaction": {
             "properties": {
-                "label": {
+                "emoji": {
                     "type": "string"
                 },
-                "type": {
+                "group_id": {
                     "type": "string"
                 },
-                "value": {
+                "is_remove": {
+                    "type": "boolean"
+                },
+                "target_author": {
+                    "type": "string"
+                },
+                "target_timestamp": {
+                    "type": "integer"
+                },
+                "target_uuid": {
                     "type": "string"
                 }
             },
+            "required": [
+                "emoji",
+                "is_remove",
+                "target_timestamp"
+            ],
             "type": "object"
         },
-        "receive.ContactName": {
+        "data.ReceivedReceipt": {
             "properties": {
-                "family": {
-                    "type": "string"
+                "timestamps": {
+                    "items": {
+                        "type": "integer"
+                    },
+                    "type": "array"
                 },
-                "given": {
+                "type": {
+                    "enum": [
+                        "delivery",
+                        "read",
+                        "viewed"
+                    ],
                     "type": "string"
                 },
-                "middle": {
-                    "type": "string"
+                "when": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "timestamps",
+                "type"
+            ],
+            "type": "object"
+        },
+        "data.ReceivedSource": {
+            "properties": {
+                "device": {
+                    "type": "integer"
                 },
-                "nickname": {
+                "name": {
                     "type": "string"
                 },
-                "prefix": {
+                "number": {
                     "type": "string"
                 },
-                "suffix": {
+                "uuid": {
                     "type": "string"
                 }
             },
             "type": "object"
         },
-        "receive.ContactPhone": {
+        "data.ReceivedSticker": {
             "properties": {
-                "label": {
+                "pack_id": {
                     "type": "string"
                 },
-                "type": {
-                    "type": "string"
+                "sticker_id": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "pack_id",
+                "sticker_id"
+            ],
+            "type": "object"
+        },
+        "data.ReceivedStory": {
+            "properties": {
+                "allows_replies": {
+                    "type": "boolean"
                 },
-                "value": {
+                "attachment": {
+                    "$ref": "#/definitions/data.ReceivedAttachment"
+                },
+                "group_id": {
                     "type": "string"
+                },
+                "text_attachment": {
+                    "type": "object"
                 }
             },
+            "required": [
+                "allows_replies"
+            ],
             "type": "object"
         },
-        "receive.DataMessage": {
+        "data.ReceivedSync": {
             "properties": {
-                "adminDelete": {
-                    "$ref": "#/definitions/receive.AdminDelete"
-                },
-                "attachments": {
+                "blocked_group_ids": {
                     "items": {
-                        "$ref": "#/definitions/receive.Attachment"
+                        "type": "string"
                     },
                     "type": "array"
                 },
-                "contacts": {
+                "blocked_numbers": {
                     "items": {
-                        "$ref": "#/definitions/receive.SharedContact"
+                        "type": "string"
                     },
                     "type": "array"
                 },
-                "expiresInSeconds": {
-                    "type": "integer"
-                },
-                "groupInfo": {
-                    "$ref": "#/definitions/receive.GroupInfo"
-                },
-                "isExpirationUpdate": {
-                    "type": "boolean"
-                },
-                "mentions": {
+                "read_messages": {
                     "items": {
-                        "$ref": "#/definitions/receive.Mention"
+                        "$ref": "#/definitions/data.ReceivedSyncRead"
                     },
                     "type": "array"
                 },
-                "message": {
+                "sent": {
+                    "$ref": "#/definitions/data.ReceivedSyncSent"
+                },
+                "type": {
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "data.ReceivedSyncRead": {
+            "properties": {
+                "sender": {
                     "type": "string"
                 },
-                "payment": {
-                    "$ref": "#/definitions/receive.Payment"
+                "sender_uuid": {
+                    "type": "string"
                 },
-                "pinMessage": {
-                    "$ref": "#/definitions/receive.PinMessage"
+                "timestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "timestamp"
+            ],
+            "type": "object"
+        },
+        "data.ReceivedSyncSent": {
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/data.ReceivedDataMessage"
                 },
-                "pollCreate": {
-                    "$ref": "#/definitions/receive.PollCreate"
+                "destination": {
+                    "type": "string"
                 },
-                "pollTerminate": {
-                    "$ref": "#/definitions/receive.PollTerminate"
+                "destination_uuid": {
+                    "type": "string"
                 },
-                "pollVote": {
-                    "$ref": "#/definitions/receive.PollVote"
+                "edit": {
+                    "$ref": "#/definitions/data.ReceivedEdit"
+                }
+            },
+            "required": [
+                "data"
+            ],
+            "type": "object"
+        },
+        "data.ReceivedTextStyle": {
+            "properties": {
+                "length": {
+                    "type": "integer"
                 },
-                "previews": {
-                    "items": {
-                        "$ref": "#/definitions/receive.Preview"
-                    },
-                    "type": "array"
-                },
-                "quote": {
-                    "$ref": "#/definitions/receive.Quote"
-                },
-                "reaction": {
-                    "$ref": "#/definitions/receive.Reaction"
-                },
-                "remoteDelete": {
-                    "$ref": "#/definitions/receive.RemoteDelete"
-                },
-                "sticker": {
-                    "$ref": "#/definitions/receive.Sticker"
-                },
-                "storyContext": {
-                    "$ref": "#/definitions/receive.StoryContext"
-                },
-                "textStyles": {
-                    "items": {
-                        "$ref": "#/definitions/receive.TextStyle"
-                    },
-                    "type": "array"
-                },
-                "timestamp": {
+                "start": {
                     "type": "integer"
                 },
-                "unpinMessage": {
-                    "$ref": "#/definitions/receive.UnpinMessage"
-                },
-                "viewOnce": {
-                    "type": "boolean"
+                "style": {
+                    "type": "string"
                 }
             },
             "required": [
-                "timestamp"
+                "length",
+                "start",
+                "style"
             ],
             "type": "object"
         },
-        "receive.EditMessage": {
+        "data.ReceivedTyping": {
             "properties": {
-                "dataMessage": {
-                    "$ref": "#/definitions/receive.DataMessage"
+                "action": {
+                    "enum": [
+                        "STARTED",
+                        "STOPPED"
+                    ],
+                    "type": "string"
                 },
-                "targetSentTimestamp": {
-                    "type": "integer"
+                "group_id": {
+                    "type": "string"
                 }
             },
             "required": [
-                "targetSentTimestamp"
+                "action"
             ],
             "type": "object"
         },
-        "receive.Error": {
+        "data.SendMessageError": {
             "properties": {
-                "message": {
+                "number": {
                     "type": "string"
                 },
-                "type": {
-                    "type": "string"
-                }
-            },
-            "type": "object"
-        },
-        "receive.GroupInfo": {
-            "properties": {
-                "groupId": {
+                "reason": {
                     "type": "string"
                 },
-                "groupName": {
+                "username": {
                     "type": "string"
                 },
-                "revision": {
-                    "type": "integer"
-                },
-                "type": {
+                "uuid": {
                     "type": "string"
                 }
             },
-            "required": [
-                "revision"
-            ],
             "type": "object"
         },
-        "receive.Internal": {
+        "data.SendMessageErrors": {
             "properties": {
-                "capabilities": {
+                "recipients": {
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/data.SendMessageError"
                     },
                     "type": "array"
-                },
-                "discoverableByPhonenumber": {
-                    "type": "boolean"
-                },
-                "sharesPhoneNumber": {
-                    "type": "boolean"
-                },
-                "unidentifiedAccessMode": {
-                    "type": "string"
-                }
-            },
-            "type": "object"
-        },
-        "receive.Mention": {
-            "properties": {
-                "length": {
-                    "type": "integer"
-                },
-                "name": {
-                    "type": "string"
-                },
-                "number": {
-                    "type": "string"
-                },
-                "start": {
-                    "type": "integer"
-                },
-                "uuid": {
-                    "type": "string"
                 }
             },
-            "required": [
-                "start",
-                "length"
-            ],
             "type": "object"
         },
-        "receive.MessageEnvelope": {
+        "data.SendMessageResponse": {
             "properties": {
-                "callMessage": {
-                    "$ref": "#/definitions/receive.CallMessage"
-                },
-                "dataMessage": {
-                    "$ref": "#/definitions/receive.DataMessage"
-                },
-                "editMessage": {
-                    "$ref": "#/definitions/receive.EditMessage"
-                },
-                "receiptMessage": {
-                    "$ref": "#/definitions/receive.ReceiptMessage"
-                },
-                "serverDeliveredTimestamp": {
-                    "type": "integer"
-                },
-                "serverReceivedTimestamp": {
-                    "type": "integer"
-                },
-                "source": {
-                    "type": "string"
-                },
-                "sourceDevice": {
-                    "type": "integer"
-                },
-                "sourceName": {
-                    "type": "string"
-                },
-                "sourceNumber": {
-                    "type": "string"
-                },
-                "sourceUuid": {
-                    "type": "string"
-                },
-                "storyMessage": {
-                    "$ref": "#/definitions/receive.StoryMessage"
-                },
-                "syncMessage": {
-                    "$ref": "#/definitions/receive.SyncMessage"
+                "errors": {
+                    "$ref": "#/definitions/data.SendMessageErrors"
                 },
                 "timestamp": {
-                    "type": "integer"
-                },
-                "typingMessage": {
-                    "$ref": "#/definitions/receive.TypingMessage"
-                }
-            },
-            "required": [
-                "timestamp",
-                "serverReceivedTimestamp",
-                "serverDeliveredTimestamp"
-            ],
-            "type": "object"
-        },
-        "receive.Payment": {
-            "properties": {
-                "note": {
-                    "type": "string"
-                },
-                "receipt": {
                     "type": "string"
                 }
             },
             "required": [
-                "receipt"
+                "timestamp"
             ],
             "type": "object"
         },
-        "receive.PinMessage": {
+        "receive.AdminDelete": {
             "properties": {
-                "pinDurationSeconds": {
-                    "type": "integer"
-                },
                 "targetAuthor": {
                     "type": "string"
                 },
@@ -1669,414 +2458,285 @@ const docTemplate = `{
                     "type": "integer"
                 }
             },
-            "required": [
-                "targetSentTimestamp",
-                "pinDurationSeconds"
-            ],
-            "type": "object"
-        },
-        "receive.PollCreate": {
-            "properties": {
-                "allowMultiple": {
-                    "type": "boolean"
-                },
-                "options": {
-                    "items": {
-                        "type": "string"
-                    },
-                    "type": "array"
-                },
-                "question": {
-                    "type": "string"
-                }
-            },
-            "required": [
-                "allowMultiple"
-            ],
-            "type": "object"
-        },
-        "receive.PollTerminate": {
-            "properties": {
-                "targetSentTimestamp": {
-                    "type": "integer"
-                }
-            },
             "required": [
                 "targetSentTimestamp"
             ],
             "type": "object"
         },
-        "receive.PollVote": {
+        "receive.Attachment": {
             "properties": {
-                "author": {
+                "caption": {
                     "type": "string"
                 },
-                "authorNumber": {
+                "contentType": {
                     "type": "string"
                 },
-                "authorUuid": {
+                "filename": {
                     "type": "string"
                 },
-                "optionIndexes": {
-                    "items": {
-                        "type": "integer"
-                    },
-                    "type": "array"
+                "height": {
+                    "type": "integer"
                 },
-                "targetSentTimestamp": {
+                "id": {
+                    "type": "string"
+                },
+                "size": {
                     "type": "integer"
                 },
-                "voteCount": {
+                "uploadTimestamp": {
+                    "type": "integer"
+                },
+                "width": {
                     "type": "integer"
                 }
             },
-            "required": [
-                "targetSentTimestamp",
-                "voteCount"
-            ],
             "type": "object"
         },
-        "receive.Preview": {
+        "receive.AttachmentData": {
             "properties": {
-                "description": {
-                    "type": "string"
-                },
-                "image": {
-                    "$ref": "#/definitions/receive.Attachment"
-                },
-                "title": {
-                    "type": "string"
-                },
-                "url": {
+                "data": {
                     "type": "string"
                 }
             },
             "type": "object"
         },
-        "receive.Profile": {
+        "receive.CallMessage": {
             "properties": {
-                "about": {
-                    "type": "string"
-                },
-                "aboutEmoji": {
-                    "type": "string"
-                },
-                "familyName": {
-                    "type": "string"
-                },
-                "givenName": {
-                    "type": "string"
+                "answerMessage": {
+                    "properties": {
+                        "id": {
+                            "type": "integer"
+                        },
+                        "opaque": {
+                            "type": "string"
+                        }
+                    },
+                    "type": "object"
                 },
-                "hasAvatar": {
-                    "type": "boolean"
+                "busyMessage": {
+                    "properties": {
+                        "id": {
+                            "type": "integer"
+                        }
+                    },
+                    "type": "object"
                 },
-                "lastUpdateTimestamp": {
-                    "type": "integer"
+                "hangupMessage": {
+                    "properties": {
+                        "deviceId": {
+                            "type": "integer"
+                        },
+                        "id": {
+                            "type": "integer"
+                        },
+                        "type": {
+                            "type": "string"
+                        }
+                    },
+                    "required": [
+                        "deviceId"
+                    ],
+                    "type": "object"
                 },
-                "mobileCoinAddress": {
-                    "type": "string"
+                "iceUpdateMessages": {
+                    "items": {
+                        "properties": {
+                            "id": {
+                                "type": "integer"
+                            },
+                            "opaque": {
+                                "type": "string"
+                            }
+                        },
+                        "type": "object"
+                    },
+                    "type": "array"
+                },
+                "offerMessage": {
+                    "properties": {
+                        "id": {
+                            "type": "integer"
+                        },
+                        "opaque": {
+                            "type": "string"
+                        },
+                        "type": {
+                            "type": "string"
+                        }
+                    },
+                    "type": "object"
                 }
             },
-            "required": [
-                "lastUpdateTimestamp",
-                "hasAvatar"
-            ],
             "type": "object"
         },
-        "receive.Quote": {
+        "receive.Contact": {
             "properties": {
-                "attachments": {
-                    "items": {
-                        "$ref": "#/definitions/receive.QuotedAttachment"
-                    },
-                    "type": "array"
-                },
-                "author": {
+                "color": {
                     "type": "string"
                 },
-                "authorNumber": {
+                "familyName": {
                     "type": "string"
                 },
-                "authorUuid": {
+                "givenName": {
                     "type": "string"
                 },
-                "id": {
+                "internal": {
+                    "$ref": "#/definitions/receive.Internal"
+                },
+                "isArchived": {
+                    "type": "boolean"
+                },
+                "isBlocked": {
+                    "type": "boolean"
+                },
+                "isHidden": {
+                    "type": "boolean"
+                },
+                "messageExpirationTime": {
                     "type": "integer"
                 },
-                "mentions": {
-                    "items": {
-                        "$ref": "#/definitions/receive.Mention"
-                    },
-                    "type": "array"
+                "name": {
+                    "type": "string"
                 },
-                "text": {
+                "nickFamilyName": {
                     "type": "string"
                 },
-                "textStyles": {
-                    "items": {
-                        "$ref": "#/definitions/receive.TextStyle"
-                    },
-                    "type": "array"
+                "nickGivenName": {
+                    "type": "string"
+                },
+                "nickName": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "profile": {
+                    "$ref": "#/definitions/receive.Profile"
+                },
+                "profileSharing": {
+                    "type": "boolean"
+                },
+                "unregistered": {
+                    "type": "boolean"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
                 }
             },
             "required": [
-                "id"
+                "isArchived",
+                "isBlocked",
+                "isHidden",
+                "messageExpirationTime",
+                "profileSharing",
+                "unregistered"
             ],
             "type": "object"
         },
-        "receive.QuotedAttachment": {
+        "receive.ContactAddress": {
             "properties": {
-                "contentType": {
+                "city": {
                     "type": "string"
                 },
-                "filename": {
+                "country": {
                     "type": "string"
                 },
-                "thumbnail": {
-                    "$ref": "#/definitions/receive.Attachment"
-                }
-            },
-            "type": "object"
-        },
-        "receive.Reaction": {
-            "properties": {
-                "emoji": {
+                "label": {
                     "type": "string"
                 },
-                "isRemove": {
-                    "type": "boolean"
+                "neighborhood": {
+                    "type": "string"
                 },
-                "targetAuthor": {
+                "pobox": {
                     "type": "string"
                 },
-                "targetAuthorNumber": {
+                "postcode": {
                     "type": "string"
                 },
-                "targetAuthorUuid": {
+                "region": {
                     "type": "string"
                 },
-                "targetSentTimestamp": {
-                    "type": "integer"
+                "street": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
                 }
             },
-            "required": [
-                "targetSentTimestamp",
-                "isRemove"
-            ],
             "type": "object"
         },
-        "receive.ReceiptMessage": {
+        "receive.ContactAvatar": {
             "properties": {
-                "isDelivery": {
-                    "type": "boolean"
-                },
-                "isRead": {
-                    "type": "boolean"
+                "attachment": {
+                    "$ref": "#/definitions/receive.Attachment"
                 },
-                "isViewed": {
+                "isProfile": {
                     "type": "boolean"
-                },
-                "timestamps": {
-                    "items": {
-                        "type": "integer"
-                    },
-                    "type": "array"
-                },
-                "when": {
-                    "type": "integer"
                 }
             },
             "required": [
-                "when",
-                "isDelivery",
-                "isRead",
-                "isViewed"
+                "isProfile"
             ],
             "type": "object"
         },
-        "receive.RecipientAddress": {
+        "receive.ContactEmail": {
             "properties": {
-                "number": {
+                "label": {
                     "type": "string"
                 },
-                "username": {
+                "type": {
                     "type": "string"
                 },
-                "uuid": {
+                "value": {
                     "type": "string"
                 }
             },
             "type": "object"
         },
-        "receive.RemoteDelete": {
-            "properties": {
-                "timestamp": {
-                    "type": "integer"
-                }
-            },
-            "required": [
-                "timestamp"
-            ],
-            "type": "object"
-        },
-        "receive.SendMessageResult": {
+        "receive.ContactName": {
             "properties": {
-                "groupId": {
+                "family": {
                     "type": "string"
                 },
-                "recipientAddress": {
-                    "$ref": "#/definitions/receive.RecipientAddress"
+                "given": {
+                    "type": "string"
                 },
-                "retryAfterSeconds": {
-                    "type": "integer"
+                "middle": {
+                    "type": "string"
                 },
-                "token": {
+                "nickname": {
                     "type": "string"
                 },
-                "type": {
-                    "enum": [
-                        "SUCCESS",
-                        "NETWORK_FAILURE",
-                        "UNREGISTERED_FAILURE",
-                        "IDENTITY_FAILURE",
-                        "RATE_LIMIT_FAILURE",
-                        "INVALID_PRE_KEY_FAILURE"
-                    ],
+                "prefix": {
+                    "type": "string"
+                },
+                "suffix": {
                     "type": "string"
                 }
             },
             "type": "object"
         },
-        "receive.SharedContact": {
+        "receive.ContactPhone": {
             "properties": {
-                "address": {
-                    "items": {
-                        "$ref": "#/definitions/receive.ContactAddress"
-                    },
-                    "type": "array"
-                },
-                "avatar": {
-                    "$ref": "#/definitions/receive.ContactAvatar"
-                },
-                "email": {
-                    "items": {
-                        "$ref": "#/definitions/receive.ContactEmail"
-                    },
-                    "type": "array"
-                },
-                "name": {
-                    "$ref": "#/definitions/receive.ContactName"
-                },
-                "organization": {
-                    "type": "string"
-                },
-                "phone": {
-                    "items": {
-                        "$ref": "#/definitions/receive.ContactPhone"
-                    },
-                    "type": "array"
-                }
-            },
-            "type": "object"
-        },
-        "receive.Sticker": {
-            "properties": {
-                "packId": {
-                    "type": "string"
-                },
-                "stickerId": {
-                    "type": "integer"
-                }
-            },
-            "required": [
-                "stickerId"
-            ],
-            "type": "object"
-        },
-        "receive.StoryContext": {
-            "properties": {
-                "authorNumber": {
+                "label": {
                     "type": "string"
                 },
-                "authorUuid": {
+                "type": {
                     "type": "string"
                 },
-                "sentTimestamp": {
-                    "type": "integer"
-                }
-            },
-            "required": [
-                "sentTimestamp"
-            ],
-            "type": "object"
-        },
-        "receive.StoryMessage": {
-            "properties": {
-                "allowsReplies": {
-                    "type": "boolean"
-                },
-                "fileAttachment": {
-                    "$ref": "#/definitions/receive.Attachment"
-                },
-                "groupId": {
+                "value": {
                     "type": "string"
-                },
-                "textAttachment": {
-                    "properties": {
-                        "backgroundColor": {
-                            "type": "string"
-                        },
-                        "backgroundGradient": {
-                            "properties": {
-                                "angle": {
-                                    "type": "integer"
-                                },
-                                "colors": {
-                                    "items": {
-                                        "type": "string"
-                                    },
-                                    "type": "array"
-                                },
-                                "endColor": {
-                                    "type": "string"
-                                },
-                                "positions": {
-                                    "items": {
-                                        "type": "number"
-                                    },
-                                    "type": "array"
-                                },
-                                "startColor": {
-                                    "type": "string"
-                                }
-                            },
-                            "type": "object"
-                        },
-                        "preview": {
-                            "$ref": "#/definitions/receive.Preview"
-                        },
-                        "style": {
-                            "type": "string"
-                        },
-                        "text": {
-                            "type": "string"
-                        },
-                        "textBackgroundColor": {
-                            "type": "string"
-                        },
-                        "textForegroundColor": {
-                            "type": "string"
-                        }
-                    },
-                    "type": "object"
                 }
             },
-            "required": [
-                "allowsReplies"
-            ],
             "type": "object"
         },
-        "receive.SyncDataMessage": {
+        "receive.DataMessage": {
             "properties": {
                 "adminDelete": {
                     "$ref": "#/definitions/receive.AdminDelete"
@@ -2093,18 +2753,6 @@ const docTemplate = `{
                     },
                     "type": "array"
                 },
-                "destination": {
-                    "type": "string"
-                },
-                "destinationNumber": {
-                    "type": "string"
-                },
-                "destinationUuid": {
-                    "type": "string"
-                },
-                "editMessage": {
-                    "$ref": "#/definitions/receive.EditMessage"
-                },
                 "expiresInSeconds": {
                     "type": "integer"
                 },
@@ -2175,201 +2823,1630 @@ const docTemplate = `{
                     "type": "boolean"
                 }
             },
+            "required": [
+                "timestamp"
+            ],
             "type": "object"
         },
-        "receive.SyncMessage": {
+        "receive.EditMessage": {
             "properties": {
-                "blockedGroupIds": {
-                    "items": {
-                        "type": "string"
-                    },
-                    "type": "array"
+                "dataMessage": {
+                    "$ref": "#/definitions/receive.DataMessage"
                 },
-                "blockedNumbers": {
-                    "items": {
-                        "type": "string"
-                    },
-                    "type": "array"
+                "targetSentTimestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "targetSentTimestamp"
+            ],
+            "type": "object"
+        },
+        "receive.Error": {
+            "properties": {
+                "message": {
+                    "type": "string"
                 },
-                "readMessages": {
+                "type": {
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "receive.GroupInfo": {
+            "properties": {
+                "groupId": {
+                    "type": "string"
+                },
+                "groupName": {
+                    "type": "string"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "revision"
+            ],
+            "type": "object"
+        },
+        "receive.Internal": {
+            "properties": {
+                "capabilities": {
                     "items": {
-                        "$ref": "#/definitions/receive.SyncReadMessage"
+                        "type": "string"
                     },
                     "type": "array"
                 },
-                "sentMessage": {
-                    "$ref": "#/definitions/receive.SyncDataMessage"
+                "discoverableByPhonenumber": {
+                    "type": "boolean"
                 },
-                "sentStoryMessage": {
-                    "$ref": "#/definitions/receive.SyncStoryMessage"
+                "sharesPhoneNumber": {
+                    "type": "boolean"
                 },
-                "type": {
-                    "enum": [
-                        "CONTACTS_SYNC",
-                        "GROUPS_SYNC",
-                        "REQUEST_SYNC"
-                    ],
+                "unidentifiedAccessMode": {
                     "type": "string"
                 }
             },
             "type": "object"
         },
-        "receive.SyncReadMessage": {
+        "receive.Mention": {
             "properties": {
-                "sender": {
-                    "type": "string"
+                "length": {
+                    "type": "integer"
                 },
-                "senderNumber": {
+                "name": {
                     "type": "string"
                 },
-                "senderUuid": {
+                "number": {
                     "type": "string"
                 },
-                "timestamp": {
+                "start": {
                     "type": "integer"
+                },
+                "uuid": {
+                    "type": "string"
                 }
             },
             "required": [
-                "timestamp"
+                "start",
+                "length"
             ],
             "type": "object"
         },
-        "receive.SyncStoryMessage": {
+        "receive.MessageEnvelope": {
             "properties": {
-                "allowsReplies": {
-                    "type": "boolean"
+                "callMessage": {
+                    "$ref": "#/definitions/receive.CallMessage"
                 },
-                "destinationNumber": {
-                    "type": "string"
+                "dataMessage": {
+                    "$ref": "#/definitions/receive.DataMessage"
                 },
-                "destinationUuid": {
-                    "type": "string"
+                "editMessage": {
+                    "$ref": "#/definitions/receive.EditMessage"
                 },
-                "fileAttachment": {
-                    "$ref": "#/definitions/receive.Attachment"
+                "receiptMessage": {
+                    "$ref": "#/definitions/receive.ReceiptMessage"
                 },
-                "groupId": {
+                "serverDeliveredTimestamp": {
+                    "type": "integer"
+                },
+                "serverReceivedTimestamp": {
+                    "type": "integer"
+                },
+                "source": {
                     "type": "string"
                 },
-                "textAttachment": {
-                    "properties": {
-                        "backgroundColor": {
-                            "type": "string"
-                        },
-                        "backgroundGradient": {
-                            "properties": {
-                                "angle": {
-                                    "type": "integer"
-                                },
-                                "colors": {
-                                    "items": {
-                                        "type": "string"
-                                    },
-                                    "type": "array"
-                                },
-                                "endColor": {
-                                    "type": "string"
-                                },
-                                "positions": {
-                                    "items": {
-                                        "type": "number"
-                                    },
-                                    "type": "array"
-                                },
-                                "startColor": {
-                                    "type": "string"
-                                }
-                            },
-                            "type": "object"
-                        },
-                        "preview": {
-                            "$ref": "#/definitions/receive.Preview"
-                        },
-                        "style": {
-                            "type": "string"
-                        },
-                        "text": {
-                            "type": "string"
-                        },
-                        "textBackgroundColor": {
-                            "type": "string"
-                        },
-                        "textForegroundColor": {
-                            "type": "string"
-                        }
-                    },
-                    "type": "object"
+                "sourceDevice": {
+                    "type": "integer"
+                },
+                "sourceName": {
+                    "type": "string"
+                },
+                "sourceNumber": {
+                    "type": "string"
+                },
+                "sourceUuid": {
+                    "type": "string"
+                },
+                "storyMessage": {
+                    "$ref": "#/definitions/receive.StoryMessage"
+                },
+                "syncMessage": {
+                    "$ref": "#/definitions/receive.SyncMessage"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "typingMessage": {
+                    "$ref": "#/definitions/receive.TypingMessage"
                 }
             },
+            "required": [
+                "timestamp",
+                "serverReceivedTimestamp",
+                "serverDeliveredTimestamp"
+            ],
             "type": "object"
         },
-        "receive.TextStyle": {
+        "receive.Payment": {
             "properties": {
-                "length": {
+                "note": {
+                    "type": "string"
+                },
+                "receipt": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "receipt"
+            ],
+            "type": "object"
+        },
+        "receive.PinMessage": {
+            "properties": {
+                "pinDurationSeconds": {
                     "type": "integer"
                 },
-                "start": {
+                "targetAuthor": {
+                    "type": "string"
+                },
+                "targetAuthorNumber": {
+                    "type": "string"
+                },
+                "targetAuthorUuid": {
+                    "type": "string"
+                },
+                "targetSentTimestamp": {
                     "type": "integer"
+                }
+            },
+            "required": [
+                "targetSentTimestamp",
+                "pinDurationSeconds"
+            ],
+            "type": "object"
+        },
+        "receive.PollCreate": {
+            "properties": {
+                "allowMultiple": {
+                    "type": "boolean"
                 },
-                "style": {
+                "options": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "question": {
                     "type": "string"
                 }
             },
             "required": [
-                "start",
-                "length"
+                "allowMultiple"
             ],
             "type": "object"
         },
-        "receive.TypingMessage": {
+        "receive.PollTerminate": {
             "properties": {
-                "action": {
+                "targetSentTimestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "targetSentTimestamp"
+            ],
+            "type": "object"
+        },
+        "receive.PollVote": {
+            "properties": {
+                "author": {
                     "type": "string"
                 },
-                "groupId": {
+                "authorNumber": {
                     "type": "string"
                 },
-                "timestamp": {
+                "authorUuid": {
+                    "type": "string"
+                },
+                "optionIndexes": {
+                    "items": {
+                        "type": "integer"
+                    },
+                    "type": "array"
+                },
+                "targetSentTimestamp": {
+                    "type": "integer"
+                },
+                "voteCount": {
                     "type": "integer"
                 }
             },
             "required": [
-                "timestamp"
+                "targetSentTimestamp",
+                "voteCount"
             ],
             "type": "object"
         },
-        "receive.UnpinMessage": {
+        "receive.Preview": {
             "properties": {
-                "targetAuthor": {
+                "description": {
                     "type": "string"
                 },
-                "targetAuthorNumber": {
+                "image": {
+                    "$ref": "#/definitions/receive.Attachment"
+                },
+                "title": {
                     "type": "string"
                 },
-                "targetAuthorUuid": {
+                "url": {
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "receive.Profile": {
+            "properties": {
+                "about": {
+                    "type": "string"
+                },
+                "aboutEmoji": {
+                    "type": "string"
+                },
+                "familyName": {
+                    "type": "string"
+                },
+                "givenName": {
+                    "type": "string"
+                },
+                "hasAvatar": {
+                    "type": "boolean"
+                },
+                "lastUpdateTimestamp": {
+                    "type": "integer"
+                },
+                "mobileCoinAddress": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "lastUpdateTimestamp",
+                "hasAvatar"
+            ],
+            "type": "object"
+        },
+        "receive.Quote": {
+            "properties": {
+                "attachments": {
+                    "items": {
+                        "$ref": "#/definitions/receive.QuotedAttachment"
+                    },
+                    "type": "array"
+                },
+                "author": {
+                    "type": "string"
+                },
+                "authorNumber": {
+                    "type": "string"
+                },
+                "authorUuid": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mentions": {
+                    "items": {
+                        "$ref": "#/definitions/receive.Mention"
+                    },
+                    "type": "array"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "textStyles": {
+                    "items": {
+                        "$ref": "#/definitions/receive.TextStyle"
+                    },
+                    "type": "array"
+                }
+            },
+            "required": [
+                "id"
+            ],
+            "type": "object"
+        },
+        "receive.QuotedAttachment": {
+            "properties": {
+                "contentType": {
                     "type": "string"
                 },
-                "targetSentTimestamp": {
-                    "type": "integer"
-                }
-            },
-            "required": [
-                "targetSentTimestamp"
-            ],
-            "type": "object"
-        }
-    },
-    "host": "{{.Host}}",
-    "info": {
-        "contact": {},
-        "description": "{{escape .Description}}",
-        "title": "{{.Title}}",
-        "version": "{{.Version}}"
-    },
-    "paths": {
-        "/v1/about": {
+                "filename": {
+                    "type": "string"
+                },
+                "thumbnail": {
+                    "$ref": "#/definitions/receive.Attachment"
+                }
+            },
+            "type": "object"
+        },
+        "receive.Reaction": {
+            "properties": {
+                "emoji": {
+                    "type": "string"
+                },
+                "isRemove": {
+                    "type": "boolean"
+                },
+                "targetAuthor": {
+                    "type": "string"
+                },
+                "targetAuthorNumber": {
+                    "type": "string"
+                },
+                "targetAuthorUuid": {
+                    "type": "string"
+                },
+                "targetSentTimestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "targetSentTimestamp",
+                "isRemove"
+            ],
+            "type": "object"
+        },
+        "receive.ReceiptMessage": {
+            "properties": {
+                "isDelivery": {
+                    "type": "boolean"
+                },
+                "isRead": {
+                    "type": "boolean"
+                },
+                "isViewed": {
+                    "type": "boolean"
+                },
+                "timestamps": {
+                    "items": {
+                        "type": "integer"
+                    },
+                    "type": "array"
+                },
+                "when": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "when",
+                "isDelivery",
+                "isRead",
+                "isViewed"
+            ],
+            "type": "object"
+        },
+        "receive.RecipientAddress": {
+            "properties": {
+                "number": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "receive.RemoteDelete": {
+            "properties": {
+                "timestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "timestamp"
+            ],
+            "type": "object"
+        },
+        "receive.SendMessageResult": {
+            "properties": {
+                "groupId": {
+                    "type": "string"
+                },
+                "recipientAddress": {
+                    "$ref": "#/definitions/receive.RecipientAddress"
+                },
+                "retryAfterSeconds": {
+                    "type": "integer"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "type": {
+                    "enum": [
+                        "SUCCESS",
+                        "NETWORK_FAILURE",
+                        "UNREGISTERED_FAILURE",
+                        "IDENTITY_FAILURE",
+                        "RATE_LIMIT_FAILURE",
+                        "INVALID_PRE_KEY_FAILURE"
+                    ],
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "receive.SharedContact": {
+            "properties": {
+                "address": {
+                    "items": {
+                        "$ref": "#/definitions/receive.ContactAddress"
+                    },
+                    "type": "array"
+                },
+                "avatar": {
+                    "$ref": "#/definitions/receive.ContactAvatar"
+                },
+                "email": {
+                    "items": {
+                        "$ref": "#/definitions/receive.ContactEmail"
+                    },
+                    "type": "array"
+                },
+                "name": {
+                    "$ref": "#/definitions/receive.ContactName"
+                },
+                "organization": {
+                    "type": "string"
+                },
+                "phone": {
+                    "items": {
+                        "$ref": "#/definitions/receive.ContactPhone"
+                    },
+                    "type": "array"
+                }
+            },
+            "type": "object"
+        },
+        "receive.Sticker": {
+            "properties": {
+                "packId": {
+                    "type": "string"
+                },
+                "stickerId": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "stickerId"
+            ],
+            "type": "object"
+        },
+        "receive.StoryContext": {
+            "properties": {
+                "authorNumber": {
+                    "type": "string"
+                },
+                "authorUuid": {
+                    "type": "string"
+                },
+                "sentTimestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "sentTimestamp"
+            ],
+            "type": "object"
+        },
+        "receive.StoryMessage": {
+            "properties": {
+                "allowsReplies": {
+                    "type": "boolean"
+                },
+                "fileAttachment": {
+                    "$ref": "#/definitions/receive.Attachment"
+                },
+                "groupId": {
+                    "type": "string"
+                },
+                "textAttachment": {
+                    "properties": {
+                        "backgroundColor": {
+                            "type": "string"
+                        },
+                        "backgroundGradient": {
+                            "properties": {
+                                "angle": {
+                                    "type": "integer"
+                                },
+                                "colors": {
+                                    "items": {
+                                        "type": "string"
+                                    },
+                                    "type": "array"
+                                },
+                                "endColor": {
+                                    "type": "string"
+                                },
+                                "positions": {
+                                    "items": {
+                                        "type": "number"
+                                    },
+                                    "type": "array"
+                                },
+                                "startColor": {
+                                    "type": "string"
+                                }
+                            },
+                            "type": "object"
+                        },
+                        "preview": {
+                            "$ref": "#/definitions/receive.Preview"
+                        },
+                        "style": {
+                            "type": "string"
+                        },
+                        "text": {
+                            "type": "string"
+                        },
+                        "textBackgroundColor": {
+                            "type": "string"
+                        },
+                        "textForegroundColor": {
+                            "type": "string"
+                        }
+                    },
+                    "type": "object"
+                }
+            },
+            "required": [
+                "allowsReplies"
+            ],
+            "type": "object"
+        },
+        "receive.SyncDataMessage": {
+            "properties": {
+                "adminDelete": {
+                    "$ref": "#/definitions/receive.AdminDelete"
+                },
+                "attachments": {
+                    "items": {
+                        "$ref": "#/definitions/receive.Attachment"
+                    },
+                    "type": "array"
+                },
+                "contacts": {
+                    "items": {
+                        "$ref": "#/definitions/receive.SharedContact"
+                    },
+                    "type": "array"
+                },
+                "destination": {
+                    "type": "string"
+                },
+                "destinationNumber": {
+                    "type": "string"
+                },
+                "destinationUuid": {
+                    "type": "string"
+                },
+                "editMessage": {
+                    "$ref": "#/definitions/receive.EditMessage"
+                },
+                "expiresInSeconds": {
+                    "type": "integer"
+                },
+                "groupInfo": {
+                    "$ref": "#/definitions/receive.GroupInfo"
+                },
+                "isExpirationUpdate": {
+                    "type": "boolean"
+                },
+                "mentions": {
+                    "items": {
+                        "$ref": "#/definitions/receive.Mention"
+                    },
+                    "type": "array"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "payment": {
+                    "$ref": "#/definitions/receive.Payment"
+                },
+                "pinMessage": {
+                    "$ref": "#/definitions/receive.PinMessage"
+                },
+                "pollCreate": {
+                    "$ref": "#/definitions/receive.PollCreate"
+                },
+                "pollTerminate": {
+                    "$ref": "#/definitions/receive.PollTerminate"
+                },
+                "pollVote": {
+                    "$ref": "#/definitions/receive.PollVote"
+                },
+                "previews": {
+                    "items": {
+                        "$ref": "#/definitions/receive.Preview"
+                    },
+                    "type": "array"
+                },
+                "quote": {
+                    "$ref": "#/definitions/receive.Quote"
+                },
+                "reaction": {
+                    "$ref": "#/definitions/receive.Reaction"
+                },
+                "remoteDelete": {
+                    "$ref": "#/definitions/receive.RemoteDelete"
+                },
+                "sticker": {
+                    "$ref": "#/definitions/receive.Sticker"
+                },
+                "storyContext": {
+                    "$ref": "#/definitions/receive.StoryContext"
+                },
+                "textStyles": {
+                    "items": {
+                        "$ref": "#/definitions/receive.TextStyle"
+                    },
+                    "type": "array"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "unpinMessage": {
+                    "$ref": "#/definitions/receive.UnpinMessage"
+                },
+                "viewOnce": {
+                    "type": "boolean"
+                }
+            },
+            "type": "object"
+        },
+        "receive.SyncMessage": {
+            "properties": {
+                "blockedGroupIds": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "blockedNumbers": {
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "readMessages": {
+                    "items": {
+                        "$ref": "#/definitions/receive.SyncReadMessage"
+                    },
+                    "type": "array"
+                },
+                "sentMessage": {
+                    "$ref": "#/definitions/receive.SyncDataMessage"
+                },
+                "sentStoryMessage": {
+                    "$ref": "#/definitions/receive.SyncStoryMessage"
+                },
+                "type": {
+                    "enum": [
+                        "CONTACTS_SYNC",
+                        "GROUPS_SYNC",
+                        "REQUEST_SYNC"
+                    ],
+                    "type": "string"
+                }
+            },
+            "type": "object"
+        },
+        "receive.SyncReadMessage": {
+            "properties": {
+                "sender": {
+                    "type": "string"
+                },
+                "senderNumber": {
+                    "type": "string"
+                },
+                "senderUuid": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "timestamp"
+            ],
+            "type": "object"
+        },
+        "receive.SyncStoryMessage": {
+            "properties": {
+                "allowsReplies": {
+                    "type": "boolean"
+                },
+                "destinationNumber": {
+                    "type": "string"
+                },
+                "destinationUuid": {
+                    "type": "string"
+                },
+                "fileAttachment": {
+                    "$ref": "#/definitions/receive.Attachment"
+                },
+                "groupId": {
+                    "type": "string"
+                },
+                "textAttachment": {
+                    "properties": {
+                        "backgroundColor": {
+                            "type": "string"
+                        },
+                        "backgroundGradient": {
+                            "properties": {
+                                "angle": {
+                                    "type": "integer"
+                                },
+                                "colors": {
+                                    "items": {
+                                        "type": "string"
+                                    },
+                                    "type": "array"
+                                },
+                                "endColor": {
+                                    "type": "string"
+                                },
+                                "positions": {
+                                    "items": {
+                                        "type": "number"
+                                    },
+                                    "type": "array"
+                                },
+                                "startColor": {
+                                    "type": "string"
+                                }
+                            },
+                            "type": "object"
+                        },
+                        "preview": {
+                            "$ref": "#/definitions/receive.Preview"
+                        },
+                        "style": {
+                            "type": "string"
+                        },
+                        "text": {
+                            "type": "string"
+                        },
+                        "textBackgroundColor": {
+                            "type": "string"
+                        },
+                        "textForegroundColor": {
+                            "type": "string"
+                        }
+                    },
+                    "type": "object"
+                }
+            },
+            "type": "object"
+        },
+        "receive.TextStyle": {
+            "properties": {
+                "length": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "style": {
+                    "type": "string"
+                }
+            },
+            "required": [
+                "start",
+                "length"
+            ],
+            "type": "object"
+        },
+        "receive.TypingMessage": {
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "groupId": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "timestamp"
+            ],
+            "type": "object"
+        },
+        "receive.UnpinMessage": {
+            "properties": {
+                "targetAuthor": {
+                    "type": "string"
+                },
+                "targetAuthorNumber": {
+                    "type": "string"
+                },
+                "targetAuthorUuid": {
+                    "type": "string"
+                },
+                "targetSentTimestamp": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "targetSentTimestamp"
+            ],
+            "type": "object"
+        },
+        "utils.AutoReceiveScheduleSettings": {
+            "properties": {
+                "ignore_attachments": {
+                    "type": "boolean"
+                },
+                "ignore_avatars": {
+                    "type": "boolean"
+                },
+                "ignore_stickers": {
+                    "type": "boolean"
+                },
+                "ignore_stories": {
+                    "type": "boolean"
+                },
+                "receive_timeout": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "send_read_receipts": {
+                    "type": "boolean"
+                }
+            },
+            "required": [
+                "ignore_attachments",
+                "ignore_avatars",
+                "ignore_stickers",
+                "ignore_stories",
+                "receive_timeout",
+                "schedule",
+                "send_read_receipts"
+            ],
+            "type": "object"
+        },
+        "utils.PluginStatus": {
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "init_status": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_run": {
+                    "type": "string"
+                },
+                "last_run_duration_ms": {
+                    "type": "integer"
+                },
+                "last_run_successful": {
+                    "type": "boolean"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "next_run": {
+                    "type": "string"
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "trigger": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "active",
+                "init_status",
+                "name",
+                "version"
+            ],
+            "type": "object"
+        },
+        "utils.SettingValue": {
+            "properties": {
+                "source": {
+                    "enum": [
+                        "default",
+                        "file",
+                        "env",
+                        "api"
+                    ],
+                    "type": "string"
+                },
+                "value": {}
+            },
+            "required": [
+                "source",
+                "value"
+            ],
+            "type": "object"
+        }
+    },
+    "host": "{{.Host}}",
+    "info": {
+        "contact": {},
+        "description": "{{escape .Description}}",
+        "title": "{{.Title}}",
+        "version": "{{.Version}}"
+    },
+    "paths": {
+        "/v1/about": {
+            "get": {
+                "description": "Returns the supported API versions and the internal build nr",
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/client.About"
+                        }
+                    }
+                },
+                "summary": "Lists general information about the API",
+                "tags": [
+                    "General"
+                ]
+            }
+        },
+        "/v1/accounts": {
+            "get": {
+                "description": "Lists all of the accounts linked or registered. With 'expand=true' the details of every account (see '/v1/accounts/{number}') are returned instead of the numbers only.",
+                "parameters": [
+                    {
+                        "description": "Return the details of the accounts",
+                        "in": "query",
+                        "name": "expand",
+                        "type": "boolean"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "items": {
+                                "type": "string"
+                            },
+                            "type": "array"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "List all accounts",
+                "tags": [
+                    "Accounts"
+                ]
+            }
+        },
+        "/v1/accounts/import": {
+            "post": {
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "description": "Import an account from an archive that was created with '/v1/accounts/{number}/export'. The archive must not be larger than 256 MiB (1 GiB once extracted). signal-cli is paused while the account is imported - in json-rpc mode the signal-cli daemon is stopped, so requests that need signal-cli fail in the meantime, and loads the account when it is started again.",
+                "parameters": [
+                    {
+                        "description": "Archive",
+                        "in": "formData",
+                        "name": "archive",
+                        "required": true,
+                        "type": "file"
+                    },
+                    {
+                        "description": "Password of the archive",
+                        "in": "formData",
+                        "name": "password",
+                        "required": true,
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.ImportAccountResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Import an account.",
+                "tags": [
+                    "Accounts"
+                ]
+            }
+        },
+        "/v1/accounts/{number}": {
+            "get": {
+                "description": "Shows the details of an account: uuid, username, profile, device id, registration lock, discoverability settings, trust mode and the time the last message was received. The device id, registration lock and discoverability settings are read from the signal-cli data files and are omitted in case they can't be determined.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/client.AccountDetails"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Show account details.",
+                "tags": [
+                    "Accounts"
+                ]
+            }
+        },
+        "/v1/accounts/{number}/export": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Export the signal-cli data of the account together with its account specific settings as an archive, which is encrypted with the given password. The archive can be imported on another host with '/v1/accounts/import'. signal-cli is paused while the account is exported - in json-rpc mode the signal-cli daemon is stopped, so requests that need signal-cli fail in the meantime. Don't use the account on this host anymore after it was imported on another host.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ExportAccountRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Archive",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Export an account.",
+                "tags": [
+                    "Accounts"
+                ]
+            }
+        },
+        "/v1/accounts/{number}/pin": {
+            "delete": {
+                "description": "Removes a Signal Pin",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Remove Pin",
+                "tags": [
+                    "Accounts"
+                ]
+            },
+            "post": {
+                "description": "Sets a new Signal Pin",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.SetPinRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Set Pin",
+                "tags": [
+                    "Accounts"
+                ]
+            }
+        },
+        "/v1/accounts/{number}/rate-limit-challenge": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "When running into rate limits, sometimes the limit can be lifted, by solving a CAPTCHA. To get the captcha token, go to https://signalcaptchas.org/challenge/generate.html For the staging environment, use: https://signalcaptchas.org/staging/registration/generate.html. The \"challenge_token\" is the token from the failed send attempt. The \"captcha\" is the captcha result, starting with signalcaptcha://",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.RateLimitChallengeRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Lift rate limit restrictions by solving a captcha.",
+                "tags": [
+                    "Accounts"
+                ]
+            }
+        },
+        "/v1/accounts/{number}/settings": {
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Update the account attributes on the signal server.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.UpdateAccountSettingsRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Update the account settings.",
+                "tags": [
+                    "Accounts"
+                ]
+            }
+        },
+        "/v1/accounts/{number}/username": {
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Delete the username associated with this account.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Remove a username.",
+                "tags": [
+                    "Accounts"
+                ]
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Allows to set the username that should be used for this account. This can either be just the nickname (e.g. test) or the complete username with discriminator (e.g. test.123). Returns the new username with discriminator and the username link.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.SetUsernameRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/client.SetUsernameResponse"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Set a username.",
+                "tags": [
+                    "Accounts"
+                ]
+            }
+        },
+        "/v1/attachments": {
+            "get": {
+                "description": "List all downloaded attachments",
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "items": {
+                                "type": "string"
+                            },
+                            "type": "array"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "List all attachments.",
+                "tags": [
+                    "Attachments"
+                ]
+            }
+        },
+        "/v1/attachments/{attachment}": {
+            "delete": {
+                "description": "Remove the attachment with the given id from filesystem.",
+                "parameters": [
+                    {
+                        "description": "Attachment ID",
+                        "in": "path",
+                        "name": "attachment",
+                        "required": true,
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Remove attachment.",
+                "tags": [
+                    "Attachments"
+                ]
+            },
+            "get": {
+                "description": "Serve the attachment with the given id",
+                "parameters": [
+                    {
+                        "description": "Attachment ID",
+                        "in": "path",
+                        "name": "attachment",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Expiration timestamp of a signed URL",
+                        "in": "query",
+                        "name": "expires",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Signature of a signed URL",
+                        "in": "query",
+                        "name": "signature",
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Serve Attachment.",
+                "tags": [
+                    "Attachments"
+                ]
+            }
+        },
+        "/v1/configuration": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "List the effective REST API configuration. For every setting, the source of the value is returned: ` + "`" + `default` + "`" + `, ` + "`" + `file` + "`" + ` (config file), ` + "`" + `env` + "`" + ` (environment variable) or ` + "`" + `api` + "`" + ` (changed via the REST API).",
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ConfigurationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "List the REST API configuration.",
+                "tags": [
+                    "General"
+                ]
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Change the REST API configuration at runtime: the log level, the webhook URL for received messages (json-rpc mode only), the default text mode, the auto receive schedule (not supported in json-rpc mode), the attachment retention and the trust modes of accounts (not supported in json-rpc mode). Only the specified settings are changed. The settings listed in ` + "`" + `reset` + "`" + ` are reset to the value from the config file or the environment. The changes are persisted and applied at once - in case one of the settings is invalid, nothing is changed.",
+                "parameters": [
+                    {
+                        "description": "Configuration",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.Configuration"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Set the REST API configuration.",
+                "tags": [
+                    "General"
+                ]
+            }
+        },
+        "/v1/configuration/{number}/settings": {
             "get": {
-                "description": "Returns the supported API versions and the internal build nr",
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "List account specific settings.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
@@ -2377,19 +4454,124 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/client.About"
+                            "$ref": "#/definitions/api.AccountSettingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
                         }
                     }
                 },
-                "summary": "Lists general information about the API",
+                "summary": "List account specific settings.",
+                "tags": [
+                    "General"
+                ]
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Set account specific settings.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.TrustModeRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Set account specific settings.",
+                "tags": [
+                    "General"
+                ]
+            },
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Replace the account specific settings. The settings are used as defaults whenever a request doesn't specify the value explicitly: the text mode, notify_self and the signature (which is appended to every message) are applied when sending messages, expiration_in_seconds (the disappearing messages timer) is applied to groups that are created via the REST API, send_read_receipts is applied when receiving messages and receive_webhook_url replaces the global receive webhook URL for the messages of the account (json-rpc mode only). Settings that are not set fall back to the global settings. The trust mode is only changed in case it is set.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.AccountSettingsRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Update account specific settings.",
                 "tags": [
                     "General"
                 ]
             }
         },
-        "/v1/accounts": {
+        "/v1/contacts/{number}": {
             "get": {
-                "description": "Lists all of the accounts linked or registered",
+                "description": "List all contacts for the given number.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Include all known recipients, not only contacts.",
+                        "in": "query",
+                        "name": "all_recipients",
+                        "type": "string"
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
@@ -2398,10 +4580,46 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "items": {
-                                "type": "string"
+                                "$ref": "#/definitions/client.ListContactsResponse"
                             },
                             "type": "array"
                         }
+                    }
+                },
+                "summary": "List Contacts",
+                "tags": [
+                    "Contacts"
+                ]
+            },
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Updates the info associated to a number on the contact list.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Contact",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.UpdateContactRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -2410,15 +4628,60 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "List all accounts",
+                "summary": "Updates the info associated to a number on the contact list. If the contact doesn’t exist yet, it will be added.",
                 "tags": [
-                    "Accounts"
+                    "Contacts"
                 ]
             }
         },
-        "/v1/accounts/{number}/pin": {
-            "delete": {
-                "description": "Removes a Signal Pin",
+        "/v1/contacts/{number}/export": {
+            "get": {
+                "description": "Exports the contacts of the given number as CSV (with the columns 'name', 'nickname', 'note', 'number' and 'username') or vCard file. The vCards of contacts without name contain a placeholder name and are marked with 'X-SIGNAL-UNNAMED:TRUE'.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Format of the file (csv, vcard3 or vcard4). Default: csv",
+                        "in": "query",
+                        "name": "format",
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "text/csv",
+                    "text/vcard"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV or vCard file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Export contacts.",
+                "tags": [
+                    "Contacts"
+                ]
+            }
+        },
+        "/v1/contacts/{number}/import": {
+            "post": {
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "description": "Adds or updates the contacts of a CSV or vCard (3.0 and 4.0) file. The CSV file needs a header row with (some of) the columns 'name', 'nickname', 'note', 'number' and 'username'. Of a vCard, the properties FN (or N), NICKNAME, NOTE, TEL and X-SIGNAL-USERNAME are used. Every row (or card) is imported on its own, the result contains the outcome of every row. The file must not be larger than 5 MiB. In normal mode, every row starts signal-cli once, so at most 100 rows can be imported at once (use the json-rpc mode for larger imports). Optionally, the contacts are sent to the linked devices afterwards.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2426,29 +4689,62 @@ const docTemplate = `{
                         "name": "number",
                         "required": true,
                         "type": "string"
+                    },
+                    {
+                        "description": "CSV or vCard file",
+                        "in": "formData",
+                        "name": "file",
+                        "required": true,
+                        "type": "file"
+                    },
+                    {
+                        "description": "Format of the file (csv or vcard). Detected automatically, if not provided.",
+                        "in": "formData",
+                        "name": "format",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Send the contacts to the linked devices afterwards (default: false)",
+                        "in": "formData",
+                        "name": "send_contacts",
+                        "type": "boolean"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/client.ContactImportResult"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
-                "summary": "Remove Pin",
+                "summary": "Import contacts.",
                 "tags": [
-                    "Accounts"
+                    "Contacts"
                 ]
-            },
+            }
+        },
+        "/v1/contacts/{number}/sync": {
             "post": {
-                "description": "Sets a new Signal Pin",
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Send a synchronization message with the local contacts list to all linked devices. This command should only be used if this is the primary device.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2456,23 +4752,14 @@ const docTemplate = `{
                         "name": "number",
                         "required": true,
                         "type": "string"
-                    },
-                    {
-                        "description": "Request",
-                        "in": "body",
-                        "name": "data",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.SetPinRequest"
-                        }
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created"
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -2481,18 +4768,15 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Set Pin",
+                "summary": "Send a synchronization message with the local contacts list to all linked devices.",
                 "tags": [
-                    "Accounts"
+                    "Contacts"
                 ]
             }
         },
-        "/v1/accounts/{number}/rate-limit-challenge": {
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
-                "description": "When running into rate limits, sometimes the limit can be lifted, by solving a CAPTCHA. To get the captcha token, go to https://signalcaptchas.org/challenge/generate.html For the staging environment, use: https://signalcaptchas.org/staging/registration/generate.html. The \"challenge_token\" is the token from the failed send attempt. The \"captcha\" is the captcha result, starting with signalcaptcha://",
+        "/v1/contacts/{number}/{uuid}": {
+            "get": {
+                "description": "List a specific contact.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2502,41 +4786,32 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Request",
-                        "in": "body",
-                        "name": "data",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.RateLimitChallengeRequest"
-                        }
+                        "description": "Include all known recipients, not only contacts.",
+                        "in": "query",
+                        "name": "all_recipients",
+                        "type": "string"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Error"
+                            "$ref": "#/definitions/client.ListContactsResponse"
                         }
                     }
                 },
-                "summary": "Lift rate limit restrictions by solving a captcha.",
+                "summary": "List Contact",
                 "tags": [
-                    "Accounts"
+                    "Contacts"
                 ]
             }
         },
-        "/v1/accounts/{number}/settings": {
-            "put": {
-                "consumes": [
-                    "application/json"
-                ],
-                "description": "Update the account attributes on the signal server.",
+        "/v1/contacts/{number}/{uuid}/avatar": {
+            "get": {
+                "description": "Returns the avatar of a contact.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2546,41 +4821,48 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Request",
-                        "in": "body",
-                        "name": "data",
+                        "description": "Contact UUID",
+                        "in": "path",
+                        "name": "uuid",
                         "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.UpdateAccountSettingsRequest"
-                        }
+                        "type": "string"
+                    },
+                    {
+                        "description": "Expiration timestamp of a signed URL",
+                        "in": "query",
+                        "name": "expires",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Signature of a signed URL",
+                        "in": "query",
+                        "name": "signature",
+                        "type": "string"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "Image",
                         "schema": {
-                            "$ref": "#/definitions/api.Error"
+                            "type": "string"
                         }
                     }
                 },
-                "summary": "Update the account settings.",
+                "summary": "Returns the avatar of a contact",
                 "tags": [
-                    "Accounts"
+                    "Contacts"
                 ]
             }
         },
-        "/v1/accounts/{number}/username": {
-            "delete": {
+        "/v1/devices/{number}": {
+            "get": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Delete the username associated with this account.",
+                "description": "List linked devices associated to this device.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2594,8 +4876,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "items": {
+                                "$ref": "#/definitions/client.ListDevicesResponse"
+                            },
+                            "type": "array"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -2604,16 +4892,16 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Remove a username.",
+                "summary": "List linked devices.",
                 "tags": [
-                    "Accounts"
+                    "Devices"
                 ]
             },
             "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Allows to set the username that should be used for this account. This can either be just the nickname (e.g. test) or the complete username with discriminator (e.g. test.123). Returns the new username with discriminator and the username link.",
+                "description": "Links another device to this device. Only works, if this is the master device.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2628,7 +4916,7 @@ const docTemplate = `{
                         "name": "data",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.SetUsernameRequest"
+                            "$ref": "#/definitions/api.AddDeviceRequest"
                         }
                     }
                 ],
@@ -2636,12 +4924,6 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/client.SetUsernameResponse"
-                        }
-                    },
                     "204": {
                         "description": "No Content"
                     },
@@ -2652,51 +4934,33 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Set a username.",
+                "summary": "Links another device to this device.",
                 "tags": [
-                    "Accounts"
+                    "Devices"
                 ]
             }
         },
-        "/v1/attachments": {
-            "get": {
-                "description": "List all downloaded attachments",
-                "produces": [
+        "/v1/devices/{number}/local-data": {
+            "delete": {
+                "consumes": [
                     "application/json"
                 ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "items": {
-                                "type": "string"
-                            },
-                            "type": "array"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.Error"
-                        }
-                    }
-                },
-                "summary": "List all attachments.",
-                "tags": [
-                    "Attachments"
-                ]
-            }
-        },
-        "/v1/attachments/{attachment}": {
-            "delete": {
-                "description": "Remove the attachment with the given id from filesystem.",
+                "description": "Delete all local data for the specified account. Only use this after unregistering the account or after removing a linked device.",
                 "parameters": [
                     {
-                        "description": "Attachment ID",
+                        "description": "Registered Phone Number",
                         "in": "path",
-                        "name": "attachment",
+                        "name": "number",
                         "required": true,
                         "type": "string"
+                    },
+                    {
+                        "description": "Cleanup options",
+                        "in": "body",
+                        "name": "data",
+                        "schema": {
+                            "$ref": "#/definitions/api.DeleteLocalAccountDataRequest"
+                        }
                     }
                 ],
                 "produces": [
@@ -2704,10 +4968,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "type": "string"
-                        }
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -2716,31 +4977,34 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Remove attachment.",
+                "summary": "Delete local account data",
                 "tags": [
-                    "Attachments"
+                    "Devices"
                 ]
-            },
-            "get": {
-                "description": "Serve the attachment with the given id",
+            }
+        },
+        "/v1/devices/{number}/{deviceId}": {
+            "delete": {
+                "description": "Remove a linked device from the primary account.",
                 "parameters": [
                     {
-                        "description": "Attachment ID",
+                        "description": "Registered Phone Number",
                         "in": "path",
-                        "name": "attachment",
+                        "name": "number",
                         "required": true,
                         "type": "string"
+                    },
+                    {
+                        "description": "Device ID from listDevices",
+                        "in": "path",
+                        "name": "deviceId",
+                        "required": true,
+                        "type": "integer"
                     }
                 ],
-                "produces": [
-                    "application/json"
-                ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "string"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -2749,18 +5013,33 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Serve Attachment.",
+                "summary": "Remove linked device",
                 "tags": [
-                    "Attachments"
+                    "Devices"
                 ]
             }
         },
-        "/v1/configuration": {
+        "/v1/groups/{number}": {
             "get": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "List the REST API configuration.",
+                "description": "List all Signal Groups.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Expand the response to show more details (default: false)",
+                        "in": "query",
+                        "name": "expand",
+                        "type": "boolean"
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
@@ -2768,7 +5047,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Configuration"
+                            "items": {
+                                "$ref": "#/definitions/client.GroupEntry"
+                            },
+                            "type": "array"
                         }
                     },
                     "400": {
@@ -2778,35 +5060,42 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "List the REST API configuration.",
+                "summary": "List all Signal Groups.",
                 "tags": [
-                    "General"
+                    "Groups"
                 ]
             },
             "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Set the REST API configuration.",
+                "description": "Create a new Signal Group with the specified members.",
                 "parameters": [
                     {
-                        "description": "Configuration",
+                        "description": "Input Data",
                         "in": "body",
                         "name": "data",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.Configuration"
+                            "$ref": "#/definitions/api.CreateGroupRequest"
                         }
+                    },
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.CreateGroupResponse"
                         }
                     },
                     "400": {
@@ -2816,18 +5105,18 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Set the REST API configuration.",
+                "summary": "Create a new Signal Group.",
                 "tags": [
-                    "General"
+                    "Groups"
                 ]
             }
         },
-        "/v1/configuration/{number}/settings": {
-            "get": {
+        "/v1/groups/{number}/sync": {
+            "put": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "List account specific settings.",
+                "description": "Creates and updates the listed groups, adds and removes members and admins and changes the permissions, so that the groups match the desired state. Only the necessary changes are applied. Groups are identified by their id or - if no id is provided - by their name. Fields that are not provided are left as they are (e.g. if 'members' is missing, no members are added or removed). The account itself is never removed. As members might only be known by their uuid, phone numbers are resolved to uuids first - in case a phone number can't be resolved, no members or admins are removed from that group and the error of the group's result says so. With 'dry_run' the planned changes are only returned.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2837,12 +5126,12 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Request",
+                        "description": "Desired Groups",
                         "in": "body",
                         "name": "data",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.TrustModeResponse"
+                            "$ref": "#/definitions/api.SyncGroupsRequest"
                         }
                     }
                 ],
@@ -2851,7 +5140,13 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "items": {
+                                "$ref": "#/definitions/client.GroupSyncResult"
+                            },
+                            "type": "array"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -2860,16 +5155,18 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "List account specific settings.",
+                "summary": "Bring the Signal Groups into the desired state.",
                 "tags": [
-                    "General"
+                    "Groups"
                 ]
-            },
-            "post": {
+            }
+        },
+        "/v1/groups/{number}/{groupid}": {
+            "delete": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Set account specific settings.",
+                "description": "Delete the specified Signal Group.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2879,21 +5176,22 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Request",
-                        "in": "body",
-                        "name": "data",
+                        "description": "Group Id",
+                        "in": "path",
+                        "name": "groupid",
                         "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.TrustModeRequest"
-                        }
+                        "type": "string"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -2902,15 +5200,16 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Set account specific settings.",
+                "summary": "Delete a Signal Group.",
                 "tags": [
-                    "General"
+                    "Groups"
                 ]
-            }
-        },
-        "/v1/contacts/{number}": {
+            },
             "get": {
-                "description": "List all contacts for the given number.",
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "List a specific Signal Group.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2920,10 +5219,17 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Include all known recipients, not only contacts.",
-                        "in": "query",
-                        "name": "all_recipients",
+                        "description": "Group ID",
+                        "in": "path",
+                        "name": "groupid",
+                        "required": true,
                         "type": "string"
+                    },
+                    {
+                        "description": "Expand the response to show more details (default: false)",
+                        "in": "query",
+                        "name": "expand",
+                        "type": "boolean"
                     }
                 ],
                 "produces": [
@@ -2933,23 +5239,26 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "items": {
-                                "$ref": "#/definitions/client.ListContactsResponse"
-                            },
-                            "type": "array"
+                            "$ref": "#/definitions/client.GroupEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
                         }
                     }
                 },
-                "summary": "List Contacts",
+                "summary": "List a Signal Group.",
                 "tags": [
-                    "Contacts"
+                    "Groups"
                 ]
             },
             "put": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Updates the info associated to a number on the contact list.",
+                "description": "Update the state of a Signal Group.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -2959,12 +5268,19 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Contact",
+                        "description": "Group ID",
+                        "in": "path",
+                        "name": "groupid",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Input Data",
                         "in": "body",
                         "name": "data",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.UpdateContactRequest"
+                            "$ref": "#/definitions/api.UpdateGroupRequest"
                         }
                     }
                 ],
@@ -2973,7 +5289,10 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "204": {
-                        "description": "No Content"
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -2982,25 +5301,41 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Updates the info associated to a number on the contact list. If the contact doesn’t exist yet, it will be added.",
+                "summary": "Update the state of a Signal Group.",
                 "tags": [
-                    "Contacts"
+                    "Groups"
                 ]
             }
         },
-        "/v1/contacts/{number}/sync": {
-            "post": {
+        "/v1/groups/{number}/{groupid}/admins": {
+            "delete": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Send a synchronization message with the local contacts list to all linked devices. This command should only be used if this is the primary device.",
+                "description": "Remove one or more admins from an existing Signal Group.",
                 "parameters": [
+                    {
+                        "description": "Admins",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ChangeGroupAdminsRequest"
+                        }
+                    },
                     {
                         "description": "Registered Phone Number",
                         "in": "path",
                         "name": "number",
                         "required": true,
                         "type": "string"
+                    },
+                    {
+                        "description": "Group ID",
+                        "in": "path",
+                        "name": "groupid",
+                        "required": true,
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -3008,7 +5343,10 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "204": {
-                        "description": "No Content"
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -3017,16 +5355,26 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Send a synchronization message with the local contacts list to all linked devices.",
+                "summary": "Remove one or more admins from an existing Signal Group.",
                 "tags": [
-                    "Contacts"
+                    "Groups"
                 ]
-            }
-        },
-        "/v1/contacts/{number}/{uuid}": {
-            "get": {
-                "description": "List a specific contact.",
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Add one or more admins to an existing Signal Group.",
                 "parameters": [
+                    {
+                        "description": "Admins",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ChangeGroupAdminsRequest"
+                        }
+                    },
                     {
                         "description": "Registered Phone Number",
                         "in": "path",
@@ -3035,9 +5383,10 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Include all known recipients, not only contacts.",
-                        "in": "query",
-                        "name": "all_recipients",
+                        "description": "Group ID",
+                        "in": "path",
+                        "name": "groupid",
+                        "required": true,
                         "type": "string"
                     }
                 ],
@@ -3045,22 +5394,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "$ref": "#/definitions/client.ListContactsResponse"
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
                         }
                     }
                 },
-                "summary": "List Contact",
+                "summary": "Add one or more admins to an existing Signal Group.",
                 "tags": [
-                    "Contacts"
+                    "Groups"
                 ]
             }
         },
-        "/v1/contacts/{number}/{uuid}/avatar": {
+        "/v1/groups/{number}/{groupid}/audit-log": {
             "get": {
-                "description": "Returns the avatar of a contact.",
+                "description": "Show the changes of a Signal Group (members, admins, invites, join requests, name, description, permissions, disappearing messages timer and invite link), oldest first. The changes are detected by comparing the group with the previously known state of the group, whenever a group update is received or a group is changed via the REST API. 'changed_by' is only set if it is known who changed the group.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -3068,38 +5423,19 @@ const docTemplate = `{
                         "name": "number",
                         "required": true,
                         "type": "string"
-                    }
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Image",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                },
-                "summary": "Returns the avatar of a contact",
-                "tags": [
-                    "Contacts"
-                ]
-            }
-        },
-        "/v1/devices/{number}": {
-            "get": {
-                "consumes": [
-                    "application/json"
-                ],
-                "description": "List linked devices associated to this device.",
-                "parameters": [
+                    },
                     {
-                        "description": "Registered Phone Number",
+                        "description": "Group ID",
                         "in": "path",
-                        "name": "number",
+                        "name": "groupid",
                         "required": true,
                         "type": "string"
+                    },
+                    {
+                        "description": "Only return the most recent changes",
+                        "in": "query",
+                        "name": "limit",
+                        "type": "integer"
                     }
                 ],
                 "produces": [
@@ -3110,7 +5446,7 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "items": {
-                                "$ref": "#/definitions/client.ListDevicesResponse"
+                                "$ref": "#/definitions/data.GroupChange"
                             },
                             "type": "array"
                         }
@@ -3122,16 +5458,18 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "List linked devices.",
+                "summary": "Show the audit log of a Signal Group.",
                 "tags": [
-                    "Devices"
+                    "Groups"
                 ]
-            },
-            "post": {
+            }
+        },
+        "/v1/groups/{number}/{groupid}/avatar": {
+            "get": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Links another device to this device. Only works, if this is the master device.",
+                "description": "Returns the avatar of a Signal Group.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -3141,21 +5479,34 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Request",
-                        "in": "body",
-                        "name": "data",
+                        "description": "Group ID",
+                        "in": "path",
+                        "name": "groupid",
                         "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.AddDeviceRequest"
-                        }
+                        "type": "string"
+                    },
+                    {
+                        "description": "Expiration timestamp of a signed URL",
+                        "in": "query",
+                        "name": "expires",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Signature of a signed URL",
+                        "in": "query",
+                        "name": "signature",
+                        "type": "string"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "Image",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -3164,18 +5515,18 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Links another device to this device.",
+                "summary": "Returns the avatar of a Signal Group.",
                 "tags": [
-                    "Devices"
+                    "Groups"
                 ]
             }
         },
-        "/v1/devices/{number}/local-data": {
-            "delete": {
+        "/v1/groups/{number}/{groupid}/block": {
+            "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Delete all local data for the specified account. Only use this after unregistering the account or after removing a linked device.",
+                "description": "Block the specified Signal Group.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -3185,12 +5536,11 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Cleanup options",
-                        "in": "body",
-                        "name": "data",
-                        "schema": {
-                            "$ref": "#/definitions/api.DeleteLocalAccountDataRequest"
-                        }
+                        "description": "Group ID",
+                        "in": "path",
+                        "name": "groupid",
+                        "required": true,
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -3198,7 +5548,10 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "204": {
-                        "description": "No Content"
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -3207,16 +5560,28 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Delete local account data",
+                "summary": "Block a Signal Group.",
                 "tags": [
-                    "Devices"
+                    "Groups"
                 ]
             }
         },
-        "/v1/devices/{number}/{deviceId}": {
-            "delete": {
-                "description": "Remove a linked device from the primary account.",
+        "/v1/groups/{number}/{groupid}/invite-link": {
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Enable or disable the group invite link, switch whether new members need to be approved by an admin ('enabled-with-approval') and/or reset the invite link, so that the previous link can't be used anymore.",
                 "parameters": [
+                    {
+                        "description": "Invite Link",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.UpdateGroupInviteLinkRequest"
+                        }
+                    },
                     {
                         "description": "Registered Phone Number",
                         "in": "path",
@@ -3225,37 +5590,58 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Device ID from listDevices",
+                        "description": "Group ID",
                         "in": "path",
-                        "name": "deviceId",
+                        "name": "groupid",
                         "required": true,
-                        "type": "integer"
+                        "type": "string"
                     }
                 ],
+                "produces": [
+                    "application/json"
+                ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.GroupInviteLinkResponse"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
-                "summary": "Remove linked device",
+                "summary": "Update the group invite link.",
                 "tags": [
-                    "Devices"
+                    "Groups"
                 ]
             }
         },
-        "/v1/groups/{number}": {
-            "get": {
+        "/v1/groups/{number}/{groupid}/invites": {
+            "delete": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "List all Signal Groups.",
+                "description": "Revoke the invites of members that were added to the group, but didn't accept the invite yet.",
                 "parameters": [
+                    {
+                        "description": "Members",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ChangeGroupMembersRequest"
+                        }
+                    },
                     {
                         "description": "Registered Phone Number",
                         "in": "path",
@@ -3264,23 +5650,21 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Expand the response to show more details (default: false)",
-                        "in": "query",
-                        "name": "expand",
-                        "type": "boolean"
+                        "description": "Group ID",
+                        "in": "path",
+                        "name": "groupid",
+                        "required": true,
+                        "type": "string"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "items": {
-                                "$ref": "#/definitions/client.GroupEntry"
-                            },
-                            "type": "array"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -3288,32 +5672,38 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
-                "summary": "List all Signal Groups.",
+                "summary": "Revoke pending invites.",
                 "tags": [
                     "Groups"
                 ]
-            },
+            }
+        },
+        "/v1/groups/{number}/{groupid}/join": {
             "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Create a new Signal Group with the specified members.",
+                "description": "Join the specified Signal Group.",
                 "parameters": [
                     {
-                        "description": "Input Data",
-                        "in": "body",
-                        "name": "data",
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
                         "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.CreateGroupRequest"
-                        }
+                        "type": "string"
                     },
                     {
-                        "description": "Registered Phone Number",
+                        "description": "Group ID",
                         "in": "path",
-                        "name": "number",
+                        "name": "groupid",
                         "required": true,
                         "type": "string"
                     }
@@ -3322,10 +5712,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "$ref": "#/definitions/api.CreateGroupResponse"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -3335,19 +5725,28 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Create a new Signal Group.",
+                "summary": "Join a Signal Group.",
                 "tags": [
                     "Groups"
                 ]
             }
         },
-        "/v1/groups/{number}/{groupid}": {
-            "delete": {
+        "/v1/groups/{number}/{groupid}/join-requests/approve": {
+            "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Delete the specified Signal Group.",
+                "description": "Approve the requests of members that want to join the group via the group invite link.",
                 "parameters": [
+                    {
+                        "description": "Members",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ChangeGroupMembersRequest"
+                        }
+                    },
                     {
                         "description": "Registered Phone Number",
                         "in": "path",
@@ -3356,7 +5755,7 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Group Id",
+                        "description": "Group ID",
                         "in": "path",
                         "name": "groupid",
                         "required": true,
@@ -3367,8 +5766,8 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
                             "type": "string"
                         }
@@ -3378,19 +5777,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
-                "summary": "Delete a Signal Group.",
+                "summary": "Approve pending join requests.",
                 "tags": [
                     "Groups"
                 ]
-            },
-            "get": {
+            }
+        },
+        "/v1/groups/{number}/{groupid}/join-requests/deny": {
+            "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "List a specific Signal Group.",
+                "description": "Deny the requests of members that want to join the group via the group invite link. Banned members can't request to join the group again.",
                 "parameters": [
+                    {
+                        "description": "Members",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.DenyGroupJoinRequestsRequest"
+                        }
+                    },
                     {
                         "description": "Registered Phone Number",
                         "in": "path",
@@ -3404,22 +5820,16 @@ const docTemplate = `{
                         "name": "groupid",
                         "required": true,
                         "type": "string"
-                    },
-                    {
-                        "description": "Expand the response to show more details (default: false)",
-                        "in": "query",
-                        "name": "expand",
-                        "type": "boolean"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "$ref": "#/definitions/client.GroupEntry"
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -3427,19 +5837,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
-                "summary": "List a Signal Group.",
+                "summary": "Deny pending join requests.",
                 "tags": [
                     "Groups"
                 ]
-            },
-            "put": {
+            }
+        },
+        "/v1/groups/{number}/{groupid}/members": {
+            "delete": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Update the state of a Signal Group.",
+                "description": "Remove one or more members from an existing Signal Group.",
                 "parameters": [
+                    {
+                        "description": "Members",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ChangeGroupMembersRequest"
+                        }
+                    },
                     {
                         "description": "Registered Phone Number",
                         "in": "path",
@@ -3453,15 +5880,6 @@ const docTemplate = `{
                         "name": "groupid",
                         "required": true,
                         "type": "string"
-                    },
-                    {
-                        "description": "Input Data",
-                        "in": "body",
-                        "name": "data",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.UpdateGroupRequest"
-                        }
                     }
                 ],
                 "produces": [
@@ -3481,26 +5899,24 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Update the state of a Signal Group.",
+                "summary": "Remove one or more members from an existing Signal Group.",
                 "tags": [
                     "Groups"
                 ]
-            }
-        },
-        "/v1/groups/{number}/{groupid}/admins": {
-            "delete": {
+            },
+            "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Remove one or more admins from an existing Signal Group.",
+                "description": "Add one or more members to an existing Signal Group.",
                 "parameters": [
                     {
-                        "description": "Admins",
+                        "description": "Members",
                         "in": "body",
                         "name": "data",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.ChangeGroupAdminsRequest"
+                            "$ref": "#/definitions/api.ChangeGroupMembersRequest"
                         }
                     },
                     {
@@ -3535,24 +5951,26 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Remove one or more admins from an existing Signal Group.",
+                "summary": "Add one or more members to an existing Signal Group.",
                 "tags": [
                     "Groups"
                 ]
-            },
-            "post": {
+            }
+        },
+        "/v1/groups/{number}/{groupid}/pin-message": {
+            "delete": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Add one or more admins to an existing Signal Group.",
+                "description": "Unpin a message in a Signal Group.",
                 "parameters": [
                     {
-                        "description": "Admins",
+                        "description": "Unpin",
                         "in": "body",
                         "name": "data",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.ChangeGroupAdminsRequest"
+                            "$ref": "#/definitions/api.UnpinMessageInGroupRequest"
                         }
                     },
                     {
@@ -3563,7 +5981,7 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Group ID",
+                        "description": "Group Id",
                         "in": "path",
                         "name": "groupid",
                         "required": true,
@@ -3574,8 +5992,8 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": "string"
                         }
@@ -3587,19 +6005,26 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Add one or more admins to an existing Signal Group.",
+                "summary": "Unpin a message in a Signal Group.",
                 "tags": [
                     "Groups"
                 ]
-            }
-        },
-        "/v1/groups/{number}/{groupid}/avatar": {
-            "get": {
+            },
+            "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Returns the avatar of a Signal Group.",
+                "description": "Pin a message in a Signal Group.",
                 "parameters": [
+                    {
+                        "description": "Pin",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.PinMessageInGroupRequest"
+                        }
+                    },
                     {
                         "description": "Registered Phone Number",
                         "in": "path",
@@ -3608,7 +6033,7 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Group ID",
+                        "description": "Group Id",
                         "in": "path",
                         "name": "groupid",
                         "required": true,
@@ -3620,7 +6045,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Image",
+                        "description": "OK",
                         "schema": {
                             "type": "string"
                         }
@@ -3632,18 +6057,18 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Returns the avatar of a Signal Group.",
+                "summary": "Pin a message in a Signal Group.",
                 "tags": [
                     "Groups"
                 ]
             }
         },
-        "/v1/groups/{number}/{groupid}/block": {
+        "/v1/groups/{number}/{groupid}/quit": {
             "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Block the specified Signal Group.",
+                "description": "Quit the specified Signal Group.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -3677,18 +6102,35 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Block a Signal Group.",
+                "summary": "Quit a Signal Group.",
                 "tags": [
                     "Groups"
                 ]
             }
         },
-        "/v1/groups/{number}/{groupid}/join": {
-            "post": {
-                "consumes": [
+        "/v1/health": {
+            "get": {
+                "description": "Internally used by the docker container to perform the health check.",
+                "produces": [
                     "application/json"
                 ],
-                "description": "Join the specified Signal Group.",
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "summary": "API Health Check",
+                "tags": [
+                    "General"
+                ]
+            }
+        },
+        "/v1/identities/{number}": {
+            "get": {
+                "description": "List all identities for the given number.",
                 "parameters": [
                     {
                         "description": "Registered Phone Number",
@@ -3696,52 +6138,39 @@ const docTemplate = `{
                         "name": "number",
                         "required": true,
                         "type": "string"
-                    },
-                    {
-                        "description": "Group ID",
-                        "in": "path",
-                        "name": "groupid",
-                        "required": true,
-                        "type": "string"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Error"
+                            "items": {
+                                "$ref": "#/definitions/client.IdentityEntry"
+                            },
+                            "type": "array"
                         }
                     }
                 },
-                "summary": "Join a Signal Group.",
+                "summary": "List Identities",
                 "tags": [
-                    "Groups"
+                    "Identities"
                 ]
             }
         },
-        "/v1/groups/{number}/{groupid}/members": {
-            "delete": {
-                "consumes": [
-                    "application/json"
-                ],
-                "description": "Remove one or more members from an existing Signal Group.",
+        "/v1/identities/{number}/trust/{numberToTrust}": {
+            "put": {
+                "description": "Trust an identity. When 'trust_all_known_keys' is set to' true', all known keys of this user are trusted. **This is only recommended for testing.**",
                 "parameters": [
                     {
-                        "description": "Members",
+                        "description": "Input Data",
                         "in": "body",
                         "name": "data",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.ChangeGroupMembersRequest"
+                            "$ref": "#/definitions/api.TrustIdentityRequest"
                         }
                     },
                     {
@@ -3752,9 +6181,9 @@ const docTemplate = `{
                         "type": "string"
                     },
                     {
-                        "description": "Group ID",
+                        "description": "Number To Trust",
                         "in": "path",
-                        "name": "groupid",
+                        "name": "numberToTrust",
                         "required": true,
                         "type": "string"
                     }
@@ -3768,57 +6197,39 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.Error"
-                        }
                     }
                 },
-                "summary": "Remove one or more members from an existing Signal Group.",
+                "summary": "Trust Identity",
                 "tags": [
-                    "Groups"
+                    "Identities"
                 ]
-            },
+            }
+        },
+        "/v1/onboarding": {
             "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Add one or more members to an existing Signal Group.",
+                "description": "Start linking a device (type 'link') or registering a number (type 'register'). The returned onboarding session shows the progress: a link session waits for the QR code to be scanned ('awaiting-scan', see '/v1/onboarding/{id}/qrcode' or the device_link_uri) and is 'linked' afterwards. A register session either waits for the verification code ('awaiting-verification', see '/v1/onboarding/{id}/verify') or requires a captcha ('captcha-required', see '/v1/onboarding/{id}/register') and is 'registered' afterwards. Once the onboarding is done, the session contains the number of the account. Sessions are kept for 24 hours after their last change.",
                 "parameters": [
                     {
-                        "description": "Members",
+                        "description": "Request",
                         "in": "body",
                         "name": "data",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api.ChangeGroupMembersRequest"
+                            "$ref": "#/definitions/api.StartOnboardingRequest"
                         }
-                    },
-                    {
-                        "description": "Registered Phone Number",
-                        "in": "path",
-                        "name": "number",
-                        "required": true,
-                        "type": "string"
-                    },
-                    {
-                        "description": "Group ID",
-                        "in": "path",
-                        "name": "groupid",
-                        "required": true,
-                        "type": "string"
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/data.OnboardingSession"
                         }
                     },
                     "400": {
@@ -3828,39 +6239,20 @@ const docTemplate = `{
                         }
                     }
                 },
-                "summary": "Add one or more members to an existing Signal Group.",
+                "summary": "Start the onboarding of an account.",
                 "tags": [
-                    "Groups"
+                    "Devices"
                 ]
             }
         },
-        "/v1/groups/{number}/{groupid}/pin-message": {
-            "delete": {
-                "consumes": [
-                    "application/json"
-                ],
-                "description": "Unpin a message in a Signal Group.",
+        "/v1/onboarding/{id}": {
+            "get": {
+                "description": "Show the state of an onboarding session and the number of the account, once the onboarding is done.",
                 "parameters": [
                     {
-                        "description": "Unpin",
-                        "in": "body",
-                        "name": "data",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.UnpinMessageInGroupRequest"
-                        }
-                    },
-                    {
-                        "description": "Registered Phone Number",
-                        "in": "path",
-                        "name": "number",
-                        "required": true,
-                        "type": "string"
-                    },
-                    {
-                        "description": "Group Id",
+                        "description": "Onboarding Session Id",
                         "in": "path",
-                        "name": "groupid",
+                        "name": "id",
                         "required": true,
                         "type": "string"
                     }
@@ -3872,49 +6264,38 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/data.OnboardingSession"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
                     }
                 },
-                "summary": "Unpin a message in a Signal Group.",
+                "summary": "Show the state of an onboarding session.",
                 "tags": [
-                    "Groups"
+                    "Devices"
                 ]
-            },
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
-                "description": "Pin a message in a Signal Group.",
+            }
+        },
+        "/v1/onboarding/{id}/qrcode": {
+            "get": {
+                "description": "Show the QR code that needs to be scanned with the primary device to link the device of the onboarding session.",
                 "parameters": [
                     {
-                        "description": "Pin",
-                        "in": "body",
-                        "name": "data",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.PinMessageInGroupRequest"
-                        }
-                    },
-                    {
-                        "description": "Registered Phone Number",
+                        "description": "Onboarding Session Id",
                         "in": "path",
-                        "name": "number",
+                        "name": "id",
                         "required": true,
                         "type": "string"
                     },
                     {
-                        "description": "Group Id",
-                        "in": "path",
-                        "name": "groupid",
-                        "required": true,
-                        "type": "string"
+                        "description": "QRCode Version (defaults to 10)",
+                        "in": "query",
+                        "name": "qrcode_version",
+                        "type": "integer"
                     }
                 ],
                 "produces": [
@@ -3922,7 +6303,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Image",
                         "schema": {
                             "type": "string"
                         }
@@ -3932,44 +6313,51 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
-                "summary": "Pin a message in a Signal Group.",
+                "summary": "Show the QR code of an onboarding session.",
                 "tags": [
-                    "Groups"
+                    "Devices"
                 ]
             }
         },
-        "/v1/groups/{number}/{groupid}/quit": {
+        "/v1/onboarding/{id}/register": {
             "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Quit the specified Signal Group.",
+                "description": "Request the verification code of a register session again, e.g. with the solved captcha in case the session requires a captcha. To get the captcha, go to https://signalcaptchas.org/registration/generate.html",
                 "parameters": [
                     {
-                        "description": "Registered Phone Number",
+                        "description": "Onboarding Session Id",
                         "in": "path",
-                        "name": "number",
+                        "name": "id",
                         "required": true,
                         "type": "string"
                     },
                     {
-                        "description": "Group ID",
-                        "in": "path",
-                        "name": "groupid",
-                        "required": true,
-                        "type": "string"
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "schema": {
+                            "$ref": "#/definitions/api.OnboardingRegisterRequest"
+                        }
                     }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/data.OnboardingSession"
                         }
                     },
                     "400": {
@@ -3977,46 +6365,76 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
-                "summary": "Quit a Signal Group.",
+                "summary": "Request the verification code of an onboarding session again.",
                 "tags": [
-                    "Groups"
+                    "Devices"
                 ]
             }
         },
-        "/v1/health": {
-            "get": {
-                "description": "Internally used by the docker container to perform the health check.",
+        "/v1/onboarding/{id}/verify": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Verify the number of a register session with the verification code that was sent via SMS (or voice call).",
+                "parameters": [
+                    {
+                        "description": "Onboarding Session Id",
+                        "in": "path",
+                        "name": "id",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Request",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.OnboardingVerifyRequest"
+                        }
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/data.OnboardingSession"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
                         }
                     }
                 },
-                "summary": "API Health Check",
+                "summary": "Verify the number of an onboarding session.",
                 "tags": [
-                    "General"
+                    "Devices"
                 ]
             }
         },
-        "/v1/identities/{number}": {
+        "/v1/plugins": {
             "get": {
-                "description": "List all identities for the given number.",
-                "parameters": [
-                    {
-                        "description": "Registered Phone Number",
-                        "in": "path",
-                        "name": "number",
-                        "required": true,
-                        "type": "string"
-                    }
-                ],
+                "description": "List all loaded plugins together with their version, endpoint, init status and the status of their last (scheduled or message triggered) execution.",
                 "produces": [
                     "application/json"
                 ],
@@ -4025,60 +6443,44 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "items": {
-                                "$ref": "#/definitions/client.IdentityEntry"
+                                "$ref": "#/definitions/utils.PluginStatus"
                             },
                             "type": "array"
                         }
                     }
                 },
-                "summary": "List Identities",
+                "summary": "List plugins.",
                 "tags": [
-                    "Identities"
+                    "Plugins"
                 ]
             }
         },
-        "/v1/identities/{number}/trust/{numberToTrust}": {
-            "put": {
-                "description": "Trust an identity. When 'trust_all_known_keys' is set to' true', all known keys of this user are trusted. **This is only recommended for testing.**",
-                "parameters": [
-                    {
-                        "description": "Input Data",
-                        "in": "body",
-                        "name": "data",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.TrustIdentityRequest"
-                        }
-                    },
-                    {
-                        "description": "Registered Phone Number",
-                        "in": "path",
-                        "name": "number",
-                        "required": true,
-                        "type": "string"
-                    },
-                    {
-                        "description": "Number To Trust",
-                        "in": "path",
-                        "name": "numberToTrust",
-                        "required": true,
-                        "type": "string"
-                    }
-                ],
+        "/v1/plugins/reload": {
+            "post": {
+                "description": "Reloads all plugins from the plugin directory. Plugins are also reloaded automatically whenever the plugin directory changes (see PLUGIN_RELOAD_INTERVAL).",
                 "produces": [
                     "application/json"
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "items": {
+                                "$ref": "#/definitions/utils.PluginStatus"
+                            },
+                            "type": "array"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
                         }
                     }
                 },
-                "summary": "Trust Identity",
+                "summary": "Reload plugins.",
                 "tags": [
-                    "Identities"
+                    "Plugins"
                 ]
             }
         },
@@ -4104,6 +6506,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.ClosePollRequest"
                         }
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -4146,6 +6554,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.CreatePollRequest"
                         }
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -4193,6 +6607,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.VoteRequest"
                         }
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -4357,6 +6777,12 @@ const docTemplate = `{
                         "name": "number",
                         "required": true,
                         "type": "string"
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -4402,6 +6828,12 @@ const docTemplate = `{
                         "name": "number",
                         "required": true,
                         "type": "string"
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -4672,6 +7104,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.RemoteDeleteRequest"
                         }
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -4765,6 +7203,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.SendMessageV1"
                         }
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -4782,6 +7226,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.Error"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
                 "summary": "Send a signal message.",
@@ -4790,6 +7240,46 @@ const docTemplate = `{
                 ]
             }
         },
+        "/v1/signed-urls": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Creates a time-limited, signed URL for an attachment, a contact avatar or a group avatar. Signed URLs can be shared with third parties (e.g. put into emails or other chat systems) without exposing the rest of the API. The validity (expires_in, in seconds) is limited by the signed_urls.max_validity setting.",
+                "parameters": [
+                    {
+                        "description": "Input Data",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.CreateSignedUrlRequest"
+                        }
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.SignedUrlResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Create a signed URL.",
+                "tags": [
+                    "Attachments"
+                ]
+            }
+        },
         "/v1/sticker-packs/{number}": {
             "get": {
                 "consumes": [
@@ -5011,12 +7501,141 @@ const docTemplate = `{
                 ]
             }
         },
+        "/v2/broadcast": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Send the same signal message to any mix of phone numbers, usernames and groups. The attachments are only decoded and stored once. However, signal-cli can't reuse already uploaded attachments, so they are uploaded again for every group, once for all phone numbers and once for all usernames. The message is sent to the recipients in parallel. The result of every recipient is reported individually - check the 'success' flag of each result, as some recipients might fail while others succeed. In case a recipient is rate limited, the challenge tokens that can be used to lift the rate limit restrictions via the '/v1/accounts/{number}/rate-limit-challenge' endpoint are attached to its result.",
+                "parameters": [
+                    {
+                        "description": "Input Data",
+                        "in": "body",
+                        "name": "data",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.BroadcastRequest"
+                        }
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.BroadcastResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Send a signal message to many recipients and groups at once.",
+                "tags": [
+                    "Messages"
+                ]
+            }
+        },
+        "/v2/receive/{number}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "description": "Receives Signal Messages from the Signal Network and returns them in a normalized format (snake_case fields, group ids in the REST API format and attachments resolved to download URLs). If you are running the docker container in normal/native mode, this is a GET endpoint. In json-rpc mode this is a websocket endpoint.",
+                "parameters": [
+                    {
+                        "description": "Registered Phone Number",
+                        "in": "path",
+                        "name": "number",
+                        "required": true,
+                        "type": "string"
+                    },
+                    {
+                        "description": "Receive timeout in seconds (default: 1)",
+                        "in": "query",
+                        "name": "timeout",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Specify whether the attachments of the received message should be ignored",
+                        "in": "query",
+                        "name": "ignore_attachments",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Specify whether stories should be ignored when receiving messages",
+                        "in": "query",
+                        "name": "ignore_stories",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Specify whether avatar downloads should be ignored when receiving messages",
+                        "in": "query",
+                        "name": "ignore_avatars",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Specify whether sticker pack downloads should be ignored when receiving messages",
+                        "in": "query",
+                        "name": "ignore_stickers",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Specify the maximum number of messages to receive (default: unlimited)",
+                        "in": "query",
+                        "name": "max_messages",
+                        "type": "string"
+                    },
+                    {
+                        "description": "Specify whether read receipts should be sent when receiving messages",
+                        "in": "query",
+                        "name": "send_read_receipts",
+                        "type": "string"
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "items": {
+                                "$ref": "#/definitions/data.ReceivedMessage"
+                            },
+                            "type": "array"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
+                    }
+                },
+                "summary": "Receive Signal Messages in the normalized format.",
+                "tags": [
+                    "Messages"
+                ]
+            }
+        },
         "/v2/send": {
             "post": {
                 "consumes": [
                     "application/json"
                 ],
-                "description": "Send a signal message. Set the text_mode to 'styled' in case you want to add formatting to your text message. Styling Options: \\*italic text\\*, \\*\\*bold text\\*\\*, ~strikethrough text~, ||spoiler||, \\` + "`" + `monospace\\` + "`" + `. If you want to escape a formatting character, prefix it with two backslashes.",
+                "description": "Send a signal message. Set the text_mode to 'styled' in case you want to add formatting to your text message. Styling Options: \\*italic text\\*, \\*\\*bold text\\*\\*, ~strikethrough text~, ||spoiler||, \\` + "`" + `monospace\\` + "`" + `. If you want to escape a formatting character, prefix it with two backslashes. The text_mode, notify_self and signature default to the account specific settings (see '/v1/configuration/{number}/settings'), set the signature to an empty string to send a message without signature.",
                 "parameters": [
                     {
                         "description": "Input Data",
@@ -5026,6 +7645,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.SendMessageV2"
                         }
+                    },
+                    {
+                        "description": "Unique key that identifies the request. Retries with the same key return the stored response instead of sending again.",
+                        "in": "header",
+                        "name": "Idempotency-Key",
+                        "type": "string"
                     }
                 ],
                 "produces": [
@@ -5035,7 +7660,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api.SendMessageResponse"
+                            "$ref": "#/definitions/data.SendMessageResponse"
                         }
                     },
                     "400": {
@@ -5043,6 +7668,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.SendMessageError"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.Error"
+                        }
                     }
                 },
                 "summary": "Send a signal message.",
@@ -5102,6 +7733,10 @@ const docTemplate = `{
         {
             "description": "List and Install Sticker Packs",
             "name": "Sticker Packs"
+        },
+        {
+            "description": "List and manage plugins.",
+            "name": "Plugins"
         }
     ]
 }`
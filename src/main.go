@@ -2,15 +2,21 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"plugin"
 	"strconv"
+	"time"
 
 	"github.com/bbernhard/signal-cli-rest-api/api"
 	"github.com/bbernhard/signal-cli-rest-api/client"
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
 	docs "github.com/bbernhard/signal-cli-rest-api/docs"
 	"github.com/bbernhard/signal-cli-rest-api/utils"
 	"github.com/gin-gonic/gin"
@@ -60,26 +66,36 @@ import (
 // @tag.name Sticker Packs
 // @tag.description List and Install Sticker Packs
 
+// @tag.name Plugins
+// @tag.description List and manage plugins.
+
 // @host localhost:8080
 // @schemes http
 // @BasePath /
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "plugin-test" {
+		os.Exit(runPluginTests(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+
 	signalCliConfig := flag.String("signal-cli-config", "/home/.local/share/signal-cli/", "Config directory where signal-cli config is stored")
 	attachmentTmpDir := flag.String("attachment-tmp-dir", "/tmp/", "Attachment tmp directory")
 	avatarTmpDir := flag.String("avatar-tmp-dir", "/tmp/", "Avatar tmp directory")
 	flag.Parse()
 
-	logLevel := utils.GetEnv("LOG_LEVEL", "")
-	if logLevel != "" {
-		err := utils.SetLogLevel(logLevel)
-		if err != nil {
-			log.Error("Couldn't set log level to '", logLevel, "'. Falling back to the info log level")
-			utils.SetLogLevel("info")
-		}
+	settings, err := utils.LoadSettings(utils.GetEnv("CONFIG_FILE", ""))
+	if err != nil {
+		log.Fatal(err.Error())
 	}
 
-	if utils.GetEnv("SWAGGER_USE_HTTPS_AS_PREFERRED_SCHEME", "false") == "false" {
+	if settings.LogLevel != "" {
+		utils.SetLogLevel(settings.LogLevel)
+	}
+
+	if !settings.Swagger.UseHttpsAsPreferredScheme {
 		docs.SwaggerInfo.Schemes = []string{"http", "https"}
 	} else {
 		docs.SwaggerInfo.Schemes = []string{"https", "http"}
@@ -93,14 +109,16 @@ func main() {
 
 	router.Use(gin.Recovery())
 
-	port := utils.GetEnv("PORT", "8080")
-	if _, err := strconv.Atoi(port); err != nil {
-		log.Fatal("Invalid PORT ", port, " set. PORT needs to be a number")
-	}
+	port := strconv.Itoa(settings.Port)
 
-	defaultSwaggerIp := utils.GetEnv("HOST_IP", "127.0.0.1")
-	swaggerIp := utils.GetEnv("SWAGGER_IP", defaultSwaggerIp)
-	swaggerHost := utils.GetEnv("SWAGGER_HOST", swaggerIp+":"+port)
+	swaggerIp := settings.Swagger.Ip
+	if swaggerIp == "" {
+		swaggerIp = utils.GetEnv("HOST_IP", "127.0.0.1")
+	}
+	swaggerHost := settings.Swagger.Host
+	if swaggerHost == "" {
+		swaggerHost = swaggerIp + ":" + port
+	}
 	docs.SwaggerInfo.Host = swaggerHost
 
 	log.Info("Started Signal Messenger REST API")
@@ -110,7 +128,7 @@ func main() {
 		supportsSignalCliNative = "1"
 	}
 
-	err := os.Setenv("SUPPORTS_NATIVE", supportsSignalCliNative)
+	err = os.Setenv("SUPPORTS_NATIVE", supportsSignalCliNative)
 	if err != nil {
 		log.Fatal("Couldn't set env variable: ", err.Error())
 	}
@@ -121,12 +139,9 @@ func main() {
 	}
 
 	signalCliMode := client.Normal
-	mode := utils.GetEnv("MODE", "normal")
-	if mode == "normal" {
-		signalCliMode = client.Normal
-	} else if mode == "json-rpc" || mode == "json-rpc-native" {
+	if settings.IsJsonRpcMode() {
 		signalCliMode = client.JsonRpc
-	} else if mode == "native" {
+	} else if settings.Mode == "native" {
 		signalCliMode = client.Native
 	}
 
@@ -144,32 +159,58 @@ func main() {
 		}
 	}
 
-	if signalCliMode == client.JsonRpc {
-		_, autoReceiveScheduleEnvVariableSet := os.LookupEnv("AUTO_RECEIVE_SCHEDULE")
-		if autoReceiveScheduleEnvVariableSet {
-			log.Fatal("Env variable AUTO_RECEIVE_SCHEDULE can't be used with mode json-rpc")
-		}
-
-		_, signalCliCommandTimeoutEnvVariableSet := os.LookupEnv("SIGNAL_CLI_CMD_TIMEOUT")
-		if signalCliCommandTimeoutEnvVariableSet {
-			log.Fatal("Env variable SIGNAL_CLI_CMD_TIMEOUT can't be used with mode json-rpc")
-		}
+	jsonRpc2ClientConfigPathPath := *signalCliConfig + "/jsonrpc2.yml"
+	signalCliApiConfigPath := *signalCliConfig + "/api-config.yml"
+	signalClient := client.NewSignalClient(*signalCliConfig, *attachmentTmpDir, *avatarTmpDir, signalCliMode, jsonRpc2ClientConfigPathPath, signalCliApiConfigPath, settings.ReceiveWebhookUrl)
+	urlSigner, err := utils.NewUrlSigner(settings.SignedUrls.Secret)
+	if err != nil {
+		log.Fatal("Couldn't create URL signer: ", err.Error())
 	}
-
-	webhookUrl := utils.GetEnv("RECEIVE_WEBHOOK_URL", "")
-	if webhookUrl != "" && signalCliMode != client.JsonRpc {
-		log.Fatal("Env variable RECEIVE_WEBHOOK_URL can only be used with mode json-rpc!")
+	signalClient.SetUrlSigner(urlSigner, time.Duration(settings.SignedUrls.Validity)*time.Second, settings.SignedUrls.PublicUrl)
+	signalClient.SetAttachmentInlineMaxSize(settings.ReceiveAttachmentInlineMaxSize)
+	if settings.SignalCliCmdTimeout > 0 {
+		signalClient.SetSignalCliCommandTimeout(time.Duration(settings.SignalCliCmdTimeout) * time.Second)
 	}
 
-	jsonRpc2ClientConfigPathPath := *signalCliConfig + "/jsonrpc2.yml"
-	signalCliApiConfigPath := *signalCliConfig + "/api-config.yml"
-	signalClient := client.NewSignalClient(*signalCliConfig, *attachmentTmpDir, *avatarTmpDir, signalCliMode, jsonRpc2ClientConfigPathPath, signalCliApiConfigPath, webhookUrl)
 	err = signalClient.Init(60)
 	if err != nil {
 		log.Fatal("Couldn't init Signal Client: ", err.Error())
 	}
 
+	restApiDataStore := utils.NewKeyValueStore(filepath.Join(*signalCliConfig, "rest-api-data"))
+	err = signalClient.SetOnboardingStore(restApiDataStore)
+	if err != nil {
+		log.Fatal("Couldn't load onboarding sessions: ", err.Error())
+	}
+
+	err = signalClient.SetGroupChangesStore(restApiDataStore)
+	if err != nil {
+		log.Fatal("Couldn't load group audit log: ", err.Error())
+	}
+
+	// merge the settings with the settings that were changed via the REST API
+	settingsStore, err := utils.NewSettingsStore(settings, signalClient.GetSignalCliApiConfig())
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	settings = settingsStore.Get()
+	if settings.LogLevel != "" {
+		utils.SetLogLevel(settings.LogLevel)
+	}
+	signalClient.SetReceiveWebhookUrl(settings.ReceiveWebhookUrl)
+
+	settingsStore.OnChange(func(newSettings utils.Settings) {
+		if newSettings.LogLevel != "" {
+			utils.SetLogLevel(newSettings.LogLevel)
+		}
+		signalClient.SetReceiveWebhookUrl(newSettings.ReceiveWebhookUrl)
+	})
+	go removeExpiredAttachments(signalClient, settingsStore)
+
 	api := api.NewApi(signalClient)
+	api.SetIdempotencyStore(utils.NewIdempotencyStore(time.Duration(settings.IdempotencyKeyTtl) * time.Second))
+	api.SetSettingsStore(settingsStore)
+
 	v1 := router.Group("/v1")
 	{
 		about := v1.Group("/about")
@@ -183,6 +224,7 @@ func main() {
 			configuration.POST("", api.SetConfiguration)
 			configuration.POST(":number/settings", api.SetTrustMode)
 			configuration.GET(":number/settings", api.GetTrustMode)
+			configuration.PUT(":number/settings", api.UpdateAccountSpecificSettings)
 		}
 
 		health := v1.Group("/health")
@@ -203,7 +245,7 @@ func main() {
 
 		sendV1 := v1.Group("/send")
 		{
-			sendV1.POST("", api.Send)
+			sendV1.POST("", api.Idempotent(), api.Send)
 		}
 
 		receive := v1.Group("/receive")
@@ -217,15 +259,21 @@ func main() {
 			groups.GET(":number", api.GetGroups)
 			groups.GET(":number/:groupid", api.GetGroup)
 			groups.GET(":number/:groupid/avatar", api.GetGroupAvatar)
+			groups.GET(":number/:groupid/audit-log", api.GetGroupAuditLog)
 			groups.DELETE(":number/:groupid", api.DeleteGroup)
 			groups.POST(":number/:groupid/block", api.BlockGroup)
 			groups.POST(":number/:groupid/join", api.JoinGroup)
 			groups.POST(":number/:groupid/quit", api.QuitGroup)
+			groups.PUT(":number/sync", api.SyncGroups)
 			groups.PUT(":number/:groupid", api.UpdateGroup)
 			groups.POST(":number/:groupid/members", api.AddMembersToGroup)
 			groups.DELETE(":number/:groupid/members", api.RemoveMembersFromGroup)
 			groups.POST(":number/:groupid/admins", api.AddAdminsToGroup)
 			groups.DELETE(":number/:groupid/admins", api.RemoveAdminsFromGroup)
+			groups.POST(":number/:groupid/join-requests/approve", api.ApproveGroupJoinRequests)
+			groups.POST(":number/:groupid/join-requests/deny", api.DenyGroupJoinRequests)
+			groups.DELETE(":number/:groupid/invites", api.RevokeGroupInvites)
+			groups.PUT(":number/:groupid/invite-link", api.UpdateGroupInviteLink)
 			groups.POST(":number/:groupid/pin-message", api.PinMessageInGroup)
 			groups.DELETE(":number/:groupid/pin-message", api.UnpinMessageInGroup)
 		}
@@ -236,9 +284,21 @@ func main() {
 			link.GET("/raw", api.GetQrCodeLinkUri)
 		}
 
+		onboarding := v1.Group("onboarding")
+		{
+			onboarding.POST("", api.StartOnboarding)
+			onboarding.GET(":id", api.GetOnboardingSession)
+			onboarding.GET(":id/qrcode", api.GetOnboardingQrCode)
+			onboarding.POST(":id/register", api.RetryOnboardingRegistration)
+			onboarding.POST(":id/verify", api.VerifyOnboarding)
+		}
+
 		accounts := v1.Group("accounts")
 		{
 			accounts.GET("", api.GetAccounts)
+			accounts.POST("import", api.ImportAccount)
+			accounts.GET(":number", api.GetAccount)
+			accounts.POST(":number/export", api.ExportAccount)
 			accounts.POST(":number/rate-limit-challenge", api.SubmitRateLimitChallenge)
 			accounts.PUT(":number/settings", api.UpdateAccountSettings)
 			accounts.POST(":number/username", api.SetUsername)
@@ -262,6 +322,11 @@ func main() {
 			attachments.GET(":attachment", api.ServeAttachment)
 		}
 
+		signedUrls := v1.Group("signed-urls")
+		{
+			signedUrls.POST("", api.CreateSignedUrl)
+		}
+
 		stickerPacks := v1.Group("sticker-packs")
 		{
 			stickerPacks.GET(":number", api.ListInstalledStickerPacks)
@@ -287,13 +352,13 @@ func main() {
 
 		remoteDelete := v1.Group("remote-delete")
 		{
-			remoteDelete.DELETE(":number", api.RemoteDelete)
+			remoteDelete.DELETE(":number", api.Idempotent(), api.RemoteDelete)
 		}
 
 		reactions := v1.Group("/reactions")
 		{
-			reactions.POST(":number", api.SendReaction)
-			reactions.DELETE(":number", api.RemoveReaction)
+			reactions.POST(":number", api.Idempotent(), api.SendReaction)
+			reactions.DELETE(":number", api.Idempotent(), api.RemoveReaction)
 		}
 
 		receipts := v1.Group("/receipts")
@@ -314,64 +379,163 @@ func main() {
 			contacts.GET(":number/:uuid", api.ListContact)
 			contacts.GET(":number/:uuid/avatar", api.GetProfileAvatar)
 			contacts.POST(":number/sync", api.SendContacts)
+			contacts.POST(":number/import", api.ImportContacts)
+			contacts.GET(":number/export", api.ExportContacts)
 		}
 
 		polls := v1.Group("/polls")
 		{
-			polls.POST(":number", api.CreatePoll)
-			polls.POST(":number/vote", api.VoteInPoll)
-			polls.DELETE(":number", api.ClosePoll)
+			polls.POST(":number", api.Idempotent(), api.CreatePoll)
+			polls.POST(":number/vote", api.Idempotent(), api.VoteInPoll)
+			polls.DELETE(":number", api.Idempotent(), api.ClosePoll)
 		}
 
-		if utils.GetEnv("ENABLE_PLUGINS", "false") == "true" {
-			signalCliRestApiPluginSharedObjDir := utils.GetEnv("SIGNAL_CLI_REST_API_PLUGIN_SHARED_OBJ_DIR", "")
-			sharedObj, err := plugin.Open(signalCliRestApiPluginSharedObjDir + "signal-cli-rest-api_plugin_loader.so")
-			if err != nil {
-				log.Fatal("Couldn't load shared object: ", err)
-			}
+		if settings.Plugins.Enabled {
+			pluginHandlerSymbol := loadPluginHandlerSymbol()
 
-			pluginHandlerSymbol, err := sharedObj.Lookup("PluginHandler")
-			if err != nil {
-				log.Fatal("Couldn't get PluginHandler: ", err)
+			pluginHandler, ok := pluginHandlerSymbol.(utils.PluginHandler)
+			if !ok {
+				log.Fatal("Couldn't cast PluginHandler")
 			}
 
-			pluginHandler, ok := pluginHandlerSymbol.(utils.PluginHandler)
+			configurablePluginHandler, ok := pluginHandlerSymbol.(interface {
+				SetSignalClient(signalClient *client.SignalClient)
+				SetDefaultTimeout(defaultTimeout time.Duration)
+				SetKeyValueStore(kvStore *utils.KeyValueStore)
+				SetStatePoolSize(size int)
+			})
 			if !ok {
 				log.Fatal("Couldn't cast PluginHandler")
 			}
+			configurablePluginHandler.SetSignalClient(signalClient)
+			configurablePluginHandler.SetKeyValueStore(utils.NewKeyValueStore(filepath.Join(*signalCliConfig, "plugin-data", "kv")))
+			configurablePluginHandler.SetDefaultTimeout(time.Duration(settings.Plugins.Timeout) * time.Second)
+			configurablePluginHandler.SetStatePoolSize(settings.Plugins.StatePoolSize)
+
+			pluginManager := utils.NewPluginManager("/plugins", "/v1/plugins", pluginHandler)
+			pluginManager.AddReservedRoute("POST", "/reload", api.ReloadPlugins)
+			api.SetPluginManager(pluginManager)
+
+			err = pluginManager.Reload()
+			if err != nil {
+				log.Fatal("Couldn't load plugin configs: ", err.Error())
+			}
+
+			if settings.Plugins.ReloadInterval > 0 {
+				pluginManager.Watch(time.Duration(settings.Plugins.ReloadInterval) * time.Second)
+			}
 
 			plugins := v1.Group("/plugins")
 			{
-				pluginConfigs := utils.NewPluginConfigs()
-				err := pluginConfigs.Load("/plugins")
+				plugins.GET("", api.ListPlugins)
+				plugins.Any("/*path", pluginManager.Handler())
+			}
+
+			signalClient.AddReceivedMessageHandler(func(data []byte) {
+				receivePluginConfigs := pluginManager.TriggeredPlugins(utils.ReceivePluginTrigger)
+				if len(receivePluginConfigs) == 0 {
+					return
+				}
+
+				var message struct {
+					Account  string          `json:"account"`
+					Envelope json.RawMessage `json:"envelope"`
+				}
+				err := json.Unmarshal(data, &message)
+				if err != nil || len(message.Envelope) == 0 {
+					return
+				}
+
+				normalizedMessage, err := client.NormalizeReceivedMessageJson(data)
 				if err != nil {
-					log.Fatal("Couldn't load plugin configs: ", err.Error())
+					log.Error("Couldn't parse received message: ", err.Error())
+					return
 				}
 
-				for _, pluginConfig := range pluginConfigs.Configs {
-					if pluginConfig.Version > 1 {
-						err = pluginHandler.InitPlugin(pluginConfig)
-						if err != nil {
-							log.Error("Couldn't initialize plugin ", pluginConfig.Endpoint)
-							continue
+				for _, pluginConfig := range receivePluginConfigs {
+					if !pluginConfig.MatchesReceivedMessage(message.Account, normalizedMessage.GroupId(), normalizedMessage.Source.Number, normalizedMessage.Source.Uuid) {
+						continue
+					}
+
+					startedAt := time.Now()
+					err = pluginHandler.OnMessage(pluginConfig, message.Account, message.Envelope)
+					if err != nil {
+						log.Error("Couldn't execute on_message of plugin ", pluginConfig.ScriptPath, ": ", err.Error())
+					}
+					pluginManager.RecordRun(pluginConfig, startedAt, err)
+				}
+			})
+
+			signalClient.AddPreSendHook(func(signalCliSendRequest *ds.SignalCliSendRequest) error {
+				for _, pluginConfig := range pluginManager.TriggeredPlugins(utils.PreSendPluginTrigger) {
+					if !pluginConfig.MatchesAccount(signalCliSendRequest.Number) {
+						continue
+					}
+
+					request, err := client.MarshalSendRequest(*signalCliSendRequest)
+					if err != nil {
+						return err
+					}
+
+					startedAt := time.Now()
+					modifiedRequest, blockReason, err := pluginHandler.PreSend(pluginConfig, signalCliSendRequest.Number, request)
+					if err == nil && modifiedRequest != nil {
+						var modifiedSignalCliSendRequest ds.SignalCliSendRequest
+						modifiedSignalCliSendRequest, err = client.UnmarshalSendRequest(modifiedRequest, signalCliSendRequest.RecipientType)
+						if err == nil {
+							*signalCliSendRequest = modifiedSignalCliSendRequest
 						}
-					} else {
-						log.Info("Plugin ", pluginConfig.Endpoint, " still uses plugin version 1. Consider migrating to version 2! (see https://github.com/bbernhard/signal-cli-rest-api/plugins/migrate-v1-plugin-to-v2.md)")
 					}
+					pluginManager.RecordRun(pluginConfig, startedAt, err)
 
-					log.Info("Registering plugin ", pluginConfig.Endpoint)
+					if err != nil {
+						log.Error("Couldn't execute pre_send of plugin ", pluginConfig.ScriptPath, ": ", err.Error())
+						return errors.New("Couldn't execute pre_send of plugin " + pluginConfig.Name() + ": " + err.Error())
+					}
+					if blockReason != "" {
+						return &client.SendBlockedError{Reason: blockReason}
+					}
+				}
+				return nil
+			})
 
-					if pluginConfig.Method == "GET" {
-						plugins.GET(pluginConfig.Endpoint, pluginHandler.ExecutePlugin(pluginConfig))
-					} else if pluginConfig.Method == "POST" {
-						plugins.POST(pluginConfig.Endpoint, pluginHandler.ExecutePlugin(pluginConfig))
-					} else if pluginConfig.Method == "DELETE" {
-						plugins.DELETE(pluginConfig.Endpoint, pluginHandler.ExecutePlugin(pluginConfig))
-					} else if pluginConfig.Method == "PUT" {
-						plugins.PUT(pluginConfig.Endpoint, pluginHandler.ExecutePlugin(pluginConfig))
+			signalClient.AddPostSendHook(func(signalCliSendRequest ds.SignalCliSendRequest, resp *ds.SendMessageResponse, sendErr error) {
+				postSendPluginConfigs := pluginManager.TriggeredPlugins(utils.PostSendPluginTrigger)
+				if len(postSendPluginConfigs) == 0 {
+					return
+				}
+
+				request, err := client.MarshalSendRequest(signalCliSendRequest)
+				if err != nil {
+					log.Error("Couldn't encode send request: ", err.Error())
+					return
+				}
+
+				var response []byte
+				sendError := ""
+				if sendErr != nil {
+					sendError = sendErr.Error()
+				} else if resp != nil {
+					response, err = json.Marshal(resp)
+					if err != nil {
+						log.Error("Couldn't encode send response: ", err.Error())
+						return
 					}
 				}
-			}
+
+				for _, pluginConfig := range postSendPluginConfigs {
+					if !pluginConfig.MatchesAccount(signalCliSendRequest.Number) {
+						continue
+					}
+
+					startedAt := time.Now()
+					err = pluginHandler.PostSend(pluginConfig, signalCliSendRequest.Number, request, response, sendError)
+					if err != nil {
+						log.Error("Couldn't execute post_send of plugin ", pluginConfig.ScriptPath, ": ", err.Error())
+					}
+					pluginManager.RecordRun(pluginConfig, startedAt, err)
+				}
+			})
 		}
 	}
 
@@ -379,105 +543,231 @@ func main() {
 	{
 		sendV2 := v2.Group("/send")
 		{
-			sendV2.POST("", api.SendV2)
+			sendV2.POST("", api.Idempotent(), api.SendV2)
+		}
+
+		broadcastV2 := v2.Group("/broadcast")
+		{
+			broadcastV2.POST("", api.Idempotent(), api.Broadcast)
+		}
+
+		receiveV2 := v2.Group("/receive")
+		{
+			receiveV2.GET(":number", api.ReceiveV2)
 		}
 	}
 
 	protocol := "http"
-	if utils.GetEnv("SWAGGER_USE_HTTPS_AS_PREFERRED_SCHEME", "false") == "true" {
+	if settings.Swagger.UseHttpsAsPreferredScheme {
 		protocol = "https"
 	}
 
 	swaggerUrl := ginSwagger.URL(protocol + "://" + swaggerHost + "/swagger/doc.json")
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, swaggerUrl))
 
-	autoReceiveSchedule := utils.GetEnv("AUTO_RECEIVE_SCHEDULE", "")
-	if autoReceiveSchedule != "" {
-		p := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-		schedule, err := p.Parse(autoReceiveSchedule)
-		if err != nil {
-			log.Fatal("AUTO_RECEIVE_SCHEDULE: Invalid schedule: ", err.Error())
+	autoReceiveSchedule := settings.AutoReceiveSchedule
+	autoReceiveScheduler := startAutoReceiveScheduler(*signalCliConfig, port, autoReceiveSchedule)
+	settingsStore.OnChange(func(newSettings utils.Settings) {
+		if newSettings.AutoReceiveSchedule == autoReceiveSchedule {
+			return
 		}
-
-		type SignalCliAccountConfig struct {
-			Number string `json:"number"`
+		log.Info("Auto receive schedule changed...restarting the scheduler")
+		if autoReceiveScheduler != nil {
+			autoReceiveScheduler.Stop()
 		}
+		autoReceiveSchedule = newSettings.AutoReceiveSchedule
+		autoReceiveScheduler = startAutoReceiveScheduler(*signalCliConfig, port, autoReceiveSchedule)
+	})
+
+	router.Run(":" + port)
+}
+
+// loadPluginHandlerSymbol loads the plugin handler from the plugin loader shared object.
+func loadPluginHandlerSymbol() plugin.Symbol {
+	signalCliRestApiPluginSharedObjDir := utils.GetEnv("SIGNAL_CLI_REST_API_PLUGIN_SHARED_OBJ_DIR", "")
+	sharedObj, err := plugin.Open(signalCliRestApiPluginSharedObjDir + "signal-cli-rest-api_plugin_loader.so")
+	if err != nil {
+		log.Fatal("Couldn't load shared object: ", err)
+	}
+
+	pluginHandlerSymbol, err := sharedObj.Lookup("PluginHandler")
+	if err != nil {
+		log.Fatal("Couldn't get PluginHandler: ", err)
+	}
+	return pluginHandlerSymbol
+}
+
+// runPluginTests runs the fixture based tests of the plugins in the given
+// directory (signal-cli-rest-api plugin-test <plugin directory>) and returns
+// the exit code.
+func runPluginTests(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: signal-cli-rest-api plugin-test <plugin directory>")
+		return 2
+	}
+
+	pluginTester, ok := loadPluginHandlerSymbol().(interface {
+		RunPluginTests(directory string, out io.Writer) (int, error)
+	})
+	if !ok {
+		log.Fatal("Couldn't cast PluginHandler")
+	}
+
+	failed, err := pluginTester.RunPluginTests(args[0], os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't run plugin tests: ", err.Error())
+		return 2
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runConfigCommand implements the config subcommands:
+//
+//	signal-cli-rest-api config validate [config file]
+//	signal-cli-rest-api config get <setting> [config file]
+//
+// If no config file is specified, the CONFIG_FILE env variable is used.
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "validate" && args[0] != "get") || (args[0] == "get" && len(args) < 2) {
+		fmt.Fprintln(os.Stderr, "Usage: signal-cli-rest-api config validate [config file]")
+		fmt.Fprintln(os.Stderr, "       signal-cli-rest-api config get <setting> [config file]")
+		return 2
+	}
+
+	configFile := utils.GetEnv("CONFIG_FILE", "")
+	if args[0] == "validate" && len(args) > 1 {
+		configFile = args[1]
+	} else if args[0] == "get" && len(args) > 2 {
+		configFile = args[2]
+	}
 
-		type SignalCliAccountConfigs struct {
-			Accounts []SignalCliAccountConfig `json:"accounts"`
+	settings, err := utils.LoadSettings(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	if args[0] == "get" {
+		value, err := settings.Get(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
 		}
+		fmt.Println(value)
+		return 0
+	}
+
+	fmt.Println("Configuration is valid")
+	return 0
+}
+
+// startAutoReceiveScheduler periodically calls the receive endpoint for all accounts.
+func startAutoReceiveScheduler(signalCliConfig string, port string, autoReceiveSchedule utils.AutoReceiveScheduleSettings) *cron.Cron {
+	if autoReceiveSchedule.Schedule == "" {
+		return nil
+	}
+
+	p := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	schedule, _ := p.Parse(autoReceiveSchedule.Schedule) // already validated when loading the settings
 
-		autoReceiveScheduleReceiveTimeout := utils.GetEnv("AUTO_RECEIVE_SCHEDULE_RECEIVE_TIMEOUT", "10")
-		autoReceiveScheduleIgnoreAttachments := utils.GetEnv("AUTO_RECEIVE_SCHEDULE_IGNORE_ATTACHMENTS", "false")
-		autoReceiveScheduleIgnoreStories := utils.GetEnv("AUTO_RECEIVE_SCHEDULE_IGNORE_STORIES", "false")
-		autoReceiveScheduleIgnoreAvatars := utils.GetEnv("AUTO_RECEIVE_SCHEDULE_IGNORE_AVATARS", "false")
-		autoReceiveScheduleIgnoreStickers := utils.GetEnv("AUTO_RECEIVE_SCHEDULE_IGNORE_STICKERS", "false")
-		autoReceiveScheduleSendReadReceipts := utils.GetEnv("AUTO_RECEIVE_SCHEDULE_SEND_READ_RECEIPTS", "false")
+	type SignalCliAccountConfig struct {
+		Number string `json:"number"`
+	}
+
+	type SignalCliAccountConfigs struct {
+		Accounts []SignalCliAccountConfig `json:"accounts"`
+	}
+
+	autoReceiveScheduleReceiveTimeout := strconv.Itoa(autoReceiveSchedule.ReceiveTimeout)
+	autoReceiveScheduleIgnoreAttachments := strconv.FormatBool(autoReceiveSchedule.IgnoreAttachments)
+	autoReceiveScheduleIgnoreStories := strconv.FormatBool(autoReceiveSchedule.IgnoreStories)
+	autoReceiveScheduleIgnoreAvatars := strconv.FormatBool(autoReceiveSchedule.IgnoreAvatars)
+	autoReceiveScheduleIgnoreStickers := strconv.FormatBool(autoReceiveSchedule.IgnoreStickers)
+
+	c := cron.New()
+	c.Schedule(schedule, cron.FuncJob(func() {
+		accountsJsonPath := signalCliConfig + "/data/accounts.json"
+		if _, err := os.Stat(accountsJsonPath); err == nil {
+			signalCliConfigJsonData, err := ioutil.ReadFile(accountsJsonPath)
+			if err != nil {
+				log.Fatal("AUTO_RECEIVE_SCHEDULE: Couldn't read accounts.json: ", err.Error())
+			}
+			var signalCliAccountConfigs SignalCliAccountConfigs
+			err = json.Unmarshal(signalCliConfigJsonData, &signalCliAccountConfigs)
+			if err != nil {
+				log.Fatal("AUTO_RECEIVE_SCHEDULE: Couldn't parse accounts.json: ", err.Error())
+			}
 
-		c := cron.New()
-		c.Schedule(schedule, cron.FuncJob(func() {
-			accountsJsonPath := *signalCliConfig + "/data/accounts.json"
-			if _, err := os.Stat(accountsJsonPath); err == nil {
-				signalCliConfigJsonData, err := ioutil.ReadFile(accountsJsonPath)
+			for _, account := range signalCliAccountConfigs.Accounts {
+				client := &http.Client{}
+
+				log.Debug("AUTO_RECEIVE_SCHEDULE: Calling receive for number ", account.Number)
+				req, err := http.NewRequest("GET", "http://127.0.0.1:"+port+"/v1/receive/"+account.Number, nil)
 				if err != nil {
-					log.Fatal("AUTO_RECEIVE_SCHEDULE: Couldn't read accounts.json: ", err.Error())
+					log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't call receive for number ", account.Number, ": ", err.Error())
 				}
-				var signalCliAccountConfigs SignalCliAccountConfigs
-				err = json.Unmarshal(signalCliConfigJsonData, &signalCliAccountConfigs)
-				if err != nil {
-					log.Fatal("AUTO_RECEIVE_SCHEDULE: Couldn't parse accounts.json: ", err.Error())
+
+				q := req.URL.Query()
+				q.Add("timeout", autoReceiveScheduleReceiveTimeout)
+				q.Add("ignore_attachments", autoReceiveScheduleIgnoreAttachments)
+				q.Add("ignore_stories", autoReceiveScheduleIgnoreStories)
+				q.Add("ignore_avatars", autoReceiveScheduleIgnoreAvatars)
+				q.Add("ignore_stickers", autoReceiveScheduleIgnoreStickers)
+				if autoReceiveSchedule.SendReadReceipts { // otherwise the setting of the account applies
+					q.Add("send_read_receipts", "true")
 				}
+				req.URL.RawQuery = q.Encode()
 
-				for _, account := range signalCliAccountConfigs.Accounts {
-					client := &http.Client{}
+				resp, err := client.Do(req)
+				if err != nil {
+					log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't call receive for number ", account.Number, ": ", err.Error())
+				}
 
-					log.Debug("AUTO_RECEIVE_SCHEDULE: Calling receive for number ", account.Number)
-					req, err := http.NewRequest("GET", "http://127.0.0.1:"+port+"/v1/receive/"+account.Number, nil)
+				if resp.StatusCode != 200 {
+					jsonResp, err := ioutil.ReadAll(resp.Body)
+					resp.Body.Close()
 					if err != nil {
-						log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't call receive for number ", account.Number, ": ", err.Error())
+						log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't read json response: ", err.Error())
+						continue
 					}
 
-					q := req.URL.Query()
-					q.Add("timeout", autoReceiveScheduleReceiveTimeout)
-					q.Add("ignore_attachments", autoReceiveScheduleIgnoreAttachments)
-					q.Add("ignore_stories", autoReceiveScheduleIgnoreStories)
-					q.Add("ignore_avatars", autoReceiveScheduleIgnoreAvatars)
-					q.Add("ignore_stickers", autoReceiveScheduleIgnoreStickers)
-					q.Add("send_read_receipts", autoReceiveScheduleSendReadReceipts)
-					req.URL.RawQuery = q.Encode()
-
-					resp, err := client.Do(req)
+					type ReceiveResponse struct {
+						Error string `json:"error"`
+					}
+					var receiveResponse ReceiveResponse
+					err = json.Unmarshal(jsonResp, &receiveResponse)
 					if err != nil {
-						log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't call receive for number ", account.Number, ": ", err.Error())
+						log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't parse json response: ", err.Error())
+						continue
 					}
 
-					if resp.StatusCode != 200 {
-						jsonResp, err := ioutil.ReadAll(resp.Body)
-						resp.Body.Close()
-						if err != nil {
-							log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't read json response: ", err.Error())
-							continue
-						}
-
-						type ReceiveResponse struct {
-							Error string `json:"error"`
-						}
-						var receiveResponse ReceiveResponse
-						err = json.Unmarshal(jsonResp, &receiveResponse)
-						if err != nil {
-							log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't parse json response: ", err.Error())
-							continue
-						}
-
-						log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't call receive for number ", account.Number, ": ", receiveResponse)
-					}
+					log.Error("AUTO_RECEIVE_SCHEDULE: Couldn't call receive for number ", account.Number, ": ", receiveResponse)
 				}
-			} else {
-				log.Info("AUTO_RECEIVE_SCHEDULE: accounts.json doesn't exist")
 			}
-		}))
-		c.Start()
-	}
+		} else {
+			log.Info("AUTO_RECEIVE_SCHEDULE: accounts.json doesn't exist")
+		}
+	}))
+	c.Start()
+	return c
+}
 
-	router.Run()
+// removeExpiredAttachments periodically removes the attachments that are older than the
+// configured attachment retention.
+func removeExpiredAttachments(signalClient *client.SignalClient, settingsStore *utils.SettingsStore) {
+	for {
+		retentionDays := settingsStore.Get().AttachmentRetentionDays
+		if retentionDays > 0 {
+			removed, err := signalClient.RemoveAttachmentsOlderThan(time.Duration(retentionDays) * 24 * time.Hour)
+			if err != nil {
+				log.Error("Couldn't remove expired attachments: ", err.Error())
+			} else if removed > 0 {
+				log.Info("Removed ", removed, " expired attachments")
+			}
+		}
+		time.Sleep(time.Hour)
+	}
 }
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	"github.com/gin-gonic/gin"
+	lua "github.com/yuin/gopher-lua"
+	"gopkg.in/yaml.v2"
+	luajson "layeh.com/gopher-json"
+)
+
+// The plugin test harness runs plugins against fixture files, without a running
+// signal-cli. For a plugin "foo.def"/"foo.lua", the test cases are read from
+// "foo.test.yml". The http and signal lua modules are replaced by stubs, which
+// return the responses that are defined in the test case.
+
+type harnessRequest struct {
+	Method  string            `yaml:"method"`
+	Params  map[string]string `yaml:"params"`
+	Query   map[string]string `yaml:"query"`
+	Headers map[string]string `yaml:"headers"`
+	Payload interface{}       `yaml:"payload"`
+}
+
+type harnessSignalStub struct {
+	Response interface{} `yaml:"response"`
+	Error    string      `yaml:"error"`
+}
+
+type harnessHttpStub struct {
+	Method  string            `yaml:"method"`
+	Url     string            `yaml:"url"`
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Body    interface{}       `yaml:"body"`
+}
+
+type harnessCall struct {
+	Function string        `yaml:"function"`
+	Args     []interface{} `yaml:"args"`
+}
+
+type harnessHttpCall struct {
+	Method string `yaml:"method"`
+	Url    string `yaml:"url"`
+}
+
+type harnessExpectation struct {
+	Status       int               `yaml:"status"`
+	ContentType  string            `yaml:"content_type"`
+	Headers      map[string]string `yaml:"headers"`
+	Body         *string           `yaml:"body"`
+	BodyContains string            `yaml:"body_contains"`
+	Json         interface{}       `yaml:"json"`
+	Error        string            `yaml:"error"`
+	Blocked      string            `yaml:"blocked"`
+	SignalCalls  []harnessCall     `yaml:"signal_calls"`
+	HttpCalls    []harnessHttpCall `yaml:"http_calls"`
+}
+
+type harnessTestCase struct {
+	Name         string                       `yaml:"name"`
+	Request      *harnessRequest              `yaml:"request"`
+	Envelope     interface{}                  `yaml:"envelope"`
+	EnvelopeFile string                       `yaml:"envelope_file"`
+	SendRequest  interface{}                  `yaml:"send_request"`
+	Scheduled    bool                         `yaml:"scheduled"`
+	Account      string                       `yaml:"account"`
+	Signal       map[string]harnessSignalStub `yaml:"signal"`
+	Http         []harnessHttpStub            `yaml:"http"`
+	Expect       harnessExpectation           `yaml:"expect"`
+}
+
+type harnessTestFile struct {
+	Tests []harnessTestCase `yaml:"tests"`
+}
+
+// harnessStubs replaces the http and signal modules of the lua state and
+// records all calls.
+type harnessStubs struct {
+	testCase    harnessTestCase
+	signalCalls []harnessCall
+	httpCalls   []harnessHttpCall
+}
+
+// yamlToJson converts a value that was decoded by the yaml parser (which uses
+// map[interface{}]interface{} for objects) to JSON.
+func yamlToJson(value interface{}) ([]byte, error) {
+	return json.Marshal(normalizeYamlValue(value))
+}
+
+func normalizeYamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normalizeYamlValue(val)
+		}
+		return m
+	case []interface{}:
+		l := []interface{}{}
+		for _, val := range v {
+			l = append(l, normalizeYamlValue(val))
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// jsonEqual compares a JSON document with an expected (yaml) value.
+func jsonEqual(expected interface{}, actual []byte) (bool, error) {
+	expectedJson, err := yamlToJson(expected)
+	if err != nil {
+		return false, err
+	}
+
+	var expectedValue, actualValue interface{}
+	if err := json.Unmarshal(expectedJson, &expectedValue); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(actual, &actualValue); err != nil {
+		return false, errors.New("not valid JSON: " + string(actual))
+	}
+	return reflect.DeepEqual(expectedValue, actualValue), nil
+}
+
+// stringOrJson returns strings as is and encodes everything else as JSON.
+func stringOrJson(value interface{}) ([]byte, error) {
+	if value == nil {
+		return []byte{}, nil
+	}
+	if str, ok := value.(string); ok {
+		return []byte(str), nil
+	}
+	return yamlToJson(value)
+}
+
+func (h *harnessStubs) doHttpRequest(req *http.Request) (*http.Response, error) {
+	h.httpCalls = append(h.httpCalls, harnessHttpCall{Method: req.Method, Url: req.URL.String()})
+
+	for _, stub := range h.testCase.Http {
+		method := stub.Method
+		if method == "" {
+			method = "GET"
+		}
+		if !strings.EqualFold(method, req.Method) || stub.Url != req.URL.String() {
+			continue
+		}
+
+		body, err := stringOrJson(stub.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		status := stub.Status
+		if status == 0 {
+			status = 200
+		}
+
+		header := http.Header{}
+		for key, value := range stub.Headers {
+			header.Set(key, value)
+		}
+
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Header:     header,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+	return nil, errors.New("No stub for HTTP request " + req.Method + " " + req.URL.String())
+}
+
+func (h *harnessStubs) signalStub(name string) lua.LGFunction {
+	return func(l *lua.LState) int {
+		call := harnessCall{Function: name}
+		for i := 1; i <= l.GetTop(); i++ {
+			data, err := luajson.Encode(l.Get(i))
+			if err != nil {
+				call.Args = append(call.Args, l.Get(i).String())
+				continue
+			}
+			var arg interface{}
+			json.Unmarshal(data, &arg)
+			call.Args = append(call.Args, arg)
+		}
+		h.signalCalls = append(h.signalCalls, call)
+
+		stub, exists := h.testCase.Signal[name]
+		if !exists {
+			return pushError(l, errors.New("signal."+name+" is not stubbed"))
+		}
+		if stub.Error != "" {
+			return pushError(l, errors.New(stub.Error))
+		}
+		if stub.Response == nil {
+			return pushOk(l)
+		}
+		return pushValue(l, normalizeYamlValue(stub.Response))
+	}
+}
+
+// signalLoader loads the signal module and replaces all of its functions with
+// stubs.
+func (h *harnessStubs) signalLoader(l *lua.LState) int {
+	newSignalModule(nil, nil).Loader(l)
+	mod := l.Get(-1).(*lua.LTable)
+
+	names := []string{}
+	mod.ForEach(func(key lua.LValue, _ lua.LValue) {
+		names = append(names, key.String())
+	})
+	for _, name := range names {
+		mod.RawSetString(name, l.NewFunction(h.signalStub(name)))
+	}
+	return 1
+}
+
+func endpointPath(pluginConfig utils.PluginConfig, params map[string]string) string {
+	parts := strings.Split(strings.TrimPrefix(pluginConfig.Endpoint, "/"), "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = url.PathEscape(params[strings.TrimPrefix(part, ":")])
+		}
+	}
+	return "/v1/plugins/" + strings.Join(parts, "/")
+}
+
+func (p plugHandler) runEndpointTest(pluginConfig utils.PluginConfig, testCase harnessTestCase) []string {
+	failures := []string{}
+
+	method := testCase.Request.Method
+	if method == "" {
+		method = pluginConfig.Method
+	}
+
+	payload, err := stringOrJson(testCase.Request.Payload)
+	if err != nil {
+		return []string{"invalid payload: " + err.Error()}
+	}
+
+	router := gin.New()
+	router.Handle(pluginConfig.Method, "/v1/plugins/"+strings.TrimPrefix(pluginConfig.Endpoint, "/"), p.ExecutePlugin(pluginConfig))
+
+	req, err := http.NewRequest(method, endpointPath(pluginConfig, testCase.Request.Params), bytes.NewReader(payload))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	query := req.URL.Query()
+	for key, value := range testCase.Request.Query {
+		query.Set(key, value)
+	}
+	req.URL.RawQuery = query.Encode()
+	for key, value := range testCase.Request.Headers {
+		req.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	status := testCase.Expect.Status
+	if status == 0 {
+		status = 200
+	}
+	if w.Code != status {
+		failures = append(failures, fmt.Sprintf("expected status %d, got %d (%s)", status, w.Code, w.Body.String()))
+	}
+	if testCase.Expect.ContentType != "" && w.Header().Get("Content-Type") != testCase.Expect.ContentType {
+		failures = append(failures, "expected content type "+testCase.Expect.ContentType+", got "+w.Header().Get("Content-Type"))
+	}
+	for key, value := range testCase.Expect.Headers {
+		if w.Header().Get(key) != value {
+			failures = append(failures, "expected header "+key+": "+value+", got "+w.Header().Get(key))
+		}
+	}
+	if testCase.Expect.Body != nil && w.Body.String() != *testCase.Expect.Body {
+		failures = append(failures, "expected body "+*testCase.Expect.Body+", got "+w.Body.String())
+	}
+	if testCase.Expect.BodyContains != "" && !strings.Contains(w.Body.String(), testCase.Expect.BodyContains) {
+		failures = append(failures, "expected body to contain "+testCase.Expect.BodyContains+", got "+w.Body.String())
+	}
+	if testCase.Expect.Json != nil {
+		equal, err := jsonEqual(testCase.Expect.Json, w.Body.Bytes())
+		if err != nil || !equal {
+			failures = append(failures, "unexpected JSON response "+w.Body.String())
+		}
+	}
+	return failures
+}
+
+func checkError(expected string, err error) []string {
+	if expected == "" && err != nil {
+		return []string{"unexpected error: " + err.Error()}
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		return []string{fmt.Sprintf("expected error containing %q, got %v", expected, err)}
+	}
+	return []string{}
+}
+
+func (p plugHandler) runTestCase(pluginConfig utils.PluginConfig, testCase harnessTestCase, directory string) ([]string, *harnessStubs) {
+	stubs := &harnessStubs{testCase: testCase}
+	p.stubs = stubs
+
+	if pluginConfig.Version > 1 {
+		if err := p.InitPlugin(pluginConfig); err != nil {
+			return []string{"init failed: " + err.Error()}, stubs
+		}
+	}
+
+	failures := []string{}
+	if testCase.Request != nil {
+		failures = p.runEndpointTest(pluginConfig, testCase)
+	} else if testCase.Envelope != nil || testCase.EnvelopeFile != "" {
+		envelope, err := yamlToJson(testCase.Envelope)
+		if testCase.EnvelopeFile != "" {
+			envelope, err = os.ReadFile(filepath.Join(directory, testCase.EnvelopeFile))
+		}
+		if err != nil {
+			return []string{"invalid envelope: " + err.Error()}, stubs
+		}
+		failures = checkError(testCase.Expect.Error, p.OnMessage(pluginConfig, testCase.Account, envelope))
+	} else if testCase.SendRequest != nil {
+		request, err := yamlToJson(testCase.SendRequest)
+		if err != nil {
+			return []string{"invalid send request: " + err.Error()}, stubs
+		}
+		modifiedRequest, blockReason, err := p.PreSend(pluginConfig, testCase.Account, request)
+		failures = checkError(testCase.Expect.Error, err)
+		if blockReason != testCase.Expect.Blocked {
+			failures = append(failures, fmt.Sprintf("expected block reason %q, got %q", testCase.Expect.Blocked, blockReason))
+		}
+		if testCase.Expect.Json != nil {
+			if modifiedRequest == nil {
+				modifiedRequest = request
+			}
+			equal, err := jsonEqual(testCase.Expect.Json, modifiedRequest)
+			if err != nil || !equal {
+				failures = append(failures, "unexpected send request "+string(modifiedRequest))
+			}
+		}
+	} else if testCase.Scheduled {
+		failures = checkError(testCase.Expect.Error, p.ExecuteScheduledPlugin(pluginConfig))
+	} else {
+		return []string{"test case needs a request, envelope, send_request or needs to be scheduled"}, stubs
+	}
+
+	if testCase.Expect.SignalCalls != nil {
+		if len(testCase.Expect.SignalCalls) != len(stubs.signalCalls) {
+			failures = append(failures, fmt.Sprintf("expected %d signal calls, got %d", len(testCase.Expect.SignalCalls), len(stubs.signalCalls)))
+		} else {
+			for i, expectedCall := range testCase.Expect.SignalCalls {
+				call := stubs.signalCalls[i]
+				if call.Function != expectedCall.Function {
+					failures = append(failures, "expected call of signal."+expectedCall.Function+", got signal."+call.Function)
+					continue
+				}
+				if expectedCall.Args != nil {
+					actualArgs, _ := json.Marshal(call.Args)
+					if equal, err := jsonEqual(expectedCall.Args, actualArgs); err != nil || !equal {
+						failures = append(failures, "unexpected arguments for signal."+call.Function+": "+string(actualArgs))
+					}
+				}
+			}
+		}
+	}
+
+	if testCase.Expect.HttpCalls != nil {
+		expectedCalls, _ := json.Marshal(testCase.Expect.HttpCalls)
+		actualCalls, _ := json.Marshal(stubs.httpCalls)
+		if !bytes.Equal(expectedCalls, actualCalls) {
+			failures = append(failures, "unexpected HTTP requests: "+string(actualCalls))
+		}
+	}
+
+	return failures, stubs
+}
+
+// RunPluginTests runs the test cases of all plugins in the given directory and
+// writes the results to out. It returns the number of failed test cases.
+func (p plugHandler) RunPluginTests(directory string, out io.Writer) (int, error) {
+	gin.SetMode(gin.TestMode)
+
+	pluginConfigs := utils.NewPluginConfigs()
+	err := pluginConfigs.Load(directory)
+	if err != nil {
+		return 0, err
+	}
+
+	kvDirectory, err := os.MkdirTemp("", "plugin-test-kv")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(kvDirectory)
+	p.kvStore = utils.NewKeyValueStore(kvDirectory)
+
+	total := 0
+	failed := 0
+	for _, pluginConfig := range pluginConfigs.Configs {
+		testFilePath := strings.TrimSuffix(pluginConfig.ScriptPath, filepath.Ext(pluginConfig.ScriptPath)) + ".test.yml"
+		data, err := os.ReadFile(testFilePath)
+		if os.IsNotExist(err) {
+			fmt.Fprintln(out, "?   "+pluginConfig.Name()+" [no test file]")
+			continue
+		} else if err != nil {
+			return failed, err
+		}
+
+		var testFile harnessTestFile
+		err = yaml.Unmarshal(data, &testFile)
+		if err != nil {
+			return failed, errors.New("Couldn't parse " + testFilePath + ": " + err.Error())
+		}
+
+		for _, testCase := range testFile.Tests {
+			total++
+			failures, _ := p.runTestCase(pluginConfig, testCase, filepath.Dir(testFilePath))
+			if len(failures) == 0 {
+				fmt.Fprintln(out, "ok  "+pluginConfig.Name()+": "+testCase.Name)
+				continue
+			}
+
+			failed++
+			fmt.Fprintln(out, "FAIL "+pluginConfig.Name()+": "+testCase.Name)
+			for _, failure := range failures {
+				fmt.Fprintln(out, "     "+failure)
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d of %d tests passed\n", total-failed, total)
+	return failed, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	lua "github.com/yuin/gopher-lua"
+	luajson "layeh.com/gopher-json"
+)
+
+// kvModule implements the "kv" lua module, a persistent key/value store with a
+// separate namespace per plugin. Values can be anything that can be encoded as
+// JSON (strings, numbers, booleans and tables).
+type kvModule struct {
+	kvStore       *utils.KeyValueStore
+	namespaceName string
+}
+
+func newKvModule(kvStore *utils.KeyValueStore, pluginConfig utils.PluginConfig) *kvModule {
+	return &kvModule{kvStore: kvStore, namespaceName: pluginConfig.Id()}
+}
+
+func (m *kvModule) Loader(l *lua.LState) int {
+	mod := l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"get":    m.get,
+		"set":    m.set,
+		"delete": m.delete,
+		"list":   m.list,
+	})
+	l.Push(mod)
+	return 1
+}
+
+func (m *kvModule) namespace(l *lua.LState) *utils.KeyValueNamespace {
+	if m.kvStore == nil {
+		l.RaiseError("kv module is not available")
+		return nil
+	}
+
+	namespace, err := m.kvStore.Namespace(m.namespaceName)
+	if err != nil {
+		l.RaiseError("%s", err.Error())
+		return nil
+	}
+	return namespace
+}
+
+// kv.get(key) returns the value or nil, if the key doesn't exist
+func (m *kvModule) get(l *lua.LState) int {
+	namespace := m.namespace(l)
+	key := l.CheckString(1)
+
+	value, exists := namespace.Get(key)
+	if !exists {
+		l.Push(lua.LNil)
+		l.Push(lua.LNil)
+		return 2
+	}
+
+	luaValue, err := luajson.Decode(l, value)
+	if err != nil {
+		return pushError(l, err)
+	}
+	l.Push(luaValue)
+	l.Push(lua.LNil)
+	return 2
+}
+
+// kv.set(key, value [, ttl]) with the ttl in seconds
+func (m *kvModule) set(l *lua.LState) int {
+	namespace := m.namespace(l)
+	key := l.CheckString(1)
+	value := l.CheckAny(2)
+	ttl := l.OptNumber(3, 0)
+
+	if value == lua.LNil {
+		l.ArgError(2, "value expected")
+		return 0
+	}
+
+	data, err := luajson.Encode(value)
+	if err != nil {
+		return pushError(l, errors.New("Couldn't encode value: "+err.Error()))
+	}
+
+	err = namespace.Set(key, data, time.Duration(float64(ttl)*float64(time.Second)))
+	if err != nil {
+		return pushError(l, err)
+	}
+	return pushOk(l)
+}
+
+// kv.delete(key)
+func (m *kvModule) delete(l *lua.LState) int {
+	namespace := m.namespace(l)
+
+	err := namespace.Delete(l.CheckString(1))
+	if err != nil {
+		return pushError(l, err)
+	}
+	return pushOk(l)
+}
+
+// kv.list([prefix]) returns a table with all entries, whose key starts with the prefix
+func (m *kvModule) list(l *lua.LState) int {
+	namespace := m.namespace(l)
+
+	result := l.NewTable()
+	for key, value := range namespace.List(l.OptString(1, "")) {
+		luaValue, err := luajson.Decode(l, value)
+		if err != nil {
+			return pushError(l, err)
+		}
+		result.RawSetString(key, luaValue)
+	}
+	l.Push(result)
+	l.Push(lua.LNil)
+	return 2
+}
@@ -3,13 +3,13 @@ package main
 import (
 	"errors"
 	"io"
-	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	gluasql "github.com/bbernhard/gluasql"
 	"github.com/bbernhard/signal-cli-rest-api/api"
+	"github.com/bbernhard/signal-cli-rest-api/client"
 	"github.com/bbernhard/signal-cli-rest-api/utils"
-	"github.com/cjoudrey/gluahttp"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 	lua "github.com/yuin/gopher-lua"
@@ -20,12 +20,25 @@ import (
 type PluginInputData struct {
 	Params      map[string]string
 	QueryParams map[string]string
+	Headers     map[string]string
+	Method      string
 	Payload     string
 }
 
 type PluginOutputData struct {
 	payload        string
 	httpStatusCode int
+	headers        map[string]string
+	contentType    string
+	body           []byte
+}
+
+func newPluginOutputData() *PluginOutputData {
+	return &PluginOutputData{
+		payload:        "",
+		httpStatusCode: 200,
+		headers:        make(map[string]string),
+	}
 }
 
 func (p *PluginOutputData) SetPayload(payload string) {
@@ -44,25 +57,63 @@ func (p *PluginOutputData) HttpStatusCode() int {
 	return p.httpStatusCode
 }
 
-func execPluginV1(c *gin.Context, pluginConfig utils.PluginConfig) {
+// SetHeader sets a HTTP response header (e.g. "Location" for redirects).
+func (p *PluginOutputData) SetHeader(name string, value string) {
+	p.headers[name] = value
+}
+
+func (p *PluginOutputData) Headers() map[string]string {
+	return p.headers
+}
+
+// SetContentType sets the content type of the response (default:
+// application/json).
+func (p *PluginOutputData) SetContentType(contentType string) {
+	p.contentType = contentType
+}
+
+func (p *PluginOutputData) ContentType() string {
+	if p.contentType == "" {
+		return "application/json"
+	}
+	return p.contentType
+}
+
+// SetBody sets the raw response body. As lua strings can contain arbitrary
+// bytes, this can also be used for binary data (e.g. images). If a body is set,
+// it takes precedence over the payload.
+func (p *PluginOutputData) SetBody(body string) {
+	p.body = []byte(body)
+}
+
+func (p *PluginOutputData) Body() []byte {
+	if p.body != nil {
+		return p.body
+	}
+	return []byte(p.payload)
+}
+
+func (p *PluginOutputData) write(c *gin.Context) {
+	for name, value := range p.headers {
+		c.Header(name, value)
+	}
+	c.Data(p.HttpStatusCode(), p.ContentType(), p.Body())
+}
+
+func newPluginInputData(c *gin.Context, pluginConfig utils.PluginConfig) (*PluginInputData, error) {
 	jsonData, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(400, api.Error{Msg: "Couldn't process request - invalid input data"})
-		log.Error(err.Error())
-		return
+		return nil, err
 	}
 
 	pluginInputData := &PluginInputData{
 		Params:      make(map[string]string),
 		QueryParams: make(map[string]string),
+		Headers:     make(map[string]string),
+		Method:      c.Request.Method,
 		Payload:     string(jsonData),
 	}
 
-	pluginOutputData := &PluginOutputData{
-		payload:        "",
-		httpStatusCode: 200,
-	}
-
 	parts := strings.Split(pluginConfig.Endpoint, "/")
 	for _, part := range parts {
 		if strings.HasPrefix(part, ":") {
@@ -76,62 +127,53 @@ func execPluginV1(c *gin.Context, pluginConfig utils.PluginConfig) {
 		pluginInputData.QueryParams[key] = values[0]
 	}
 
-	l := lua.NewState()
-	l.SetGlobal("pluginInputData", luar.New(l, pluginInputData))
-	l.SetGlobal("pluginOutputData", luar.New(l, pluginOutputData))
-	l.PreloadModule("http", gluahttp.NewHttpModule(&http.Client{}).Loader)
-	luajson.Preload(l)
-	gluasql.Preload(l)
-	defer l.Close()
-	if err := l.DoFile(pluginConfig.ScriptPath); err != nil {
-		log.Error("Error executing lua script: ", err)
-		c.JSON(400, api.Error{Msg: err.Error()})
-		return
+	for key, values := range c.Request.Header {
+		pluginInputData.Headers[key] = strings.Join(values, ", ")
 	}
 
-	c.JSON(pluginOutputData.HttpStatusCode(), pluginOutputData.Payload())
+	return pluginInputData, nil
 }
 
-func execPluginV2(c *gin.Context, pluginConfig utils.PluginConfig) {
-	jsonData, err := io.ReadAll(c.Request.Body)
+func (p plugHandler) execPluginV1(c *gin.Context, pluginConfig utils.PluginConfig) {
+	pluginInputData, err := newPluginInputData(c, pluginConfig)
 	if err != nil {
 		c.JSON(400, api.Error{Msg: "Couldn't process request - invalid input data"})
 		log.Error(err.Error())
 		return
 	}
 
-	pluginInputData := &PluginInputData{
-		Params:      make(map[string]string),
-		QueryParams: make(map[string]string),
-		Payload:     string(jsonData),
-	}
+	pluginOutputData := newPluginOutputData()
 
-	pluginOutputData := &PluginOutputData{
-		payload:        "",
-		httpStatusCode: 200,
+	s := p.acquireState(pluginConfig)
+	defer s.release()
+	l := s.l
+	l.SetGlobal("pluginInputData", luar.New(l, pluginInputData))
+	l.SetGlobal("pluginOutputData", luar.New(l, pluginOutputData))
+	if err := s.run(); err != nil {
+		log.Error("Error executing lua script: ", err)
+		c.JSON(400, api.Error{Msg: err.Error()})
+		return
 	}
 
-	parts := strings.Split(pluginConfig.Endpoint, "/")
-	for _, part := range parts {
-		if strings.HasPrefix(part, ":") {
-			paramName := strings.TrimPrefix(part, ":")
-			pluginInputData.Params[paramName] = c.Param(paramName)
-		}
-	}
+	pluginOutputData.write(c)
+}
 
-	queryParams := c.Request.URL.Query()
-	for key, values := range queryParams {
-		pluginInputData.QueryParams[key] = values[0]
+func (p plugHandler) execPluginV2(c *gin.Context, pluginConfig utils.PluginConfig) {
+	pluginInputData, err := newPluginInputData(c, pluginConfig)
+	if err != nil {
+		c.JSON(400, api.Error{Msg: "Couldn't process request - invalid input data"})
+		log.Error(err.Error())
+		return
 	}
 
-	l := lua.NewState()
+	pluginOutputData := newPluginOutputData()
+
+	s := p.acquireState(pluginConfig)
+	defer s.release()
+	l := s.l
 	l.SetGlobal("pluginInputData", luar.New(l, pluginInputData))
 	l.SetGlobal("pluginOutputData", luar.New(l, pluginOutputData))
-	l.PreloadModule("http", gluahttp.NewHttpModule(&http.Client{}).Loader)
-	luajson.Preload(l)
-	gluasql.Preload(l)
-	defer l.Close()
-	if err := l.DoFile(pluginConfig.ScriptPath); err != nil {
+	if err := s.run(); err != nil {
 		log.Error("Error executing lua script: ", err)
 		c.JSON(400, api.Error{Msg: err.Error()})
 		return
@@ -142,7 +184,7 @@ func execPluginV2(c *gin.Context, pluginConfig utils.PluginConfig) {
 
 	// Check if it exists and is a function
 	if fn, ok := lv.(*lua.LFunction); ok {
-		err := l.CallByParam(lua.P{
+		err := s.call(lua.P{
 			Fn:      fn,
 			NRet:    1, // exec function returns one value
 			Protect: true,
@@ -160,12 +202,9 @@ func execPluginV2(c *gin.Context, pluginConfig utils.PluginConfig) {
 		if ret != lua.LNil {
 			log.Error("Couldn't execute plugin")
 			c.JSON(400, "Couldn't execute plugin")
+			return
 		}
-		c.Data(
-			pluginOutputData.HttpStatusCode(),
-			"application/json",
-			[]byte(pluginOutputData.Payload()),
-		)
+		pluginOutputData.write(c)
 	} else {
 		log.Error("Couldn't execute plugin. No exec function implemented!")
 		c.JSON(400, "Couldn't execute plugin. No exec function implemented!")
@@ -173,14 +212,23 @@ func execPluginV2(c *gin.Context, pluginConfig utils.PluginConfig) {
 }
 
 type plugHandler struct {
+	signalClient   *client.SignalClient
+	defaultTimeout time.Duration
+	kvStore        *utils.KeyValueStore
+	states         *pluginStatePool
+	stubs          *harnessStubs // only set by the plugin test harness
+}
+
+func (p *plugHandler) SetSignalClient(signalClient *client.SignalClient) {
+	p.signalClient = signalClient
 }
 
 func (p plugHandler) ExecutePlugin(pluginConfig utils.PluginConfig) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		if pluginConfig.Version == 1 {
-			execPluginV1(c, pluginConfig)
+			p.execPluginV1(c, pluginConfig)
 		} else {
-			execPluginV2(c, pluginConfig)
+			p.execPluginV2(c, pluginConfig)
 		}
 	}
 
@@ -188,12 +236,10 @@ func (p plugHandler) ExecutePlugin(pluginConfig utils.PluginConfig) gin.HandlerF
 }
 
 func (p plugHandler) InitPlugin(pluginConfig utils.PluginConfig) error {
-	l := lua.NewState()
-	l.PreloadModule("http", gluahttp.NewHttpModule(&http.Client{}).Loader)
-	luajson.Preload(l)
-	gluasql.Preload(l)
-	defer l.Close()
-	err := l.DoFile(pluginConfig.ScriptPath)
+	s := p.acquireState(pluginConfig)
+	defer s.release()
+	l := s.l
+	err := s.run()
 	if err != nil {
 		log.Error("Error executing lua script: ", err)
 	}
@@ -203,7 +249,7 @@ func (p plugHandler) InitPlugin(pluginConfig utils.PluginConfig) error {
 
 	// Check if it exists and is a function
 	if fn, ok := lv.(*lua.LFunction); ok {
-		err := l.CallByParam(lua.P{
+		err := s.call(lua.P{
 			Fn:      fn,
 			NRet:    2, // init function returns two values
 			Protect: true,
@@ -225,5 +271,183 @@ func (p plugHandler) InitPlugin(pluginConfig utils.PluginConfig) error {
 	return nil
 }
 
+func (p plugHandler) OnMessage(pluginConfig utils.PluginConfig, account string, envelope []byte) error {
+	s := p.acquireState(pluginConfig)
+	defer s.release()
+	l := s.l
+	err := s.run()
+	if err != nil {
+		return err
+	}
+
+	// Get global "on_message"
+	fn, ok := l.GetGlobal("on_message").(*lua.LFunction)
+	if !ok {
+		return errors.New("Couldn't execute plugin. No on_message function implemented!")
+	}
+
+	envelopeValue, err := luajson.Decode(l, envelope)
+	if err != nil {
+		return err
+	}
+
+	return s.call(lua.P{
+		Fn:      fn,
+		NRet:    0,
+		Protect: true,
+	}, envelopeValue, lua.LString(account))
+}
+
+// PreSend invokes the pre_send function of the plugin with the (JSON encoded)
+// send request. The pre_send function can return a modified request, which is
+// then returned JSON encoded (nil means that the request wasn't modified), or
+// block the message by returning nil and a reason.
+func (p plugHandler) PreSend(pluginConfig utils.PluginConfig, account string, request []byte) ([]byte, string, error) {
+	s := p.acquireState(pluginConfig)
+	defer s.release()
+	s.inSendHook = true
+	l := s.l
+	err := s.run()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Get global "pre_send"
+	fn, ok := l.GetGlobal("pre_send").(*lua.LFunction)
+	if !ok {
+		return nil, "", errors.New("Couldn't execute plugin. No pre_send function implemented!")
+	}
+
+	requestValue, err := luajson.Decode(l, request)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = s.call(lua.P{
+		Fn:      fn,
+		NRet:    2, // pre_send function returns two values
+		Protect: true,
+	}, requestValue, lua.LString(account))
+	if err != nil {
+		return nil, "", err
+	}
+
+	modifiedRequest := l.Get(-2)
+	blockReason := l.Get(-1)
+	l.Pop(2)
+
+	if blockReason != lua.LNil {
+		return nil, blockReason.String(), nil
+	}
+
+	if modifiedRequest == lua.LNil {
+		return nil, "", nil
+	}
+
+	if _, ok := modifiedRequest.(*lua.LTable); !ok {
+		return nil, "", errors.New("pre_send needs to return a table or nil")
+	}
+	modifiedRequestJson, err := luajson.Encode(modifiedRequest)
+	if err != nil {
+		return nil, "", err
+	}
+	return modifiedRequestJson, "", nil
+}
+
+// PostSend invokes the post_send function of the plugin with the (JSON encoded)
+// send request and response. In case sending failed, the response is nil and
+// the error message is passed instead.
+func (p plugHandler) PostSend(pluginConfig utils.PluginConfig, account string, request []byte, response []byte, sendError string) error {
+	s := p.acquireState(pluginConfig)
+	defer s.release()
+	s.inSendHook = true
+	l := s.l
+	err := s.run()
+	if err != nil {
+		return err
+	}
+
+	// Get global "post_send"
+	fn, ok := l.GetGlobal("post_send").(*lua.LFunction)
+	if !ok {
+		return errors.New("Couldn't execute plugin. No post_send function implemented!")
+	}
+
+	requestValue, err := luajson.Decode(l, request)
+	if err != nil {
+		return err
+	}
+
+	var responseValue lua.LValue = lua.LNil
+	if response != nil {
+		responseValue, err = luajson.Decode(l, response)
+		if err != nil {
+			return err
+		}
+	}
+
+	var sendErrorValue lua.LValue = lua.LNil
+	if sendError != "" {
+		sendErrorValue = lua.LString(sendError)
+	}
+
+	return s.call(lua.P{
+		Fn:      fn,
+		NRet:    0,
+		Protect: true,
+	}, requestValue, responseValue, sendErrorValue, lua.LString(account))
+}
+
+// ExecuteScheduledPlugin runs the exec function of a plugin that is triggered by
+// a schedule. As there is no HTTP request, the input data is empty and the
+// output data is discarded.
+func (p plugHandler) ExecuteScheduledPlugin(pluginConfig utils.PluginConfig) error {
+	pluginInputData := &PluginInputData{
+		Params:      make(map[string]string),
+		QueryParams: make(map[string]string),
+		Headers:     make(map[string]string),
+		Payload:     "",
+	}
+
+	pluginOutputData := newPluginOutputData()
+
+	s := p.acquireState(pluginConfig)
+	defer s.release()
+	l := s.l
+	l.SetGlobal("pluginInputData", luar.New(l, pluginInputData))
+	l.SetGlobal("pluginOutputData", luar.New(l, pluginOutputData))
+	err := s.run()
+	if err != nil {
+		return err
+	}
+
+	// Get global "exec"
+	fn, ok := l.GetGlobal("exec").(*lua.LFunction)
+	if !ok {
+		return errors.New("Couldn't execute plugin. No exec function implemented!")
+	}
+
+	err = s.call(lua.P{
+		Fn:      fn,
+		NRet:    1, // exec function returns one value
+		Protect: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	ret := l.Get(-1)
+	l.Pop(1)
+	if ret != lua.LNil {
+		return errors.New("Couldn't execute plugin: " + ret.String())
+	}
+
+	if pluginOutputData.HttpStatusCode() >= 400 {
+		return errors.New("Plugin returned status code " + strconv.Itoa(pluginOutputData.HttpStatusCode()) + ": " + pluginOutputData.Payload())
+	}
+
+	return nil
+}
+
 // exported
 var PluginHandler plugHandler
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gluasql "github.com/bbernhard/gluasql"
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	"github.com/cjoudrey/gluahttp"
+	lua "github.com/yuin/gopher-lua"
+	luajson "layeh.com/gopher-json"
+)
+
+var errInstructionLimitExceeded = errors.New("instruction limit exceeded")
+
+// instructionLimitContext aborts the lua script after the given number of
+// instructions. gopher-lua checks the context of the lua state before every
+// instruction, so every call to Done() corresponds to one executed instruction.
+type instructionLimitContext struct {
+	context.Context
+	remaining int64
+	exceeded  chan struct{}
+	once      sync.Once
+}
+
+func newInstructionLimitContext(parent context.Context, maxInstructions int64) *instructionLimitContext {
+	return &instructionLimitContext{
+		Context:   parent,
+		remaining: maxInstructions,
+		exceeded:  make(chan struct{}),
+	}
+}
+
+func (c *instructionLimitContext) Done() <-chan struct{} {
+	if atomic.AddInt64(&c.remaining, -1) < 0 {
+		c.once.Do(func() { close(c.exceeded) })
+		return c.exceeded
+	}
+	return c.Context.Done()
+}
+
+func (c *instructionLimitContext) Err() error {
+	select {
+	case <-c.exceeded:
+		return errInstructionLimitExceeded
+	default:
+		return c.Context.Err()
+	}
+}
+
+// newHttpClient creates the HTTP client for the http module, which only allows
+// requests (and redirects) to the allowed hosts of the plugin. The requests are
+// cancelled together with the context of the current call.
+func newHttpClient(callContext func() context.Context, pluginConfig utils.PluginConfig) func(req *http.Request) (*http.Response, error) {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !pluginConfig.HostAllowed(req.URL.Hostname()) {
+				return errors.New("Redirect to host " + req.URL.Hostname() + " is not allowed")
+			}
+			return nil
+		},
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+		if !pluginConfig.HostAllowed(req.URL.Hostname()) {
+			return nil, errors.New("Requests to host " + req.URL.Hostname() + " are not allowed")
+		}
+		return client.Do(req.WithContext(callContext()))
+	}
+}
+
+// newPluginState creates a new lua state for the plugin, which only has access
+// to the modules on the allowlist of the plugin. The limits of the plugin are
+// applied when a call begins (see pluginState.begin).
+func (p plugHandler) newPluginState(pluginConfig utils.PluginConfig) *pluginState {
+	options := lua.Options{
+		SkipOpenLibs:  true,
+		CallStackSize: pluginConfig.Limits.MaxCallDepth,
+	}
+	if maxStackSize := pluginConfig.Limits.MaxStackSize; maxStackSize > 0 {
+		if maxStackSize < lua.RegistrySize {
+			// the data stack doesn't grow by default, so just make it smaller
+			options.RegistrySize = maxStackSize
+		} else {
+			options.RegistryMaxSize = maxStackSize
+		}
+	}
+	l := lua.NewState(options)
+	s := &pluginState{l: l, config: pluginConfig, ctx: context.Background()}
+
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.LoadLibName, lua.OpenPackage},
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+		{lua.CoroutineLibName, lua.OpenCoroutine},
+		{lua.IoLibName, lua.OpenIo},
+		{lua.OsLibName, lua.OpenOs},
+		{lua.DebugLibName, lua.OpenDebug},
+		{lua.ChannelLibName, lua.OpenChannel},
+	} {
+		if utils.StringInSlice(lib.name, utils.PluginModules) && !pluginConfig.ModuleAllowed(lib.name) {
+			continue
+		}
+		l.Push(l.NewFunction(lib.fn))
+		l.Push(lua.LString(lib.name))
+		l.Call(1, 0)
+	}
+
+	if pluginConfig.ModuleAllowed("http") {
+		if p.stubs != nil {
+			l.PreloadModule("http", gluahttp.NewHttpModuleWithDo(p.stubs.doHttpRequest).Loader)
+		} else {
+			l.PreloadModule("http", gluahttp.NewHttpModuleWithDo(newHttpClient(s.context, pluginConfig)).Loader)
+		}
+	}
+	if pluginConfig.ModuleAllowed("signal") {
+		if p.stubs != nil {
+			l.PreloadModule("signal", p.stubs.signalLoader)
+		} else {
+			l.PreloadModule("signal", newSignalModule(p.signalClient, func() bool { return s.inSendHook }).Loader)
+		}
+	}
+	if pluginConfig.ModuleAllowed("kv") {
+		l.PreloadModule("kv", newKvModule(p.kvStore, pluginConfig).Loader)
+	}
+	if pluginConfig.ModuleAllowed("json") {
+		luajson.Preload(l)
+	}
+	if pluginConfig.ModuleAllowed("sqlite3") {
+		gluasql.Preload(l)
+	}
+
+	return s
+}
+
+func (p *plugHandler) SetKeyValueStore(kvStore *utils.KeyValueStore) {
+	p.kvStore = kvStore
+}
+
+func (p *plugHandler) SetDefaultTimeout(defaultTimeout time.Duration) {
+	p.defaultTimeout = defaultTimeout
+}
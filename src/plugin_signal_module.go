@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/bbernhard/signal-cli-rest-api/client"
+	lua "github.com/yuin/gopher-lua"
+	luajson "layeh.com/gopher-json"
+)
+
+// signalModule implements the "signal" lua module, which allows plugins to use
+// the SignalClient directly (instead of calling the REST API via HTTP).
+// In case of an error, the module functions return nil and the error message.
+// Messages that are sent from within a send hook (pre_send/post_send) don't run
+// the send hooks again, as that would invoke the hook recursively.
+type signalModule struct {
+	signalClient *client.SignalClient
+	inSendHook   func() bool
+}
+
+func newSignalModule(signalClient *client.SignalClient, inSendHook func() bool) *signalModule {
+	return &signalModule{signalClient: signalClient, inSendHook: inSendHook}
+}
+
+func (m *signalModule) Loader(l *lua.LState) int {
+	mod := l.SetFuncs(l.NewTable(), map[string]lua.LGFunction{
+		"send":              m.send,
+		"react":             m.react,
+		"send_receipt":      m.sendReceipt,
+		"get_groups":        m.getGroups,
+		"get_group":         m.getGroup,
+		"get_contacts":      m.getContacts,
+		"get_contact":       m.getContact,
+		"list_attachments":  m.listAttachments,
+		"get_attachment":    m.getAttachment,
+		"remove_attachment": m.removeAttachment,
+	})
+	l.Push(mod)
+	return 1
+}
+
+func (m *signalModule) available(l *lua.LState) bool {
+	if m.signalClient == nil {
+		l.RaiseError("signal module is not available")
+		return false
+	}
+	return true
+}
+
+func pushError(l *lua.LState, err error) int {
+	l.Push(lua.LNil)
+	l.Push(lua.LString(err.Error()))
+	return 2
+}
+
+func pushValue(l *lua.LState, value interface{}) int {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return pushError(l, err)
+	}
+
+	luaValue, err := luajson.Decode(l, data)
+	if err != nil {
+		return pushError(l, err)
+	}
+
+	l.Push(luaValue)
+	l.Push(lua.LNil)
+	return 2
+}
+
+func pushOk(l *lua.LState) int {
+	l.Push(lua.LTrue)
+	l.Push(lua.LNil)
+	return 2
+}
+
+func stringsFromLua(l *lua.LState, value lua.LValue) []string {
+	result := []string{}
+	switch v := value.(type) {
+	case lua.LString:
+		result = append(result, string(v))
+	case *lua.LTable:
+		v.ForEach(func(_ lua.LValue, entry lua.LValue) {
+			result = append(result, entry.String())
+		})
+	case *lua.LNilType:
+	default:
+		l.ArgError(2, "string or table expected")
+	}
+	return result
+}
+
+func optString(options *lua.LTable, key string) *string {
+	if options == nil {
+		return nil
+	}
+	if value, ok := options.RawGetString(key).(lua.LString); ok {
+		str := string(value)
+		return &str
+	}
+	return nil
+}
+
+func optInt64(options *lua.LTable, key string) *int64 {
+	if options == nil {
+		return nil
+	}
+	if value, ok := options.RawGetString(key).(lua.LNumber); ok {
+		number := int64(value)
+		return &number
+	}
+	return nil
+}
+
+func optBool(options *lua.LTable, key string) *bool {
+	if options == nil {
+		return nil
+	}
+	if value, ok := options.RawGetString(key).(lua.LBool); ok {
+		b := bool(value)
+		return &b
+	}
+	return nil
+}
+
+// signal.send(number, recipients, message [, options])
+func (m *signalModule) send(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	number := l.CheckString(1)
+	recipients := stringsFromLua(l, l.Get(2))
+	message := l.OptString(3, "")
+	options := l.OptTable(4, nil)
+
+	base64Attachments := []string{}
+	sticker := ""
+	if options != nil {
+		base64Attachments = stringsFromLua(l, options.RawGetString("base64_attachments"))
+		if value := optString(options, "sticker"); value != nil {
+			sticker = *value
+		}
+	}
+
+	sendV2 := m.signalClient.SendV2
+	if m.inSendHook != nil && m.inSendHook() {
+		sendV2 = m.signalClient.SendV2WithoutHooks
+	}
+	resp, err := sendV2(number, message, recipients, base64Attachments, sticker, nil,
+		optInt64(options, "quote_timestamp"), optString(options, "quote_author"), optString(options, "quote_message"), nil,
+		optString(options, "text_mode"), optInt64(options, "edit_timestamp"), optBool(options, "notify_self"), nil, optBool(options, "view_once"))
+	if err != nil {
+		return pushError(l, err)
+	}
+
+	if len(*resp) == 1 {
+		return pushValue(l, (*resp)[0])
+	}
+	return pushValue(l, *resp)
+}
+
+// signal.react(number, recipient, emoji, target_author, timestamp [, remove])
+func (m *signalModule) react(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	err := m.signalClient.SendReaction(l.CheckString(1), l.CheckString(2), l.CheckString(3), l.CheckString(4), l.CheckInt64(5), l.OptBool(6, false))
+	if err != nil {
+		return pushError(l, err)
+	}
+	return pushOk(l)
+}
+
+// signal.send_receipt(number, recipient, receipt_type, timestamp)
+func (m *signalModule) sendReceipt(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	receiptType := l.CheckString(3)
+	if receiptType != "read" && receiptType != "viewed" {
+		l.ArgError(3, "receipt_type needs to be either 'read' or 'viewed'")
+		return 0
+	}
+
+	err := m.signalClient.SendReceipt(l.CheckString(1), l.CheckString(2), receiptType, l.CheckInt64(4))
+	if err != nil {
+		return pushError(l, err)
+	}
+	return pushOk(l)
+}
+
+// signal.get_groups(number)
+func (m *signalModule) getGroups(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	groups, err := m.signalClient.GetGroups(l.CheckString(1))
+	if err != nil {
+		return pushError(l, err)
+	}
+	return pushValue(l, groups)
+}
+
+// signal.get_group(number, group_id)
+func (m *signalModule) getGroup(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	group, err := m.signalClient.GetGroup(l.CheckString(1), l.CheckString(2))
+	if err != nil {
+		return pushError(l, err)
+	}
+	if group == nil {
+		l.Push(lua.LNil)
+		l.Push(lua.LString("No group with that group id found"))
+		return 2
+	}
+	return pushValue(l, group)
+}
+
+// signal.get_contacts(number)
+func (m *signalModule) getContacts(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	contacts, err := m.signalClient.ListContacts(l.CheckString(1), false, "")
+	if err != nil {
+		return pushError(l, err)
+	}
+	return pushValue(l, contacts)
+}
+
+// signal.get_contact(number, uuid)
+func (m *signalModule) getContact(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	contacts, err := m.signalClient.ListContacts(l.CheckString(1), true, l.CheckString(2))
+	if err != nil {
+		return pushError(l, err)
+	}
+	if len(contacts) == 0 {
+		l.Push(lua.LNil)
+		l.Push(lua.LString("No contact with that uuid found"))
+		return 2
+	}
+	return pushValue(l, contacts[0])
+}
+
+// signal.list_attachments()
+func (m *signalModule) listAttachments(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	attachments, err := m.signalClient.GetAttachments()
+	if err != nil {
+		return pushError(l, err)
+	}
+	return pushValue(l, attachments)
+}
+
+// signal.get_attachment(id) returns the (binary) content of the attachment
+func (m *signalModule) getAttachment(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	attachment, err := m.signalClient.GetAttachment(l.CheckString(1))
+	if err != nil {
+		return pushError(l, err)
+	}
+	l.Push(lua.LString(string(attachment)))
+	l.Push(lua.LNil)
+	return 2
+}
+
+// signal.remove_attachment(id)
+func (m *signalModule) removeAttachment(l *lua.LState) int {
+	if !m.available(l) {
+		return 0
+	}
+
+	err := m.signalClient.RemoveAttachment(l.CheckString(1))
+	if err != nil {
+		return pushError(l, err)
+	}
+	return pushOk(l)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bbernhard/signal-cli-rest-api/client"
+	ds "github.com/bbernhard/signal-cli-rest-api/datastructs"
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func newTestSignalClient(t *testing.T) *client.SignalClient {
+	configDir := t.TempDir()
+	signalClient := client.NewSignalClient(configDir, t.TempDir(), t.TempDir(), client.Normal, "",
+		filepath.Join(configDir, "api-config.yml"), "")
+	if err := signalClient.Init(0); err != nil {
+		t.Fatal(err)
+	}
+	return signalClient
+}
+
+func TestSignalModuleNotAvailable(t *testing.T) {
+	l := lua.NewState()
+	defer l.Close()
+	l.PreloadModule("signal", newSignalModule(nil, nil).Loader)
+
+	err := l.DoString(`require("signal").send("+431212131491291", "+4354546464654", "Hello")`)
+	if err == nil || !strings.Contains(err.Error(), "signal module is not available") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSignalModuleArguments(t *testing.T) {
+	l := lua.NewState()
+	defer l.Close()
+
+	recipients := l.NewTable()
+	recipients.Append(lua.LString("+431"))
+	recipients.Append(lua.LString("group.abc"))
+	if result := stringsFromLua(l, recipients); len(result) != 2 || result[1] != "group.abc" {
+		t.Errorf("unexpected recipients %v", result)
+	}
+	if result := stringsFromLua(l, lua.LString("+431")); len(result) != 1 || result[0] != "+431" {
+		t.Errorf("unexpected recipients %v", result)
+	}
+	if result := stringsFromLua(l, lua.LNil); len(result) != 0 {
+		t.Errorf("unexpected recipients %v", result)
+	}
+
+	options := l.NewTable()
+	options.RawSetString("text_mode", lua.LString("styled"))
+	options.RawSetString("quote_timestamp", lua.LNumber(1700000000000))
+	options.RawSetString("notify_self", lua.LTrue)
+	if value := optString(options, "text_mode"); value == nil || *value != "styled" {
+		t.Errorf("unexpected text_mode %v", value)
+	}
+	if value := optInt64(options, "quote_timestamp"); value == nil || *value != 1700000000000 {
+		t.Errorf("unexpected quote_timestamp %v", value)
+	}
+	if value := optBool(options, "notify_self"); value == nil || !*value {
+		t.Errorf("unexpected notify_self %v", value)
+	}
+	if optString(options, "quote_author") != nil || optString(nil, "text_mode") != nil {
+		t.Errorf("expected missing options to be nil")
+	}
+}
+
+func TestSignalModuleSendFromSendHookSkipsHooks(t *testing.T) {
+	signalClient := newTestSignalClient(t)
+	hookCalls := 0
+	signalClient.AddPreSendHook(func(signalCliSendRequest *ds.SignalCliSendRequest) error {
+		hookCalls++
+		return errors.New("blocked by hook")
+	})
+
+	scriptPath := filepath.Join(t.TempDir(), "notify.lua")
+	script := `
+local signal = require("signal")
+
+function exec()
+	local _, err = signal.send("+431212131491291", "+4354546464654", "Hello")
+	sendError = err
+end
+
+function pre_send(request, account)
+	local _, err = signal.send(account, "+4354546464654", "A message was sent")
+	if err == "blocked by hook" then
+		error("the send hook was invoked recursively")
+	end
+	return nil
+end
+`
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := plugHandler{signalClient: signalClient}
+
+	// messages that are sent outside of a send hook run the hooks
+	pluginConfig := utils.PluginConfig{Schedule: "@every 1h", Version: 2, ScriptPath: scriptPath}
+	if err := p.ExecuteScheduledPlugin(pluginConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hookCalls != 1 {
+		t.Fatalf("expected the hook to be invoked once, got %d", hookCalls)
+	}
+
+	// messages that are sent from within a send hook don't
+	pluginConfig = utils.PluginConfig{Trigger: utils.PreSendPluginTrigger, Version: 2, ScriptPath: scriptPath}
+	_, _, err := p.PreSend(pluginConfig, "+431212131491291", []byte(`{"message": "Hello", "recipients": ["+4354546464654"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hookCalls != 1 {
+		t.Fatalf("expected the hook not to be invoked again, got %d calls", hookCalls)
+	}
+}
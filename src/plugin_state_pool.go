@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// pluginState is a lua state that executes a plugin. In case the plugin handler
+// has a state pool, the state is returned to the pool once the call is done
+// and the compiled script is shared between all states of the plugin.
+type pluginState struct {
+	l          *lua.LState
+	config     utils.PluginConfig
+	pool       *pluginStatePool
+	proto      *lua.FunctionProto
+	compileErr error
+	ctx        context.Context
+	cancel     context.CancelFunc
+	tables     map[*lua.LTable]*tableSnapshot
+	failed     bool
+	inSendHook bool
+}
+
+// context returns the context of the current call, which is used to cancel
+// the HTTP requests of the http module once the plugin times out.
+func (s *pluginState) context() context.Context {
+	return s.ctx
+}
+
+// begin prepares the lua state for a call and applies the limits of the plugin.
+func (s *pluginState) begin(defaultTimeout time.Duration) {
+	s.inSendHook = false
+	timeout := s.config.Timeout(defaultTimeout)
+	if timeout > 0 {
+		s.ctx, s.cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+
+	if s.config.Limits.MaxInstructions > 0 {
+		s.l.SetContext(newInstructionLimitContext(s.ctx, s.config.Limits.MaxInstructions))
+	} else if timeout > 0 {
+		s.l.SetContext(s.ctx)
+	}
+}
+
+// run executes the main chunk of the plugin script.
+func (s *pluginState) run() error {
+	var err error
+	if s.proto == nil && s.compileErr == nil {
+		err = s.l.DoFile(s.config.ScriptPath)
+	} else if s.compileErr != nil {
+		err = s.compileErr
+	} else {
+		s.l.Push(s.l.NewFunctionFromProto(s.proto))
+		err = s.l.PCall(0, lua.MultRet, nil)
+	}
+
+	if err != nil {
+		s.failed = true
+	}
+	return err
+}
+
+// call invokes a function of the plugin script.
+func (s *pluginState) call(cp lua.P, args ...lua.LValue) error {
+	err := s.l.CallByParam(cp, args...)
+	if err != nil {
+		s.failed = true
+	}
+	return err
+}
+
+// snapshot remembers the contents of all tables of a freshly created lua
+// state, i.e the globals, the loaded modules and the tables of the standard
+// libraries (including nested tables and the metatable of strings), so that
+// the state can be reset after every call.
+func (s *pluginState) snapshot() {
+	s.tables = make(map[*lua.LTable]*tableSnapshot)
+	snapshotTables(s.tables, s.l.G.Global)
+	snapshotTables(s.tables, s.loadedModules())
+	if stringMetatable, ok := s.l.GetMetatable(lua.LString("")).(*lua.LTable); ok {
+		snapshotTables(s.tables, stringMetatable)
+	}
+}
+
+func (s *pluginState) loadedModules() *lua.LTable {
+	loaded, _ := s.l.GetField(s.l.Get(lua.RegistryIndex), "_LOADED").(*lua.LTable)
+	return loaded
+}
+
+// reset removes everything a call left behind: globals that were added or
+// changed by the script, changes to the tables of the standard libraries (e.g.
+// string.foo) and modules that were loaded via require. As the modules are
+// loaded again by the next call, changes to their tables are dropped as well.
+func (s *pluginState) reset() {
+	s.l.SetTop(0)
+	for table, snapshot := range s.tables {
+		snapshot.restore(table)
+	}
+	s.failed = false
+}
+
+// release ends the call. The lua state is returned to the pool, unless the call
+// failed (e.g. because it exceeded one of the limits) or the pool is full.
+func (s *pluginState) release() {
+	s.cancel()
+	s.l.RemoveContext()
+
+	if s.pool == nil || s.failed {
+		s.l.Close()
+		return
+	}
+
+	s.reset()
+	s.pool.put(s)
+}
+
+type tableSnapshot struct {
+	fields    map[lua.LValue]lua.LValue
+	metatable lua.LValue
+}
+
+// snapshotTables remembers the contents of the table and of all tables that
+// can be reached from it.
+func snapshotTables(snapshots map[*lua.LTable]*tableSnapshot, table *lua.LTable) {
+	if table == nil {
+		return
+	}
+	if _, exists := snapshots[table]; exists {
+		return
+	}
+
+	snapshot := &tableSnapshot{fields: make(map[lua.LValue]lua.LValue), metatable: table.Metatable}
+	snapshots[table] = snapshot
+	table.ForEach(func(key lua.LValue, value lua.LValue) {
+		snapshot.fields[key] = value
+	})
+	for _, value := range snapshot.fields {
+		if nestedTable, ok := value.(*lua.LTable); ok {
+			snapshotTables(snapshots, nestedTable)
+		}
+	}
+	if metatable, ok := table.Metatable.(*lua.LTable); ok {
+		snapshotTables(snapshots, metatable)
+	}
+}
+
+func (snapshot *tableSnapshot) restore(table *lua.LTable) {
+	keys := []lua.LValue{}
+	table.ForEach(func(key lua.LValue, value lua.LValue) {
+		if snapshotValue, exists := snapshot.fields[key]; !exists || snapshotValue != value {
+			keys = append(keys, key)
+		}
+	})
+	for _, key := range keys {
+		table.RawSet(key, lua.LNil)
+	}
+	for key, value := range snapshot.fields {
+		table.RawSet(key, value)
+	}
+	table.Metatable = snapshot.metatable
+}
+
+type compiledScript struct {
+	modTime time.Time
+	size    int64
+	proto   *lua.FunctionProto
+	err     error
+}
+
+type idlePluginStates struct {
+	config utils.PluginConfig
+	states chan *pluginState
+}
+
+// pluginStatePool caches the compiled plugin scripts and keeps up to size idle
+// lua states per plugin, which saves creating a new lua state, loading the
+// modules and parsing the script on every call.
+type pluginStatePool struct {
+	size    int
+	mutex   sync.Mutex
+	scripts map[string]compiledScript
+	idle    map[string]*idlePluginStates
+}
+
+func newPluginStatePool(size int) *pluginStatePool {
+	return &pluginStatePool{
+		size:    size,
+		scripts: make(map[string]compiledScript),
+		idle:    make(map[string]*idlePluginStates),
+	}
+}
+
+// compile returns the compiled plugin script. The script is compiled again
+// whenever it changes.
+func (p *pluginStatePool) compile(scriptPath string) (*lua.FunctionProto, error) {
+	info, err := os.Stat(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	script, exists := p.scripts[scriptPath]
+	p.mutex.Unlock()
+	if exists && script.modTime.Equal(info.ModTime()) && script.size == info.Size() {
+		return script.proto, script.err
+	}
+
+	script = compiledScript{modTime: info.ModTime(), size: info.Size()}
+	script.proto, script.err = compileScript(scriptPath)
+
+	p.mutex.Lock()
+	p.scripts[scriptPath] = script
+	p.mutex.Unlock()
+	return script.proto, script.err
+}
+
+func compileScript(scriptPath string) (*lua.FunctionProto, error) {
+	file, err := os.Open(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	chunk, err := parse.Parse(bufio.NewReader(file), scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	return lua.Compile(chunk, scriptPath)
+}
+
+// get returns an idle lua state of the plugin (or nil). Idle states that were
+// created with a different plugin configuration are discarded.
+func (p *pluginStatePool) get(pluginConfig utils.PluginConfig) *pluginState {
+	p.mutex.Lock()
+	idle, exists := p.idle[pluginConfig.ScriptPath]
+	if !exists || !reflect.DeepEqual(idle.config, pluginConfig) {
+		if exists {
+			close(idle.states)
+			for s := range idle.states {
+				s.l.Close()
+			}
+		}
+		idle = &idlePluginStates{config: pluginConfig, states: make(chan *pluginState, p.size)}
+		p.idle[pluginConfig.ScriptPath] = idle
+	}
+	p.mutex.Unlock()
+
+	select {
+	case s := <-idle.states:
+		return s
+	default:
+		return nil
+	}
+}
+
+func (p *pluginStatePool) put(s *pluginState) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	idle, exists := p.idle[s.config.ScriptPath]
+	if exists && reflect.DeepEqual(idle.config, s.config) {
+		select {
+		case idle.states <- s:
+			return
+		default:
+		}
+	}
+	s.l.Close()
+}
+
+// acquireState returns a lua state for a call of the plugin. The returned state
+// needs to be released once the call is done.
+func (p plugHandler) acquireState(pluginConfig utils.PluginConfig) *pluginState {
+	if p.states == nil || p.stubs != nil {
+		s := p.newPluginState(pluginConfig)
+		s.begin(p.defaultTimeout)
+		return s
+	}
+
+	proto, err := p.states.compile(pluginConfig.ScriptPath)
+	s := p.states.get(pluginConfig)
+	if s == nil {
+		s = p.newPluginState(pluginConfig)
+		s.snapshot()
+		s.pool = p.states
+	}
+	s.proto = proto
+	s.compileErr = err
+	s.begin(p.defaultTimeout)
+	return s
+}
+
+func (p *plugHandler) SetStatePoolSize(size int) {
+	if size > 0 {
+		p.states = newPluginStatePool(size)
+	} else {
+		p.states = nil
+	}
+}
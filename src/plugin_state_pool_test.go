@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bbernhard/signal-cli-rest-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const benchmarkPluginScript = `
+local json = require("json")
+
+function exec()
+	local payload = json.decode(pluginInputData.payload)
+	pluginOutputData:SetPayload(json.encode({number = pluginInputData.Params.number, message = payload.message}))
+	pluginOutputData:SetHttpStatusCode(201)
+end
+`
+
+func newBenchmarkPlugin(b *testing.B) utils.PluginConfig {
+	scriptPath := filepath.Join(b.TempDir(), "webhook.lua")
+	if err := os.WriteFile(scriptPath, []byte(benchmarkPluginScript), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return utils.PluginConfig{Endpoint: "/webhook/:number", Method: "POST", Version: 2, ScriptPath: scriptPath}
+}
+
+func TestStatePoolResetsState(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "counter.lua")
+	script := `
+counter = (counter or 0) + 1
+if package.loaded["json"] ~= nil then
+	error("json module was loaded by a previous call")
+end
+local json = require("json")
+
+function exec()
+	if pluginInputData.payload == "fail" then
+		error("failed")
+	end
+	pluginOutputData:SetPayload(json.encode({counter = counter}))
+end
+`
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pluginConfig := utils.PluginConfig{Endpoint: "/counter", Method: "POST", Version: 2, ScriptPath: scriptPath}
+
+	p := plugHandler{}
+	p.SetStatePoolSize(1)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/plugins/counter", p.ExecutePlugin(pluginConfig))
+
+	for _, payload := range []string{"", "fail", "", ""} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/plugins/counter", bytes.NewReader([]byte(payload)))
+		router.ServeHTTP(w, req)
+		if payload == "fail" {
+			if w.Code != 400 {
+				t.Fatalf("expected the plugin to fail, got %d", w.Code)
+			}
+			continue
+		}
+		if w.Code != 200 || w.Body.String() != `{"counter":1}` {
+			t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestStatePoolResetsStandardLibraries(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "modify.lua")
+	script := `
+local json = require("json")
+if string.foo ~= nil or json.foo ~= nil or package.path == "modified" or getmetatable(_G) ~= nil or ("x"):upper() ~= "X" then
+	error("the standard libraries were modified by a previous call")
+end
+
+function exec()
+	string.foo = "bar"
+	getmetatable("").__index = {upper = function() return "modified" end}
+	json.foo = "bar"
+	package.path = "modified"
+	setmetatable(_G, {})
+end
+`
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pluginConfig := utils.PluginConfig{Endpoint: "/modify", Method: "POST", Version: 2, ScriptPath: scriptPath}
+
+	p := plugHandler{}
+	p.SetStatePoolSize(1)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/plugins/modify", p.ExecutePlugin(pluginConfig))
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v1/plugins/modify", bytes.NewReader(nil))
+		router.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+func benchmarkExecutePlugin(b *testing.B, statePoolSize int) {
+	gin.SetMode(gin.TestMode)
+	pluginConfig := newBenchmarkPlugin(b)
+
+	p := plugHandler{}
+	p.SetStatePoolSize(statePoolSize)
+	router := gin.New()
+	router.POST("/v1/plugins/webhook/:number", p.ExecutePlugin(pluginConfig))
+
+	payload := []byte(`{"message": "Hello World"}`)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/v1/plugins/webhook/+431", bytes.NewReader(payload))
+			router.ServeHTTP(w, req)
+			if w.Code != 201 {
+				b.Fatal("unexpected response: ", w.Body.String())
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "req/s")
+}
+
+func BenchmarkExecutePluginWithoutStatePool(b *testing.B) {
+	benchmarkExecutePlugin(b, 0)
+}
+
+func BenchmarkExecutePluginWithStatePool(b *testing.B) {
+	benchmarkExecutePlugin(b, 4)
+}
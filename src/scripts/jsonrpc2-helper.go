@@ -37,6 +37,11 @@ func main() {
 		}
 	}
 
+	settings, err := utils.LoadSettings(utils.GetEnv("CONFIG_FILE", ""))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
 	jsonRpc2ClientConfig := utils.NewJsonRpc2ClientConfig()
 
 	var tcpPort int64 = 6001
@@ -44,60 +49,48 @@ func main() {
 	jsonRpc2ClientConfig.AddEntry(utils.MULTI_ACCOUNT_NUMBER, utils.JsonRpc2ClientConfigEntry{TcpPort: tcpPort})
 
 	signalCliBinary := "signal-cli"
-	signalMode := utils.GetEnv("MODE", "json-rpc")
-	if signalMode == "json-rpc-native" {
+	if settings.Mode == "json-rpc-native" {
 		signalCliBinary = "signal-cli-native"
-	} else if signalMode != "json-rpc" {
+	} else if settings.Mode != "json-rpc" {
 		log.Fatal("The mode needs to be either 'json-rpc' or 'json-rpc-native'")
 	}
 
 	signalCliIgnoreAttachments := ""
-	ignoreAttachments := utils.GetEnv("JSON_RPC_IGNORE_ATTACHMENTS", "")
-	if ignoreAttachments == "true" {
+	if settings.JsonRpc.IgnoreAttachments {
 		signalCliIgnoreAttachments = " --ignore-attachments"
 	}
 
 	signalCliIgnoreStories := ""
-	ignoreStories := utils.GetEnv("JSON_RPC_IGNORE_STORIES", "")
-	if ignoreStories == "true" {
+	if settings.JsonRpc.IgnoreStories {
 		signalCliIgnoreStories = " --ignore-stories"
 	}
 
 	signalCliIgnoreAvatars := ""
-	ignoreAvatars := utils.GetEnv("JSON_RPC_IGNORE_AVATARS", "")
-	if ignoreAvatars == "true" {
+	if settings.JsonRpc.IgnoreAvatars {
 		signalCliIgnoreAvatars = " --ignore-avatars"
 	}
 
 	signalCliIgnoreStickers := ""
-	ignoreStickers := utils.GetEnv("JSON_RPC_IGNORE_STICKERS", "")
-	if ignoreStickers == "true" {
+	if settings.JsonRpc.IgnoreStickers {
 		signalCliIgnoreStickers = " --ignore-stickers"
 	}
 
-	supervisorctlProgramName := "signal-cli-json-rpc-1"
+	supervisorctlProgramName := utils.SIGNAL_CLI_DAEMON_PROGRAM
 	supervisorctlLogFolder := "/var/log/" + supervisorctlProgramName
-	_, err := exec.Command("mkdir", "-p", supervisorctlLogFolder).Output()
+	_, err = exec.Command("mkdir", "-p", supervisorctlLogFolder).Output()
 	if err != nil {
 		log.Fatal("Couldn't create log folder ", supervisorctlLogFolder, ": ", err.Error())
 	}
 
 	trustNewIdentities := ""
-	trustNewIdentitiesEnv := utils.GetEnv("JSON_RPC_TRUST_NEW_IDENTITIES", "")
-	if trustNewIdentitiesEnv == "on-first-use" {
-		trustNewIdentities = " --trust-new-identities on-first-use"
-	} else if trustNewIdentitiesEnv == "always" {
-		trustNewIdentities = " --trust-new-identities always"
-	} else if trustNewIdentitiesEnv == "never" {
-		trustNewIdentities = " --trust-new-identities never"
-	} else if trustNewIdentitiesEnv != "" {
-		log.Fatal("Invalid JSON_RPC_TRUST_NEW_IDENTITIES environment variable set!")
+	if settings.JsonRpc.TrustNewIdentities != "" {
+		trustNewIdentities = " --trust-new-identities " + settings.JsonRpc.TrustNewIdentities
 	}
 
 	log.Info("Updated jsonrpc2.yml")
 
 	//write supervisorctl config
-	supervisorctlConfigFilename := "/etc/supervisor/conf.d/" + "signal-cli-json-rpc-1.conf"
+	supervisorctlConfigFilename := "/etc/supervisor/conf.d/" + supervisorctlProgramName + ".conf"
 
 	supervisorctlConfig := fmt.Sprintf(supervisorctlConfigTemplate, supervisorctlProgramName, supervisorctlProgramName, signalCliBinary,
 		signalCliConfigDir, trustNewIdentities, signalCliIgnoreAttachments, signalCliIgnoreStories,
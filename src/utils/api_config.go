@@ -4,7 +4,9 @@ import (
 	"errors"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"sync"
 )
 
 type SignalCliTrustMode int
@@ -39,15 +41,76 @@ func StringToTrustMode(trustMode string) (SignalCliTrustMode, error) {
 
 type SignalCliApiConfigEntry struct {
 	TrustMode SignalCliTrustMode `yaml:"trust_mode"`
+	Settings  AccountSettings    `yaml:"settings,omitempty"`
+}
+
+// AccountSettings contains the defaults of an account. They are used whenever a
+// request doesn't specify the value explicitly. Settings that are not set (nil)
+// fall back to the global settings.
+type AccountSettings struct {
+	TextMode            *string `yaml:"text_mode,omitempty" json:"text_mode,omitempty" enums:"normal,styled"`
+	NotifySelf          *bool   `yaml:"notify_self,omitempty" json:"notify_self,omitempty"`
+	ExpirationInSeconds *int    `yaml:"expiration_in_seconds,omitempty" json:"expiration_in_seconds,omitempty"`
+	ReceiveWebhookUrl   *string `yaml:"receive_webhook_url,omitempty" json:"receive_webhook_url,omitempty"`
+	SendReadReceipts    *bool   `yaml:"send_read_receipts,omitempty" json:"send_read_receipts,omitempty"`
+	Signature           *string `yaml:"signature,omitempty" json:"signature,omitempty"`
+}
+
+func (s AccountSettings) validate() []string {
+	problems := []string{}
+	if s.TextMode != nil && !StringInSlice(*s.TextMode, []string{"normal", "styled"}) {
+		problems = append(problems, "text_mode: needs to be either normal or styled")
+	}
+	if s.ExpirationInSeconds != nil && *s.ExpirationInSeconds < 0 {
+		problems = append(problems, "expiration_in_seconds: needs to be a positive number (in seconds)")
+	}
+	if s.ReceiveWebhookUrl != nil && *s.ReceiveWebhookUrl != "" {
+		if _, err := url.ParseRequestURI(*s.ReceiveWebhookUrl); err != nil {
+			problems = append(problems, "receive_webhook_url: invalid URL")
+		}
+	}
+	return problems
+}
+
+// SettingsOverrides contains the settings that were changed via the REST API.
+// Settings that are not set (nil) aren't overridden.
+type SettingsOverrides struct {
+	LogLevel                *string                      `yaml:"log_level,omitempty" json:"log_level,omitempty"`
+	ReceiveWebhookUrl       *string                      `yaml:"receive_webhook_url,omitempty" json:"receive_webhook_url,omitempty"`
+	DefaultSignalTextMode   *string                      `yaml:"default_signal_text_mode,omitempty" json:"default_signal_text_mode,omitempty"`
+	AutoReceiveSchedule     *AutoReceiveScheduleSettings `yaml:"auto_receive_schedule,omitempty" json:"auto_receive_schedule,omitempty"`
+	AttachmentRetentionDays *int                         `yaml:"attachment_retention_days,omitempty" json:"attachment_retention_days,omitempty"`
+}
+
+// reset removes the override of the given setting. It returns false, in case
+// the setting can't be changed at runtime.
+func (o *SettingsOverrides) reset(key string) bool {
+	switch key {
+	case "log_level":
+		o.LogLevel = nil
+	case "receive_webhook_url":
+		o.ReceiveWebhookUrl = nil
+	case "default_signal_text_mode":
+		o.DefaultSignalTextMode = nil
+	case "auto_receive_schedule":
+		o.AutoReceiveSchedule = nil
+	case "attachment_retention_days":
+		o.AttachmentRetentionDays = nil
+	default:
+		return false
+	}
+	return true
 }
 
 type SignalCliApiConfigEntries struct {
-	Entries map[string]SignalCliApiConfigEntry `yaml:"config,omitempty"`
+	Entries  map[string]SignalCliApiConfigEntry `yaml:"config,omitempty"`
+	Settings SettingsOverrides                  `yaml:"settings,omitempty"`
 }
 
 type SignalCliApiConfig struct {
 	config SignalCliApiConfigEntries
 	path   string
+	mutex  sync.RWMutex
 }
 
 func NewSignalCliApiConfig() *SignalCliApiConfig {
@@ -55,6 +118,9 @@ func NewSignalCliApiConfig() *SignalCliApiConfig {
 }
 
 func (c *SignalCliApiConfig) Load(path string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	c.path = path
 	if _, err := os.Stat(path); err == nil {
 		data, err := ioutil.ReadFile(path)
@@ -62,16 +128,21 @@ func (c *SignalCliApiConfig) Load(path string) error {
 			return err
 		}
 
-		err = yaml.Unmarshal(data, &c.config)
+		var config SignalCliApiConfigEntries
+		err = yaml.Unmarshal(data, &config)
 		if err != nil {
 			return err
 		}
+		c.config = config
 	}
 
 	return nil
 }
 
 func (c *SignalCliApiConfig) GetTrustModeForNumber(number string) (SignalCliTrustMode, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	if val, ok := c.config.Entries[number]; ok {
 		return val.TrustMode, nil
 	}
@@ -79,18 +150,148 @@ func (c *SignalCliApiConfig) GetTrustModeForNumber(number string) (SignalCliTrus
 	return NeverTrust, errors.New("Number " + number + " not found in local map")
 }
 
+// GetTrustModes returns the trust modes of all numbers that have a trust mode set.
+func (c *SignalCliApiConfig) GetTrustModes() map[string]SignalCliTrustMode {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	trustModes := make(map[string]SignalCliTrustMode)
+	for number, entry := range c.config.Entries {
+		trustModes[number] = entry.TrustMode
+	}
+	return trustModes
+}
+
 func (c *SignalCliApiConfig) SetTrustModeForNumber(number string, trustMode SignalCliTrustMode) {
-	if c.config.Entries == nil {
-		c.config.Entries = make(map[string]SignalCliApiConfigEntry)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.config.setTrustModeForNumber(number, trustMode)
+}
+
+func (c *SignalCliApiConfigEntries) setTrustModeForNumber(number string, trustMode SignalCliTrustMode) {
+	if c.Entries == nil {
+		c.Entries = make(map[string]SignalCliApiConfigEntry)
+	}
+	entry := c.Entries[number]
+	entry.TrustMode = trustMode
+	c.Entries[number] = entry
+}
+
+// UpdateTrustModeForNumber changes the trust mode of the given account and
+// persists the config. Only the entry of the account is changed, so that
+// concurrent changes of the settings overrides aren't overwritten.
+func (c *SignalCliApiConfig) UpdateTrustModeForNumber(number string, trustMode SignalCliTrustMode) error {
+	return c.updateEntry(number, func(entry *SignalCliApiConfigEntry) {
+		entry.TrustMode = trustMode
+	})
+}
+
+// GetAccountSettings returns the settings of the given account (the settings are
+// empty in case nothing was set for the account).
+func (c *SignalCliApiConfig) GetAccountSettings(number string) AccountSettings {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.config.Entries[number].Settings
+}
+
+// SetAccountSettings replaces the settings of the given account and changes its
+// trust mode (in case it is set). The settings are validated and persisted
+// together with the trust mode first - in case something is invalid or the
+// config can't be persisted, nothing is changed.
+func (c *SignalCliApiConfig) SetAccountSettings(number string, settings AccountSettings, trustMode *SignalCliTrustMode) error {
+	if problems := settings.validate(); len(problems) > 0 {
+		return &SettingsError{Problems: problems}
+	}
+
+	return c.updateEntry(number, func(entry *SignalCliApiConfigEntry) {
+		entry.Settings = settings
+		if trustMode != nil {
+			entry.TrustMode = *trustMode
+		}
+	})
+}
+
+// GetEntry returns the config entry (trust mode and settings) of the given account.
+func (c *SignalCliApiConfig) GetEntry(number string) (SignalCliApiConfigEntry, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	entry, exists := c.config.Entries[number]
+	return entry, exists
+}
+
+// SetEntry replaces the config entry of the given account and persists the config.
+func (c *SignalCliApiConfig) SetEntry(number string, entry SignalCliApiConfigEntry) error {
+	return c.updateEntry(number, func(e *SignalCliApiConfigEntry) {
+		*e = entry
+	})
+}
+
+func (c *SignalCliApiConfig) updateEntry(number string, change func(entry *SignalCliApiConfigEntry)) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	config := SignalCliApiConfigEntries{Entries: make(map[string]SignalCliApiConfigEntry), Settings: c.config.Settings}
+	for n, entry := range c.config.Entries {
+		config.Entries[n] = entry
+	}
+	entry := config.Entries[number]
+	change(&entry)
+	config.Entries[number] = entry
+
+	err := c.persist(config)
+	if err != nil {
+		return err
+	}
+	c.config = config
+	return nil
+}
+
+func (c *SignalCliApiConfig) GetSettingsOverrides() SettingsOverrides {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.config.Settings
+}
+
+// Update changes the settings overrides and trust modes and persists them. In
+// case the config can't be persisted, nothing is changed.
+func (c *SignalCliApiConfig) Update(overrides SettingsOverrides, trustModes map[string]SignalCliTrustMode) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	config := SignalCliApiConfigEntries{Entries: make(map[string]SignalCliApiConfigEntry), Settings: overrides}
+	for number, entry := range c.config.Entries {
+		config.Entries[number] = entry
 	}
-	c.config.Entries[number] = SignalCliApiConfigEntry{TrustMode: trustMode}
+	for number, trustMode := range trustModes {
+		config.setTrustModeForNumber(number, trustMode)
+	}
+
+	err := c.persist(config)
+	if err != nil {
+		return err
+	}
+	c.config = config
+	return nil
 }
 
 func (c *SignalCliApiConfig) Persist() error {
-	out, err := yaml.Marshal(&c.config)
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.persist(c.config)
+}
+
+// persist writes the config to a temporary file first and replaces the config
+// file afterwards, so that the config file is never written partially.
+func (c *SignalCliApiConfig) persist(config SignalCliApiConfigEntries) error {
+	out, err := yaml.Marshal(&config)
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(c.path, out, 0644)
+	tmpPath := c.path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, out, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, c.path)
 }
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSetAccountSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-config.yml")
+	apiConfig := NewSignalCliApiConfig()
+	expectEqual(t, apiConfig.Load(path) == nil, true)
+
+	textMode := "styled"
+	signature := "-- sent by the REST API"
+	err := apiConfig.SetAccountSettings("+431212131491291", AccountSettings{TextMode: &textMode, Signature: &signature}, nil)
+	expectEqual(t, err == nil, true)
+
+	invalidTextMode := "bold"
+	invalidExpiration := -1
+	trustMode := AlwaysTrust
+	err = apiConfig.SetAccountSettings("+431212131491291", AccountSettings{TextMode: &invalidTextMode, ExpirationInSeconds: &invalidExpiration}, &trustMode)
+	settingsError, isSettingsError := err.(*SettingsError)
+	expectEqual(t, isSettingsError && len(settingsError.Problems) == 2, true)
+	// the trust mode isn't changed either, as the settings are invalid
+	storedTrustMode, _ := apiConfig.GetTrustModeForNumber("+431212131491291")
+	expectEqual(t, storedTrustMode == OnFirstUseTrust, true)
+
+	reloadedApiConfig := NewSignalCliApiConfig()
+	expectEqual(t, reloadedApiConfig.Load(path) == nil, true)
+	accountSettings := reloadedApiConfig.GetAccountSettings("+431212131491291")
+	expectEqual(t, *accountSettings.TextMode == "styled" && *accountSettings.Signature == signature, true)
+	expectEqual(t, accountSettings.NotifySelf == nil, true)
+	expectEqual(t, reloadedApiConfig.GetAccountSettings("+431212131491292").TextMode == nil, true)
+
+	err = reloadedApiConfig.SetAccountSettings("+431212131491291", AccountSettings{}, &trustMode)
+	expectEqual(t, err == nil, true)
+	storedTrustMode, err = reloadedApiConfig.GetTrustModeForNumber("+431212131491291")
+	expectEqual(t, err == nil && storedTrustMode == AlwaysTrust, true)
+	expectEqual(t, reloadedApiConfig.GetAccountSettings("+431212131491291").TextMode == nil, true)
+}
+
+func TestUpdateTrustModeForNumberKeepsSettingsOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-config.yml")
+	apiConfig := NewSignalCliApiConfig()
+	expectEqual(t, apiConfig.Load(path) == nil, true)
+
+	logLevel := "debug"
+	expectEqual(t, apiConfig.Update(SettingsOverrides{LogLevel: &logLevel}, nil) == nil, true)
+	expectEqual(t, apiConfig.UpdateTrustModeForNumber("+431212131491291", AlwaysTrust) == nil, true)
+
+	reloadedApiConfig := NewSignalCliApiConfig()
+	expectEqual(t, reloadedApiConfig.Load(path) == nil, true)
+	trustMode, err := reloadedApiConfig.GetTrustModeForNumber("+431212131491291")
+	expectEqual(t, err == nil && trustMode == AlwaysTrust, true)
+	overrides := reloadedApiConfig.GetSettingsOverrides()
+	expectEqual(t, overrides.LogLevel != nil && *overrides.LogLevel == "debug", true)
+}
@@ -9,6 +9,10 @@ import (
 
 const MULTI_ACCOUNT_NUMBER string = "<multi-account>"
 
+// SIGNAL_CLI_DAEMON_PROGRAM is the name of the supervisor program that runs the
+// signal-cli daemon in json-rpc mode.
+const SIGNAL_CLI_DAEMON_PROGRAM string = "signal-cli-json-rpc-1"
+
 type JsonRpc2ClientConfigEntry struct {
 	TcpPort int64 `yaml:"tcp_port"`
 }
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrIdempotencyKeyReused = errors.New("The Idempotency-Key was already used for a different request")
+
+type IdempotencyEntry struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type idempotencyRecord struct {
+	requestHash string
+	entry       *IdempotencyEntry
+	expiresAt   time.Time
+	done        chan struct{}
+}
+
+// IdempotencyStore remembers the outcome of requests that were sent with an
+// idempotency key, so that retries of the same request can be answered without
+// executing the request again.
+type IdempotencyStore struct {
+	mutex   sync.Mutex
+	records map[string]*idempotencyRecord
+	ttl     time.Duration
+}
+
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		records: make(map[string]*idempotencyRecord),
+		ttl:     ttl,
+	}
+}
+
+func (s *IdempotencyStore) removeExpired(now time.Time) {
+	for key, record := range s.records {
+		if record.entry != nil && now.After(record.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// Begin returns the stored outcome for the given key. If there is no stored
+// outcome yet, nil is returned and the caller is expected to execute the request
+// and report the outcome via Complete. If a request with the same key is
+// currently in progress, Begin waits until it is finished.
+func (s *IdempotencyStore) Begin(key string, requestHash string) (*IdempotencyEntry, error) {
+	for {
+		s.mutex.Lock()
+		s.removeExpired(time.Now())
+
+		record, exists := s.records[key]
+		if !exists {
+			s.records[key] = &idempotencyRecord{requestHash: requestHash, done: make(chan struct{})}
+			s.mutex.Unlock()
+			return nil, nil
+		}
+
+		if record.requestHash != requestHash {
+			s.mutex.Unlock()
+			return nil, ErrIdempotencyKeyReused
+		}
+
+		if record.entry != nil {
+			s.mutex.Unlock()
+			return record.entry, nil
+		}
+
+		done := record.done
+		s.mutex.Unlock()
+		<-done
+	}
+}
+
+// Complete stores the outcome of a request that was started with Begin. If
+// no entry is provided (e.g. because the request failed), the key is released
+// again, so that the request can be retried.
+func (s *IdempotencyStore) Complete(key string, entry *IdempotencyEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.records[key]
+	if !exists {
+		return
+	}
+
+	if entry == nil {
+		delete(s.records, key)
+	} else {
+		record.entry = entry
+		record.expiresAt = time.Now().Add(s.ttl)
+	}
+	close(record.done)
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStoreReplaysOutcome(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour)
+
+	entry, err := store.Begin("key", "hash")
+	expectEqual(t, entry == nil && err == nil, true)
+	store.Complete("key", &IdempotencyEntry{StatusCode: 201, Body: []byte(`{"timestamp":"1"}`)})
+
+	entry, err = store.Begin("key", "hash")
+	expectEqual(t, err == nil, true)
+	expectEqual(t, entry != nil && string(entry.Body) == `{"timestamp":"1"}`, true)
+}
+
+func TestIdempotencyStoreRejectsDifferentRequest(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour)
+
+	store.Begin("key", "hash")
+	store.Complete("key", &IdempotencyEntry{StatusCode: 201})
+
+	_, err := store.Begin("key", "other-hash")
+	expectEqual(t, err == ErrIdempotencyKeyReused, true)
+}
+
+func TestIdempotencyStoreReleasesFailedRequests(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour)
+
+	store.Begin("key", "hash")
+	store.Complete("key", nil)
+
+	entry, err := store.Begin("key", "hash")
+	expectEqual(t, entry == nil && err == nil, true)
+}
+
+func TestIdempotencyStoreExpiresOutcome(t *testing.T) {
+	store := NewIdempotencyStore(-time.Second)
+
+	store.Begin("key", "hash")
+	store.Complete("key", &IdempotencyEntry{StatusCode: 201})
+
+	entry, err := store.Begin("key", "hash")
+	expectEqual(t, entry == nil && err == nil, true)
+}
+
+func TestIdempotencyStoreWaitsForRequestInProgress(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour)
+	store.Begin("key", "hash")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		store.Complete("key", &IdempotencyEntry{StatusCode: 201})
+	}()
+
+	entry, err := store.Begin("key", "hash")
+	expectEqual(t, err == nil && entry != nil && entry.StatusCode == 201, true)
+}
@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespaces can be nested with slashes (e.g. "a/notify"), every nested
+// namespace is stored in a subfolder. Segments of the name that aren't safe to
+// use as file names are sanitized (see keyValueNamespaceFileName).
+var validKeyValueNamespaceSegment = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9._-]*$`)
+var invalidKeyValueNamespaceCharacter = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// keyValueNamespaceFileName returns the (relative) file name of the namespace
+// without extension. Segments with other characters than letters, digits, '.',
+// '_' and '-' (e.g. spaces or '+') are sanitized and get a hash of the original
+// segment appended, so that different names don't end up in the same file and
+// the file can't be outside of the store's directory.
+func keyValueNamespaceFileName(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		if validKeyValueNamespaceSegment.MatchString(segment) {
+			continue
+		}
+		hash := sha256.Sum256([]byte(segment))
+		sanitized := invalidKeyValueNamespaceCharacter.ReplaceAllString(segment, "_")
+		sanitized = strings.TrimLeft(sanitized, ".")
+		segments[i] = sanitized + "-" + hex.EncodeToString(hash[:4])
+	}
+	return filepath.Join(segments...)
+}
+
+type keyValueEntry struct {
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+}
+
+func (e *keyValueEntry) expired(now time.Time) bool {
+	return e.ExpiresAt != nil && now.After(*e.ExpiresAt)
+}
+
+// KeyValueStore is a simple persistent key/value store, which is split into
+// namespaces (e.g. one per plugin). Every namespace is kept in memory and is
+// written to a separate JSON file on every change.
+type KeyValueStore struct {
+	directory  string
+	mutex      sync.Mutex
+	namespaces map[string]*KeyValueNamespace
+}
+
+func NewKeyValueStore(directory string) *KeyValueStore {
+	return &KeyValueStore{
+		directory:  directory,
+		namespaces: make(map[string]*KeyValueNamespace),
+	}
+}
+
+// Namespace returns the namespace with the given name. In case the namespace
+// doesn't exist yet, it is created.
+func (s *KeyValueStore) Namespace(name string) (*KeyValueNamespace, error) {
+	if name == "" {
+		return nil, errors.New("Invalid namespace '" + name + "'")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if namespace, exists := s.namespaces[name]; exists {
+		return namespace, nil
+	}
+
+	namespace := &KeyValueNamespace{
+		path:    filepath.Join(s.directory, keyValueNamespaceFileName(name)+".json"),
+		entries: make(map[string]keyValueEntry),
+	}
+
+	data, err := os.ReadFile(namespace.path)
+	if err == nil {
+		err = json.Unmarshal(data, &namespace.entries)
+		if err != nil {
+			return nil, errors.New("Couldn't parse key/value store " + namespace.path + ": " + err.Error())
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	s.namespaces[name] = namespace
+	return namespace, nil
+}
+
+type KeyValueNamespace struct {
+	path    string
+	mutex   sync.Mutex
+	entries map[string]keyValueEntry
+}
+
+// update applies the change to a copy of the entries and writes them to disk.
+// The entries of the namespace are only replaced once they were persisted, so
+// that a failed write doesn't leave changes behind that are lost on restart.
+// Expired entries are dropped. The caller needs to hold the mutex.
+func (n *KeyValueNamespace) update(change func(entries map[string]keyValueEntry)) error {
+	now := time.Now()
+	entries := make(map[string]keyValueEntry, len(n.entries)+1)
+	for key, entry := range n.entries {
+		if !entry.expired(now) {
+			entries[key] = entry
+		}
+	}
+	change(entries)
+
+	err := persistKeyValueEntries(n.path, entries)
+	if err != nil {
+		return err
+	}
+	n.entries = entries
+	return nil
+}
+
+// persistKeyValueEntries writes the entries to disk. The file is replaced
+// atomically, so that a crash doesn't leave a corrupted file behind.
+func persistKeyValueEntries(path string, entries map[string]keyValueEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Get returns the (JSON encoded) value for the given key.
+func (n *KeyValueNamespace) Get(key string) (json.RawMessage, bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	entry, exists := n.entries[key]
+	if !exists || entry.expired(time.Now()) {
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+// Set stores the (JSON encoded) value for the given key. If the ttl is greater
+// than 0, the entry expires after the ttl.
+func (n *KeyValueNamespace) Set(key string, value json.RawMessage, ttl time.Duration) error {
+	if !json.Valid(value) {
+		return errors.New("Value is not valid JSON")
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	entry := keyValueEntry{Value: value}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		entry.ExpiresAt = &expiresAt
+	}
+	return n.update(func(entries map[string]keyValueEntry) {
+		entries[key] = entry
+	})
+}
+
+func (n *KeyValueNamespace) Delete(key string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if _, exists := n.entries[key]; !exists {
+		return nil
+	}
+	return n.update(func(entries map[string]keyValueEntry) {
+		delete(entries, key)
+	})
+}
+
+// List returns all entries, whose key starts with the given prefix.
+func (n *KeyValueNamespace) List(prefix string) map[string]json.RawMessage {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	now := time.Now()
+	result := make(map[string]json.RawMessage)
+	for key, entry := range n.entries {
+		if strings.HasPrefix(key, prefix) && !entry.expired(now) {
+			result[key] = entry.Value
+		}
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyValueStoreSetGetDelete(t *testing.T) {
+	store := NewKeyValueStore(t.TempDir())
+	namespace, err := store.Namespace("my-plugin")
+	expectEqual(t, err == nil, true)
+
+	expectEqual(t, namespace.Set("counter", json.RawMessage(`42`), 0) == nil, true)
+	value, exists := namespace.Get("counter")
+	expectEqual(t, exists && string(value) == "42", true)
+
+	expectEqual(t, namespace.Delete("counter") == nil, true)
+	_, exists = namespace.Get("counter")
+	expectEqual(t, exists, false)
+
+	expectEqual(t, namespace.Set("invalid", json.RawMessage(`{`), 0) != nil, true)
+}
+
+func TestKeyValueStorePersistsNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	namespace, _ := NewKeyValueStore(dir).Namespace("a")
+	namespace.Set("state:+431", json.RawMessage(`{"step":2}`), 0)
+	namespace.Set("state:+432", json.RawMessage(`{"step":1}`), 0)
+	namespace.Set("other", json.RawMessage(`"x"`), 0)
+
+	otherNamespace, _ := NewKeyValueStore(dir).Namespace("b")
+	_, exists := otherNamespace.Get("other")
+	expectEqual(t, exists, false)
+
+	reloadedNamespace, _ := NewKeyValueStore(dir).Namespace("a")
+	entries := reloadedNamespace.List("state:")
+	expectEqual(t, len(entries) == 2 && string(entries["state:+431"]) == `{"step":2}`, true)
+}
+
+func TestKeyValueStoreExpiresEntries(t *testing.T) {
+	namespace, _ := NewKeyValueStore(t.TempDir()).Namespace("a")
+	namespace.Set("short", json.RawMessage(`1`), time.Nanosecond)
+	namespace.Set("long", json.RawMessage(`1`), time.Hour)
+	time.Sleep(time.Millisecond)
+
+	_, exists := namespace.Get("short")
+	expectEqual(t, exists, false)
+	expectEqual(t, len(namespace.List("")) == 1, true)
+}
+
+func TestKeyValueStoreInvalidNamespace(t *testing.T) {
+	store := NewKeyValueStore(t.TempDir())
+	_, err := store.Namespace("")
+	expectEqual(t, err != nil, true)
+}
+
+func TestKeyValueStoreSanitizesNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	paths := make(map[string]bool)
+	for _, name := range []string{"my plugin", "my+plugin", "my_plugin", "../etc", "a/../../etc", "a/", "a/.", ".hidden"} {
+		namespace, err := NewKeyValueStore(dir).Namespace(name)
+		expectEqual(t, err == nil, true)
+		expectEqual(t, namespace.Set("key", json.RawMessage(`"`+name+`"`), 0) == nil, true)
+
+		relPath, err := filepath.Rel(dir, namespace.path)
+		expectEqual(t, err == nil && !strings.HasPrefix(relPath, "..") && !strings.HasPrefix(filepath.Base(relPath), "."), true)
+		expectEqual(t, paths[namespace.path], false)
+		paths[namespace.path] = true
+
+		reloadedNamespace, _ := NewKeyValueStore(dir).Namespace(name)
+		value, exists := reloadedNamespace.Get("key")
+		expectEqual(t, exists && string(value) == `"`+name+`"`, true)
+	}
+}
+
+func TestKeyValueStoreKeepsEntriesOnFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	namespace, _ := NewKeyValueStore(dir).Namespace("a")
+	expectEqual(t, namespace.Set("key", json.RawMessage(`1`), 0) == nil, true)
+
+	// the temporary file can't be written, as there is a directory in its place
+	expectEqual(t, os.Mkdir(namespace.path+".tmp", 0700) == nil, true)
+	expectEqual(t, namespace.Set("key", json.RawMessage(`2`), 0) != nil, true)
+	expectEqual(t, namespace.Set("other", json.RawMessage(`3`), 0) != nil, true)
+	expectEqual(t, namespace.Delete("key") != nil, true)
+
+	value, exists := namespace.Get("key")
+	expectEqual(t, exists && string(value) == "1", true)
+	_, exists = namespace.Get("other")
+	expectEqual(t, exists, false)
+}
+
+func TestKeyValueStoreNestedNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	namespace, err := NewKeyValueStore(dir).Namespace("a/notify")
+	expectEqual(t, err == nil, true)
+	expectEqual(t, namespace.Set("key", json.RawMessage(`1`), 0) == nil, true)
+
+	otherNamespace, _ := NewKeyValueStore(dir).Namespace("b/notify")
+	_, exists := otherNamespace.Get("key")
+	expectEqual(t, exists, false)
+
+	reloadedNamespace, _ := NewKeyValueStore(dir).Namespace("a/notify")
+	_, exists = reloadedNamespace.Get("key")
+	expectEqual(t, exists, true)
+}
+
+func TestKeyValueStoreConcurrentAccess(t *testing.T) {
+	namespace, _ := NewKeyValueStore(t.TempDir()).Namespace("a")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			namespace.Set("key", json.RawMessage(`1`), 0)
+			namespace.Get("key")
+			namespace.List("")
+		}()
+	}
+	wg.Wait()
+
+	value, exists := namespace.Get("key")
+	expectEqual(t, exists && string(value) == "1", true)
+}
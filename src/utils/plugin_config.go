@@ -1,19 +1,168 @@
 package utils
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	ReceivePluginTrigger  = "receive"
+	PreSendPluginTrigger  = "pre_send"
+	PostSendPluginTrigger = "post_send"
+)
+
+// PluginModules are the lua modules that can be enabled via the modules
+// allowlist of a plugin. The basic lua libraries (base, package, table, string,
+// math and coroutine) are always available.
+var PluginModules = []string{"http", "json", "sqlite3", "signal", "kv", "io", "os", "debug", "channel"}
+
+type PluginLimits struct {
+	// Wall-clock timeout (in seconds)
+	Timeout         int   `yaml:"timeout,omitempty"`
+	MaxInstructions int64 `yaml:"max_instructions,omitempty"`
+	// Maximum number of values on the lua data stack. This is not a memory
+	// limit, the size of tables and strings is not limited.
+	MaxStackSize int `yaml:"max_stack_size,omitempty"`
+	MaxCallDepth int `yaml:"max_call_depth,omitempty"`
+}
+
+type PluginHttpConfig struct {
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty"`
+}
+
+type PluginFilter struct {
+	Accounts []string `yaml:"accounts,omitempty"`
+	Groups   []string `yaml:"groups,omitempty"`
+	Senders  []string `yaml:"senders,omitempty"`
+}
+
 type PluginConfig struct {
-	Endpoint   string `yaml:"endpoint"`
-	Method     string `yaml:"method"`
-	Version    int    `yaml:"version,omitempty"`
+	Endpoint   string           `yaml:"endpoint"`
+	Method     string           `yaml:"method"`
+	Version    int              `yaml:"version,omitempty"`
+	Trigger    string           `yaml:"trigger,omitempty"`
+	Filter     PluginFilter     `yaml:"filter,omitempty"`
+	Schedule   string           `yaml:"schedule,omitempty"`
+	Limits     PluginLimits     `yaml:"limits,omitempty"`
+	Modules    []string         `yaml:"modules,omitempty"`
+	Http       PluginHttpConfig `yaml:"http,omitempty"`
 	ScriptPath string
+	// path of the plugin definition relative to the plugin directory,
+	// without extension
+	relativePath string
+}
+
+// Name returns the name of the plugin, i.e the filename of the lua script
+// without extension.
+func (p *PluginConfig) Name() string {
+	return strings.TrimSuffix(filepath.Base(p.ScriptPath), filepath.Ext(p.ScriptPath))
+}
+
+// Id returns the path of the plugin relative to the plugin directory without
+// extension and with forward slashes (e.g "a/notify"). Other than the name, it
+// is unique among all loaded plugins.
+func (p *PluginConfig) Id() string {
+	if p.relativePath == "" {
+		return p.Name()
+	}
+	return p.relativePath
+}
+
+// ModuleAllowed checks whether the plugin is allowed to use the given lua
+// module. If no allowlist is specified, all modules are allowed.
+func (p *PluginConfig) ModuleAllowed(module string) bool {
+	return p.Modules == nil || StringInSlice(module, p.Modules)
+}
+
+// HostAllowed checks whether the plugin is allowed to send HTTP requests to the
+// given host. Entries of the allowlist either match the host exactly or, when
+// prefixed with "*.", all of its subdomains. If no allowlist is specified, all
+// hosts are allowed.
+func (p *PluginConfig) HostAllowed(host string) bool {
+	if p.Http.AllowedHosts == nil {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, allowedHost := range p.Http.AllowedHosts {
+		allowedHost = strings.ToLower(allowedHost)
+		if strings.HasPrefix(allowedHost, "*.") {
+			if strings.HasSuffix(host, allowedHost[1:]) {
+				return true
+			}
+		} else if host == allowedHost {
+			return true
+		}
+	}
+	return false
+}
+
+// Timeout returns the wall-clock timeout of the plugin. If the plugin doesn't
+// specify a timeout, the default timeout is used. A timeout of 0 means that
+// the execution time is not limited.
+func (p *PluginConfig) Timeout(defaultTimeout time.Duration) time.Duration {
+	if p.Limits.Timeout > 0 {
+		return time.Duration(p.Limits.Timeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+func (p *PluginConfig) validateLimits() error {
+	if p.Limits.Timeout < 0 || p.Limits.MaxInstructions < 0 || p.Limits.MaxStackSize < 0 || p.Limits.MaxCallDepth < 0 {
+		return errors.New("limits need to be positive numbers")
+	}
+
+	for _, module := range p.Modules {
+		if !StringInSlice(module, PluginModules) {
+			return errors.New("unknown module '" + module + "'")
+		}
+	}
+
+	if p.Http.AllowedHosts != nil && !p.ModuleAllowed("http") {
+		return errors.New("http.allowed_hosts requires the http module")
+	}
+	return nil
+}
+
+// ParseSchedule parses the cron schedule of the plugin. The same format as for
+// the AUTO_RECEIVE_SCHEDULE is used.
+func (p *PluginConfig) ParseSchedule() (cron.Schedule, error) {
+	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	return parser.Parse(p.Schedule)
+}
+
+// MatchesAccount checks whether the account passes the account filter of the
+// plugin.
+func (p *PluginConfig) MatchesAccount(account string) bool {
+	return len(p.Filter.Accounts) == 0 || StringInSlice(account, p.Filter.Accounts)
+}
+
+// MatchesReceivedMessage checks whether a received message passes the filters of
+// the plugin. Empty filters match everything; the sender filter matches both the
+// phone number and the uuid of the sender.
+func (p *PluginConfig) MatchesReceivedMessage(account string, groupId string, senderNumber string, senderUuid string) bool {
+	if !p.MatchesAccount(account) {
+		return false
+	}
+
+	if len(p.Filter.Groups) > 0 && (groupId == "" || !StringInSlice(groupId, p.Filter.Groups)) {
+		return false
+	}
+
+	if len(p.Filter.Senders) > 0 {
+		if !(senderNumber != "" && StringInSlice(senderNumber, p.Filter.Senders)) && !(senderUuid != "" && StringInSlice(senderUuid, p.Filter.Senders)) {
+			return false
+		}
+	}
+
+	return true
 }
 
 func NewPluginConfigs() *PluginConfigs {
@@ -27,6 +176,10 @@ type PluginConfigs struct {
 func (c *PluginConfigs) Load(baseDirectory string) error {
 
 	err := filepath.Walk(baseDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
 		if info.IsDir() {
 			return nil
 		}
@@ -47,7 +200,27 @@ func (c *PluginConfigs) Load(baseDirectory string) error {
 			if err != nil {
 				return err
 			}
+			if pluginConfig.Trigger != "" && !StringInSlice(pluginConfig.Trigger, []string{ReceivePluginTrigger, PreSendPluginTrigger, PostSendPluginTrigger}) {
+				return errors.New("Invalid trigger '" + pluginConfig.Trigger + "' in plugin definition " + path)
+			}
+			if pluginConfig.Trigger != "" && pluginConfig.Version < 2 {
+				return errors.New("Plugin definition " + path + " uses a trigger, which requires plugin version 2")
+			}
+			if pluginConfig.Schedule != "" {
+				if pluginConfig.Version < 2 {
+					return errors.New("Plugin definition " + path + " uses a schedule, which requires plugin version 2")
+				}
+				if _, err := pluginConfig.ParseSchedule(); err != nil {
+					return errors.New("Invalid schedule in plugin definition " + path + ": " + err.Error())
+				}
+			}
+			if err := pluginConfig.validateLimits(); err != nil {
+				return errors.New("Invalid plugin definition " + path + ": " + err.Error())
+			}
 			pluginConfig.ScriptPath = strings.TrimSuffix(path, filepath.Ext(path)) + ".lua"
+			if relativePath, err := filepath.Rel(baseDirectory, path); err == nil {
+				pluginConfig.relativePath = filepath.ToSlash(strings.TrimSuffix(relativePath, filepath.Ext(relativePath)))
+			}
 			c.Configs = append(c.Configs, pluginConfig)
 		}
 		return nil
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPluginConfigMatchesReceivedMessage(t *testing.T) {
+	pluginConfig := PluginConfig{}
+	expectEqual(t, pluginConfig.MatchesReceivedMessage("+431", "", "+432", "uuid"), true)
+
+	pluginConfig.Filter.Accounts = []string{"+431"}
+	expectEqual(t, pluginConfig.MatchesReceivedMessage("+431", "", "+432", "uuid"), true)
+	expectEqual(t, pluginConfig.MatchesReceivedMessage("+439", "", "+432", "uuid"), false)
+
+	pluginConfig.Filter.Groups = []string{"group.abc"}
+	expectEqual(t, pluginConfig.MatchesReceivedMessage("+431", "group.abc", "+432", "uuid"), true)
+	expectEqual(t, pluginConfig.MatchesReceivedMessage("+431", "", "+432", "uuid"), false)
+
+	pluginConfig.Filter.Senders = []string{"uuid"}
+	expectEqual(t, pluginConfig.MatchesReceivedMessage("+431", "group.abc", "+432", "uuid"), true)
+	expectEqual(t, pluginConfig.MatchesReceivedMessage("+431", "group.abc", "+432", "other-uuid"), false)
+}
+
+func TestPluginConfigsLoadReceiveTrigger(t *testing.T) {
+	dir := t.TempDir()
+	def := "trigger: receive\nversion: 2\nfilter:\n  groups:\n    - group.abc\n"
+	if err := os.WriteFile(filepath.Join(dir, "on-message.def"), []byte(def), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pluginConfigs := NewPluginConfigs()
+	err := pluginConfigs.Load(dir)
+	expectEqual(t, err == nil, true)
+	expectEqual(t, len(pluginConfigs.Configs) == 1, true)
+	expectEqual(t, pluginConfigs.Configs[0].Trigger == ReceivePluginTrigger, true)
+	expectEqual(t, pluginConfigs.Configs[0].Filter.Groups[0] == "group.abc", true)
+	expectEqual(t, pluginConfigs.Configs[0].ScriptPath == filepath.Join(dir, "on-message.lua"), true)
+}
+
+func TestPluginConfigsLoadInvalidTrigger(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "invalid.def"), []byte("trigger: foo\nversion: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewPluginConfigs().Load(dir)
+	expectEqual(t, err != nil, true)
+}
+
+func TestPluginConfigsLoadSchedule(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "digest.def"), []byte("schedule: \"*/5 * * * *\"\nversion: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pluginConfigs := NewPluginConfigs()
+	err := pluginConfigs.Load(dir)
+	expectEqual(t, err == nil, true)
+	expectEqual(t, pluginConfigs.Configs[0].Schedule == "*/5 * * * *", true)
+	expectEqual(t, pluginConfigs.Configs[0].Name() == "digest", true)
+}
+
+func TestPluginConfigsLoadNestedPluginsHaveUniqueIds(t *testing.T) {
+	dir := t.TempDir()
+	for _, subdir := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, subdir, "notify.def"), []byte("trigger: receive\nversion: 2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pluginConfigs := NewPluginConfigs()
+	err := pluginConfigs.Load(dir)
+	expectEqual(t, err == nil && len(pluginConfigs.Configs) == 2, true)
+	expectEqual(t, pluginConfigs.Configs[0].Name() == "notify" && pluginConfigs.Configs[1].Name() == "notify", true)
+	expectEqual(t, pluginConfigs.Configs[0].Id() == "a/notify" && pluginConfigs.Configs[1].Id() == "b/notify", true)
+}
+
+func TestPluginConfigsLoadInvalidSchedule(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "invalid.def"), []byte("schedule: \"every minute\"\nversion: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, NewPluginConfigs().Load(dir) != nil, true)
+
+	if err := os.WriteFile(filepath.Join(dir, "invalid.def"), []byte("schedule: \"* * * * *\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, NewPluginConfigs().Load(dir) != nil, true)
+}
+
+func TestPluginStatusStore(t *testing.T) {
+	pluginConfig := PluginConfig{Schedule: "* * * * *", ScriptPath: "/plugins/digest.lua"}
+	store := NewPluginStatusStore()
+	store.Register(pluginConfig)
+
+	statuses := store.List()
+	expectEqual(t, len(statuses) == 1 && statuses[0].Name == "digest" && statuses[0].LastRun == nil, true)
+
+	store.SetRunResult(pluginConfig, time.Now(), errors.New("failed"))
+	statuses = store.List()
+	expectEqual(t, *statuses[0].LastRunSuccessful == false && statuses[0].LastError == "failed", true)
+
+	store.SetRunResult(pluginConfig, time.Now(), nil)
+	statuses = store.List()
+	expectEqual(t, *statuses[0].LastRunSuccessful == true && statuses[0].LastError == "failed", true)
+}
+
+func TestPluginConfigHostAllowed(t *testing.T) {
+	pluginConfig := PluginConfig{}
+	expectEqual(t, pluginConfig.HostAllowed("example.com"), true)
+
+	pluginConfig.Http.AllowedHosts = []string{"api.example.com", "*.example.org"}
+	expectEqual(t, pluginConfig.HostAllowed("api.example.com"), true)
+	expectEqual(t, pluginConfig.HostAllowed("API.example.com"), true)
+	expectEqual(t, pluginConfig.HostAllowed("example.com"), false)
+	expectEqual(t, pluginConfig.HostAllowed("www.example.org"), true)
+	expectEqual(t, pluginConfig.HostAllowed("example.org"), false)
+	expectEqual(t, pluginConfig.HostAllowed("evilexample.org"), false)
+}
+
+func TestPluginConfigsLoadLimits(t *testing.T) {
+	dir := t.TempDir()
+	def := "endpoint: /test\nmethod: GET\nversion: 2\nlimits:\n  timeout: 5\n  max_instructions: 1000\nmodules:\n  - http\n  - json\nhttp:\n  allowed_hosts:\n    - api.example.com\n"
+	if err := os.WriteFile(filepath.Join(dir, "test.def"), []byte(def), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pluginConfigs := NewPluginConfigs()
+	expectEqual(t, pluginConfigs.Load(dir) == nil, true)
+	pluginConfig := pluginConfigs.Configs[0]
+	expectEqual(t, pluginConfig.Timeout(time.Minute) == 5*time.Second, true)
+	expectEqual(t, pluginConfig.Limits.MaxInstructions == 1000, true)
+	expectEqual(t, pluginConfig.ModuleAllowed("json"), true)
+	expectEqual(t, pluginConfig.ModuleAllowed("os"), false)
+	expectEqual(t, (&PluginConfig{}).Timeout(time.Minute) == time.Minute, true)
+}
+
+func TestPluginConfigModuleAllowed(t *testing.T) {
+	// without an allowlist, all modules are available (as before the allowlist was introduced)
+	pluginConfig := PluginConfig{}
+	expectEqual(t, pluginConfig.ModuleAllowed("json") && pluginConfig.ModuleAllowed("channel"), true)
+	expectEqual(t, pluginConfig.ModuleAllowed("io") && pluginConfig.ModuleAllowed("os") && pluginConfig.ModuleAllowed("debug"), true)
+
+	pluginConfig.Modules = []string{"json", "os"}
+	expectEqual(t, pluginConfig.ModuleAllowed("os") && !pluginConfig.ModuleAllowed("io"), true)
+}
+
+func TestPluginConfigsLoadInvalidModules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "test.def"), []byte("version: 2\nmodules:\n  - foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, NewPluginConfigs().Load(dir) != nil, true)
+
+	if err := os.WriteFile(filepath.Join(dir, "test.def"), []byte("version: 2\nmodules:\n  - json\nhttp:\n  allowed_hosts:\n    - example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, NewPluginConfigs().Load(dir) != nil, true)
+}
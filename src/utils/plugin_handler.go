@@ -7,4 +7,8 @@ import (
 type PluginHandler interface {
 	ExecutePlugin(pluginConfig PluginConfig) gin.HandlerFunc
 	InitPlugin(pluginConfig PluginConfig) error
+	OnMessage(pluginConfig PluginConfig, account string, envelope []byte) error
+	ExecuteScheduledPlugin(pluginConfig PluginConfig) error
+	PreSend(pluginConfig PluginConfig, account string, request []byte) ([]byte, string, error)
+	PostSend(pluginConfig PluginConfig, account string, request []byte, response []byte, sendError string) error
 }
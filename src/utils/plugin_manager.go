@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+type pluginRoute struct {
+	method  string
+	path    string
+	handler gin.HandlerFunc
+}
+
+// PluginManager loads the plugins from the plugin directory and (re-)loads
+// them whenever the plugin directory changes. The plugin endpoints are served
+// by a separate router, which gets replaced on every reload. That way, plugins
+// can be added, changed or removed without registering new routes in the main
+// router.
+type PluginManager struct {
+	baseDirectory  string
+	routePrefix    string
+	pluginHandler  PluginHandler
+	statuses       *PluginStatusStore
+	reservedRoutes []pluginRoute
+	reloadMutex    sync.Mutex
+	mutex          sync.RWMutex
+	router         *gin.Engine
+	scheduler      *cron.Cron
+	triggerPlugins map[string][]PluginConfig
+	fingerprint    string
+}
+
+func NewPluginManager(baseDirectory string, routePrefix string, pluginHandler PluginHandler) *PluginManager {
+	return &PluginManager{
+		baseDirectory: baseDirectory,
+		routePrefix:   routePrefix,
+		pluginHandler: pluginHandler,
+		statuses:      NewPluginStatusStore(),
+		router:        gin.New(),
+	}
+}
+
+// AddReservedRoute registers a route (relative to the route prefix) that takes
+// precedence over the plugin endpoints, e.g. for managing the plugins.
+func (m *PluginManager) AddReservedRoute(method string, path string, handler gin.HandlerFunc) {
+	m.reservedRoutes = append(m.reservedRoutes, pluginRoute{method: method, path: path, handler: handler})
+}
+
+func (m *PluginManager) Statuses() []PluginStatus {
+	return m.statuses.List()
+}
+
+// TriggeredPlugins returns the active plugins with the given trigger (e.g. the
+// plugins that are triggered by incoming messages).
+func (m *PluginManager) TriggeredPlugins(trigger string) []PluginConfig {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.triggerPlugins[trigger]
+}
+
+// RecordRun records the outcome of a plugin execution in the plugin status.
+func (m *PluginManager) RecordRun(pluginConfig PluginConfig, startedAt time.Time, err error) {
+	m.statuses.SetRunResult(pluginConfig, startedAt, err)
+}
+
+// Handler dispatches requests to the plugin endpoints.
+func (m *PluginManager) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.mutex.RLock()
+		router := m.router
+		m.mutex.RUnlock()
+
+		router.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
+
+func (m *PluginManager) addRoute(router *gin.Engine, method string, path string, handler gin.HandlerFunc) (err error) {
+	// gin panics in case a route conflicts with an already registered one
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Couldn't register endpoint: %v", r)
+		}
+	}()
+
+	router.Handle(method, m.routePath(path), handler)
+	return nil
+}
+
+// routePath prefixes the (relative) path of a plugin endpoint with the route
+// prefix. Plugin endpoints can be specified with or without leading slash.
+func (m *PluginManager) routePath(path string) string {
+	return m.routePrefix + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (m *PluginManager) scheduleFunc(pluginConfig PluginConfig, schedule cron.Schedule) func() {
+	return func() {
+		startedAt := time.Now()
+		err := m.pluginHandler.ExecuteScheduledPlugin(pluginConfig)
+		if err != nil {
+			log.Error("Couldn't execute scheduled plugin ", pluginConfig.ScriptPath, ": ", err.Error())
+		}
+		m.statuses.SetRunResult(pluginConfig, startedAt, err)
+		m.statuses.SetNextRun(pluginConfig, schedule.Next(time.Now()))
+	}
+}
+
+// Reload loads all plugins from the plugin directory, initializes them and
+// replaces the currently active plugins. In case the plugin definitions can't
+// be loaded, the currently active plugins are kept.
+func (m *PluginManager) Reload() error {
+	m.reloadMutex.Lock()
+	defer m.reloadMutex.Unlock()
+
+	fingerprint, err := m.directoryFingerprint()
+	if err != nil {
+		return err
+	}
+
+	pluginConfigs := NewPluginConfigs()
+	err = pluginConfigs.Load(m.baseDirectory)
+	if err != nil {
+		m.fingerprint = fingerprint
+		return err
+	}
+
+	router := gin.New()
+	for _, reservedRoute := range m.reservedRoutes {
+		router.Handle(reservedRoute.method, m.routePath(reservedRoute.path), reservedRoute.handler)
+	}
+	scheduler := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+	triggerPlugins := make(map[string][]PluginConfig)
+
+	m.statuses.Retain(pluginConfigs.Configs)
+	for _, pluginConfig := range pluginConfigs.Configs {
+		m.statuses.Register(pluginConfig)
+
+		if pluginConfig.Version > 1 {
+			err = m.pluginHandler.InitPlugin(pluginConfig)
+			if err != nil {
+				log.Error("Couldn't initialize plugin ", pluginConfig.ScriptPath, ": ", err.Error())
+				m.statuses.SetInitResult(pluginConfig, PluginInitFailed, err)
+				continue
+			}
+			m.statuses.SetInitResult(pluginConfig, PluginInitOk, nil)
+		} else {
+			log.Info("Plugin ", pluginConfig.Endpoint, " still uses plugin version 1. Consider migrating to version 2! (see https://github.com/bbernhard/signal-cli-rest-api/plugins/migrate-v1-plugin-to-v2.md)")
+			m.statuses.SetInitResult(pluginConfig, PluginInitSkipped, nil)
+		}
+
+		if pluginConfig.Endpoint != "" {
+			if !StringInSlice(pluginConfig.Method, []string{"GET", "POST", "PUT", "DELETE"}) {
+				m.statuses.SetError(pluginConfig, errors.New("Invalid method '"+pluginConfig.Method+"'"))
+				continue
+			}
+
+			log.Info("Registering plugin ", pluginConfig.Endpoint)
+			err = m.addRoute(router, pluginConfig.Method, pluginConfig.Endpoint, m.pluginHandler.ExecutePlugin(pluginConfig))
+			if err != nil {
+				log.Error("Couldn't register plugin ", pluginConfig.ScriptPath, ": ", err.Error())
+				m.statuses.SetError(pluginConfig, err)
+				continue
+			}
+		}
+
+		if pluginConfig.Trigger != "" {
+			log.Info("Registering ", pluginConfig.Trigger, " trigger for plugin ", pluginConfig.ScriptPath)
+			triggerPlugins[pluginConfig.Trigger] = append(triggerPlugins[pluginConfig.Trigger], pluginConfig)
+		}
+
+		if pluginConfig.Schedule != "" {
+			schedule, _ := pluginConfig.ParseSchedule() // already validated when loading the plugin definition
+			log.Info("Scheduling plugin ", pluginConfig.ScriptPath, " (", pluginConfig.Schedule, ")")
+			scheduler.Schedule(schedule, cron.FuncJob(m.scheduleFunc(pluginConfig, schedule)))
+			m.statuses.SetNextRun(pluginConfig, schedule.Next(time.Now()))
+		}
+	}
+
+	m.mutex.Lock()
+	oldScheduler := m.scheduler
+	m.router = router
+	m.scheduler = scheduler
+	m.triggerPlugins = triggerPlugins
+	m.fingerprint = fingerprint
+	m.mutex.Unlock()
+
+	if oldScheduler != nil {
+		oldScheduler.Stop()
+	}
+	scheduler.Start()
+
+	return nil
+}
+
+// directoryFingerprint calculates a checksum over the names, sizes and
+// modification times of all plugin files.
+func (m *PluginManager) directoryFingerprint() (string, error) {
+	entries := []string{}
+	err := filepath.Walk(m.baseDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || (filepath.Ext(path) != ".def" && filepath.Ext(path) != ".lua") {
+			return nil
+		}
+
+		entries = append(entries, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(entries)
+	hash := sha256.New()
+	for _, entry := range entries {
+		hash.Write([]byte(entry + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Changed checks whether any of the plugin files were added, changed or
+// removed since the last reload.
+func (m *PluginManager) Changed() bool {
+	fingerprint, err := m.directoryFingerprint()
+	if err != nil {
+		log.Debug("Couldn't check plugin directory for changes: ", err.Error())
+		return false
+	}
+
+	m.reloadMutex.Lock()
+	defer m.reloadMutex.Unlock()
+	return fingerprint != m.fingerprint
+}
+
+// Watch polls the plugin directory in the given interval and reloads the
+// plugins whenever something changed.
+func (m *PluginManager) Watch(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+			if !m.Changed() {
+				continue
+			}
+
+			log.Info("Plugin directory changed...reloading plugins")
+			err := m.Reload()
+			if err != nil {
+				log.Error("Couldn't reload plugins: ", err.Error())
+			}
+		}
+	}()
+}
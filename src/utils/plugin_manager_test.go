@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakePluginHandler struct {
+	initErr error
+}
+
+func (f *fakePluginHandler) ExecutePlugin(pluginConfig PluginConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.String(200, pluginConfig.Name())
+	}
+}
+
+func (f *fakePluginHandler) InitPlugin(pluginConfig PluginConfig) error {
+	return f.initErr
+}
+
+func (f *fakePluginHandler) OnMessage(pluginConfig PluginConfig, account string, envelope []byte) error {
+	return nil
+}
+
+func (f *fakePluginHandler) ExecuteScheduledPlugin(pluginConfig PluginConfig) error {
+	return nil
+}
+
+func (f *fakePluginHandler) PreSend(pluginConfig PluginConfig, account string, request []byte) ([]byte, string, error) {
+	return nil, "", nil
+}
+
+func (f *fakePluginHandler) PostSend(pluginConfig PluginConfig, account string, request []byte, response []byte, sendError string) error {
+	return nil
+}
+
+func writePluginDefinition(t *testing.T, path string, def string) {
+	if err := os.WriteFile(path, []byte(def), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// make sure that the modification time changes, even on file systems with a coarse resolution
+	modTime := time.Now().Add(time.Duration(len(def)) * time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servePluginRequest(pluginManager *PluginManager, method string, path string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Any("/v1/plugins/*path", pluginManager.Handler())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPluginManagerReload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	writePluginDefinition(t, filepath.Join(dir, "hello.def"), "endpoint: /hello/:number\nmethod: GET\nversion: 2\n")
+	writePluginDefinition(t, filepath.Join(dir, "no-slash.def"), "endpoint: no-slash/:number\nmethod: POST\nversion: 2\n")
+
+	pluginManager := NewPluginManager(dir, "/v1/plugins", &fakePluginHandler{})
+	pluginManager.AddReservedRoute("POST", "/reload", func(c *gin.Context) { c.String(200, "reloaded") })
+	expectEqual(t, pluginManager.Reload() == nil, true)
+	expectEqual(t, pluginManager.Changed(), false)
+
+	w := servePluginRequest(pluginManager, "GET", "/v1/plugins/hello/+431")
+	expectEqual(t, w.Code == 200 && w.Body.String() == "hello", true)
+	w = servePluginRequest(pluginManager, "POST", "/v1/plugins/no-slash/+431")
+	expectEqual(t, w.Code == 200 && w.Body.String() == "no-slash", true)
+	w = servePluginRequest(pluginManager, "POST", "/v1/plugins/reload")
+	expectEqual(t, w.Body.String() == "reloaded", true)
+
+	writePluginDefinition(t, filepath.Join(dir, "hello.def"), "endpoint: /hello-world\nmethod: GET\nversion: 2\n")
+	expectEqual(t, pluginManager.Changed(), true)
+	expectEqual(t, pluginManager.Reload() == nil, true)
+
+	w = servePluginRequest(pluginManager, "GET", "/v1/plugins/hello/+431")
+	expectEqual(t, w.Code == 404, true)
+	w = servePluginRequest(pluginManager, "GET", "/v1/plugins/hello-world")
+	expectEqual(t, w.Code == 200, true)
+
+	statuses := pluginManager.Statuses()
+	expectEqual(t, len(statuses) == 2 && statuses[0].Endpoint == "/hello-world" && statuses[0].InitStatus == PluginInitOk && statuses[0].Active, true)
+}
+
+func TestPluginManagerReloadKeepsPluginsOnError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	writePluginDefinition(t, filepath.Join(dir, "hello.def"), "endpoint: /hello\nmethod: GET\nversion: 2\n")
+
+	pluginManager := NewPluginManager(dir, "/v1/plugins", &fakePluginHandler{})
+	expectEqual(t, pluginManager.Reload() == nil, true)
+
+	writePluginDefinition(t, filepath.Join(dir, "broken.def"), "trigger: foo\nversion: 2\n")
+	expectEqual(t, pluginManager.Reload() != nil, true)
+	expectEqual(t, pluginManager.Changed(), false)
+
+	w := servePluginRequest(pluginManager, "GET", "/v1/plugins/hello")
+	expectEqual(t, w.Code == 200, true)
+}
+
+func TestPluginManagerReportsFailingPlugins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	writePluginDefinition(t, filepath.Join(dir, "a.def"), "endpoint: /reload\nmethod: POST\nversion: 1\n")
+	writePluginDefinition(t, filepath.Join(dir, "b.def"), "endpoint: /b\nmethod: GET\nversion: 2\n")
+
+	pluginManager := NewPluginManager(dir, "/v1/plugins", &fakePluginHandler{initErr: errors.New("init failed")})
+	pluginManager.AddReservedRoute("POST", "/reload", func(c *gin.Context) { c.String(200, "reloaded") })
+	expectEqual(t, pluginManager.Reload() == nil, true)
+
+	statuses := pluginManager.Statuses()
+	expectEqual(t, statuses[0].Active == false && statuses[0].LastError != "", true)
+	expectEqual(t, statuses[1].InitStatus == PluginInitFailed && statuses[1].LastError == "init failed", true)
+}
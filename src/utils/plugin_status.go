@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	PluginInitOk      = "ok"
+	PluginInitFailed  = "failed"
+	PluginInitSkipped = "skipped"
+)
+
+type PluginStatus struct {
+	Name              string     `json:"name"`
+	Version           int        `json:"version"`
+	Endpoint          string     `json:"endpoint,omitempty"`
+	Method            string     `json:"method,omitempty"`
+	Trigger           string     `json:"trigger,omitempty"`
+	Schedule          string     `json:"schedule,omitempty"`
+	InitStatus        string     `json:"init_status"`
+	Active            bool       `json:"active"`
+	LastRun           *time.Time `json:"last_run,omitempty"`
+	LastRunDurationMs int64      `json:"last_run_duration_ms,omitempty"`
+	LastRunSuccessful *bool      `json:"last_run_successful,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	NextRun           *time.Time `json:"next_run,omitempty"`
+}
+
+// PluginStatusStore keeps track of the loaded plugins and of their (background)
+// executions, i.e of plugins that are triggered by a schedule or by incoming
+// messages.
+type PluginStatusStore struct {
+	mutex    sync.RWMutex
+	statuses map[string]*PluginStatus
+	order    []string
+}
+
+func NewPluginStatusStore() *PluginStatusStore {
+	return &PluginStatusStore{
+		statuses: make(map[string]*PluginStatus),
+	}
+}
+
+// Register adds a plugin to the store. If the plugin is already known (e.g.
+// because the plugins were reloaded), the information about the last run is
+// kept.
+func (s *PluginStatusStore) Register(pluginConfig PluginConfig) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status, exists := s.statuses[pluginConfig.ScriptPath]
+	if !exists {
+		status = &PluginStatus{}
+		s.statuses[pluginConfig.ScriptPath] = status
+		s.order = append(s.order, pluginConfig.ScriptPath)
+	}
+
+	status.Name = pluginConfig.Name()
+	status.Version = pluginConfig.Version
+	status.Endpoint = pluginConfig.Endpoint
+	status.Method = pluginConfig.Method
+	status.Trigger = pluginConfig.Trigger
+	status.Schedule = pluginConfig.Schedule
+	status.InitStatus = PluginInitSkipped
+	status.Active = false
+	status.NextRun = nil
+}
+
+// Retain removes all plugins from the store that are not part of the given
+// plugin configs.
+func (s *PluginStatusStore) Retain(pluginConfigs []PluginConfig) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	order := []string{}
+	for _, scriptPath := range s.order {
+		found := false
+		for _, pluginConfig := range pluginConfigs {
+			if pluginConfig.ScriptPath == scriptPath {
+				found = true
+				break
+			}
+		}
+
+		if found {
+			order = append(order, scriptPath)
+		} else {
+			delete(s.statuses, scriptPath)
+		}
+	}
+	s.order = order
+}
+
+// SetInitResult records the outcome of the plugin initialization. Plugins that
+// were initialized successfully are considered active.
+func (s *PluginStatusStore) SetInitResult(pluginConfig PluginConfig, initStatus string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status, exists := s.statuses[pluginConfig.ScriptPath]
+	if !exists {
+		return
+	}
+
+	status.InitStatus = initStatus
+	status.Active = initStatus != PluginInitFailed
+	if err != nil {
+		status.LastError = err.Error()
+	}
+}
+
+// SetError records an error, that isn't related to a specific run (e.g. a
+// plugin endpoint that conflicts with another one) and deactivates the plugin.
+func (s *PluginStatusStore) SetError(pluginConfig PluginConfig, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if status, exists := s.statuses[pluginConfig.ScriptPath]; exists {
+		status.Active = false
+		status.LastError = err.Error()
+	}
+}
+
+// SetRunResult records the outcome of a plugin execution. A nil error marks the
+// run as successful.
+func (s *PluginStatusStore) SetRunResult(pluginConfig PluginConfig, startedAt time.Time, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status, exists := s.statuses[pluginConfig.ScriptPath]
+	if !exists {
+		return
+	}
+
+	successful := err == nil
+	status.LastRun = &startedAt
+	status.LastRunDurationMs = time.Since(startedAt).Milliseconds()
+	status.LastRunSuccessful = &successful
+	if err != nil {
+		status.LastError = err.Error()
+	}
+}
+
+func (s *PluginStatusStore) SetNextRun(pluginConfig PluginConfig, nextRun time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if status, exists := s.statuses[pluginConfig.ScriptPath]; exists {
+		status.NextRun = &nextRun
+	}
+}
+
+func (s *PluginStatusStore) List() []PluginStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	statuses := []PluginStatus{}
+	for _, scriptPath := range s.order {
+		statuses = append(statuses, *s.statuses[scriptPath])
+	}
+	return statuses
+}
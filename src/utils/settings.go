@@ -0,0 +1,369 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v2"
+)
+
+type AutoReceiveScheduleSettings struct {
+	Schedule          string `yaml:"schedule" json:"schedule" env:"AUTO_RECEIVE_SCHEDULE"`
+	ReceiveTimeout    int    `yaml:"receive_timeout" json:"receive_timeout" env:"AUTO_RECEIVE_SCHEDULE_RECEIVE_TIMEOUT"`
+	IgnoreAttachments bool   `yaml:"ignore_attachments" json:"ignore_attachments" env:"AUTO_RECEIVE_SCHEDULE_IGNORE_ATTACHMENTS"`
+	IgnoreStories     bool   `yaml:"ignore_stories" json:"ignore_stories" env:"AUTO_RECEIVE_SCHEDULE_IGNORE_STORIES"`
+	IgnoreAvatars     bool   `yaml:"ignore_avatars" json:"ignore_avatars" env:"AUTO_RECEIVE_SCHEDULE_IGNORE_AVATARS"`
+	IgnoreStickers    bool   `yaml:"ignore_stickers" json:"ignore_stickers" env:"AUTO_RECEIVE_SCHEDULE_IGNORE_STICKERS"`
+	SendReadReceipts  bool   `yaml:"send_read_receipts" json:"send_read_receipts" env:"AUTO_RECEIVE_SCHEDULE_SEND_READ_RECEIPTS"`
+}
+
+type JsonRpcSettings struct {
+	IgnoreAttachments  bool   `yaml:"ignore_attachments" env:"JSON_RPC_IGNORE_ATTACHMENTS"`
+	IgnoreStories      bool   `yaml:"ignore_stories" env:"JSON_RPC_IGNORE_STORIES"`
+	IgnoreAvatars      bool   `yaml:"ignore_avatars" env:"JSON_RPC_IGNORE_AVATARS"`
+	IgnoreStickers     bool   `yaml:"ignore_stickers" env:"JSON_RPC_IGNORE_STICKERS"`
+	TrustNewIdentities string `yaml:"trust_new_identities" env:"JSON_RPC_TRUST_NEW_IDENTITIES"`
+}
+
+type SwaggerSettings struct {
+	Host                      string `yaml:"host" env:"SWAGGER_HOST"`
+	Ip                        string `yaml:"ip" env:"SWAGGER_IP"`
+	UseHttpsAsPreferredScheme bool   `yaml:"use_https_as_preferred_scheme" env:"SWAGGER_USE_HTTPS_AS_PREFERRED_SCHEME"`
+}
+
+type SignedUrlSettings struct {
+	Secret      string `yaml:"secret" env:"SIGNED_URL_SECRET" secret:"true"`
+	Validity    int    `yaml:"validity" env:"SIGNED_URL_VALIDITY"`
+	MaxValidity int    `yaml:"max_validity" env:"SIGNED_URL_MAX_VALIDITY"`
+	PublicUrl   string `yaml:"public_url" env:"PUBLIC_URL"`
+}
+
+// maxSignedUrlValidity is the upper limit of signed_urls.max_validity (10 years).
+const maxSignedUrlValidity = 10 * 365 * 24 * 60 * 60
+
+type PluginSettings struct {
+	Enabled        bool `yaml:"enabled" env:"ENABLE_PLUGINS"`
+	Timeout        int  `yaml:"timeout" env:"PLUGIN_TIMEOUT"`
+	ReloadInterval int  `yaml:"reload_interval" env:"PLUGIN_RELOAD_INTERVAL"`
+	StatePoolSize  int  `yaml:"state_pool_size" env:"PLUGIN_STATE_POOL_SIZE"`
+}
+
+// Settings contains the configuration of the REST API. The settings are read
+// from an (optional) YAML file. Every setting can be overridden with the
+// environment variable in its env tag.
+type Settings struct {
+	Port                           int                         `yaml:"port" env:"PORT"`
+	LogLevel                       string                      `yaml:"log_level" env:"LOG_LEVEL"`
+	Mode                           string                      `yaml:"mode" env:"MODE"`
+	DefaultSignalTextMode          string                      `yaml:"default_signal_text_mode" env:"DEFAULT_SIGNAL_TEXT_MODE"`
+	SignalCliCmdTimeout            int                         `yaml:"signal_cli_cmd_timeout" env:"SIGNAL_CLI_CMD_TIMEOUT"`
+	ReceiveWebhookUrl              string                      `yaml:"receive_webhook_url" env:"RECEIVE_WEBHOOK_URL"`
+	ReceiveAttachmentInlineMaxSize int64                       `yaml:"receive_attachment_inline_max_size" env:"RECEIVE_ATTACHMENT_INLINE_MAX_SIZE"`
+	IdempotencyKeyTtl              int                         `yaml:"idempotency_key_ttl" env:"IDEMPOTENCY_KEY_TTL"`
+	BroadcastMaxConcurrency        int                         `yaml:"broadcast_max_concurrency" env:"BROADCAST_MAX_CONCURRENCY"`
+	AttachmentRetentionDays        int                         `yaml:"attachment_retention_days" env:"ATTACHMENT_RETENTION_DAYS"`
+	AutoReceiveSchedule            AutoReceiveScheduleSettings `yaml:"auto_receive_schedule"`
+	JsonRpc                        JsonRpcSettings             `yaml:"json_rpc"`
+	Swagger                        SwaggerSettings             `yaml:"swagger"`
+	SignedUrls                     SignedUrlSettings           `yaml:"signed_urls"`
+	Plugins                        PluginSettings              `yaml:"plugins"`
+	sources                        map[string]string
+}
+
+const (
+	SettingSourceDefault = "default"
+	SettingSourceFile    = "file"
+	SettingSourceEnv     = "env"
+	SettingSourceApi     = "api"
+)
+
+// SettingsError contains all the problems that were found in the settings.
+type SettingsError struct {
+	Problems []string
+}
+
+func (e *SettingsError) Error() string {
+	return "Invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		sources:                 make(map[string]string),
+		Port:                    8080,
+		Mode:                    "normal",
+		DefaultSignalTextMode:   "normal",
+		IdempotencyKeyTtl:       86400,
+		BroadcastMaxConcurrency: 4,
+		AutoReceiveSchedule: AutoReceiveScheduleSettings{
+			ReceiveTimeout: 10,
+		},
+		SignedUrls: SignedUrlSettings{
+			Validity:    3600,
+			MaxValidity: 604800,
+		},
+		Plugins: PluginSettings{
+			Timeout:        60,
+			ReloadInterval: 5,
+			StatePoolSize:  4,
+		},
+	}
+}
+
+// LoadSettings reads the settings file (if a path is given), applies the
+// environment variable overrides and validates the result. All problems are
+// reported at once in a SettingsError.
+func LoadSettings(path string) (Settings, error) {
+	settings := DefaultSettings()
+	problems := []string{}
+
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return settings, err
+		}
+
+		err = yaml.UnmarshalStrict(data, &settings)
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			problems = append(problems, typeErr.Errors...)
+		} else if err != nil {
+			return settings, err
+		}
+
+		var fileSettings map[interface{}]interface{}
+		if yaml.Unmarshal(data, &fileSettings) == nil {
+			settings.setSources(fileSettings, "")
+		}
+	}
+
+	problems = append(problems, settings.applyEnvOverrides(reflect.ValueOf(&settings).Elem(), "")...)
+	problems = append(problems, settings.validate()...)
+
+	if len(problems) > 0 {
+		return settings, &SettingsError{Problems: problems}
+	}
+	return settings, nil
+}
+
+// setSources marks all settings in the config file as set by the file.
+func (s *Settings) setSources(fileSettings map[interface{}]interface{}, prefix string) {
+	for key, value := range fileSettings {
+		name := prefix + fmt.Sprint(key)
+		if nested, ok := value.(map[interface{}]interface{}); ok {
+			s.setSources(nested, name+".")
+			continue
+		}
+		s.sources[name] = SettingSourceFile
+	}
+}
+
+func (s *Settings) applyEnvOverrides(v reflect.Value, prefix string) []string {
+	problems := []string{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		name := prefix + yamlName(v.Type().Field(i))
+		if field.Kind() == reflect.Struct {
+			problems = append(problems, s.applyEnvOverrides(field, name+".")...)
+			continue
+		}
+
+		envName := v.Type().Field(i).Tag.Get("env")
+		value, exists := os.LookupEnv(envName)
+		if envName == "" || !exists || value == "" {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int, reflect.Int64:
+			intValue, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				problems = append(problems, envName+": '"+value+"' is not a number")
+				continue
+			}
+			field.SetInt(intValue)
+		case reflect.Bool:
+			boolValue, err := strconv.ParseBool(value)
+			if err != nil {
+				problems = append(problems, envName+": '"+value+"' needs to be either true or false")
+				continue
+			}
+			field.SetBool(boolValue)
+		}
+		s.sources[name] = SettingSourceEnv
+	}
+	return problems
+}
+
+func yamlName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
+}
+
+// Source returns where the value of a setting comes from (default, file, env or api).
+func (s *Settings) Source(key string) string {
+	if source, exists := s.sources[key]; exists {
+		return source
+	}
+	return SettingSourceDefault
+}
+
+// Values returns the values of all settings by their (dotted) name. The values
+// of secret settings are masked.
+func (s *Settings) Values() map[string]interface{} {
+	values := make(map[string]interface{})
+	collectValues(reflect.ValueOf(*s), "", values)
+	return values
+}
+
+func collectValues(v reflect.Value, prefix string, values map[string]interface{}) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := prefix + yamlName(field)
+		if v.Field(i).Kind() == reflect.Struct {
+			collectValues(v.Field(i), name+".", values)
+			continue
+		}
+
+		value := v.Field(i).Interface()
+		if field.Tag.Get("secret") == "true" && !v.Field(i).IsZero() {
+			value = "********"
+		}
+		values[name] = value
+	}
+}
+
+func (s *Settings) copySources() map[string]string {
+	sources := make(map[string]string)
+	for key, source := range s.sources {
+		sources[key] = source
+	}
+	return sources
+}
+
+func (s *Settings) IsJsonRpcMode() bool {
+	return s.Mode == "json-rpc" || s.Mode == "json-rpc-native"
+}
+
+func (s *Settings) validate() []string {
+	problems := []string{}
+
+	if s.Port < 1 || s.Port > 65535 {
+		problems = append(problems, "port: needs to be between 1 and 65535")
+	}
+	if !StringInSlice(s.LogLevel, []string{"", "debug", "info", "warn", "error"}) {
+		problems = append(problems, "log_level: needs to be one of debug, info, warn or error")
+	}
+	if !StringInSlice(s.Mode, []string{"normal", "native", "json-rpc", "json-rpc-native"}) {
+		problems = append(problems, "mode: needs to be one of normal, native, json-rpc or json-rpc-native")
+	}
+	if !StringInSlice(s.DefaultSignalTextMode, []string{"normal", "styled"}) {
+		problems = append(problems, "default_signal_text_mode: needs to be either normal or styled")
+	}
+	if s.SignalCliCmdTimeout < 0 {
+		problems = append(problems, "signal_cli_cmd_timeout: needs to be a positive number (in seconds)")
+	} else if s.SignalCliCmdTimeout > 0 && s.IsJsonRpcMode() {
+		problems = append(problems, "signal_cli_cmd_timeout: can't be used with mode json-rpc")
+	}
+	if s.ReceiveWebhookUrl != "" {
+		if !s.IsJsonRpcMode() {
+			problems = append(problems, "receive_webhook_url: can only be used with mode json-rpc")
+		}
+		if _, err := url.ParseRequestURI(s.ReceiveWebhookUrl); err != nil {
+			problems = append(problems, "receive_webhook_url: invalid URL")
+		}
+	}
+	if s.ReceiveAttachmentInlineMaxSize < 0 {
+		problems = append(problems, "receive_attachment_inline_max_size: needs to be a positive number (in bytes)")
+	}
+	if s.IdempotencyKeyTtl <= 0 {
+		problems = append(problems, "idempotency_key_ttl: needs to be a positive number (in seconds)")
+	}
+	if s.BroadcastMaxConcurrency <= 0 {
+		problems = append(problems, "broadcast_max_concurrency: needs to be a positive number")
+	}
+	if s.AttachmentRetentionDays < 0 {
+		problems = append(problems, "attachment_retention_days: needs to be a positive number (in days)")
+	}
+
+	if s.AutoReceiveSchedule.Schedule != "" {
+		if s.IsJsonRpcMode() {
+			problems = append(problems, "auto_receive_schedule.schedule: can't be used with mode json-rpc")
+		}
+		p := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+		if _, err := p.Parse(s.AutoReceiveSchedule.Schedule); err != nil {
+			problems = append(problems, "auto_receive_schedule.schedule: invalid schedule: "+err.Error())
+		}
+	}
+	if s.AutoReceiveSchedule.ReceiveTimeout <= 0 {
+		problems = append(problems, "auto_receive_schedule.receive_timeout: needs to be a positive number (in seconds)")
+	}
+
+	if !StringInSlice(s.JsonRpc.TrustNewIdentities, []string{"", "on-first-use", "always", "never"}) {
+		problems = append(problems, "json_rpc.trust_new_identities: needs to be one of on-first-use, always or never")
+	}
+
+	if s.SignedUrls.Validity <= 0 {
+		problems = append(problems, "signed_urls.validity: needs to be a positive number (in seconds)")
+	}
+	if s.SignedUrls.MaxValidity < s.SignedUrls.Validity || s.SignedUrls.MaxValidity > maxSignedUrlValidity {
+		problems = append(problems, "signed_urls.max_validity: needs to be between signed_urls.validity and "+
+			strconv.Itoa(maxSignedUrlValidity)+" (in seconds)")
+	}
+
+	if s.Plugins.Timeout < 0 {
+		problems = append(problems, "plugins.timeout: needs to be a positive number (in seconds)")
+	}
+	if s.Plugins.ReloadInterval < 0 {
+		problems = append(problems, "plugins.reload_interval: needs to be a positive number (in seconds)")
+	}
+	if s.Plugins.StatePoolSize < 0 {
+		problems = append(problems, "plugins.state_pool_size: needs to be a positive number")
+	}
+
+	return problems
+}
+
+// Get returns the value of a setting, e.g. "mode" or "plugins.enabled".
+func (s *Settings) Get(key string) (string, error) {
+	v := reflect.ValueOf(*s)
+	for _, name := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return "", errors.New("Unknown setting " + key)
+		}
+
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" && yamlName(v.Type().Field(i)) == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", errors.New("Unknown setting " + key)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	}
+	return "", errors.New("Setting " + key + " is not a single value")
+}
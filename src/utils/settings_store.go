@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type SettingValue struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source" enums:"default,file,env,api"`
+}
+
+// SettingsStore contains the effective settings: the settings from the config
+// file and the environment variables, merged with the settings that were
+// changed via the REST API (which take precedence). The changes are persisted
+// in the api-config.yml.
+type SettingsStore struct {
+	mutex       sync.RWMutex
+	updateMutex sync.Mutex // makes sure that the listeners see the updates in the right order
+	base        Settings
+	settings    Settings
+	overrides   SettingsOverrides
+	apiConfig   *SignalCliApiConfig
+	listeners   []func(settings Settings)
+}
+
+// settingsOverrideKeys contains the settings that can be changed at runtime.
+var settingsOverrideKeys = []string{"log_level", "receive_webhook_url", "default_signal_text_mode", "auto_receive_schedule",
+	"attachment_retention_days"}
+
+// NewSettingsStore merges the base settings with the persisted overrides.
+// Overrides that are invalid (e.g. because the mode was changed in the meantime
+// and the override can't be used with the new mode) are ignored, so that they
+// don't prevent the REST API from starting.
+func NewSettingsStore(base Settings, apiConfig *SignalCliApiConfig) (*SettingsStore, error) {
+	overrides := dropInvalidSettingsOverrides(base, apiConfig.GetSettingsOverrides())
+	settings, err := mergeSettings(base, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SettingsStore{
+		base:      base,
+		settings:  settings,
+		overrides: overrides,
+		apiConfig: apiConfig,
+	}, nil
+}
+
+func dropInvalidSettingsOverrides(base Settings, overrides SettingsOverrides) SettingsOverrides {
+	if _, err := mergeSettings(base, overrides); err == nil {
+		return overrides
+	}
+
+	// check every override on its own
+	for _, key := range settingsOverrideKeys {
+		single := overrides
+		for _, otherKey := range settingsOverrideKeys {
+			if otherKey != key {
+				single.reset(otherKey)
+			}
+		}
+		if _, err := mergeSettings(base, single); err != nil {
+			log.Warn("Ignoring the setting ", key, " that was changed via the REST API: ", err.Error())
+			overrides.reset(key)
+		}
+	}
+
+	// the overrides might still be invalid in combination
+	if _, err := mergeSettings(base, overrides); err != nil {
+		log.Warn("Ignoring all settings that were changed via the REST API: ", err.Error())
+		return SettingsOverrides{}
+	}
+	return overrides
+}
+
+func mergeSettings(base Settings, overrides SettingsOverrides) (Settings, error) {
+	settings := base
+	settings.sources = base.copySources()
+
+	if overrides.LogLevel != nil {
+		settings.LogLevel = *overrides.LogLevel
+		settings.sources["log_level"] = SettingSourceApi
+	}
+	if overrides.ReceiveWebhookUrl != nil {
+		settings.ReceiveWebhookUrl = *overrides.ReceiveWebhookUrl
+		settings.sources["receive_webhook_url"] = SettingSourceApi
+	}
+	if overrides.DefaultSignalTextMode != nil {
+		settings.DefaultSignalTextMode = *overrides.DefaultSignalTextMode
+		settings.sources["default_signal_text_mode"] = SettingSourceApi
+	}
+	if overrides.AutoReceiveSchedule != nil {
+		settings.AutoReceiveSchedule = *overrides.AutoReceiveSchedule
+		for key := range settings.Values() {
+			if strings.HasPrefix(key, "auto_receive_schedule.") {
+				settings.sources[key] = SettingSourceApi
+			}
+		}
+	}
+	if overrides.AttachmentRetentionDays != nil {
+		settings.AttachmentRetentionDays = *overrides.AttachmentRetentionDays
+		settings.sources["attachment_retention_days"] = SettingSourceApi
+	}
+
+	if problems := settings.validate(); len(problems) > 0 {
+		return settings, &SettingsError{Problems: problems}
+	}
+	return settings, nil
+}
+
+// OnChange registers a function that is called with the new settings whenever
+// the settings are changed.
+func (s *SettingsStore) OnChange(listener func(settings Settings)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *SettingsStore) Get() Settings {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.settings
+}
+
+func (s *SettingsStore) Overrides() SettingsOverrides {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.overrides
+}
+
+// Values returns the values of all settings together with their source.
+func (s *SettingsStore) Values() map[string]SettingValue {
+	settings := s.Get()
+	values := make(map[string]SettingValue)
+	for key, value := range settings.Values() {
+		values[key] = SettingValue{Value: value, Source: settings.Source(key)}
+	}
+	return values
+}
+
+// Update applies the given overrides (on top of the current overrides) and
+// trust modes. The changes are validated and persisted first - in case
+// something is invalid or the changes can't be persisted, nothing is changed.
+func (s *SettingsStore) Update(update SettingsOverrides, reset []string, trustModes map[string]SignalCliTrustMode) error {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+
+	// the overrides are only changed here, so they can be read without holding the mutex
+	overrides := s.overrides
+	for _, key := range reset {
+		if !overrides.reset(key) {
+			return &SettingsError{Problems: []string{key + ": can't be changed at runtime"}}
+		}
+	}
+	if update.LogLevel != nil {
+		overrides.LogLevel = update.LogLevel
+	}
+	if update.ReceiveWebhookUrl != nil {
+		overrides.ReceiveWebhookUrl = update.ReceiveWebhookUrl
+	}
+	if update.DefaultSignalTextMode != nil {
+		overrides.DefaultSignalTextMode = update.DefaultSignalTextMode
+	}
+	if update.AutoReceiveSchedule != nil {
+		overrides.AutoReceiveSchedule = update.AutoReceiveSchedule
+	}
+	if update.AttachmentRetentionDays != nil {
+		overrides.AttachmentRetentionDays = update.AttachmentRetentionDays
+	}
+
+	settings, err := mergeSettings(s.base, overrides)
+	if err != nil {
+		return err
+	}
+
+	err = s.apiConfig.Update(overrides, trustModes)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.settings = settings
+	s.overrides = overrides
+	listeners := s.listeners
+	s.mutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(settings)
+	}
+	return nil
+}
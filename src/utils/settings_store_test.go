@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSettingsStoreUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-config.yml")
+	apiConfig := NewSignalCliApiConfig()
+	expectEqual(t, apiConfig.Load(path) == nil, true)
+
+	base := DefaultSettings()
+	store, err := NewSettingsStore(base, apiConfig)
+	expectEqual(t, err == nil, true)
+
+	changed := 0
+	store.OnChange(func(settings Settings) {
+		changed += 1
+	})
+
+	textMode := "styled"
+	retentionDays := 30
+	err = store.Update(SettingsOverrides{DefaultSignalTextMode: &textMode, AttachmentRetentionDays: &retentionDays}, nil, map[string]SignalCliTrustMode{"+431212131491291": AlwaysTrust})
+	expectEqual(t, err == nil, true)
+	expectEqual(t, changed == 1, true)
+	expectEqual(t, store.Get().DefaultSignalTextMode == "styled", true)
+	expectEqual(t, store.Values()["attachment_retention_days"].Source == SettingSourceApi, true)
+
+	// invalid changes are rejected and nothing is changed
+	invalidTextMode := "bold"
+	err = store.Update(SettingsOverrides{DefaultSignalTextMode: &invalidTextMode}, nil, nil)
+	_, isSettingsError := err.(*SettingsError)
+	expectEqual(t, isSettingsError, true)
+	expectEqual(t, changed == 1, true)
+	expectEqual(t, store.Get().DefaultSignalTextMode == "styled", true)
+
+	// the changes survive a restart
+	reloadedApiConfig := NewSignalCliApiConfig()
+	expectEqual(t, reloadedApiConfig.Load(path) == nil, true)
+	reloadedStore, err := NewSettingsStore(base, reloadedApiConfig)
+	expectEqual(t, err == nil, true)
+	expectEqual(t, reloadedStore.Get().AttachmentRetentionDays == 30, true)
+	trustMode, err := reloadedApiConfig.GetTrustModeForNumber("+431212131491291")
+	expectEqual(t, err == nil && trustMode == AlwaysTrust, true)
+
+	err = reloadedStore.Update(SettingsOverrides{}, []string{"default_signal_text_mode"}, nil)
+	expectEqual(t, err == nil, true)
+	expectEqual(t, reloadedStore.Get().DefaultSignalTextMode == base.DefaultSignalTextMode, true)
+	expectEqual(t, reloadedStore.Values()["default_signal_text_mode"].Source == SettingSourceDefault, true)
+
+	err = reloadedStore.Update(SettingsOverrides{}, []string{"port"}, nil)
+	expectEqual(t, err != nil, true)
+}
+
+func TestSettingsStoreIgnoresInvalidOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-config.yml")
+	apiConfig := NewSignalCliApiConfig()
+	expectEqual(t, apiConfig.Load(path) == nil, true)
+
+	// the webhook was configured in json-rpc mode, but the api was restarted in normal mode since then
+	webhookUrl := "http://localhost:8080/webhook"
+	retentionDays := 30
+	err := apiConfig.Update(SettingsOverrides{ReceiveWebhookUrl: &webhookUrl, AttachmentRetentionDays: &retentionDays}, nil)
+	expectEqual(t, err == nil, true)
+
+	base := DefaultSettings()
+	expectEqual(t, base.IsJsonRpcMode(), false)
+	store, err := NewSettingsStore(base, apiConfig)
+	expectEqual(t, err == nil, true)
+	expectEqual(t, store.Get().ReceiveWebhookUrl == "", true)
+	expectEqual(t, store.Overrides().ReceiveWebhookUrl == nil, true)
+	expectEqual(t, store.Get().AttachmentRetentionDays == 30, true)
+	expectEqual(t, store.Values()["attachment_retention_days"].Source == SettingSourceApi, true)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSettingsFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSettingsDefaults(t *testing.T) {
+	settings, err := LoadSettings("")
+	expectEqual(t, err == nil, true)
+	expectEqual(t, settings.Port == 8080 && settings.Mode == "normal" && settings.Plugins.Timeout == 60, true)
+}
+
+func TestLoadSettingsFileAndEnvOverrides(t *testing.T) {
+	path := writeSettingsFile(t, "port: 9000\nmode: json-rpc\njson_rpc:\n  ignore_stories: true\nplugins:\n  enabled: true\n  timeout: 10\n")
+	t.Setenv("PLUGIN_TIMEOUT", "20")
+	t.Setenv("JSON_RPC_IGNORE_ATTACHMENTS", "true")
+	t.Setenv("RECEIVE_WEBHOOK_URL", "http://localhost:5000/hook")
+	t.Setenv("BROADCAST_MAX_CONCURRENCY", "")
+
+	settings, err := LoadSettings(path)
+	expectEqual(t, err == nil, true)
+	expectEqual(t, settings.Port == 9000 && settings.IsJsonRpcMode(), true)
+	expectEqual(t, settings.JsonRpc.IgnoreStories && settings.JsonRpc.IgnoreAttachments, true)
+	expectEqual(t, settings.Plugins.Enabled && settings.Plugins.Timeout == 20, true)
+	expectEqual(t, settings.ReceiveWebhookUrl == "http://localhost:5000/hook", true)
+	expectEqual(t, settings.BroadcastMaxConcurrency == 4, true)
+}
+
+func TestLoadSettingsReportsAllProblems(t *testing.T) {
+	path := writeSettingsFile(t, "port: abc\nmode: json-rpc\nunknown_setting: 1\nauto_receive_schedule:\n  schedule: \"* * *\"\n")
+	t.Setenv("IDEMPOTENCY_KEY_TTL", "forever")
+	t.Setenv("DEFAULT_SIGNAL_TEXT_MODE", "fancy")
+
+	_, err := LoadSettings(path)
+	settingsErr, ok := err.(*SettingsError)
+	if !ok {
+		t.Fatalf("expected a SettingsError, got %v", err)
+	}
+
+	// port (type), unknown_setting, IDEMPOTENCY_KEY_TTL, default_signal_text_mode, the
+	// auto receive schedule in json-rpc mode and the invalid schedule itself
+	if len(settingsErr.Problems) != 6 {
+		t.Fatalf("unexpected problems: %v", settingsErr.Problems)
+	}
+}
+
+func TestLoadSettingsSignedUrlMaxValidity(t *testing.T) {
+	path := writeSettingsFile(t, "signed_urls:\n  validity: 7200\n  max_validity: 3600\n")
+	_, err := LoadSettings(path)
+	expectEqual(t, err != nil, true)
+
+	t.Setenv("SIGNED_URL_MAX_VALIDITY", "99999999999")
+	_, err = LoadSettings("")
+	expectEqual(t, err != nil, true)
+}
+
+func TestSettingsGet(t *testing.T) {
+	settings := DefaultSettings()
+
+	value, err := settings.Get("mode")
+	expectEqual(t, err == nil && value == "normal", true)
+	value, err = settings.Get("plugins.reload_interval")
+	expectEqual(t, err == nil && value == "5", true)
+	value, err = settings.Get("signed_urls.public_url")
+	expectEqual(t, err == nil && value == "", true)
+
+	_, err = settings.Get("plugins")
+	expectEqual(t, err != nil, true)
+	_, err = settings.Get("plugins.unknown")
+	expectEqual(t, err != nil, true)
+}
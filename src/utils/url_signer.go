@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type UrlSigner struct {
+	secret []byte
+}
+
+// NewUrlSigner creates a signer for time-limited download URLs. If no secret is
+// provided, a random one is generated - signed URLs then become invalid as soon
+// as the REST API is restarted.
+func NewUrlSigner(secret string) (*UrlSigner, error) {
+	if secret != "" {
+		return &UrlSigner{secret: []byte(secret)}, nil
+	}
+
+	randomSecret := make([]byte, 32)
+	_, err := rand.Read(randomSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &UrlSigner{secret: randomSecret}, nil
+}
+
+func (u *UrlSigner) signature(path string, expires string) string {
+	mac := hmac.New(sha256.New, u.secret)
+	mac.Write([]byte(path + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns the given (escaped) path with the query parameters 'expires' and
+// 'signature' appended.
+func (u *UrlSigner) Sign(path string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", u.signature(path, expires))
+	return path + "?" + query.Encode()
+}
+
+func (u *UrlSigner) Verify(path string, expires string, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("Invalid expiration timestamp")
+	}
+
+	if !hmac.Equal([]byte(u.signature(path, expires)), []byte(signature)) {
+		return errors.New("Invalid signature")
+	}
+
+	if time.Now().Unix() > expiresAt {
+		return errors.New("Signed URL expired")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseSignedUrl(t *testing.T, signedUrl string) (string, string, string) {
+	u, err := url.Parse(signedUrl)
+	if err != nil {
+		t.Fatalf("couldn't parse signed url: %v", err)
+	}
+	return u.EscapedPath(), u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestUrlSignerSignAndVerify(t *testing.T) {
+	signer, _ := NewUrlSigner("secret")
+	signedUrl := signer.Sign("/v1/attachments/abc.png", time.Now().Add(time.Hour))
+	if !strings.HasPrefix(signedUrl, "/v1/attachments/abc.png?") {
+		t.Fatalf("unexpected signed url %s", signedUrl)
+	}
+
+	path, expires, signature := parseSignedUrl(t, signedUrl)
+	expectEqual(t, signer.Verify(path, expires, signature) == nil, true)
+}
+
+func TestUrlSignerRejectsOtherPath(t *testing.T) {
+	signer, _ := NewUrlSigner("secret")
+	_, expires, signature := parseSignedUrl(t, signer.Sign("/v1/attachments/abc.png", time.Now().Add(time.Hour)))
+	expectEqual(t, signer.Verify("/v1/attachments/other.png", expires, signature) == nil, false)
+}
+
+func TestUrlSignerRejectsOtherSecret(t *testing.T) {
+	signer, _ := NewUrlSigner("secret")
+	otherSigner, _ := NewUrlSigner("")
+	path, expires, signature := parseSignedUrl(t, signer.Sign("/v1/attachments/abc.png", time.Now().Add(time.Hour)))
+	expectEqual(t, otherSigner.Verify(path, expires, signature) == nil, false)
+}
+
+func TestUrlSignerRejectsExpiredUrl(t *testing.T) {
+	signer, _ := NewUrlSigner("secret")
+	path, expires, signature := parseSignedUrl(t, signer.Sign("/v1/attachments/abc.png", time.Now().Add(-time.Minute)))
+	expectEqual(t, signer.Verify(path, expires, signature) == nil, false)
+}